@@ -16,3 +16,11 @@ func LoadConfig() {
 func GetString(key string) string {
 	return viper.GetString(key)
 }
+
+func GetInt(key string) int {
+	return viper.GetInt(key)
+}
+
+func GetFloat(key string) float64 {
+	return viper.GetFloat64(key)
+}
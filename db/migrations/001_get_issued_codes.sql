@@ -0,0 +1,7 @@
+-- every code ever issued, used to restore the redis code counter on startup.
+CREATE OR REPLACE FUNCTION func_GetIssuedCodes()
+RETURNS TABLE (code TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT gs.code::TEXT FROM "GameStatus" gs;
+$$;
@@ -0,0 +1,72 @@
+CREATE TABLE IF NOT EXISTS "VoucherBatches" (
+    id            SERIAL PRIMARY KEY,
+    label         TEXT        NOT NULL,
+    "gameId"      INT         NOT NULL,
+    name          TEXT        NOT NULL,
+    price         INT         NOT NULL,
+    "playTime"    INT,
+    levels        INT,
+    "totalCodes"  INT         NOT NULL,
+    "expiresAt"   TIMESTAMPTZ,
+    "createdBy"   INT         NOT NULL,
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "VoucherCodes" (
+    code       TEXT PRIMARY KEY,
+    "batchId"  INT  NOT NULL REFERENCES "VoucherBatches"(id)
+);
+
+CREATE OR REPLACE FUNCTION func_InsertVoucherBatch(
+    p_label TEXT, p_game_id INT, p_name TEXT, p_price INT, p_play_time INT,
+    p_levels INT, p_total INT, p_expires_at TIMESTAMPTZ, p_created_by INT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "VoucherBatches" (label, "gameId", name, price, "playTime", levels, "totalCodes", "expiresAt", "createdBy")
+    VALUES (p_label, p_game_id, p_name, p_price, p_play_time, p_levels, p_total, p_expires_at, p_created_by)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertVoucherCode(p_batch_id INT, p_code TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "VoucherCodes" (code, "batchId") VALUES (p_code, p_batch_id);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetVoucherBatches()
+RETURNS TABLE (id INT, label TEXT, game_id INT, name TEXT, price INT, play_time INT, levels INT,
+               total_codes INT, redeemed INT, expires_at TIMESTAMPTZ, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT b.id, b.label, b."gameId", b.name, b.price, b."playTime", b.levels, b."totalCodes",
+           COUNT(gs.code) FILTER (WHERE gs."isPlayed")::INT, b."expiresAt", b."createdAt"
+    FROM "VoucherBatches" b
+    LEFT JOIN "VoucherCodes" vc ON vc."batchId" = b.id
+    LEFT JOIN "GameStatus" gs ON gs.code = vc.code
+    GROUP BY b.id
+    ORDER BY b."createdAt" DESC;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetVoucherCodes(p_batch_id INT)
+RETURNS TABLE (code TEXT, is_played BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT vc.code, COALESCE(gs."isPlayed", FALSE)
+    FROM "VoucherCodes" vc
+    LEFT JOIN "GameStatus" gs ON gs.code = vc.code
+    WHERE vc."batchId" = p_batch_id
+    ORDER BY vc.code;
+$$;
+
+-- NULL when the code is not a voucher or the batch never expires.
+CREATE OR REPLACE FUNCTION func_GetVoucherExpiry(p_code TEXT)
+RETURNS TIMESTAMPTZ
+LANGUAGE sql STABLE
+AS $$
+    SELECT b."expiresAt"
+    FROM "VoucherCodes" vc
+    JOIN "VoucherBatches" b ON b.id = vc."batchId"
+    WHERE vc.code = p_code;
+$$;
@@ -0,0 +1,98 @@
+ALTER TABLE "GameStatus" ADD COLUMN IF NOT EXISTS "expiresAt" TIMESTAMPTZ;
+ALTER TABLE "GameStatus" ADD COLUMN IF NOT EXISTS "isExpired" BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- "gameId" NULL is the default policy for every game without its own row.
+CREATE TABLE IF NOT EXISTS "CodeValidityPolicies" (
+    id                 SERIAL PRIMARY KEY,
+    "gameId"           INT UNIQUE,
+    "validDays"        INT     NOT NULL CHECK ("validDays" > 0),
+    "convertToCredit"  BOOLEAN NOT NULL DEFAULT FALSE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "CodeValidityPolicies_default" ON "CodeValidityPolicies" ((1)) WHERE "gameId" IS NULL;
+
+-- credit left over from expired codes, claimable by the payment reference.
+CREATE TABLE IF NOT EXISTS "WalletLedger" (
+    id           SERIAL PRIMARY KEY,
+    "paymentId"  TEXT        NOT NULL,
+    code         TEXT,
+    amount       INT         NOT NULL,
+    reason       TEXT        NOT NULL,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- p_expires_at wins when given (vouchers), otherwise the policy of the game or the default one.
+CREATE OR REPLACE FUNCTION func_SetCodeExpiry(p_code TEXT, p_expires_at TIMESTAMPTZ)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "GameStatus" gs
+    SET "expiresAt" = COALESCE(p_expires_at, gs."createdAt" + make_interval(days => (
+        SELECT p."validDays" FROM "CodeValidityPolicies" p
+        WHERE p."gameId" = gs."gameId" OR p."gameId" IS NULL
+        ORDER BY p."gameId" NULLS LAST
+        LIMIT 1)))
+    WHERE gs.code = p_code;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetCodeExpiry(p_code TEXT)
+RETURNS TABLE (expires_at TIMESTAMPTZ, is_expired BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT gs."expiresAt", gs."isExpired" FROM "GameStatus" gs WHERE gs.code = p_code;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetCodeValidityPolicies()
+RETURNS TABLE (game_id INT, valid_days INT, convert_to_credit BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "gameId", "validDays", "convertToCredit" FROM "CodeValidityPolicies" ORDER BY "gameId" NULLS FIRST;
+$$;
+
+CREATE OR REPLACE FUNCTION func_UpsertCodeValidityPolicy(p_game_id INT, p_valid_days INT, p_convert_to_credit BOOLEAN)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE "CodeValidityPolicies"
+    SET "validDays" = p_valid_days, "convertToCredit" = p_convert_to_credit
+    WHERE "gameId" IS NOT DISTINCT FROM p_game_id;
+
+    IF NOT FOUND THEN
+        INSERT INTO "CodeValidityPolicies" ("gameId", "validDays", "convertToCredit")
+        VALUES (p_game_id, p_valid_days, p_convert_to_credit);
+    END IF;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeleteCodeValidityPolicy(p_game_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    DELETE FROM "CodeValidityPolicies" WHERE "gameId" IS NOT DISTINCT FROM p_game_id;
+$$;
+
+-- marks every unplayed code past its expiry, credits the value when the policy says so.
+-- vouchers are never converted, nobody paid for them.
+CREATE OR REPLACE FUNCTION func_ExpireCodes()
+RETURNS TABLE (code TEXT, credited INT)
+LANGUAGE sql
+AS $$
+    WITH expired AS (
+        UPDATE "GameStatus" gs
+        SET "isExpired" = TRUE
+        WHERE NOT gs."isPlayed" AND NOT gs."isExpired" AND gs."expiresAt" < now()
+        RETURNING gs.code, gs."gameId", gs.price, gs."paymentId"
+    ), credits AS (
+        INSERT INTO "WalletLedger" ("paymentId", code, amount, reason)
+        SELECT e."paymentId", e.code, e.price, 'expired code'
+        FROM expired e
+        WHERE e."paymentId" NOT LIKE 'voucher-%'
+          AND COALESCE((SELECT p."convertToCredit" FROM "CodeValidityPolicies" p
+                        WHERE p."gameId" = e."gameId" OR p."gameId" IS NULL
+                        ORDER BY p."gameId" NULLS LAST LIMIT 1), FALSE)
+        RETURNING code, amount
+    )
+    SELECT e.code::TEXT, COALESCE(c.amount, 0) FROM expired e LEFT JOIN credits c ON c.code = e.code;
+$$;
+
+DROP FUNCTION IF EXISTS func_GetVoucherExpiry(TEXT);
@@ -0,0 +1,42 @@
+CREATE TABLE IF NOT EXISTS "Players" (
+    id           SERIAL PRIMARY KEY,
+    phone        TEXT        NOT NULL UNIQUE,
+    name         TEXT,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "lastLogin"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "GameStatus" ADD COLUMN IF NOT EXISTS "playerId" INT REFERENCES "Players"(id);
+ALTER TABLE "WalletLedger" ADD COLUMN IF NOT EXISTS "playerId" INT REFERENCES "Players"(id);
+
+-- creates the player on the first login, returns the id either way.
+CREATE OR REPLACE FUNCTION func_UpsertPlayer(p_phone TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "Players" (phone) VALUES (p_phone)
+    ON CONFLICT (phone) DO UPDATE SET "lastLogin" = now()
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetPlayer(p_player_id INT)
+RETURNS TABLE (id INT, phone TEXT, name TEXT, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, phone, name, "createdAt" FROM "Players" WHERE id = p_player_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_UpdatePlayerName(p_player_id INT, p_name TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "Players" SET name = p_name WHERE id = p_player_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_AttachCodeToPlayer(p_code TEXT, p_player_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "GameStatus" SET "playerId" = p_player_id WHERE code = p_code;
+    UPDATE "WalletLedger" SET "playerId" = p_player_id WHERE code = p_code;
+$$;
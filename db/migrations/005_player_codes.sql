@@ -0,0 +1,25 @@
+ALTER TABLE "GameStatus" ADD COLUMN IF NOT EXISTS "machineId" TEXT;
+
+CREATE OR REPLACE FUNCTION func_RecordCodeMachine(p_code TEXT, p_machine_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "GameStatus" SET "machineId" = p_machine_id WHERE code = p_code;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetPlayerCodes(p_player_id INT)
+RETURNS TABLE (code TEXT, game_id INT, name TEXT, play_time INT, levels INT, price INT,
+               purchased_at TIMESTAMPTZ, expires_at TIMESTAMPTZ, status TEXT, machine_id TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT gs.code, gs."gameId", gs.name, gs."playTime", gs.levels, gs.price, gs."createdAt", gs."expiresAt",
+           CASE
+               WHEN gs."isPlayed" THEN 'played'
+               WHEN gs."isExpired" OR gs."expiresAt" < now() THEN 'expired'
+               ELSE 'unplayed'
+           END,
+           gs."machineId"
+    FROM "GameStatus" gs
+    WHERE gs."playerId" = p_player_id
+    ORDER BY gs."createdAt" DESC;
+$$;
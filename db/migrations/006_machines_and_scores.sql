@@ -0,0 +1,69 @@
+CREATE TABLE IF NOT EXISTS "Machines" (
+    id           TEXT PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    venue        TEXT        NOT NULL,
+    "secretKey"  TEXT        NOT NULL,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "Scores" (
+    id           SERIAL PRIMARY KEY,
+    "gameId"     INT         NOT NULL,
+    code         TEXT        NOT NULL UNIQUE, -- one score per code, a replayed request can't add another
+    "machineId"  TEXT        NOT NULL REFERENCES "Machines"(id),
+    venue        TEXT        NOT NULL,
+    "playerId"   INT REFERENCES "Players"(id),
+    name         TEXT        NOT NULL,
+    score        BIGINT      NOT NULL,
+    "isRemoved"  BOOLEAN     NOT NULL DEFAULT FALSE,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION func_InsertMachine(p_id TEXT, p_name TEXT, p_venue TEXT, p_secret_key TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "Machines" (id, name, venue, "secretKey") VALUES (p_id, p_name, p_venue, p_secret_key);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetMachines()
+RETURNS TABLE (id TEXT, name TEXT, venue TEXT, secret_key TEXT, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, name, venue, "secretKey", "createdAt" FROM "Machines" ORDER BY id;
+$$;
+
+-- NULL when the code doesn't exist or was bought for another game.
+CREATE OR REPLACE FUNCTION func_InsertScore(p_game_id INT, p_code TEXT, p_machine_id TEXT, p_name TEXT, p_score BIGINT)
+RETURNS TABLE (id INT, player_id INT, name TEXT, venue TEXT, created_at TIMESTAMPTZ)
+LANGUAGE sql
+AS $$
+    INSERT INTO "Scores" ("gameId", code, "machineId", venue, "playerId", name, score)
+    SELECT p_game_id, gs.code, m.id, m.venue, gs."playerId", COALESCE(p.name, p_name), p_score
+    FROM "GameStatus" gs
+    JOIN "Machines" m ON m.id = p_machine_id
+    LEFT JOIN "Players" p ON p.id = gs."playerId"
+    WHERE gs.code = p_code AND gs."gameId" = p_game_id
+    RETURNING id, "playerId", name, venue, "createdAt";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetScores(p_game_id INT, p_limit INT)
+RETURNS TABLE (id INT, game_id INT, code TEXT, machine_id TEXT, venue TEXT, player_id INT,
+               name TEXT, score BIGINT, is_removed BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, "gameId", code, "machineId", venue, "playerId", name, score, "isRemoved", "createdAt"
+    FROM "Scores"
+    WHERE p_game_id IS NULL OR "gameId" = p_game_id
+    ORDER BY "createdAt" DESC
+    LIMIT p_limit;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RemoveScore(p_score_id INT)
+RETURNS TABLE (id INT, game_id INT, code TEXT, machine_id TEXT, venue TEXT, player_id INT,
+               name TEXT, score BIGINT, is_removed BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql
+AS $$
+    UPDATE "Scores" SET "isRemoved" = TRUE WHERE id = p_score_id
+    RETURNING id, "gameId", code, "machineId", venue, "playerId", name, score, "isRemoved", "createdAt";
+$$;
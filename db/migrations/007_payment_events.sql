@@ -0,0 +1,49 @@
+-- filled from the razorpay webhooks, the amounts are in paise.
+CREATE TABLE IF NOT EXISTS "CapturedPayments" (
+    "paymentId"   TEXT PRIMARY KEY,
+    "orderId"     TEXT        NOT NULL,
+    amount        BIGINT      NOT NULL,
+    method        TEXT        NOT NULL,
+    purpose       TEXT        NOT NULL,
+    "playerId"    INT REFERENCES "Players"(id),
+    "gameId"      INT,
+    "capturedAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "Refunds" (
+    "refundId"    TEXT PRIMARY KEY,
+    "paymentId"   TEXT        NOT NULL REFERENCES "CapturedPayments"("paymentId"),
+    amount        BIGINT      NOT NULL,
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- FALSE when the webhook is a retry of an already recorded capture.
+CREATE OR REPLACE FUNCTION func_RecordPaymentCapture(p_payment_id TEXT, p_order_id TEXT, p_amount BIGINT,
+    p_method TEXT, p_purpose TEXT, p_player_id INT, p_game_id INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO "CapturedPayments" ("paymentId", "orderId", amount, method, purpose, "playerId", "gameId")
+    VALUES (p_payment_id, p_order_id, p_amount, p_method, p_purpose, p_player_id, p_game_id)
+    ON CONFLICT ("paymentId") DO NOTHING;
+    RETURN FOUND;
+END;
+$$;
+
+-- returns the captured payment the refund belongs to, nothing when the refund was already recorded.
+CREATE OR REPLACE FUNCTION func_RecordRefund(p_refund_id TEXT, p_payment_id TEXT, p_amount BIGINT)
+RETURNS TABLE (payment_amount BIGINT, player_id INT, purpose TEXT)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO "Refunds" ("refundId", "paymentId", amount)
+    VALUES (p_refund_id, p_payment_id, p_amount)
+    ON CONFLICT ("refundId") DO NOTHING;
+
+    IF FOUND THEN
+        RETURN QUERY SELECT cp.amount, cp."playerId", cp.purpose
+                     FROM "CapturedPayments" cp WHERE cp."paymentId" = p_payment_id;
+    END IF;
+END;
+$$;
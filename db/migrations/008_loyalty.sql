@@ -0,0 +1,170 @@
+-- every matching rule multiplies the earned points, "gameId" and weekday NULL match everything.
+CREATE TABLE IF NOT EXISTS "LoyaltyRules" (
+    id           SERIAL PRIMARY KEY,
+    "gameId"     INT,
+    weekday      INT CHECK (weekday BETWEEN 0 AND 6), -- 0 is sunday
+    multiplier   NUMERIC(5, 2) NOT NULL CHECK (multiplier > 0),
+    description  TEXT          NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS "LoyaltyRewards" (
+    id           SERIAL PRIMARY KEY,
+    title        TEXT    NOT NULL,
+    points       INT     NOT NULL CHECK (points > 0),
+    kind         TEXT    NOT NULL CHECK (kind IN ('game', 'product')),
+    "gameId"     INT,
+    name         TEXT,
+    price        INT,
+    "playTime"   INT,
+    levels       INT,
+    "productId"  INT,
+    "isActive"   BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+-- earn entries are positive, everything else negative. the balance is the sum.
+CREATE TABLE IF NOT EXISTS "LoyaltyLedger" (
+    id           SERIAL PRIMARY KEY,
+    "playerId"   INT         NOT NULL REFERENCES "Players"(id),
+    points       INT         NOT NULL,
+    kind         TEXT        NOT NULL CHECK (kind IN ('earn', 'reversal', 'redeem', 'redeem-cancel', 'expiry')),
+    "paymentId"  TEXT,
+    "rewardId"   INT REFERENCES "LoyaltyRewards"(id),
+    "expiresAt"  TIMESTAMPTZ,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "LoyaltyLedger_player" ON "LoyaltyLedger" ("playerId");
+CREATE INDEX IF NOT EXISTS "LoyaltyLedger_payment" ON "LoyaltyLedger" ("paymentId");
+
+CREATE OR REPLACE FUNCTION func_GetLoyaltyRules()
+RETURNS TABLE (id INT, game_id INT, weekday INT, multiplier NUMERIC, description TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, "gameId", weekday, multiplier, description FROM "LoyaltyRules" ORDER BY id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertLoyaltyRule(p_game_id INT, p_weekday INT, p_multiplier NUMERIC, p_description TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "LoyaltyRules" ("gameId", weekday, multiplier, description)
+    VALUES (p_game_id, p_weekday, p_multiplier, p_description)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeleteLoyaltyRule(p_rule_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    DELETE FROM "LoyaltyRules" WHERE id = p_rule_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetLoyaltyRewards()
+RETURNS TABLE (id INT, title TEXT, points INT, kind TEXT, game_id INT, name TEXT, price INT,
+               play_time INT, levels INT, product_id INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, title, points, kind, "gameId", name, price, "playTime", levels, "productId"
+    FROM "LoyaltyRewards" WHERE "isActive" ORDER BY points;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertLoyaltyReward(p_title TEXT, p_points INT, p_kind TEXT, p_game_id INT,
+    p_name TEXT, p_price INT, p_play_time INT, p_levels INT, p_product_id INT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "LoyaltyRewards" (title, points, kind, "gameId", name, price, "playTime", levels, "productId")
+    VALUES (p_title, p_points, p_kind, p_game_id, p_name, p_price, p_play_time, p_levels, p_product_id)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeactivateLoyaltyReward(p_reward_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "LoyaltyRewards" SET "isActive" = FALSE WHERE id = p_reward_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertLoyaltyEntry(p_player_id INT, p_points INT, p_kind TEXT,
+    p_payment_id TEXT, p_reward_id INT, p_expires_at TIMESTAMPTZ)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "LoyaltyLedger" ("playerId", points, kind, "paymentId", "rewardId", "expiresAt")
+    VALUES (p_player_id, p_points, p_kind, p_payment_id, p_reward_id, p_expires_at)
+    RETURNING id;
+$$;
+
+-- points still standing for a payment, what a full refund has to take back.
+CREATE OR REPLACE FUNCTION func_GetPaymentPoints(p_payment_id TEXT)
+RETURNS TABLE (player_id INT, earned INT, reversed INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "playerId",
+           COALESCE(SUM(points) FILTER (WHERE kind = 'earn'), 0)::INT,
+           COALESCE(-SUM(points) FILTER (WHERE kind = 'reversal'), 0)::INT
+    FROM "LoyaltyLedger"
+    WHERE "paymentId" = p_payment_id
+    GROUP BY "playerId";
+$$;
+
+-- tier is decided on the points earned in the last year, net of refunds.
+CREATE OR REPLACE FUNCTION func_GetLoyaltySummary(p_player_id INT)
+RETURNS TABLE (balance INT, yearly_earned INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT COALESCE(SUM(points), 0)::INT,
+           COALESCE(SUM(points) FILTER (WHERE kind IN ('earn', 'reversal') AND "createdAt" > now() - INTERVAL '1 year'), 0)::INT
+    FROM "LoyaltyLedger"
+    WHERE "playerId" = p_player_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetLoyaltyLedger(p_player_id INT, p_limit INT)
+RETURNS TABLE (id INT, points INT, kind TEXT, payment_id TEXT, reward_id INT, expires_at TIMESTAMPTZ, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, points, kind, "paymentId", "rewardId", "expiresAt", "createdAt"
+    FROM "LoyaltyLedger" WHERE "playerId" = p_player_id
+    ORDER BY "createdAt" DESC LIMIT p_limit;
+$$;
+
+-- takes the points off in the same transaction as the balance check, NULL when the balance is too low.
+CREATE OR REPLACE FUNCTION func_RedeemLoyaltyPoints(p_player_id INT, p_reward_id INT)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_cost INT;
+    v_entry_id INT;
+BEGIN
+    PERFORM pg_advisory_xact_lock(p_player_id);
+
+    SELECT points INTO v_cost FROM "LoyaltyRewards" WHERE id = p_reward_id AND "isActive";
+    IF v_cost IS NULL OR (SELECT COALESCE(SUM(points), 0) FROM "LoyaltyLedger" WHERE "playerId" = p_player_id) < v_cost THEN
+        RETURN NULL;
+    END IF;
+
+    INSERT INTO "LoyaltyLedger" ("playerId", points, kind, "rewardId")
+    VALUES (p_player_id, -v_cost, 'redeem', p_reward_id)
+    RETURNING id INTO v_entry_id;
+    RETURN v_entry_id;
+END;
+$$;
+
+-- oldest points are spent first, so whatever expired earning isn't covered by the debits so far is lost.
+CREATE OR REPLACE FUNCTION func_ExpireLoyaltyPoints()
+RETURNS INT
+LANGUAGE sql
+AS $$
+    WITH due AS (
+        SELECT "playerId",
+               COALESCE(SUM(points) FILTER (WHERE kind = 'earn' AND "expiresAt" < now()), 0)
+             + COALESCE(SUM(points) FILTER (WHERE kind <> 'earn'), 0) AS lost
+        FROM "LoyaltyLedger"
+        GROUP BY "playerId"
+    ), expired AS (
+        INSERT INTO "LoyaltyLedger" ("playerId", points, kind)
+        SELECT "playerId", -lost, 'expiry' FROM due WHERE lost > 0
+        RETURNING 1
+    )
+    SELECT COUNT(*)::INT FROM expired;
+$$;
@@ -0,0 +1,110 @@
+ALTER TABLE "Players" ADD COLUMN IF NOT EXISTS "referralCode" TEXT UNIQUE;
+
+CREATE TABLE IF NOT EXISTS "PlayerDevices" (
+    "playerId"   INT         NOT NULL REFERENCES "Players"(id),
+    "deviceId"   TEXT        NOT NULL,
+    "lastSeen"   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("playerId", "deviceId")
+);
+
+CREATE TABLE IF NOT EXISTS "Referrals" (
+    "referredId"   INT PRIMARY KEY REFERENCES "Players"(id), -- a player can only be referred once
+    "referrerId"   INT         NOT NULL REFERENCES "Players"(id),
+    "deviceId"     TEXT        NOT NULL,
+    status         TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'converted')),
+    "paymentId"    TEXT,
+    "createdAt"    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "convertedAt"  TIMESTAMPTZ
+);
+
+CREATE OR REPLACE FUNCTION func_RecordPlayerDevice(p_player_id INT, p_device_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "PlayerDevices" ("playerId", "deviceId") VALUES (p_player_id, p_device_id)
+    ON CONFLICT ("playerId", "deviceId") DO UPDATE SET "lastSeen" = now();
+$$;
+
+-- keeps the existing code, p_code is only used the first time.
+CREATE OR REPLACE FUNCTION func_SetReferralCode(p_player_id INT, p_code TEXT)
+RETURNS TEXT
+LANGUAGE sql
+AS $$
+    UPDATE "Players" SET "referralCode" = COALESCE("referralCode", p_code)
+    WHERE id = p_player_id
+    RETURNING "referralCode";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetReferralStats(p_player_id INT)
+RETURNS TABLE (referral_code TEXT, referred INT, converted INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p."referralCode",
+           (SELECT COUNT(*) FROM "Referrals" r WHERE r."referrerId" = p.id)::INT,
+           (SELECT COUNT(*) FROM "Referrals" r WHERE r."referrerId" = p.id AND r.status = 'converted')::INT
+    FROM "Players" p WHERE p.id = p_player_id;
+$$;
+
+-- the checks the application can't do without racing, the reason is NULL when the referral was recorded.
+CREATE OR REPLACE FUNCTION func_ApplyReferral(p_player_id INT, p_code TEXT, p_device_id TEXT, p_max_referrals INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_referrer_id INT;
+BEGIN
+    SELECT id INTO v_referrer_id FROM "Players" WHERE "referralCode" = p_code;
+
+    IF v_referrer_id IS NULL THEN
+        RETURN 'unknown referral code';
+    ELSIF v_referrer_id = p_player_id THEN
+        RETURN 'you can''t refer yourself';
+    ELSIF EXISTS (SELECT 1 FROM "Referrals" WHERE "referredId" = p_player_id) THEN
+        RETURN 'a referral code was already applied';
+    ELSIF EXISTS (SELECT 1 FROM "CapturedPayments" WHERE "playerId" = p_player_id) THEN
+        RETURN 'referral codes only work before the first purchase';
+    ELSIF EXISTS (SELECT 1 FROM "PlayerDevices" WHERE "playerId" = v_referrer_id AND "deviceId" = p_device_id)
+       OR EXISTS (SELECT 1 FROM "Referrals" WHERE "deviceId" = p_device_id) THEN
+        RETURN 'this device was already used for a referral';
+    ELSIF (SELECT COUNT(*) FROM "Referrals" WHERE "referrerId" = v_referrer_id) >= p_max_referrals THEN
+        RETURN 'this referral code has been used too many times';
+    END IF;
+
+    INSERT INTO "Referrals" ("referredId", "referrerId", "deviceId") VALUES (p_player_id, v_referrer_id, p_device_id);
+    RETURN NULL;
+END;
+$$;
+
+-- converts the pending referral on the first captured payment, returns the referrer when it did.
+CREATE OR REPLACE FUNCTION func_ConvertReferral(p_player_id INT, p_payment_id TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    UPDATE "Referrals" SET status = 'converted', "paymentId" = p_payment_id, "convertedAt" = now()
+    WHERE "referredId" = p_player_id AND status = 'pending'
+      AND (SELECT COUNT(*) FROM "CapturedPayments" WHERE "playerId" = p_player_id) = 1
+    RETURNING "referrerId";
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertWalletCredit(p_player_id INT, p_reference TEXT, p_amount INT, p_reason TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "WalletLedger" ("playerId", "paymentId", amount, reason)
+    VALUES (p_player_id, p_reference, p_amount, p_reason);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetReferralReport(p_from TIMESTAMPTZ, p_to TIMESTAMPTZ)
+RETURNS TABLE (referrer_id INT, phone TEXT, referred INT, converted INT, revenue BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT r."referrerId", p.phone, COUNT(*)::INT,
+           COUNT(*) FILTER (WHERE r.status = 'converted')::INT,
+           COALESCE(SUM(cp.amount), 0)::BIGINT
+    FROM "Referrals" r
+    JOIN "Players" p ON p.id = r."referrerId"
+    LEFT JOIN "CapturedPayments" cp ON cp."paymentId" = r."paymentId"
+    WHERE r."createdAt" >= p_from AND r."createdAt" < p_to
+    GROUP BY r."referrerId", p.phone
+    ORDER BY 4 DESC, 3 DESC;
+$$;
@@ -0,0 +1,135 @@
+-- one session per code, driven by the cabinet events.
+CREATE TABLE IF NOT EXISTS "PlaySessions" (
+    id            SERIAL PRIMARY KEY,
+    code          TEXT        NOT NULL UNIQUE,
+    "gameId"      INT         NOT NULL,
+    "machineId"   TEXT        NOT NULL REFERENCES "Machines"(id),
+    "playerId"    INT REFERENCES "Players"(id),
+    system        TEXT,
+    "maxLevel"    INT         NOT NULL DEFAULT 0,
+    "isCompleted" BOOLEAN     NOT NULL DEFAULT FALSE,
+    "startedAt"   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "endedAt"     TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS "PlaySessions_player" ON "PlaySessions" ("playerId");
+
+CREATE TABLE IF NOT EXISTS "Achievements" (
+    id            SERIAL PRIMARY KEY,
+    title         TEXT    NOT NULL,
+    description   TEXT    NOT NULL,
+    kind          TEXT    NOT NULL CHECK (kind IN ('games_played', 'all_levels', 'systems_played')),
+    threshold     INT     NOT NULL CHECK (threshold > 0),
+    "gameId"      INT,      -- only count this game
+    "windowDays"  INT,      -- only count the last n days
+    badge         TEXT,     -- image url
+    "isActive"    BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS "PlayerAchievements" (
+    "playerId"       INT         NOT NULL REFERENCES "Players"(id),
+    "achievementId"  INT         NOT NULL REFERENCES "Achievements"(id),
+    "earnedAt"       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("playerId", "achievementId")
+);
+
+CREATE OR REPLACE FUNCTION func_StartSession(p_code TEXT, p_game_id INT, p_machine_id TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "PlaySessions" (code, "gameId", "machineId", "playerId", system)
+    SELECT gs.code, gs."gameId", p_machine_id, gs."playerId", (SELECT system FROM func_CheckGameCode(p_code))
+    FROM "GameStatus" gs
+    WHERE gs.code = p_code AND gs."gameId" = p_game_id
+    ON CONFLICT (code) DO UPDATE SET "machineId" = EXCLUDED."machineId"
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RecordSessionLevel(p_code TEXT, p_level INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "PlaySessions" SET "maxLevel" = GREATEST("maxLevel", p_level)
+    WHERE code = p_code AND "endedAt" IS NULL;
+$$;
+
+-- completed means every purchased level was cleared, or the cabinet says the game was finished.
+CREATE OR REPLACE FUNCTION func_EndSession(p_code TEXT, p_level INT, p_completed BOOLEAN)
+RETURNS TABLE (id INT, code TEXT, game_id INT, machine_id TEXT, player_id INT, system TEXT,
+               max_level INT, is_completed BOOLEAN, started_at TIMESTAMPTZ, ended_at TIMESTAMPTZ)
+LANGUAGE sql
+AS $$
+    UPDATE "PlaySessions" ps
+    SET "endedAt" = now(),
+        "maxLevel" = GREATEST(ps."maxLevel", p_level),
+        "isCompleted" = p_completed OR (gs.levels IS NOT NULL AND GREATEST(ps."maxLevel", p_level) >= gs.levels)
+    FROM "GameStatus" gs
+    WHERE ps.code = p_code AND gs.code = ps.code AND ps."endedAt" IS NULL
+    RETURNING ps.id, ps.code, ps."gameId", ps."machineId", ps."playerId", ps.system,
+              ps."maxLevel", ps."isCompleted", ps."startedAt", ps."endedAt";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetAchievements(p_include_inactive BOOLEAN)
+RETURNS TABLE (id INT, title TEXT, description TEXT, kind TEXT, threshold INT, game_id INT,
+               window_days INT, badge TEXT, is_active BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, title, description, kind, threshold, "gameId", "windowDays", badge, "isActive"
+    FROM "Achievements" WHERE p_include_inactive OR "isActive" ORDER BY id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_UpsertAchievement(p_id INT, p_title TEXT, p_description TEXT, p_kind TEXT,
+    p_threshold INT, p_game_id INT, p_window_days INT, p_badge TEXT, p_is_active BOOLEAN)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_id INT;
+BEGIN
+    IF p_id IS NULL THEN
+        INSERT INTO "Achievements" (title, description, kind, threshold, "gameId", "windowDays", badge, "isActive")
+        VALUES (p_title, p_description, p_kind, p_threshold, p_game_id, p_window_days, p_badge, p_is_active)
+        RETURNING id INTO v_id;
+    ELSE
+        UPDATE "Achievements"
+        SET title = p_title, description = p_description, kind = p_kind, threshold = p_threshold,
+            "gameId" = p_game_id, "windowDays" = p_window_days, badge = p_badge, "isActive" = p_is_active
+        WHERE id = p_id
+        RETURNING id INTO v_id;
+    END IF;
+    RETURN v_id;
+END;
+$$;
+
+-- progress of every active achievement for the player, counted from the ended sessions.
+CREATE OR REPLACE FUNCTION func_GetAchievementProgress(p_player_id INT)
+RETURNS TABLE (id INT, title TEXT, description TEXT, kind TEXT, threshold INT, game_id INT,
+               window_days INT, badge TEXT, is_active BOOLEAN, progress INT, earned_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT a.id, a.title, a.description, a.kind, a.threshold, a."gameId", a."windowDays", a.badge, a."isActive",
+           (SELECT CASE a.kind
+                       WHEN 'games_played' THEN COUNT(*)
+                       WHEN 'all_levels' THEN COUNT(*) FILTER (WHERE s."isCompleted")
+                       WHEN 'systems_played' THEN COUNT(DISTINCT s.system)
+                   END
+            FROM "PlaySessions" s
+            WHERE s."playerId" = p_player_id AND s."endedAt" IS NOT NULL
+              AND (a."gameId" IS NULL OR s."gameId" = a."gameId")
+              AND (a."windowDays" IS NULL OR s."endedAt" > now() - make_interval(days => a."windowDays")))::INT,
+           pa."earnedAt"
+    FROM "Achievements" a
+    LEFT JOIN "PlayerAchievements" pa ON pa."achievementId" = a.id AND pa."playerId" = p_player_id
+    WHERE a."isActive" OR pa."earnedAt" IS NOT NULL
+    ORDER BY a.id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_AwardAchievement(p_player_id INT, p_achievement_id INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO "PlayerAchievements" ("playerId", "achievementId") VALUES (p_player_id, p_achievement_id)
+    ON CONFLICT DO NOTHING;
+    RETURN FOUND;
+END;
+$$;
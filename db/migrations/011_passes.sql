@@ -0,0 +1,179 @@
+-- minutes NULL is unlimited play, the price is in rupees like the game tiers.
+CREATE TABLE IF NOT EXISTS "PassProducts" (
+    id                 SERIAL PRIMARY KEY,
+    name               TEXT    NOT NULL,
+    description        TEXT    NOT NULL,
+    price              INT     NOT NULL CHECK (price > 0),
+    minutes            INT CHECK (minutes > 0),
+    "weekdaysOnly"     BOOLEAN NOT NULL DEFAULT FALSE,
+    "periodDays"       INT     NOT NULL DEFAULT 30 CHECK ("periodDays" > 0),
+    "razorpayPlanId"   TEXT,    -- needed for the recurring subscriptions
+    "isActive"         BOOLEAN NOT NULL DEFAULT TRUE
+);
+
+CREATE TABLE IF NOT EXISTS "PassSubscriptions" (
+    id             TEXT PRIMARY KEY, -- razorpay subscription id
+    "playerId"     INT         NOT NULL REFERENCES "Players"(id),
+    "passId"       INT         NOT NULL REFERENCES "PassProducts"(id),
+    "isCancelled"  BOOLEAN     NOT NULL DEFAULT FALSE,
+    "createdAt"    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- one row per paid period, renewals start where the previous period ends.
+CREATE TABLE IF NOT EXISTS "PlayerPasses" (
+    id                SERIAL PRIMARY KEY,
+    "playerId"        INT         NOT NULL REFERENCES "Players"(id),
+    "passId"          INT         NOT NULL REFERENCES "PassProducts"(id),
+    "paymentId"       TEXT        NOT NULL UNIQUE,
+    "subscriptionId"  TEXT REFERENCES "PassSubscriptions"(id),
+    "startsAt"        TIMESTAMPTZ NOT NULL,
+    "endsAt"          TIMESTAMPTZ NOT NULL,
+    "revokedAt"       TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS "PlayerPasses_player" ON "PlayerPasses" ("playerId", "endsAt");
+
+-- minutes are held when the code is issued and brought down to the real length when the session ends.
+CREATE TABLE IF NOT EXISTS "PassUsage" (
+    code              TEXT PRIMARY KEY,
+    "playerPassId"    INT         NOT NULL REFERENCES "PlayerPasses"(id),
+    minutes           INT         NOT NULL,
+    "isSettled"       BOOLEAN     NOT NULL DEFAULT FALSE,
+    "createdAt"       TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION func_GetPassProducts(p_include_inactive BOOLEAN)
+RETURNS TABLE (id INT, name TEXT, description TEXT, price INT, minutes INT, weekdays_only BOOLEAN,
+               period_days INT, razorpay_plan_id TEXT, is_active BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, name, description, price, minutes, "weekdaysOnly", "periodDays", "razorpayPlanId", "isActive"
+    FROM "PassProducts" WHERE p_include_inactive OR "isActive" ORDER BY price;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertPassProduct(p_name TEXT, p_description TEXT, p_price INT, p_minutes INT,
+    p_weekdays_only BOOLEAN, p_period_days INT, p_razorpay_plan_id TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "PassProducts" (name, description, price, minutes, "weekdaysOnly", "periodDays", "razorpayPlanId")
+    VALUES (p_name, p_description, p_price, p_minutes, p_weekdays_only, p_period_days, p_razorpay_plan_id)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeactivatePassProduct(p_pass_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "PassProducts" SET "isActive" = FALSE WHERE id = p_pass_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertPassSubscription(p_subscription_id TEXT, p_player_id INT, p_pass_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "PassSubscriptions" (id, "playerId", "passId") VALUES (p_subscription_id, p_player_id, p_pass_id);
+$$;
+
+-- FALSE when the subscription isn't the player's or is already cancelled.
+CREATE OR REPLACE FUNCTION func_CancelPassSubscription(p_subscription_id TEXT, p_player_id INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    UPDATE "PassSubscriptions" SET "isCancelled" = TRUE
+    WHERE id = p_subscription_id AND "playerId" = p_player_id AND NOT "isCancelled";
+    RETURN FOUND;
+END;
+$$;
+
+-- NULL when the payment already granted a period (webhook retries).
+CREATE OR REPLACE FUNCTION func_GrantPass(p_player_id INT, p_pass_id INT, p_payment_id TEXT, p_subscription_id TEXT)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_starts_at TIMESTAMPTZ;
+    v_id INT;
+BEGIN
+    SELECT GREATEST(now(), COALESCE(MAX("endsAt"), now())) INTO v_starts_at
+    FROM "PlayerPasses"
+    WHERE "playerId" = p_player_id AND "passId" = p_pass_id AND "revokedAt" IS NULL;
+
+    INSERT INTO "PlayerPasses" ("playerId", "passId", "paymentId", "subscriptionId", "startsAt", "endsAt")
+    SELECT p_player_id, p_pass_id, p_payment_id, p_subscription_id, v_starts_at,
+           v_starts_at + make_interval(days => p."periodDays")
+    FROM "PassProducts" p WHERE p.id = p_pass_id
+    ON CONFLICT ("paymentId") DO NOTHING
+    RETURNING id INTO v_id;
+
+    RETURN v_id;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RevokePass(p_payment_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "PlayerPasses" SET "revokedAt" = now() WHERE "paymentId" = p_payment_id AND "revokedAt" IS NULL;
+$$;
+
+-- every period the player holds that hasn't ended, with the minutes used so far.
+CREATE OR REPLACE FUNCTION func_GetPlayerPasses(p_player_id INT)
+RETURNS TABLE (id INT, pass_id INT, name TEXT, minutes INT, minutes_used INT, weekdays_only BOOLEAN,
+               starts_at TIMESTAMPTZ, ends_at TIMESTAMPTZ, subscription_id TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT pp.id, pp."passId", p.name, p.minutes,
+           COALESCE((SELECT SUM(u.minutes) FROM "PassUsage" u WHERE u."playerPassId" = pp.id), 0)::INT,
+           p."weekdaysOnly", pp."startsAt", pp."endsAt", pp."subscriptionId"
+    FROM "PlayerPasses" pp
+    JOIN "PassProducts" p ON p.id = pp."passId"
+    WHERE pp."playerId" = p_player_id AND pp."revokedAt" IS NULL AND pp."endsAt" > now()
+    ORDER BY pp."startsAt";
+$$;
+
+-- called inside the save game status transaction, returns why the pass can't cover the code or ''.
+CREATE OR REPLACE FUNCTION func_UsePass(p_player_pass_id INT, p_player_id INT, p_code TEXT, p_minutes INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_pass RECORD;
+    v_used INT;
+BEGIN
+    SELECT pp."startsAt", pp."endsAt", p.minutes, p."weekdaysOnly" INTO v_pass
+    FROM "PlayerPasses" pp
+    JOIN "PassProducts" p ON p.id = pp."passId"
+    WHERE pp.id = p_player_pass_id AND pp."playerId" = p_player_id AND pp."revokedAt" IS NULL
+    FOR UPDATE OF pp;
+
+    IF NOT FOUND THEN
+        RETURN 'pass not found';
+    END IF;
+    IF now() < v_pass."startsAt" OR now() >= v_pass."endsAt" THEN
+        RETURN 'pass is not active right now';
+    END IF;
+    IF v_pass."weekdaysOnly" AND EXTRACT(ISODOW FROM now()) IN (6, 7) THEN
+        RETURN 'pass only covers weekdays';
+    END IF;
+
+    IF v_pass.minutes IS NOT NULL THEN
+        SELECT COALESCE(SUM(minutes), 0) INTO v_used FROM "PassUsage" WHERE "playerPassId" = p_player_pass_id;
+        IF v_used + p_minutes > v_pass.minutes THEN
+            RETURN format('only %s minutes left on the pass', GREATEST(v_pass.minutes - v_used, 0));
+        END IF;
+    END IF;
+
+    INSERT INTO "PassUsage" (code, "playerPassId", minutes) VALUES (p_code, p_player_pass_id, p_minutes);
+    RETURN '';
+END;
+$$;
+
+-- the held minutes are replaced by what was actually played, never more than was held.
+CREATE OR REPLACE FUNCTION func_SettlePassUsage(p_code TEXT, p_minutes INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "PassUsage" SET minutes = LEAST(minutes, p_minutes), "isSettled" = TRUE
+    WHERE code = p_code AND NOT "isSettled";
+$$;
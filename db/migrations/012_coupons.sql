@@ -0,0 +1,104 @@
+-- value is a percentage or rupees depending on the kind, NULL restrictions match everything.
+CREATE TABLE IF NOT EXISTS "Coupons" (
+    id                    SERIAL PRIMARY KEY,
+    code                  TEXT        NOT NULL UNIQUE,
+    kind                  TEXT        NOT NULL CHECK (kind IN ('percent', 'flat')),
+    value                 INT         NOT NULL CHECK (value > 0),
+    "startsAt"            TIMESTAMPTZ,
+    "endsAt"              TIMESTAMPTZ,
+    "maxUses"             INT,
+    "maxUsesPerPlayer"    INT,
+    "gameId"              INT,
+    "tierLabel"           INT,     -- minutes or levels of the tier
+    "productType"         TEXT,    -- payment purpose, game, shop or pass
+    "isActive"            BOOLEAN     NOT NULL DEFAULT TRUE,
+    "createdAt"           TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS "CouponRedemptions" (
+    id            SERIAL PRIMARY KEY,
+    "couponId"    INT         NOT NULL REFERENCES "Coupons"(id),
+    "playerId"    INT REFERENCES "Players"(id),
+    reference     TEXT        NOT NULL UNIQUE, -- payment reference of the code
+    discount      INT         NOT NULL,
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "CouponRedemptions_coupon" ON "CouponRedemptions" ("couponId", "playerId");
+
+CREATE OR REPLACE FUNCTION func_GetCoupons()
+RETURNS TABLE (id INT, code TEXT, kind TEXT, value INT, starts_at TIMESTAMPTZ, ends_at TIMESTAMPTZ,
+               max_uses INT, max_uses_per_player INT, game_id INT, tier_label INT, product_type TEXT,
+               is_active BOOLEAN, uses INT, player_uses INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT c.id, c.code, c.kind, c.value, c."startsAt", c."endsAt", c."maxUses", c."maxUsesPerPlayer",
+           c."gameId", c."tierLabel", c."productType", c."isActive",
+           (SELECT COUNT(*) FROM "CouponRedemptions" r WHERE r."couponId" = c.id)::INT, 0
+    FROM "Coupons" c ORDER BY c.id DESC;
+$$;
+
+-- player_uses is 0 for guests.
+CREATE OR REPLACE FUNCTION func_GetCoupon(p_code TEXT, p_player_id INT)
+RETURNS TABLE (id INT, code TEXT, kind TEXT, value INT, starts_at TIMESTAMPTZ, ends_at TIMESTAMPTZ,
+               max_uses INT, max_uses_per_player INT, game_id INT, tier_label INT, product_type TEXT,
+               is_active BOOLEAN, uses INT, player_uses INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT c.id, c.code, c.kind, c.value, c."startsAt", c."endsAt", c."maxUses", c."maxUsesPerPlayer",
+           c."gameId", c."tierLabel", c."productType", c."isActive",
+           (SELECT COUNT(*) FROM "CouponRedemptions" r WHERE r."couponId" = c.id)::INT,
+           (SELECT COUNT(*) FROM "CouponRedemptions" r WHERE r."couponId" = c.id AND r."playerId" = p_player_id)::INT
+    FROM "Coupons" c WHERE c.code = upper(p_code);
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertCoupon(p_code TEXT, p_kind TEXT, p_value INT, p_starts_at TIMESTAMPTZ,
+    p_ends_at TIMESTAMPTZ, p_max_uses INT, p_max_uses_per_player INT, p_game_id INT, p_tier_label INT,
+    p_product_type TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "Coupons" (code, kind, value, "startsAt", "endsAt", "maxUses", "maxUsesPerPlayer",
+                           "gameId", "tierLabel", "productType")
+    VALUES (upper(p_code), p_kind, p_value, p_starts_at, p_ends_at, p_max_uses, p_max_uses_per_player,
+            p_game_id, p_tier_label, p_product_type)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeactivateCoupon(p_coupon_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "Coupons" SET "isActive" = FALSE WHERE id = p_coupon_id;
+$$;
+
+-- called in the same transaction that saves the purchase, the coupon row is locked so the
+-- limits hold under concurrent checkouts. returns why the coupon was refused or ''.
+CREATE OR REPLACE FUNCTION func_RedeemCoupon(p_code TEXT, p_player_id INT, p_reference TEXT, p_discount INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_coupon RECORD;
+BEGIN
+    SELECT id, "maxUses", "maxUsesPerPlayer" INTO v_coupon
+    FROM "Coupons" WHERE code = upper(p_code) AND "isActive"
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN 'coupon not found';
+    END IF;
+    IF v_coupon."maxUses" IS NOT NULL AND
+       (SELECT COUNT(*) FROM "CouponRedemptions" WHERE "couponId" = v_coupon.id) >= v_coupon."maxUses" THEN
+        RETURN 'coupon has been used up';
+    END IF;
+    IF v_coupon."maxUsesPerPlayer" IS NOT NULL AND
+       (SELECT COUNT(*) FROM "CouponRedemptions" WHERE "couponId" = v_coupon.id AND "playerId" = p_player_id)
+           >= v_coupon."maxUsesPerPlayer" THEN
+        RETURN 'coupon already used';
+    END IF;
+
+    INSERT INTO "CouponRedemptions" ("couponId", "playerId", reference, discount)
+    VALUES (v_coupon.id, p_player_id, p_reference, p_discount);
+    RETURN '';
+END;
+$$;
@@ -0,0 +1,68 @@
+-- a rule either multiplies the catalogue price or overrides one tier of a game.
+-- windows with the end before the start run past midnight, the weekday is the day they start.
+CREATE TABLE IF NOT EXISTS "PricingRules" (
+    id                SERIAL PRIMARY KEY,
+    weekday           INT CHECK (weekday BETWEEN 0 AND 6), -- 0 is sunday, NULL every day
+    "startTime"       TIME    NOT NULL,
+    "endTime"         TIME    NOT NULL,
+    venue             TEXT,
+    "gameId"          INT,
+    "tierLabel"       INT,
+    multiplier        NUMERIC(4, 2) CHECK (multiplier > 0),
+    "overridePrice"   INT CHECK ("overridePrice" > 0),
+    description       TEXT    NOT NULL,
+    CHECK ((multiplier IS NULL) <> ("overridePrice" IS NULL))
+);
+
+-- remembered so the game is priced by the rule that was active when the order was created.
+CREATE TABLE IF NOT EXISTS "PaymentOrders" (
+    "orderId"     TEXT PRIMARY KEY,
+    purpose       TEXT        NOT NULL,
+    amount        BIGINT      NOT NULL,
+    venue         TEXT,
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION func_GetPricingRules()
+RETURNS TABLE (id INT, weekday INT, start_time TEXT, end_time TEXT, venue TEXT, game_id INT, tier_label INT,
+               multiplier DOUBLE PRECISION, override_price INT, description TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, weekday, to_char("startTime", 'HH24:MI'), to_char("endTime", 'HH24:MI'), venue, "gameId",
+           "tierLabel", multiplier::DOUBLE PRECISION, "overridePrice", description
+    FROM "PricingRules" ORDER BY id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertPricingRule(p_weekday INT, p_start_time TEXT, p_end_time TEXT, p_venue TEXT,
+    p_game_id INT, p_tier_label INT, p_multiplier DOUBLE PRECISION, p_override_price INT, p_description TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "PricingRules" (weekday, "startTime", "endTime", venue, "gameId", "tierLabel", multiplier,
+                                "overridePrice", description)
+    VALUES (p_weekday, p_start_time::TIME, p_end_time::TIME, p_venue, p_game_id, p_tier_label, p_multiplier,
+            p_override_price, p_description)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeletePricingRule(p_rule_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    DELETE FROM "PricingRules" WHERE id = p_rule_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertPaymentOrder(p_order_id TEXT, p_purpose TEXT, p_amount BIGINT, p_venue TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "PaymentOrders" ("orderId", purpose, amount, venue) VALUES (p_order_id, p_purpose, p_amount, p_venue)
+    ON CONFLICT ("orderId") DO NOTHING;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetPaymentOrder(p_order_id TEXT)
+RETURNS TABLE (created_at TIMESTAMPTZ, venue TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "createdAt", venue FROM "PaymentOrders" WHERE "orderId" = p_order_id;
+$$;
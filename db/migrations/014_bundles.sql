@@ -0,0 +1,157 @@
+-- a bundle is either a set of game codes or one balance code worth minutes on any game.
+CREATE TABLE IF NOT EXISTS "Bundles" (
+    id            SERIAL PRIMARY KEY,
+    name          TEXT    NOT NULL,
+    description   TEXT    NOT NULL,
+    price         INT     NOT NULL CHECK (price > 0),
+    kind          TEXT    NOT NULL CHECK (kind IN ('codes', 'balance')),
+    minutes       INT CHECK (minutes > 0),
+    "isActive"    BOOLEAN NOT NULL DEFAULT TRUE,
+    CHECK ((kind = 'balance') = (minutes IS NOT NULL))
+);
+
+CREATE TABLE IF NOT EXISTS "BundleItems" (
+    id            SERIAL PRIMARY KEY,
+    "bundleId"    INT     NOT NULL REFERENCES "Bundles"(id),
+    "gameId"      INT     NOT NULL,
+    "isTimed"     BOOLEAN NOT NULL,
+    "playTime"    INT,
+    levels        INT,
+    quantity      INT     NOT NULL DEFAULT 1 CHECK (quantity > 0)
+);
+
+CREATE TABLE IF NOT EXISTS "BundlePurchases" (
+    id            SERIAL PRIMARY KEY,
+    "bundleId"    INT         NOT NULL REFERENCES "Bundles"(id),
+    "paymentId"   TEXT        NOT NULL UNIQUE,
+    "playerId"    INT REFERENCES "Players"(id),
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- the game codes of a purchase, and the balance code for balance bundles.
+CREATE TABLE IF NOT EXISTS "BundleCodes" (
+    code          TEXT PRIMARY KEY,
+    "purchaseId"  INT     NOT NULL REFERENCES "BundlePurchases"(id),
+    "isBalance"   BOOLEAN NOT NULL DEFAULT FALSE,
+    minutes       INT      -- balance codes only
+);
+
+CREATE TABLE IF NOT EXISTS "BalanceDraws" (
+    "gameCode"     TEXT PRIMARY KEY,
+    "balanceCode"  TEXT        NOT NULL REFERENCES "BundleCodes"(code),
+    minutes        INT         NOT NULL,
+    "createdAt"    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE OR REPLACE FUNCTION func_GetBundles(p_include_inactive BOOLEAN)
+RETURNS TABLE (id INT, name TEXT, description TEXT, price INT, kind TEXT, minutes INT, is_active BOOLEAN,
+               item_id INT, game_id INT, is_timed BOOLEAN, play_time INT, levels INT, quantity INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT b.id, b.name, b.description, b.price, b.kind, b.minutes, b."isActive",
+           i.id, i."gameId", i."isTimed", i."playTime", i.levels, i.quantity
+    FROM "Bundles" b
+    LEFT JOIN "BundleItems" i ON i."bundleId" = b.id
+    WHERE p_include_inactive OR b."isActive"
+    ORDER BY b.price, b.id, i.id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertBundle(p_name TEXT, p_description TEXT, p_price INT, p_kind TEXT, p_minutes INT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "Bundles" (name, description, price, kind, minutes)
+    VALUES (p_name, p_description, p_price, p_kind, p_minutes)
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertBundleItem(p_bundle_id INT, p_game_id INT, p_is_timed BOOLEAN,
+    p_play_time INT, p_levels INT, p_quantity INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "BundleItems" ("bundleId", "gameId", "isTimed", "playTime", levels, quantity)
+    VALUES (p_bundle_id, p_game_id, p_is_timed, p_play_time, p_levels, p_quantity);
+$$;
+
+CREATE OR REPLACE FUNCTION func_DeactivateBundle(p_bundle_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "Bundles" SET "isActive" = FALSE WHERE id = p_bundle_id;
+$$;
+
+-- NULL when the payment was already fulfilled.
+CREATE OR REPLACE FUNCTION func_InsertBundlePurchase(p_bundle_id INT, p_payment_id TEXT, p_player_id INT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "BundlePurchases" ("bundleId", "paymentId", "playerId")
+    VALUES (p_bundle_id, p_payment_id, p_player_id)
+    ON CONFLICT ("paymentId") DO NOTHING
+    RETURNING id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertBundleCode(p_purchase_id INT, p_code TEXT, p_is_balance BOOLEAN, p_minutes INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "BundleCodes" (code, "purchaseId", "isBalance", minutes)
+    VALUES (p_code, p_purchase_id, p_is_balance, p_minutes);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetBundlePurchaseCodes(p_payment_id TEXT)
+RETURNS TABLE (code TEXT, is_balance BOOLEAN, minutes INT, minutes_left INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT c.code, c."isBalance", c.minutes,
+           CASE WHEN c."isBalance" THEN
+               c.minutes - COALESCE((SELECT SUM(d.minutes) FROM "BalanceDraws" d WHERE d."balanceCode" = c.code), 0)
+           END::INT
+    FROM "BundleCodes" c
+    JOIN "BundlePurchases" p ON p.id = c."purchaseId"
+    WHERE p."paymentId" = p_payment_id
+    ORDER BY c."isBalance" DESC, c.code;
+$$;
+
+-- holds the minutes for a game code about to be issued, returns the minutes left or -1 when
+-- the balance is too low. The row lock keeps two cabinets from spending the same minutes.
+CREATE OR REPLACE FUNCTION func_DrawBalance(p_balance_code TEXT, p_game_code TEXT, p_minutes INT)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_minutes INT;
+    v_used INT;
+BEGIN
+    SELECT minutes INTO v_minutes FROM "BundleCodes" WHERE code = p_balance_code AND "isBalance" FOR UPDATE;
+    IF NOT FOUND THEN
+        RETURN NULL;
+    END IF;
+
+    SELECT COALESCE(SUM(minutes), 0) INTO v_used FROM "BalanceDraws" WHERE "balanceCode" = p_balance_code;
+    IF v_used + p_minutes > v_minutes THEN
+        RETURN -1;
+    END IF;
+
+    INSERT INTO "BalanceDraws" ("gameCode", "balanceCode", minutes) VALUES (p_game_code, p_balance_code, p_minutes);
+    RETURN v_minutes - v_used - p_minutes;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_ReleaseBalanceDraw(p_game_code TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    DELETE FROM "BalanceDraws" WHERE "gameCode" = p_game_code;
+$$;
+
+-- balance codes come from the same counter, they have to be seen when it's restored.
+CREATE OR REPLACE FUNCTION func_GetIssuedCodes()
+RETURNS TABLE (code TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT gs.code::TEXT FROM "GameStatus" gs
+    UNION ALL
+    SELECT bc.code FROM "BundleCodes" bc WHERE bc."isBalance";
+$$;
@@ -0,0 +1,251 @@
+-- the id is the venue text machines, scores, pricing rules and orders already carry.
+CREATE TABLE IF NOT EXISTS "Venues" (
+    id           TEXT PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    city         TEXT        NOT NULL DEFAULT '',
+    "isActive"   BOOLEAN     NOT NULL DEFAULT TRUE,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- the cabinets registered so far become the first venues.
+INSERT INTO "Venues" (id, name)
+SELECT DISTINCT venue, venue FROM "Machines"
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE "Machines" DROP CONSTRAINT IF EXISTS "Machines_venue_fkey";
+ALTER TABLE "Machines" ADD CONSTRAINT "Machines_venue_fkey" FOREIGN KEY (venue) REFERENCES "Venues"(id);
+
+-- an admin without rows here manages every venue, otherwise only the listed ones.
+CREATE TABLE IF NOT EXISTS "AdminVenues" (
+    "adminId"   INT  NOT NULL,
+    "venueId"   TEXT NOT NULL REFERENCES "Venues"(id),
+    PRIMARY KEY ("adminId", "venueId")
+);
+
+-- games are available everywhere unless a venue turns them off.
+CREATE TABLE IF NOT EXISTS "VenueGames" (
+    "venueId"      TEXT    NOT NULL REFERENCES "Venues"(id),
+    "gameId"       INT     NOT NULL,
+    "isAvailable"  BOOLEAN NOT NULL,
+    PRIMARY KEY ("venueId", "gameId")
+);
+
+-- replaces the catalogue price of one tier at the venue, the tier itself has to exist in the catalogue.
+CREATE TABLE IF NOT EXISTS "VenuePrices" (
+    "venueId"   TEXT NOT NULL REFERENCES "Venues"(id),
+    "gameId"    INT  NOT NULL,
+    "itemType"  TEXT NOT NULL CHECK ("itemType" IN ('time', 'level')),
+    label       INT  NOT NULL,
+    price       INT  NOT NULL CHECK (price > 0),
+    PRIMARY KEY ("venueId", "gameId", "itemType", label)
+);
+
+-- stock kept at the venue, a venue only sells the products it has a row for.
+CREATE TABLE IF NOT EXISTS "VenueInventory" (
+    "venueId"    TEXT NOT NULL REFERENCES "Venues"(id),
+    "productId"  INT  NOT NULL,
+    units        INT  NOT NULL CHECK (units >= 0),
+    PRIMARY KEY ("venueId", "productId")
+);
+
+-- the venue a code was bought for, codes without a row play anywhere.
+CREATE TABLE IF NOT EXISTS "CodeVenues" (
+    code        TEXT PRIMARY KEY,
+    "venueId"   TEXT NOT NULL REFERENCES "Venues"(id)
+);
+
+CREATE OR REPLACE FUNCTION func_GetVenues(p_venues TEXT[])
+RETURNS TABLE (id TEXT, name TEXT, city TEXT, is_active BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, name, city, "isActive", "createdAt" FROM "Venues"
+    WHERE p_venues IS NULL OR id = ANY(p_venues)
+    ORDER BY id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertVenue(p_id TEXT, p_name TEXT, p_city TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "Venues" (id, name, city) VALUES (p_id, p_name, p_city);
+$$;
+
+-- FALSE when there is no such venue.
+CREATE OR REPLACE FUNCTION func_UpdateVenue(p_id TEXT, p_name TEXT, p_city TEXT, p_is_active BOOLEAN)
+RETURNS BOOLEAN
+LANGUAGE sql
+AS $$
+    WITH updated AS (
+        UPDATE "Venues" SET name = p_name, city = p_city, "isActive" = p_is_active
+        WHERE id = p_id
+        RETURNING id
+    )
+    SELECT EXISTS (SELECT 1 FROM updated);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetAdminVenues(p_admin_id INT)
+RETURNS TABLE (venue_id TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "venueId" FROM "AdminVenues" WHERE "adminId" = p_admin_id ORDER BY "venueId";
+$$;
+
+-- replaces the venues of the admin, an empty list makes them a global admin again.
+CREATE OR REPLACE FUNCTION func_SetAdminVenues(p_admin_id INT, p_venues TEXT[])
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    DELETE FROM "AdminVenues" WHERE "adminId" = p_admin_id;
+    INSERT INTO "AdminVenues" ("adminId", "venueId")
+    SELECT p_admin_id, v FROM unnest(p_venues) AS v;
+END;
+$$;
+
+-- the games playable at the venue, a NULL venue gives the whole catalogue.
+CREATE OR REPLACE FUNCTION func_GetGamesForUsers(p_venue TEXT)
+RETURNS TABLE (game_id INT, name TEXT, thumbnail TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT g.game_id::INT, g.name::TEXT, g.thumbnail::TEXT
+    FROM func_GetGamesForUsers() AS g(game_id, name, thumbnail)
+    WHERE p_venue IS NULL OR NOT EXISTS (
+        SELECT 1 FROM "VenueGames" vg
+        WHERE vg."venueId" = p_venue AND vg."gameId" = g.game_id::INT AND NOT vg."isAvailable"
+    );
+$$;
+
+-- the catalogue tiers with the venue prices in place, games turned off at the venue have no tiers.
+CREATE OR REPLACE FUNCTION func_GetGamesPrices(p_venue TEXT)
+RETURNS TABLE (item_type TEXT, label INT, price INT, game_id INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p.item_type::TEXT, p.label::INT, COALESCE(vp.price, p.price::INT), p.game_id::INT
+    FROM func_GetGamesPrices() AS p(item_type, label, price, game_id)
+    LEFT JOIN "VenuePrices" vp
+        ON vp."venueId" = p_venue AND vp."gameId" = p.game_id::INT AND vp."itemType" = p.item_type::TEXT
+        AND vp.label = p.label::INT
+    WHERE p_venue IS NULL OR NOT EXISTS (
+        SELECT 1 FROM "VenueGames" vg
+        WHERE vg."venueId" = p_venue AND vg."gameId" = p.game_id::INT AND NOT vg."isAvailable"
+    );
+$$;
+
+CREATE OR REPLACE FUNCTION func_SetVenueGame(p_venue TEXT, p_game_id INT, p_is_available BOOLEAN)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "VenueGames" ("venueId", "gameId", "isAvailable") VALUES (p_venue, p_game_id, p_is_available)
+    ON CONFLICT ("venueId", "gameId") DO UPDATE SET "isAvailable" = EXCLUDED."isAvailable";
+$$;
+
+-- a NULL price goes back to the catalogue price.
+CREATE OR REPLACE FUNCTION func_SetVenuePrice(p_venue TEXT, p_game_id INT, p_item_type TEXT, p_label INT, p_price INT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF p_price IS NULL THEN
+        DELETE FROM "VenuePrices"
+        WHERE "venueId" = p_venue AND "gameId" = p_game_id AND "itemType" = p_item_type AND label = p_label;
+        RETURN;
+    END IF;
+
+    INSERT INTO "VenuePrices" ("venueId", "gameId", "itemType", label, price)
+    VALUES (p_venue, p_game_id, p_item_type, p_label, p_price)
+    ON CONFLICT ("venueId", "gameId", "itemType", label) DO UPDATE SET price = EXCLUDED.price;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_SetVenueInventory(p_venue TEXT, p_product_id INT, p_units INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "VenueInventory" ("venueId", "productId", units) VALUES (p_venue, p_product_id, p_units)
+    ON CONFLICT ("venueId", "productId") DO UPDATE SET units = EXCLUDED.units;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetVenueGames(p_venue TEXT)
+RETURNS TABLE (game_id INT, is_available BOOLEAN)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "gameId", "isAvailable" FROM "VenueGames" WHERE "venueId" = p_venue ORDER BY "gameId";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetVenuePrices(p_venue TEXT)
+RETURNS TABLE (game_id INT, item_type TEXT, label INT, price INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "gameId", "itemType", label, price FROM "VenuePrices" WHERE "venueId" = p_venue
+    ORDER BY "gameId", "itemType", label;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetVenueInventory(p_venue TEXT)
+RETURNS TABLE (product_id INT, units INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "productId", units FROM "VenueInventory" WHERE "venueId" = p_venue ORDER BY "productId";
+$$;
+
+-- the shop of the venue with its own stock, a NULL venue gives the central stock of every product.
+CREATE OR REPLACE FUNCTION func_GetProducts(p_product_type INT, p_venue TEXT)
+RETURNS TABLE (id INT, product_name TEXT, price INT, description TEXT, units INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p.id::INT, p."productName"::TEXT, p.price::INT, p.description::TEXT, COALESCE(vi.units, p.units)::INT
+    FROM "Products" p
+    LEFT JOIN "VenueInventory" vi ON vi."venueId" = p_venue AND vi."productId" = p.id
+    WHERE p."productType" = p_product_type AND (p_venue IS NULL OR vi."productId" IS NOT NULL)
+    ORDER BY p.id;
+$$;
+
+-- only active venues are recorded, a made up venue leaves the code playable anywhere.
+CREATE OR REPLACE FUNCTION func_SetCodeVenue(p_code TEXT, p_venue TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "CodeVenues" (code, "venueId")
+    SELECT p_code, id FROM "Venues" WHERE id = p_venue AND "isActive"
+    ON CONFLICT (code) DO NOTHING;
+$$;
+
+-- the venue the code was bought for and the venue of the machine, either is NULL when unknown.
+CREATE OR REPLACE FUNCTION func_GetCodeVenue(p_code TEXT, p_machine_id TEXT)
+RETURNS TABLE (code_venue TEXT, machine_venue TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT (SELECT "venueId" FROM "CodeVenues" WHERE code = p_code),
+           (SELECT venue FROM "Machines" WHERE id = p_machine_id);
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetScores(p_game_id INT, p_limit INT, p_venues TEXT[])
+RETURNS TABLE (id INT, game_id INT, code TEXT, machine_id TEXT, venue TEXT, player_id INT,
+               name TEXT, score BIGINT, is_removed BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, "gameId", code, "machineId", venue, "playerId", name, score, "isRemoved", "createdAt"
+    FROM "Scores"
+    WHERE (p_game_id IS NULL OR "gameId" = p_game_id) AND (p_venues IS NULL OR venue = ANY(p_venues))
+    ORDER BY "createdAt" DESC
+    LIMIT p_limit;
+$$;
+
+-- no row when the score belongs to a venue outside p_venues.
+CREATE OR REPLACE FUNCTION func_RemoveScore(p_score_id INT, p_venues TEXT[])
+RETURNS TABLE (id INT, game_id INT, code TEXT, machine_id TEXT, venue TEXT, player_id INT,
+               name TEXT, score BIGINT, is_removed BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql
+AS $$
+    UPDATE "Scores" SET "isRemoved" = TRUE
+    WHERE id = p_score_id AND (p_venues IS NULL OR venue = ANY(p_venues))
+    RETURNING id, "gameId", code, "machineId", venue, "playerId", name, score, "isRemoved", "createdAt";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetMachines(p_venues TEXT[])
+RETURNS TABLE (id TEXT, name TEXT, venue TEXT, secret_key TEXT, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, name, venue, "secretKey", "createdAt" FROM "Machines"
+    WHERE p_venues IS NULL OR venue = ANY(p_venues)
+    ORDER BY id;
+$$;
@@ -0,0 +1,75 @@
+-- the emulator system of every game, filled from the codes already played and kept by the admins.
+CREATE TABLE IF NOT EXISTS "GameSystems" (
+    "gameId"   INT PRIMARY KEY,
+    system     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS "GameSystems_system" ON "GameSystems" (system);
+
+INSERT INTO "GameSystems" ("gameId", system)
+SELECT DISTINCT ON (gs."gameId") gs."gameId", c.system
+FROM "GameStatus" gs
+CROSS JOIN LATERAL func_CheckGameCode(gs.code::TEXT) c
+WHERE c.system IS NOT NULL
+ORDER BY gs."gameId"
+ON CONFLICT ("gameId") DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS "PlaySessions_game_started" ON "PlaySessions" ("gameId", "startedAt");
+
+CREATE OR REPLACE FUNCTION func_SetGameSystem(p_game_id INT, p_system TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "GameSystems" ("gameId", system) VALUES (p_game_id, p_system)
+    ON CONFLICT ("gameId") DO UPDATE SET system = EXCLUDED.system;
+$$;
+
+-- one page of the catalogue of the venue. from_price is the cheapest tier at the venue before the pricing
+-- rules, so a happy hour never moves a game between pages. plays counts the sessions of the last 30 days.
+-- The cursor is the sort value and the id of the last game of the previous page, newer games have higher ids.
+CREATE OR REPLACE FUNCTION func_SearchGames(p_venue TEXT, p_query TEXT, p_system TEXT, p_mode TEXT,
+                                            p_min_price INT, p_max_price INT, p_sort TEXT,
+                                            p_cursor_value BIGINT, p_cursor_id INT, p_limit INT)
+RETURNS TABLE (game_id INT, name TEXT, thumbnail TEXT, system TEXT, from_price INT, plays BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    WITH tiers AS (
+        SELECT p.game_id, MIN(p.price) AS from_price,
+               bool_or(p.item_type = 'time') AS is_timed, bool_or(p.item_type = 'level') AS is_level
+        FROM func_GetGamesPrices(p_venue) p
+        GROUP BY p.game_id
+    ), recent AS (
+        SELECT "gameId" AS game_id, COUNT(*) AS plays
+        FROM "PlaySessions"
+        WHERE "startedAt" > now() - INTERVAL '30 days'
+        GROUP BY "gameId"
+    ), games AS (
+        SELECT g.game_id, g.name, g.thumbnail, gs.system, t.from_price, COALESCE(r.plays, 0) AS plays,
+               t.is_timed, t.is_level
+        FROM func_GetGamesForUsers(p_venue) g
+        JOIN tiers t ON t.game_id = g.game_id -- games without tiers can't be bought
+        LEFT JOIN "GameSystems" gs ON gs."gameId" = g.game_id
+        LEFT JOIN recent r ON r.game_id = g.game_id
+    )
+    SELECT game_id, name, thumbnail, system, from_price, plays
+    FROM games
+    WHERE (p_query IS NULL
+           OR to_tsvector('simple', name) @@ websearch_to_tsquery('simple', p_query)
+           OR name ILIKE '%' || p_query || '%')
+      AND (p_system IS NULL OR system = p_system)
+      AND (p_mode IS NULL OR (p_mode = 'timed' AND is_timed) OR (p_mode = 'level' AND is_level))
+      AND (p_min_price IS NULL OR from_price >= p_min_price)
+      AND (p_max_price IS NULL OR from_price <= p_max_price)
+      AND (p_cursor_id IS NULL OR CASE p_sort
+            WHEN 'newest' THEN game_id < p_cursor_id
+            WHEN 'price' THEN from_price > p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            WHEN 'price_desc' THEN from_price < p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            ELSE plays < p_cursor_value OR (plays = p_cursor_value AND game_id > p_cursor_id)
+          END)
+    ORDER BY
+        CASE WHEN p_sort = 'price' THEN from_price END ASC,
+        CASE WHEN p_sort = 'price_desc' THEN from_price END DESC,
+        CASE WHEN p_sort = 'newest' THEN game_id END DESC,
+        CASE WHEN p_sort = 'popular' THEN plays END DESC,
+        game_id ASC
+    LIMIT p_limit;
+$$;
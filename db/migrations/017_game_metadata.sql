@@ -0,0 +1,109 @@
+-- what the catalogue shows about a game besides its name and prices, kept by the admins.
+-- genres and tags are stored lowercase, controls maps a button to what it does.
+CREATE TABLE IF NOT EXISTS "GameMetadata" (
+    "gameId"       INT PRIMARY KEY,
+    description    TEXT,
+    genres         TEXT[]      NOT NULL DEFAULT '{}',
+    tags           TEXT[]      NOT NULL DEFAULT '{}',
+    "minPlayers"   INT CHECK ("minPlayers" >= 1),
+    "maxPlayers"   INT CHECK ("maxPlayers" >= "minPlayers"),
+    "releaseYear"  INT,
+    controls       JSONB,
+    "ageRating"    TEXT,
+    "updatedAt"    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "GameMetadata_genres" ON "GameMetadata" USING GIN (genres);
+CREATE INDEX IF NOT EXISTS "GameMetadata_tags" ON "GameMetadata" USING GIN (tags);
+
+CREATE OR REPLACE FUNCTION func_UpsertGameMetadata(p_game_id INT, p_description TEXT, p_genres TEXT[], p_tags TEXT[],
+                                                   p_min_players INT, p_max_players INT, p_release_year INT,
+                                                   p_controls JSONB, p_age_rating TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "GameMetadata" ("gameId", description, genres, tags, "minPlayers", "maxPlayers", "releaseYear",
+                                controls, "ageRating")
+    VALUES (p_game_id, p_description, p_genres, p_tags, p_min_players, p_max_players, p_release_year,
+            p_controls, p_age_rating)
+    ON CONFLICT ("gameId") DO UPDATE SET
+        description = EXCLUDED.description, genres = EXCLUDED.genres, tags = EXCLUDED.tags,
+        "minPlayers" = EXCLUDED."minPlayers", "maxPlayers" = EXCLUDED."maxPlayers",
+        "releaseYear" = EXCLUDED."releaseYear", controls = EXCLUDED.controls,
+        "ageRating" = EXCLUDED."ageRating", "updatedAt" = now();
+$$;
+
+-- the genres to browse at the venue with how many games each has.
+CREATE OR REPLACE FUNCTION func_GetGenres(p_venue TEXT)
+RETURNS TABLE (genre TEXT, games BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT genre, COUNT(*)
+    FROM func_GetGamesForUsers(p_venue) g
+    JOIN "GameMetadata" m ON m."gameId" = g.game_id
+    CROSS JOIN LATERAL unnest(m.genres) AS genre
+    GROUP BY genre
+    ORDER BY genre;
+$$;
+
+-- the search takes the metadata filters now, the description and tags are searched along with the name.
+-- games without metadata count as single player.
+DROP FUNCTION IF EXISTS func_SearchGames(TEXT, TEXT, TEXT, TEXT, INT, INT, TEXT, BIGINT, INT, INT);
+
+CREATE OR REPLACE FUNCTION func_SearchGames(p_venue TEXT, p_query TEXT, p_system TEXT, p_mode TEXT,
+                                            p_min_price INT, p_max_price INT, p_genre TEXT, p_tag TEXT,
+                                            p_players INT, p_sort TEXT,
+                                            p_cursor_value BIGINT, p_cursor_id INT, p_limit INT)
+RETURNS TABLE (game_id INT, name TEXT, thumbnail TEXT, system TEXT, from_price INT, plays BIGINT,
+               description TEXT, genres TEXT[], tags TEXT[], min_players INT, max_players INT,
+               release_year INT, controls JSONB, age_rating TEXT)
+LANGUAGE sql STABLE
+AS $$
+    WITH tiers AS (
+        SELECT p.game_id, MIN(p.price) AS from_price,
+               bool_or(p.item_type = 'time') AS is_timed, bool_or(p.item_type = 'level') AS is_level
+        FROM func_GetGamesPrices(p_venue) p
+        GROUP BY p.game_id
+    ), recent AS (
+        SELECT "gameId" AS game_id, COUNT(*) AS plays
+        FROM "PlaySessions"
+        WHERE "startedAt" > now() - INTERVAL '30 days'
+        GROUP BY "gameId"
+    ), games AS (
+        SELECT g.game_id, g.name, g.thumbnail, gs.system, t.from_price, COALESCE(r.plays, 0) AS plays,
+               t.is_timed, t.is_level, m.description, COALESCE(m.genres, '{}') AS genres,
+               COALESCE(m.tags, '{}') AS tags, m."minPlayers" AS min_players, m."maxPlayers" AS max_players,
+               m."releaseYear" AS release_year, m.controls, m."ageRating" AS age_rating
+        FROM func_GetGamesForUsers(p_venue) g
+        JOIN tiers t ON t.game_id = g.game_id -- games without tiers can't be bought
+        LEFT JOIN "GameSystems" gs ON gs."gameId" = g.game_id
+        LEFT JOIN "GameMetadata" m ON m."gameId" = g.game_id
+        LEFT JOIN recent r ON r.game_id = g.game_id
+    )
+    SELECT game_id, name, thumbnail, system, from_price, plays, description, genres, tags, min_players,
+           max_players, release_year, controls, age_rating
+    FROM games
+    WHERE (p_query IS NULL
+           OR to_tsvector('simple', name || ' ' || COALESCE(description, '') || ' ' || array_to_string(tags, ' '))
+              @@ websearch_to_tsquery('simple', p_query)
+           OR name ILIKE '%' || p_query || '%')
+      AND (p_system IS NULL OR system = p_system)
+      AND (p_mode IS NULL OR (p_mode = 'timed' AND is_timed) OR (p_mode = 'level' AND is_level))
+      AND (p_min_price IS NULL OR from_price >= p_min_price)
+      AND (p_max_price IS NULL OR from_price <= p_max_price)
+      AND (p_genre IS NULL OR p_genre = ANY(genres))
+      AND (p_tag IS NULL OR p_tag = ANY(tags))
+      AND (p_players IS NULL OR (COALESCE(min_players, 1) <= p_players AND COALESCE(max_players, min_players, 1) >= p_players))
+      AND (p_cursor_id IS NULL OR CASE p_sort
+            WHEN 'newest' THEN game_id < p_cursor_id
+            WHEN 'price' THEN from_price > p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            WHEN 'price_desc' THEN from_price < p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            ELSE plays < p_cursor_value OR (plays = p_cursor_value AND game_id > p_cursor_id)
+          END)
+    ORDER BY
+        CASE WHEN p_sort = 'price' THEN from_price END ASC,
+        CASE WHEN p_sort = 'price_desc' THEN from_price END DESC,
+        CASE WHEN p_sort = 'newest' THEN game_id END DESC,
+        CASE WHEN p_sort = 'popular' THEN plays END DESC,
+        game_id ASC
+    LIMIT p_limit;
+$$;
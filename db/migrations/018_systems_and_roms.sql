@@ -0,0 +1,133 @@
+-- the emulated systems, core is the libretro core the cabinets load. bios lists the files the core
+-- needs next to the ROM as [{"file": "...", "sha256": "..."}].
+CREATE TABLE IF NOT EXISTS "EmulatorSystems" (
+    id           TEXT PRIMARY KEY,
+    name         TEXT        NOT NULL,
+    core         TEXT        NOT NULL DEFAULT '',
+    bios         JSONB       NOT NULL DEFAULT '[]',
+    "isActive"   BOOLEAN     NOT NULL DEFAULT TRUE,
+    "createdAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- the systems games already point to, the admins fill the cores and BIOS in afterwards.
+INSERT INTO "EmulatorSystems" (id, name)
+SELECT DISTINCT system, system FROM "GameSystems"
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE "GameSystems" DROP CONSTRAINT IF EXISTS "GameSystems_system_fkey";
+ALTER TABLE "GameSystems" ADD CONSTRAINT "GameSystems_system_fkey" FOREIGN KEY (system) REFERENCES "EmulatorSystems"(id);
+
+-- every uploaded version of the ROM of a game, the current one is what the cabinets install.
+CREATE TABLE IF NOT EXISTS "Roms" (
+    id            SERIAL PRIMARY KEY,
+    "gameId"      INT         NOT NULL,
+    "systemId"    TEXT        NOT NULL REFERENCES "EmulatorSystems"(id),
+    "fileName"    TEXT        NOT NULL,
+    "objectKey"   TEXT        NOT NULL UNIQUE,
+    sha256        TEXT        NOT NULL,
+    "sizeBytes"   BIGINT      NOT NULL,
+    version       TEXT        NOT NULL,
+    "isCurrent"   BOOLEAN     NOT NULL DEFAULT FALSE,
+    "uploadedBy"  INT         NOT NULL,
+    "uploadedAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE ("gameId", version)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "Roms_current" ON "Roms" ("gameId") WHERE "isCurrent";
+
+CREATE TABLE IF NOT EXISTS "MachineRoms" (
+    "machineId"    TEXT        NOT NULL REFERENCES "Machines"(id),
+    "romId"        INT         NOT NULL REFERENCES "Roms"(id),
+    "installedAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("machineId", "romId")
+);
+
+CREATE OR REPLACE FUNCTION func_GetEmulatorSystems()
+RETURNS TABLE (id TEXT, name TEXT, core TEXT, bios JSONB, is_active BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, name, core, bios, "isActive", "createdAt" FROM "EmulatorSystems" ORDER BY id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_UpsertEmulatorSystem(p_id TEXT, p_name TEXT, p_core TEXT, p_bios JSONB, p_is_active BOOLEAN)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "EmulatorSystems" (id, name, core, bios, "isActive") VALUES (p_id, p_name, p_core, p_bios, p_is_active)
+    ON CONFLICT (id) DO UPDATE SET
+        name = EXCLUDED.name, core = EXCLUDED.core, bios = EXCLUDED.bios, "isActive" = EXCLUDED."isActive";
+$$;
+
+-- the new version becomes the current ROM of the game.
+CREATE OR REPLACE FUNCTION func_InsertRom(p_game_id INT, p_system_id TEXT, p_file_name TEXT, p_object_key TEXT,
+                                         p_sha256 TEXT, p_size_bytes BIGINT, p_version TEXT, p_uploaded_by INT)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_rom_id INT;
+BEGIN
+    UPDATE "Roms" SET "isCurrent" = FALSE WHERE "gameId" = p_game_id AND "isCurrent";
+
+    INSERT INTO "Roms" ("gameId", "systemId", "fileName", "objectKey", sha256, "sizeBytes", version, "isCurrent", "uploadedBy")
+    VALUES (p_game_id, p_system_id, p_file_name, p_object_key, p_sha256, p_size_bytes, p_version, TRUE, p_uploaded_by)
+    RETURNING id INTO v_rom_id;
+
+    INSERT INTO "GameSystems" ("gameId", system) VALUES (p_game_id, p_system_id)
+    ON CONFLICT ("gameId") DO UPDATE SET system = EXCLUDED.system;
+
+    RETURN v_rom_id;
+END;
+$$;
+
+-- rolls the game back or forward to another uploaded version, FALSE when the ROM doesn't exist.
+CREATE OR REPLACE FUNCTION func_SetCurrentRom(p_rom_id INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_game_id INT;
+BEGIN
+    SELECT "gameId" INTO v_game_id FROM "Roms" WHERE id = p_rom_id;
+    IF v_game_id IS NULL THEN
+        RETURN FALSE;
+    END IF;
+
+    UPDATE "Roms" SET "isCurrent" = FALSE WHERE "gameId" = v_game_id AND "isCurrent";
+    UPDATE "Roms" SET "isCurrent" = TRUE WHERE id = p_rom_id;
+    RETURN TRUE;
+END;
+$$;
+
+-- p_game_id NULL gives every ROM, p_rom_id narrows it to one.
+CREATE OR REPLACE FUNCTION func_GetRoms(p_game_id INT, p_rom_id INT)
+RETURNS TABLE (id INT, game_id INT, system_id TEXT, file_name TEXT, object_key TEXT, sha256 TEXT, size_bytes BIGINT,
+               version TEXT, is_current BOOLEAN, uploaded_at TIMESTAMPTZ, machines TEXT[])
+LANGUAGE sql STABLE
+AS $$
+    SELECT r.id, r."gameId", r."systemId", r."fileName", r."objectKey", r.sha256, r."sizeBytes", r.version,
+           r."isCurrent", r."uploadedAt",
+           COALESCE(ARRAY(SELECT mr."machineId" FROM "MachineRoms" mr WHERE mr."romId" = r.id ORDER BY mr."machineId"), '{}')
+    FROM "Roms" r
+    WHERE (p_game_id IS NULL OR r."gameId" = p_game_id) AND (p_rom_id IS NULL OR r.id = p_rom_id)
+    ORDER BY r."gameId", r."uploadedAt" DESC;
+$$;
+
+-- the current ROM of the game the code was bought for, with what the cabinet needs to run it.
+CREATE OR REPLACE FUNCTION func_GetCodeRom(p_code TEXT)
+RETURNS TABLE (rom_id INT, sha256 TEXT, version TEXT, core TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT r.id, r.sha256, r.version, s.core
+    FROM "GameStatus" gs
+    JOIN "Roms" r ON r."gameId" = gs."gameId" AND r."isCurrent"
+    JOIN "EmulatorSystems" s ON s.id = r."systemId"
+    WHERE gs.code = p_code;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RecordRomInstall(p_machine_id TEXT, p_rom_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "MachineRoms" ("machineId", "romId") VALUES (p_machine_id, p_rom_id)
+    ON CONFLICT ("machineId", "romId") DO UPDATE SET "installedAt" = now();
+$$;
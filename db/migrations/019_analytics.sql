@@ -0,0 +1,179 @@
+-- nightly rollups the analytics read from, one row per game and venue a day and one per cabinet an hour.
+-- venue is '' for codes sold without one. revenue is the price of the codes sold, vouchers count as sold
+-- but bring nothing. The session lengths only count finished timed sessions, next to what was bought for them.
+CREATE TABLE IF NOT EXISTS "GameDailyStats" (
+    day                 DATE   NOT NULL,
+    "gameId"            INT    NOT NULL,
+    venue               TEXT   NOT NULL,
+    name                TEXT   NOT NULL,
+    plays               INT    NOT NULL DEFAULT 0,
+    "codesSold"         INT    NOT NULL DEFAULT 0,
+    revenue             BIGINT NOT NULL DEFAULT 0,
+    "timedSessions"     INT    NOT NULL DEFAULT 0,
+    "playedSeconds"     BIGINT NOT NULL DEFAULT 0,
+    "purchasedSeconds"  BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, "gameId", venue)
+);
+
+-- busySeconds is the time a session was running in the hour, sessions counts the ones started in it.
+CREATE TABLE IF NOT EXISTS "MachineHourlyStats" (
+    day            DATE     NOT NULL,
+    hour           SMALLINT NOT NULL CHECK (hour BETWEEN 0 AND 23),
+    "machineId"    TEXT     NOT NULL REFERENCES "Machines"(id),
+    venue          TEXT     NOT NULL,
+    sessions       INT      NOT NULL DEFAULT 0,
+    "busySeconds"  INT      NOT NULL DEFAULT 0,
+    PRIMARY KEY (day, hour, "machineId")
+);
+CREATE INDEX IF NOT EXISTS "MachineHourlyStats_venue" ON "MachineHourlyStats" (venue, day);
+
+CREATE TABLE IF NOT EXISTS "AnalyticsRollups" (
+    day         DATE PRIMARY KEY,
+    "rolledAt"  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS "GameStatus_createdAt" ON "GameStatus" ("createdAt");
+CREATE INDEX IF NOT EXISTS "PlaySessions_machine_started" ON "PlaySessions" ("machineId", "startedAt");
+
+-- replaces the rollup of the day, so a day can be rolled up again after a late fix to the data.
+-- a session that never reported its end is taken to have run for the time bought.
+CREATE OR REPLACE FUNCTION func_RollupAnalytics(p_day DATE)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_start TIMESTAMPTZ := p_day::TIMESTAMPTZ;
+    v_end   TIMESTAMPTZ := (p_day + 1)::TIMESTAMPTZ;
+BEGIN
+    DELETE FROM "GameDailyStats" WHERE day = p_day;
+    DELETE FROM "MachineHourlyStats" WHERE day = p_day;
+
+    INSERT INTO "GameDailyStats" (day, "gameId", venue, name, plays, "codesSold", revenue, "timedSessions",
+                                  "playedSeconds", "purchasedSeconds")
+    SELECT p_day, f.game_id, f.venue, MAX(f.name), SUM(f.plays), SUM(f.sold), SUM(f.revenue), SUM(f.timed),
+           SUM(f.played), SUM(f.purchased)
+    FROM (
+        SELECT gs."gameId"::INT AS game_id, COALESCE(cv."venueId", '') AS venue, gs.name::TEXT AS name,
+               0 AS plays, 1 AS sold,
+               CASE WHEN gs."paymentId" LIKE 'voucher-%' THEN 0 ELSE gs.price::BIGINT END AS revenue,
+               0 AS timed, 0::BIGINT AS played, 0::BIGINT AS purchased
+        FROM "GameStatus" gs
+        LEFT JOIN "CodeVenues" cv ON cv.code = gs.code
+        WHERE gs."createdAt" >= v_start AND gs."createdAt" < v_end
+        UNION ALL
+        SELECT ps."gameId", m.venue, gs.name::TEXT, 1, 0, 0::BIGINT,
+               CASE WHEN finished THEN 1 ELSE 0 END,
+               CASE WHEN finished THEN EXTRACT(EPOCH FROM ps."endedAt" - ps."startedAt")::BIGINT ELSE 0 END,
+               CASE WHEN finished THEN gs."playTime"::BIGINT * 60 ELSE 0 END
+        FROM "PlaySessions" ps
+        JOIN "Machines" m ON m.id = ps."machineId"
+        JOIN "GameStatus" gs ON gs.code = ps.code
+        CROSS JOIN LATERAL (SELECT ps."endedAt" IS NOT NULL AND gs."playTime" IS NOT NULL AS finished) s
+        WHERE ps."startedAt" >= v_start AND ps."startedAt" < v_end
+    ) f
+    GROUP BY f.game_id, f.venue;
+
+    INSERT INTO "MachineHourlyStats" (day, hour, "machineId", venue, sessions, "busySeconds")
+    WITH sessions AS (
+        SELECT ps."machineId", ps."startedAt",
+               COALESCE(ps."endedAt", ps."startedAt" + make_interval(mins => gs."playTime"::INT), ps."startedAt") AS ended_at
+        FROM "PlaySessions" ps
+        LEFT JOIN "GameStatus" gs ON gs.code = ps.code
+        WHERE ps."startedAt" < v_end AND ps."startedAt" >= v_start - INTERVAL '1 day' -- ones running over midnight
+    ), hours AS (
+        SELECT h AS hour, (p_day + make_interval(hours => h))::TIMESTAMPTZ AS starts_at
+        FROM generate_series(0, 23) h
+    )
+    SELECT p_day, h.hour, m.id, m.venue,
+           COUNT(s."startedAt") FILTER (WHERE s."startedAt" >= h.starts_at),
+           LEAST(COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(s.ended_at, h.starts_at + INTERVAL '1 hour')
+                                                - GREATEST(s."startedAt", h.starts_at))), 0), 3600)::INT
+    FROM "Machines" m
+    CROSS JOIN hours h
+    LEFT JOIN sessions s ON s."machineId" = m.id
+                        AND s."startedAt" < h.starts_at + INTERVAL '1 hour' AND s.ended_at > h.starts_at
+    WHERE m."createdAt" < v_end
+    GROUP BY h.hour, m.id, m.venue;
+
+    INSERT INTO "AnalyticsRollups" (day) VALUES (p_day)
+    ON CONFLICT (day) DO UPDATE SET "rolledAt" = now();
+END;
+$$;
+
+-- rolls up every finished day after the last one rolled up, from the first day with data on a fresh
+-- database. returns how many days were rolled up.
+CREATE OR REPLACE FUNCTION func_RollupPendingAnalytics()
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_day  DATE;
+    v_days INT := 0;
+BEGIN
+    SELECT COALESCE(MAX(day) + 1,
+                    LEAST((SELECT MIN("createdAt")::DATE FROM "GameStatus"),
+                          (SELECT MIN("startedAt")::DATE FROM "PlaySessions")),
+                    CURRENT_DATE)
+    INTO v_day FROM "AnalyticsRollups";
+
+    WHILE v_day < CURRENT_DATE LOOP
+        PERFORM func_RollupAnalytics(v_day);
+        v_day := v_day + 1;
+        v_days := v_days + 1;
+    END LOOP;
+    RETURN v_days;
+END;
+$$;
+
+-- p_to is exclusive, p_venues NULL for every venue.
+CREATE OR REPLACE FUNCTION func_GetGameAnalytics(p_from DATE, p_to DATE, p_venues TEXT[])
+RETURNS TABLE (game_id INT, name TEXT, plays BIGINT, codes_sold BIGINT, revenue BIGINT,
+               avg_played_minutes FLOAT8, avg_purchased_minutes FLOAT8)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "gameId", MAX(name), SUM(plays), SUM("codesSold"), SUM(revenue)::BIGINT,
+           COALESCE(SUM("playedSeconds")::FLOAT8 / NULLIF(SUM("timedSessions"), 0) / 60, 0),
+           COALESCE(SUM("purchasedSeconds")::FLOAT8 / NULLIF(SUM("timedSessions"), 0) / 60, 0)
+    FROM "GameDailyStats"
+    WHERE day >= p_from AND day < p_to AND (p_venues IS NULL OR venue = ANY(p_venues))
+    GROUP BY "gameId"
+    ORDER BY SUM(plays) DESC, "gameId";
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetGameDailyAnalytics(p_from DATE, p_to DATE, p_game_id INT, p_venues TEXT[])
+RETURNS TABLE (day DATE, plays BIGINT, codes_sold BIGINT, revenue BIGINT,
+               avg_played_minutes FLOAT8, avg_purchased_minutes FLOAT8)
+LANGUAGE sql STABLE
+AS $$
+    SELECT day, SUM(plays), SUM("codesSold"), SUM(revenue)::BIGINT,
+           COALESCE(SUM("playedSeconds")::FLOAT8 / NULLIF(SUM("timedSessions"), 0) / 60, 0),
+           COALESCE(SUM("purchasedSeconds")::FLOAT8 / NULLIF(SUM("timedSessions"), 0) / 60, 0)
+    FROM "GameDailyStats"
+    WHERE day >= p_from AND day < p_to AND "gameId" = p_game_id AND (p_venues IS NULL OR venue = ANY(p_venues))
+    GROUP BY day
+    ORDER BY day;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetMachineUtilisation(p_from DATE, p_to DATE, p_machine_id TEXT, p_venues TEXT[])
+RETURNS TABLE (machine_id TEXT, venue TEXT, day DATE, hour SMALLINT, sessions INT, busy_seconds INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "machineId", venue, day, hour, sessions, "busySeconds"
+    FROM "MachineHourlyStats"
+    WHERE day >= p_from AND day < p_to
+      AND (p_machine_id IS NULL OR "machineId" = p_machine_id)
+      AND (p_venues IS NULL OR venue = ANY(p_venues))
+    ORDER BY "machineId", day, hour;
+$$;
+
+-- weekday 0 is sunday. machine_hours is how many cabinet hours the cell covers, for the utilisation.
+CREATE OR REPLACE FUNCTION func_GetPeakHours(p_from DATE, p_to DATE, p_venues TEXT[])
+RETURNS TABLE (weekday INT, hour INT, sessions BIGINT, busy_seconds BIGINT, machine_hours BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT EXTRACT(DOW FROM day)::INT, hour::INT, SUM(sessions), SUM("busySeconds"), COUNT(*)
+    FROM "MachineHourlyStats"
+    WHERE day >= p_from AND day < p_to AND (p_venues IS NULL OR venue = ANY(p_venues))
+    GROUP BY 1, 2
+    ORDER BY 1, 2;
+$$;
@@ -0,0 +1,30 @@
+CREATE INDEX IF NOT EXISTS "CapturedPayments_capturedAt" ON "CapturedPayments" ("capturedAt");
+CREATE INDEX IF NOT EXISTS "Refunds_createdAt" ON "Refunds" ("createdAt");
+
+-- revenue per period, purpose, payment method and venue, amounts in paise. p_period is day, week or month,
+-- weeks start on monday. A refund is netted in the period it was made, under the purpose, method and venue
+-- of the refunded payment. p_to is exclusive, venue is '' for payments made without one.
+CREATE OR REPLACE FUNCTION func_GetRevenue(p_from TIMESTAMPTZ, p_to TIMESTAMPTZ, p_period TEXT, p_venues TEXT[])
+RETURNS TABLE (period DATE, purpose TEXT, method TEXT, venue TEXT, payments BIGINT, gross BIGINT, refunded BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    WITH facts AS (
+        SELECT cp."capturedAt" AS at, cp.purpose, cp.method, COALESCE(po.venue, '') AS venue,
+               1 AS payments, cp.amount AS gross, 0::BIGINT AS refunded
+        FROM "CapturedPayments" cp
+        LEFT JOIN "PaymentOrders" po ON po."orderId" = cp."orderId"
+        WHERE cp."capturedAt" >= p_from AND cp."capturedAt" < p_to
+        UNION ALL
+        SELECT r."createdAt", cp.purpose, cp.method, COALESCE(po.venue, ''), 0, 0::BIGINT, r.amount
+        FROM "Refunds" r
+        JOIN "CapturedPayments" cp ON cp."paymentId" = r."paymentId"
+        LEFT JOIN "PaymentOrders" po ON po."orderId" = cp."orderId"
+        WHERE r."createdAt" >= p_from AND r."createdAt" < p_to
+    )
+    SELECT date_trunc(p_period, at)::DATE, purpose, method, venue, SUM(payments), SUM(gross)::BIGINT,
+           SUM(refunded)::BIGINT
+    FROM facts
+    WHERE p_venues IS NULL OR venue = ANY(p_venues)
+    GROUP BY 1, 2, 3, 4
+    ORDER BY 1, 2, 3, 4;
+$$;
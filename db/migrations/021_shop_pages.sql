@@ -0,0 +1,34 @@
+-- one page of the shop of the venue, total counts every product of the listing for the pager.
+-- newest is the highest id, the default keeps the listing in id order.
+CREATE OR REPLACE FUNCTION func_GetProductsPage(p_product_type INT, p_venue TEXT, p_sort TEXT, p_limit INT, p_offset INT)
+RETURNS TABLE (id INT, product_name TEXT, price INT, description TEXT, units INT, total BIGINT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, product_name, price, description, units, COUNT(*) OVER ()
+    FROM func_GetProducts(p_product_type, p_venue)
+    ORDER BY
+        CASE WHEN p_sort = 'price' THEN price END ASC,
+        CASE WHEN p_sort = 'price_desc' THEN price END DESC,
+        CASE WHEN p_sort = 'newest' THEN id END DESC,
+        id ASC
+    LIMIT p_limit OFFSET p_offset;
+$$;
+
+CREATE OR REPLACE FUNCTION func_CountProducts(p_product_type INT, p_venue TEXT)
+RETURNS BIGINT
+LANGUAGE sql STABLE
+AS $$
+    SELECT COUNT(*) FROM func_GetProducts(p_product_type, p_venue);
+$$;
+
+-- no row when the product doesn't exist or the venue doesn't stock it.
+CREATE OR REPLACE FUNCTION func_GetProduct(p_product_id INT, p_venue TEXT)
+RETURNS TABLE (id INT, product_name TEXT, price INT, description TEXT, units INT, product_type INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p.id::INT, p."productName"::TEXT, p.price::INT, p.description::TEXT, COALESCE(vi.units, p.units)::INT,
+           p."productType"::INT
+    FROM "Products" p
+    LEFT JOIN "VenueInventory" vi ON vi."venueId" = p_venue AND vi."productId" = p.id
+    WHERE p.id = p_product_id AND (p_venue IS NULL OR vi."productId" IS NOT NULL);
+$$;
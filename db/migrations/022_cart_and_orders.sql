@@ -0,0 +1,167 @@
+-- carts of the players who are logged in, the carts of guests live in redis under their cart session.
+CREATE TABLE IF NOT EXISTS "Carts" (
+    "playerId"   INT         NOT NULL REFERENCES "Players"(id),
+    "productId"  INT         NOT NULL,
+    quantity     INT         NOT NULL CHECK (quantity > 0),
+    "updatedAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("playerId", "productId")
+);
+
+-- an order is created pending at checkout with the prices of that moment, the payment makes it paid.
+-- orders of guests keep the cart session, it is how they find them again. amount is in paise.
+CREATE TABLE IF NOT EXISTS "ShopOrders" (
+    id                 SERIAL PRIMARY KEY,
+    "razorpayOrderId"  TEXT        NOT NULL UNIQUE,
+    "playerId"         INT REFERENCES "Players"(id),
+    "guestSession"     TEXT,
+    venue              TEXT REFERENCES "Venues"(id),
+    amount             BIGINT      NOT NULL CHECK (amount > 0),
+    status             TEXT        NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'paid', 'refunded')),
+    "paymentId"        TEXT UNIQUE,
+    "createdAt"        TIMESTAMPTZ NOT NULL DEFAULT now(),
+    "paidAt"           TIMESTAMPTZ,
+    CHECK ("playerId" IS NOT NULL OR "guestSession" IS NOT NULL)
+);
+CREATE INDEX IF NOT EXISTS "ShopOrders_player" ON "ShopOrders" ("playerId");
+CREATE INDEX IF NOT EXISTS "ShopOrders_guestSession" ON "ShopOrders" ("guestSession");
+
+-- title and price are copied, the order shouldn't change with the product.
+CREATE TABLE IF NOT EXISTS "ShopOrderItems" (
+    "orderId"    INT  NOT NULL REFERENCES "ShopOrders"(id),
+    "productId"  INT  NOT NULL,
+    title        TEXT NOT NULL,
+    price        INT  NOT NULL,
+    quantity     INT  NOT NULL CHECK (quantity > 0),
+    PRIMARY KEY ("orderId", "productId")
+);
+
+CREATE OR REPLACE FUNCTION func_GetCart(p_player_id INT)
+RETURNS TABLE (product_id INT, quantity INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT "productId", quantity FROM "Carts" WHERE "playerId" = p_player_id ORDER BY "updatedAt", "productId";
+$$;
+
+-- a quantity of 0 takes the product out of the cart.
+CREATE OR REPLACE FUNCTION func_SetCartItem(p_player_id INT, p_product_id INT, p_quantity INT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF p_quantity <= 0 THEN
+        DELETE FROM "Carts" WHERE "playerId" = p_player_id AND "productId" = p_product_id;
+        RETURN;
+    END IF;
+
+    INSERT INTO "Carts" ("playerId", "productId", quantity) VALUES (p_player_id, p_product_id, p_quantity)
+    ON CONFLICT ("playerId", "productId") DO UPDATE SET quantity = EXCLUDED.quantity, "updatedAt" = now();
+END;
+$$;
+
+-- adds the cart of the guest session to the cart of the player who just logged in.
+CREATE OR REPLACE FUNCTION func_MergeCart(p_player_id INT, p_product_ids INT[], p_quantities INT[])
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "Carts" ("playerId", "productId", quantity)
+    SELECT p_player_id, i.product_id, i.quantity
+    FROM unnest(p_product_ids, p_quantities) AS i(product_id, quantity)
+    WHERE i.quantity > 0
+    ON CONFLICT ("playerId", "productId") DO UPDATE SET quantity = "Carts".quantity + EXCLUDED.quantity, "updatedAt" = now();
+$$;
+
+CREATE OR REPLACE FUNCTION func_ClearCart(p_player_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    DELETE FROM "Carts" WHERE "playerId" = p_player_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_CreateShopOrder(p_razorpay_order_id TEXT, p_player_id INT, p_guest_session TEXT,
+                                                p_venue TEXT, p_amount BIGINT, p_product_ids INT[], p_titles TEXT[],
+                                                p_prices INT[], p_quantities INT[])
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_order_id INT;
+BEGIN
+    INSERT INTO "ShopOrders" ("razorpayOrderId", "playerId", "guestSession", venue, amount)
+    VALUES (p_razorpay_order_id, p_player_id, p_guest_session, p_venue, p_amount)
+    RETURNING id INTO v_order_id;
+
+    INSERT INTO "ShopOrderItems" ("orderId", "productId", title, price, quantity)
+    SELECT v_order_id, i.product_id, i.title, i.price, i.quantity
+    FROM unnest(p_product_ids, p_titles, p_prices, p_quantities) AS i(product_id, title, price, quantity);
+
+    RETURN v_order_id;
+END;
+$$;
+
+-- no row when the razorpay order wasn't a checkout.
+CREATE OR REPLACE FUNCTION func_GetShopOrder(p_razorpay_order_id TEXT)
+RETURNS TABLE (id INT, razorpay_order_id TEXT, player_id INT, guest_session TEXT, venue TEXT, amount BIGINT,
+               status TEXT, created_at TIMESTAMPTZ, paid_at TIMESTAMPTZ, items JSON)
+LANGUAGE sql STABLE
+AS $$
+    SELECT o.id, o."razorpayOrderId", o."playerId", o."guestSession", o.venue, o.amount, o.status, o."createdAt",
+           o."paidAt",
+           (SELECT json_agg(json_build_object('productId', i."productId", 'title', i.title, 'price', i.price,
+                                              'quantity', i.quantity) ORDER BY i."productId")
+            FROM "ShopOrderItems" i WHERE i."orderId" = o.id)
+    FROM "ShopOrders" o
+    WHERE o."razorpayOrderId" = p_razorpay_order_id;
+$$;
+
+-- the orders of the player, or of the guest session when there is no player.
+CREATE OR REPLACE FUNCTION func_GetShopOrders(p_player_id INT, p_guest_session TEXT)
+RETURNS TABLE (id INT, razorpay_order_id TEXT, player_id INT, guest_session TEXT, venue TEXT, amount BIGINT,
+               status TEXT, created_at TIMESTAMPTZ, paid_at TIMESTAMPTZ, items JSON)
+LANGUAGE sql STABLE
+AS $$
+    SELECT s.*
+    FROM "ShopOrders" o
+    CROSS JOIN LATERAL func_GetShopOrder(o."razorpayOrderId") s
+    WHERE (p_player_id IS NOT NULL AND o."playerId" = p_player_id)
+       OR (p_player_id IS NULL AND o."guestSession" = p_guest_session)
+    ORDER BY o."createdAt" DESC;
+$$;
+
+-- marks the pending order paid and takes what was bought out of the cart of the player, products
+-- added to the cart after the checkout stay. false when the order was already paid.
+CREATE OR REPLACE FUNCTION func_PayShopOrder(p_razorpay_order_id TEXT, p_payment_id TEXT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_order_id  INT;
+    v_player_id INT;
+BEGIN
+    UPDATE "ShopOrders" SET status = 'paid', "paymentId" = p_payment_id, "paidAt" = now()
+    WHERE "razorpayOrderId" = p_razorpay_order_id AND status = 'pending'
+    RETURNING id, "playerId" INTO v_order_id, v_player_id;
+
+    IF v_order_id IS NULL THEN
+        RETURN FALSE;
+    END IF;
+
+    IF v_player_id IS NOT NULL THEN
+        DELETE FROM "Carts" c
+        USING "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId"
+          AND c.quantity <= i.quantity;
+
+        UPDATE "Carts" c SET quantity = c.quantity - i.quantity, "updatedAt" = now()
+        FROM "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId";
+    END IF;
+    RETURN TRUE;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RefundShopOrder(p_payment_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "ShopOrders" SET status = 'refunded' WHERE "paymentId" = p_payment_id AND status = 'paid';
+$$;
@@ -0,0 +1,325 @@
+-- the units of a product used to fit in a byte.
+ALTER TABLE "Products" ALTER COLUMN units TYPE INT;
+
+-- every change to the units of a product, venue NULL is the central stock. count is the venue stock
+-- being set to what was counted, sale the units a paid shop order took.
+CREATE TABLE IF NOT EXISTS "StockMovements" (
+    id            BIGSERIAL PRIMARY KEY,
+    "productId"   INT         NOT NULL,
+    venue         TEXT REFERENCES "Venues"(id),
+    change        INT         NOT NULL,
+    "unitsAfter"  INT         NOT NULL,
+    kind          TEXT        NOT NULL CHECK (kind IN ('restock', 'adjustment', 'count', 'sale')),
+    reason        TEXT,
+    "orderId"     INT REFERENCES "ShopOrders"(id),
+    "adminId"     INT,
+    "createdAt"   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "StockMovements_product" ON "StockMovements" ("productId", "createdAt");
+
+-- units held for a shop order between the checkout and the payment. a hold past expiresAt no longer
+-- counts even before the job marks it expired.
+CREATE TABLE IF NOT EXISTS "StockReservations" (
+    "orderId"    INT         NOT NULL REFERENCES "ShopOrders"(id),
+    "productId"  INT         NOT NULL,
+    venue        TEXT,
+    quantity     INT         NOT NULL CHECK (quantity > 0),
+    status       TEXT        NOT NULL DEFAULT 'held' CHECK (status IN ('held', 'committed', 'released', 'expired')),
+    "expiresAt"  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY ("orderId", "productId")
+);
+CREATE INDEX IF NOT EXISTS "StockReservations_held" ON "StockReservations" ("productId", venue) WHERE status = 'held';
+
+-- units held by the other orders, p_order_id NULL counts every order.
+CREATE OR REPLACE FUNCTION func_HeldUnits(p_product_id INT, p_venue TEXT, p_order_id INT)
+RETURNS INT
+LANGUAGE sql STABLE
+AS $$
+    SELECT COALESCE(SUM(quantity), 0)::INT
+    FROM "StockReservations"
+    WHERE "productId" = p_product_id AND venue IS NOT DISTINCT FROM p_venue AND status = 'held'
+      AND "expiresAt" > now() AND (p_order_id IS NULL OR "orderId" <> p_order_id);
+$$;
+
+-- locks the stock row of the product until the end of the transaction, NULL when the product doesn't
+-- exist or the venue doesn't stock it.
+CREATE OR REPLACE FUNCTION func_LockStock(p_product_id INT, p_venue TEXT)
+RETURNS INT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_units INT;
+BEGIN
+    IF p_venue IS NULL THEN
+        SELECT units INTO v_units FROM "Products" WHERE id = p_product_id FOR UPDATE;
+    ELSE
+        SELECT units INTO v_units FROM "VenueInventory"
+        WHERE "venueId" = p_venue AND "productId" = p_product_id FOR UPDATE;
+    END IF;
+    RETURN v_units;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_WriteStock(p_product_id INT, p_venue TEXT, p_units INT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    IF p_venue IS NULL THEN
+        UPDATE "Products" SET units = p_units WHERE id = p_product_id;
+    ELSE
+        UPDATE "VenueInventory" SET units = p_units WHERE "venueId" = p_venue AND "productId" = p_product_id;
+    END IF;
+END;
+$$;
+
+-- adds the change to the stock and records it. no row when the product doesn't exist, applied is
+-- false when the stock would go below the units held for orders, or below 0. a restock makes the
+-- venue start stocking the product.
+CREATE OR REPLACE FUNCTION func_ChangeStock(p_product_id INT, p_venue TEXT, p_change INT, p_kind TEXT,
+                                            p_reason TEXT, p_admin_id INT)
+RETURNS TABLE (units_after INT, applied BOOLEAN)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_units INT;
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM "Products" WHERE id = p_product_id) THEN
+        RETURN;
+    END IF;
+    IF p_venue IS NOT NULL AND p_kind = 'restock' THEN
+        INSERT INTO "VenueInventory" ("venueId", "productId", units) VALUES (p_venue, p_product_id, 0)
+        ON CONFLICT ("venueId", "productId") DO NOTHING;
+    END IF;
+
+    v_units := func_LockStock(p_product_id, p_venue);
+    IF v_units IS NULL THEN
+        RETURN;
+    END IF;
+    IF v_units + p_change < func_HeldUnits(p_product_id, p_venue, NULL) OR v_units + p_change < 0 THEN
+        RETURN QUERY SELECT v_units, FALSE;
+        RETURN;
+    END IF;
+
+    PERFORM func_WriteStock(p_product_id, p_venue, v_units + p_change);
+    INSERT INTO "StockMovements" ("productId", venue, change, "unitsAfter", kind, reason, "adminId")
+    VALUES (p_product_id, p_venue, p_change, v_units + p_change, p_kind, p_reason, p_admin_id);
+
+    RETURN QUERY SELECT v_units + p_change, TRUE;
+END;
+$$;
+
+-- setting the stock of the venue is recorded as a count.
+CREATE OR REPLACE FUNCTION func_SetVenueInventory(p_venue TEXT, p_product_id INT, p_units INT)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_units INT;
+BEGIN
+    INSERT INTO "VenueInventory" ("venueId", "productId", units) VALUES (p_venue, p_product_id, 0)
+    ON CONFLICT ("venueId", "productId") DO NOTHING;
+
+    v_units := func_LockStock(p_product_id, p_venue);
+    IF v_units = p_units THEN
+        RETURN;
+    END IF;
+
+    PERFORM func_WriteStock(p_product_id, p_venue, p_units);
+    INSERT INTO "StockMovements" ("productId", venue, change, "unitsAfter", kind)
+    VALUES (p_product_id, p_venue, p_units - v_units, p_units, 'count');
+END;
+$$;
+
+-- the shop lists what can still be bought, the units held for orders are taken off.
+CREATE OR REPLACE FUNCTION func_GetProducts(p_product_type INT, p_venue TEXT)
+RETURNS TABLE (id INT, product_name TEXT, price INT, description TEXT, units INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p.id::INT, p."productName"::TEXT, p.price::INT, p.description::TEXT,
+           GREATEST(COALESCE(vi.units, p.units) - func_HeldUnits(p.id::INT, p_venue, NULL), 0)::INT
+    FROM "Products" p
+    LEFT JOIN "VenueInventory" vi ON vi."venueId" = p_venue AND vi."productId" = p.id
+    WHERE p."productType" = p_product_type AND (p_venue IS NULL OR vi."productId" IS NOT NULL)
+    ORDER BY p.id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetProduct(p_product_id INT, p_venue TEXT)
+RETURNS TABLE (id INT, product_name TEXT, price INT, description TEXT, units INT, product_type INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT p.id::INT, p."productName"::TEXT, p.price::INT, p.description::TEXT,
+           GREATEST(COALESCE(vi.units, p.units) - func_HeldUnits(p.id::INT, p_venue, NULL), 0)::INT,
+           p."productType"::INT
+    FROM "Products" p
+    LEFT JOIN "VenueInventory" vi ON vi."venueId" = p_venue AND vi."productId" = p.id
+    WHERE p.id = p_product_id AND (p_venue IS NULL OR vi."productId" IS NOT NULL);
+$$;
+
+-- the checkout now holds the units of the order. when a product doesn't have enough units left
+-- nothing is created and the row names it with what is left.
+DROP FUNCTION IF EXISTS func_CreateShopOrder(TEXT, INT, TEXT, TEXT, BIGINT, INT[], TEXT[], INT[], INT[]);
+
+CREATE OR REPLACE FUNCTION func_CreateShopOrder(p_razorpay_order_id TEXT, p_player_id INT, p_guest_session TEXT,
+                                                p_venue TEXT, p_amount BIGINT, p_product_ids INT[], p_titles TEXT[],
+                                                p_prices INT[], p_quantities INT[], p_hold_minutes INT)
+RETURNS TABLE (order_id INT, short_product_id INT, units_left INT)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_order_id INT;
+    v_item     RECORD;
+    v_units    INT;
+BEGIN
+    -- locked in product order, two checkouts of the same products can't wait on each other.
+    FOR v_item IN
+        SELECT i.product_id, i.quantity
+        FROM unnest(p_product_ids, p_quantities) AS i(product_id, quantity)
+        ORDER BY i.product_id
+    LOOP
+        v_units := COALESCE(func_LockStock(v_item.product_id, p_venue), 0)
+                   - func_HeldUnits(v_item.product_id, p_venue, NULL);
+        IF v_units < v_item.quantity THEN
+            RETURN QUERY SELECT NULL::INT, v_item.product_id, GREATEST(v_units, 0);
+            RETURN;
+        END IF;
+    END LOOP;
+
+    INSERT INTO "ShopOrders" ("razorpayOrderId", "playerId", "guestSession", venue, amount)
+    VALUES (p_razorpay_order_id, p_player_id, p_guest_session, p_venue, p_amount)
+    RETURNING id INTO v_order_id;
+
+    INSERT INTO "ShopOrderItems" ("orderId", "productId", title, price, quantity)
+    SELECT v_order_id, i.product_id, i.title, i.price, i.quantity
+    FROM unnest(p_product_ids, p_titles, p_prices, p_quantities) AS i(product_id, title, price, quantity);
+
+    INSERT INTO "StockReservations" ("orderId", "productId", venue, quantity, "expiresAt")
+    SELECT v_order_id, i.product_id, p_venue, i.quantity, now() + make_interval(mins => p_hold_minutes)
+    FROM unnest(p_product_ids, p_quantities) AS i(product_id, quantity);
+
+    RETURN QUERY SELECT v_order_id, NULL::INT, NULL::INT;
+END;
+$$;
+
+-- paying takes the units of the order out of the stock. oversold is set when the hold had run out
+-- and other orders got the units meanwhile, the stock stops at 0 and the order needs a refund.
+-- no row when the order was already paid.
+DROP FUNCTION IF EXISTS func_PayShopOrder(TEXT, TEXT);
+
+CREATE OR REPLACE FUNCTION func_PayShopOrder(p_razorpay_order_id TEXT, p_payment_id TEXT)
+RETURNS TABLE (product_id INT, quantity INT, units_left INT, oversold BOOLEAN)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_order_id  INT;
+    v_player_id INT;
+    v_venue     TEXT;
+    v_item      RECORD;
+    v_units     INT;
+    v_left      INT;
+BEGIN
+    UPDATE "ShopOrders" SET status = 'paid', "paymentId" = p_payment_id, "paidAt" = now()
+    WHERE "razorpayOrderId" = p_razorpay_order_id AND status = 'pending'
+    RETURNING id, "playerId", venue INTO v_order_id, v_player_id, v_venue;
+
+    IF v_order_id IS NULL THEN
+        RETURN;
+    END IF;
+
+    FOR v_item IN
+        SELECT i."productId", i.quantity FROM "ShopOrderItems" i WHERE i."orderId" = v_order_id ORDER BY i."productId"
+    LOOP
+        v_units := COALESCE(func_LockStock(v_item."productId", v_venue), 0);
+        v_left := GREATEST(v_units - v_item.quantity, 0);
+
+        product_id := v_item."productId";
+        quantity := v_item.quantity;
+        units_left := v_left;
+        oversold := v_units - func_HeldUnits(v_item."productId", v_venue, v_order_id) < v_item.quantity;
+
+        PERFORM func_WriteStock(v_item."productId", v_venue, v_left);
+        INSERT INTO "StockMovements" ("productId", venue, change, "unitsAfter", kind, "orderId")
+        VALUES (v_item."productId", v_venue, v_left - v_units, v_left, 'sale', v_order_id);
+        RETURN NEXT;
+    END LOOP;
+
+    UPDATE "StockReservations" SET status = 'committed' WHERE "orderId" = v_order_id;
+
+    IF v_player_id IS NOT NULL THEN
+        DELETE FROM "Carts" c
+        USING "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId"
+          AND c.quantity <= i.quantity;
+
+        UPDATE "Carts" c SET quantity = c.quantity - i.quantity, "updatedAt" = now()
+        FROM "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId";
+    END IF;
+END;
+$$;
+
+-- gives the held units of an unpaid order back, for a failed payment.
+CREATE OR REPLACE FUNCTION func_ReleaseShopOrder(p_razorpay_order_id TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    WITH released AS (
+        UPDATE "StockReservations" r SET status = 'released'
+        FROM "ShopOrders" o
+        WHERE o."razorpayOrderId" = p_razorpay_order_id AND o.status = 'pending'
+          AND r."orderId" = o.id AND r.status = 'held'
+        RETURNING 1
+    )
+    SELECT COUNT(*)::INT FROM released;
+$$;
+
+-- marks the holds that ran out, they already stopped counting when they did.
+CREATE OR REPLACE FUNCTION func_ExpireStockReservations()
+RETURNS INT
+LANGUAGE sql
+AS $$
+    WITH expired AS (
+        UPDATE "StockReservations" SET status = 'expired'
+        WHERE status = 'held' AND "expiresAt" <= now()
+        RETURNING 1
+    )
+    SELECT COUNT(*)::INT FROM expired;
+$$;
+
+-- the stock at or under the threshold, after the held units. p_venues NULL is every venue and the
+-- central stock.
+CREATE OR REPLACE FUNCTION func_GetLowStock(p_venues TEXT[], p_threshold INT)
+RETURNS TABLE (product_id INT, title TEXT, venue TEXT, units INT, held INT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT s.product_id, s.title, s.venue, s.units, s.held
+    FROM (
+        SELECT p.id::INT AS product_id, p."productName"::TEXT AS title, NULL::TEXT AS venue, p.units::INT AS units,
+               func_HeldUnits(p.id::INT, NULL, NULL) AS held
+        FROM "Products" p
+        WHERE p_venues IS NULL
+        UNION ALL
+        SELECT p.id::INT, p."productName"::TEXT, vi."venueId", vi.units, func_HeldUnits(p.id::INT, vi."venueId", NULL)
+        FROM "VenueInventory" vi
+        JOIN "Products" p ON p.id = vi."productId"
+        WHERE p_venues IS NULL OR vi."venueId" = ANY(p_venues)
+    ) s
+    WHERE s.units - s.held <= p_threshold
+    ORDER BY s.units - s.held, s.product_id, s.venue NULLS FIRST;
+$$;
+
+-- p_to is exclusive. p_product_id NULL for every product, p_venues NULL for every venue and the
+-- central stock.
+CREATE OR REPLACE FUNCTION func_GetStockMovements(p_product_id INT, p_venues TEXT[], p_from DATE, p_to DATE, p_limit INT)
+RETURNS TABLE (id BIGINT, product_id INT, venue TEXT, change INT, units_after INT, kind TEXT, reason TEXT,
+               order_id INT, admin_id INT, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, "productId", venue, change, "unitsAfter", kind, reason, "orderId", "adminId", "createdAt"
+    FROM "StockMovements"
+    WHERE "createdAt" >= p_from AND "createdAt" < p_to
+      AND (p_product_id IS NULL OR "productId" = p_product_id)
+      AND (p_venues IS NULL OR venue = ANY(p_venues))
+    ORDER BY "createdAt" DESC, id DESC
+    LIMIT p_limit;
+$$;
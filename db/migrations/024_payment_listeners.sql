@@ -0,0 +1,76 @@
+-- a payment event is processed once every listener handled it. a listener that fails leaves the event
+-- unprocessed, the webhook fails and the razorpay retry runs only the listeners that didn't finish.
+-- the events recorded before this were handled by every listener already.
+ALTER TABLE "CapturedPayments" ADD COLUMN IF NOT EXISTS "isProcessed" BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE "CapturedPayments" ALTER COLUMN "isProcessed" SET DEFAULT FALSE;
+ALTER TABLE "Refunds" ADD COLUMN IF NOT EXISTS "isProcessed" BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE "Refunds" ALTER COLUMN "isProcessed" SET DEFAULT FALSE;
+
+-- eventId is capture:<payment id> or refund:<refund id>.
+CREATE TABLE IF NOT EXISTS "PaymentEventListeners" (
+    "eventId"      TEXT        NOT NULL,
+    listener       TEXT        NOT NULL,
+    "processedAt"  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY ("eventId", listener)
+);
+
+-- FALSE when the capture was already processed, a retry of an unfinished one is TRUE again.
+CREATE OR REPLACE FUNCTION func_RecordPaymentCapture(p_payment_id TEXT, p_order_id TEXT, p_amount BIGINT,
+    p_method TEXT, p_purpose TEXT, p_player_id INT, p_game_id INT)
+RETURNS BOOLEAN
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO "CapturedPayments" ("paymentId", "orderId", amount, method, purpose, "playerId", "gameId")
+    VALUES (p_payment_id, p_order_id, p_amount, p_method, p_purpose, p_player_id, p_game_id)
+    ON CONFLICT ("paymentId") DO NOTHING;
+
+    RETURN NOT (SELECT "isProcessed" FROM "CapturedPayments" WHERE "paymentId" = p_payment_id);
+END;
+$$;
+
+-- returns the captured payment the refund belongs to, nothing when the refund was already processed.
+CREATE OR REPLACE FUNCTION func_RecordRefund(p_refund_id TEXT, p_payment_id TEXT, p_amount BIGINT)
+RETURNS TABLE (payment_amount BIGINT, player_id INT, purpose TEXT)
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    INSERT INTO "Refunds" ("refundId", "paymentId", amount)
+    VALUES (p_refund_id, p_payment_id, p_amount)
+    ON CONFLICT ("refundId") DO NOTHING;
+
+    RETURN QUERY SELECT cp.amount, cp."playerId", cp.purpose
+                 FROM "Refunds" r
+                 JOIN "CapturedPayments" cp ON cp."paymentId" = r."paymentId"
+                 WHERE r."refundId" = p_refund_id AND NOT r."isProcessed";
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_IsListenerDone(p_event_id TEXT, p_listener TEXT)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT EXISTS (SELECT 1 FROM "PaymentEventListeners" WHERE "eventId" = p_event_id AND listener = p_listener);
+$$;
+
+CREATE OR REPLACE FUNCTION func_MarkListenerDone(p_event_id TEXT, p_listener TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "PaymentEventListeners" ("eventId", listener) VALUES (p_event_id, p_listener)
+    ON CONFLICT ("eventId", listener) DO NOTHING;
+$$;
+
+CREATE OR REPLACE FUNCTION func_FinishCapture(p_payment_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "CapturedPayments" SET "isProcessed" = TRUE WHERE "paymentId" = p_payment_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_FinishRefund(p_refund_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "Refunds" SET "isProcessed" = TRUE WHERE "refundId" = p_refund_id;
+$$;
@@ -0,0 +1,41 @@
+-- expiry entries now name the earn entry (lot) they expired. the older ones don't, they count as spent.
+ALTER TABLE "LoyaltyLedger" ADD COLUMN IF NOT EXISTS "lotId" INT REFERENCES "LoyaltyLedger"(id);
+
+-- every earn entry is a lot, less what was reversed of its payment and what of it expired already.
+-- redemptions spend the oldest lots first, an expired lot loses only what wasn't spent of it.
+-- returns the number of lots that expired.
+CREATE OR REPLACE FUNCTION func_ExpireLoyaltyPoints()
+RETURNS INT
+LANGUAGE sql
+AS $$
+    WITH lots AS (
+        SELECT e.id, e."playerId", e."createdAt", e."expiresAt",
+               GREATEST(e.points
+                   + COALESCE((SELECT SUM(r.points) FROM "LoyaltyLedger" r
+                               WHERE r.kind = 'reversal' AND r."playerId" = e."playerId"
+                                 AND r."paymentId" = e."paymentId"), 0)
+                   + COALESCE((SELECT SUM(x.points) FROM "LoyaltyLedger" x
+                               WHERE x.kind = 'expiry' AND x."lotId" = e.id), 0), 0) AS available
+        FROM "LoyaltyLedger" e
+        WHERE e.kind = 'earn'
+    ), spent AS (
+        SELECT "playerId", GREATEST(-SUM(points), 0) AS spent
+        FROM "LoyaltyLedger"
+        WHERE kind IN ('redeem', 'redeem-cancel') OR (kind = 'expiry' AND "lotId" IS NULL)
+        GROUP BY "playerId"
+    ), running AS (
+        SELECT l.id, l."playerId", l."expiresAt", l.available, COALESCE(s.spent, 0) AS spent,
+               SUM(l.available) OVER (PARTITION BY l."playerId" ORDER BY l."createdAt", l.id) AS upto
+        FROM lots l
+        LEFT JOIN spent s ON s."playerId" = l."playerId"
+    ), due AS (
+        SELECT id, "playerId", LEAST(available, GREATEST(upto - spent, 0)) AS lost
+        FROM running
+        WHERE "expiresAt" < now()
+    ), expired AS (
+        INSERT INTO "LoyaltyLedger" ("playerId", points, kind, "lotId")
+        SELECT "playerId", -lost, 'expiry', id FROM due WHERE lost > 0
+        RETURNING 1
+    )
+    SELECT COUNT(*)::INT FROM expired;
+$$;
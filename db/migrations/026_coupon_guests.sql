@@ -0,0 +1,33 @@
+-- a guest has no uses to count, a coupon limited per player needs a logged in player.
+CREATE OR REPLACE FUNCTION func_RedeemCoupon(p_code TEXT, p_player_id INT, p_reference TEXT, p_discount INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_coupon RECORD;
+BEGIN
+    SELECT id, "maxUses", "maxUsesPerPlayer" INTO v_coupon
+    FROM "Coupons" WHERE code = upper(p_code) AND "isActive"
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN 'coupon not found';
+    END IF;
+    IF v_coupon."maxUses" IS NOT NULL AND
+       (SELECT COUNT(*) FROM "CouponRedemptions" WHERE "couponId" = v_coupon.id) >= v_coupon."maxUses" THEN
+        RETURN 'coupon has been used up';
+    END IF;
+    IF v_coupon."maxUsesPerPlayer" IS NOT NULL AND p_player_id IS NULL THEN
+        RETURN 'coupon needs a logged in player';
+    END IF;
+    IF v_coupon."maxUsesPerPlayer" IS NOT NULL AND
+       (SELECT COUNT(*) FROM "CouponRedemptions" WHERE "couponId" = v_coupon.id AND "playerId" = p_player_id)
+           >= v_coupon."maxUsesPerPlayer" THEN
+        RETURN 'coupon already used';
+    END IF;
+
+    INSERT INTO "CouponRedemptions" ("couponId", "playerId", reference, discount)
+    VALUES (v_coupon.id, p_player_id, p_reference, p_discount);
+    RETURN '';
+END;
+$$;
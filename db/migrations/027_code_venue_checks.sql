@@ -0,0 +1,22 @@
+CREATE OR REPLACE FUNCTION func_IsVenueActive(p_venue TEXT)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT EXISTS (SELECT 1 FROM "Venues" WHERE id = p_venue AND "isActive");
+$$;
+
+-- a code bought for a venue only starts on the machines of that venue.
+CREATE OR REPLACE FUNCTION func_StartSession(p_code TEXT, p_game_id INT, p_machine_id TEXT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    INSERT INTO "PlaySessions" (code, "gameId", "machineId", "playerId", system)
+    SELECT gs.code, gs."gameId", p_machine_id, gs."playerId", (SELECT system FROM func_CheckGameCode(p_code))
+    FROM "GameStatus" gs
+    LEFT JOIN "CodeVenues" cv ON cv.code = gs.code
+    WHERE gs.code = p_code AND gs."gameId" = p_game_id
+      AND (cv."venueId" IS NULL
+           OR cv."venueId" = (SELECT venue FROM "Machines" WHERE id = p_machine_id))
+    ON CONFLICT (code) DO UPDATE SET "machineId" = EXCLUDED."machineId"
+    RETURNING id;
+$$;
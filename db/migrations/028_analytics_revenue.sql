@@ -0,0 +1,68 @@
+-- revenue is what was captured for the code, after the coupon. the codes the server gives away (vouchers,
+-- passes, loyalty, referral, bundle and balance codes) have no payment of their own and count as sold for 0.
+CREATE OR REPLACE FUNCTION func_RollupAnalytics(p_day DATE)
+RETURNS VOID
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_start TIMESTAMPTZ := p_day::TIMESTAMPTZ;
+    v_end   TIMESTAMPTZ := (p_day + 1)::TIMESTAMPTZ;
+BEGIN
+    DELETE FROM "GameDailyStats" WHERE day = p_day;
+    DELETE FROM "MachineHourlyStats" WHERE day = p_day;
+
+    INSERT INTO "GameDailyStats" (day, "gameId", venue, name, plays, "codesSold", revenue, "timedSessions",
+                                  "playedSeconds", "purchasedSeconds")
+    SELECT p_day, f.game_id, f.venue, MAX(f.name), SUM(f.plays), SUM(f.sold), SUM(f.revenue), SUM(f.timed),
+           SUM(f.played), SUM(f.purchased)
+    FROM (
+        SELECT gs."gameId"::INT AS game_id, COALESCE(cv."venueId", '') AS venue, gs.name::TEXT AS name,
+               0 AS plays, 1 AS sold,
+               COALESCE(cp.amount / 100, 0)::BIGINT AS revenue,
+               0 AS timed, 0::BIGINT AS played, 0::BIGINT AS purchased
+        FROM "GameStatus" gs
+        LEFT JOIN "CodeVenues" cv ON cv.code = gs.code
+        LEFT JOIN "CapturedPayments" cp ON cp."paymentId" = gs."paymentId"
+        WHERE gs."createdAt" >= v_start AND gs."createdAt" < v_end
+        UNION ALL
+        SELECT ps."gameId", m.venue, gs.name::TEXT, 1, 0, 0::BIGINT,
+               CASE WHEN finished THEN 1 ELSE 0 END,
+               CASE WHEN finished THEN EXTRACT(EPOCH FROM ps."endedAt" - ps."startedAt")::BIGINT ELSE 0 END,
+               CASE WHEN finished THEN gs."playTime"::BIGINT * 60 ELSE 0 END
+        FROM "PlaySessions" ps
+        JOIN "Machines" m ON m.id = ps."machineId"
+        JOIN "GameStatus" gs ON gs.code = ps.code
+        CROSS JOIN LATERAL (SELECT ps."endedAt" IS NOT NULL AND gs."playTime" IS NOT NULL AS finished) s
+        WHERE ps."startedAt" >= v_start AND ps."startedAt" < v_end
+    ) f
+    GROUP BY f.game_id, f.venue;
+
+    INSERT INTO "MachineHourlyStats" (day, hour, "machineId", venue, sessions, "busySeconds")
+    WITH sessions AS (
+        SELECT ps."machineId", ps."startedAt",
+               COALESCE(ps."endedAt", ps."startedAt" + make_interval(mins => gs."playTime"::INT), ps."startedAt") AS ended_at
+        FROM "PlaySessions" ps
+        LEFT JOIN "GameStatus" gs ON gs.code = ps.code
+        WHERE ps."startedAt" < v_end AND ps."startedAt" >= v_start - INTERVAL '1 day' -- ones running over midnight
+    ), hours AS (
+        SELECT h AS hour, (p_day + make_interval(hours => h))::TIMESTAMPTZ AS starts_at
+        FROM generate_series(0, 23) h
+    )
+    SELECT p_day, h.hour, m.id, m.venue,
+           COUNT(s."startedAt") FILTER (WHERE s."startedAt" >= h.starts_at),
+           LEAST(COALESCE(SUM(EXTRACT(EPOCH FROM LEAST(s.ended_at, h.starts_at + INTERVAL '1 hour')
+                                                - GREATEST(s."startedAt", h.starts_at))), 0), 3600)::INT
+    FROM "Machines" m
+    CROSS JOIN hours h
+    LEFT JOIN sessions s ON s."machineId" = m.id
+                        AND s."startedAt" < h.starts_at + INTERVAL '1 hour' AND s.ended_at > h.starts_at
+    WHERE m."createdAt" < v_end
+    GROUP BY h.hour, m.id, m.venue;
+
+    INSERT INTO "AnalyticsRollups" (day) VALUES (p_day)
+    ON CONFLICT (day) DO UPDATE SET "rolledAt" = now();
+END;
+$$;
+
+-- the days rolled up so far counted some of the free codes at their price.
+SELECT func_RollupAnalytics(day) FROM "AnalyticsRollups";
@@ -0,0 +1,44 @@
+-- a purchase is issued once all of its codes are. a failure part way leaves it unissued and the payment
+-- retry issues the codes that are missing. the purchases recorded before this were issued already.
+ALTER TABLE "BundlePurchases" ADD COLUMN IF NOT EXISTS "isIssued" BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE "BundlePurchases" ALTER COLUMN "isIssued" SET DEFAULT FALSE;
+
+-- NULL when the purchase is issued already, the id of the unissued purchase otherwise.
+CREATE OR REPLACE FUNCTION func_InsertBundlePurchase(p_bundle_id INT, p_payment_id TEXT, p_player_id INT)
+RETURNS INT
+LANGUAGE sql
+AS $$
+    WITH inserted AS (
+        INSERT INTO "BundlePurchases" ("bundleId", "paymentId", "playerId")
+        VALUES (p_bundle_id, p_payment_id, p_player_id)
+        ON CONFLICT ("paymentId") DO NOTHING
+        RETURNING id
+    )
+    SELECT id FROM inserted
+    UNION ALL
+    SELECT id FROM "BundlePurchases" WHERE "paymentId" = p_payment_id AND NOT "isIssued";
+$$;
+
+CREATE OR REPLACE FUNCTION func_FinishBundlePurchase(p_purchase_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "BundlePurchases" SET "isIssued" = TRUE WHERE id = p_purchase_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_InsertBundleCode(p_purchase_id INT, p_code TEXT, p_is_balance BOOLEAN, p_minutes INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    INSERT INTO "BundleCodes" (code, "purchaseId", "isBalance", minutes)
+    VALUES (p_code, p_purchase_id, p_is_balance, p_minutes)
+    ON CONFLICT (code) DO NOTHING;
+$$;
+
+-- the code saved under the payment reference, nothing when it wasn't issued yet.
+CREATE OR REPLACE FUNCTION func_GetCodeByReference(p_reference TEXT)
+RETURNS TEXT
+LANGUAGE sql STABLE
+AS $$
+    SELECT code FROM "GameStatus" WHERE "paymentId" = p_reference;
+$$;
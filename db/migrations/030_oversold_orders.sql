@@ -0,0 +1,82 @@
+-- an order paid after the units it held went to other orders is oversold, it's kept apart from the
+-- paid ones until it's refunded.
+ALTER TABLE "ShopOrders" DROP CONSTRAINT IF EXISTS "ShopOrders_status_check";
+ALTER TABLE "ShopOrders" ADD CONSTRAINT "ShopOrders_status_check"
+    CHECK (status IN ('pending', 'paid', 'oversold', 'refunded'));
+
+-- the sale records the quantity sold, an oversold movement gives back the units that weren't there.
+ALTER TABLE "StockMovements" DROP CONSTRAINT IF EXISTS "StockMovements_kind_check";
+ALTER TABLE "StockMovements" ADD CONSTRAINT "StockMovements_kind_check"
+    CHECK (kind IN ('restock', 'adjustment', 'count', 'sale', 'oversold'));
+
+-- paying takes the units of the order out of the stock. oversold is set when the hold had run out
+-- and other orders got the units meanwhile, the stock stops at 0 and the order is marked oversold.
+-- no row when the order was already paid.
+CREATE OR REPLACE FUNCTION func_PayShopOrder(p_razorpay_order_id TEXT, p_payment_id TEXT)
+RETURNS TABLE (product_id INT, quantity INT, units_left INT, oversold BOOLEAN)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_order_id  INT;
+    v_player_id INT;
+    v_venue     TEXT;
+    v_item      RECORD;
+    v_units     INT;
+    v_left      INT;
+    v_oversold  BOOLEAN := FALSE;
+BEGIN
+    UPDATE "ShopOrders" SET status = 'paid', "paymentId" = p_payment_id, "paidAt" = now()
+    WHERE "razorpayOrderId" = p_razorpay_order_id AND status = 'pending'
+    RETURNING id, "playerId", venue INTO v_order_id, v_player_id, v_venue;
+
+    IF v_order_id IS NULL THEN
+        RETURN;
+    END IF;
+
+    FOR v_item IN
+        SELECT i."productId", i.quantity FROM "ShopOrderItems" i WHERE i."orderId" = v_order_id ORDER BY i."productId"
+    LOOP
+        v_units := COALESCE(func_LockStock(v_item."productId", v_venue), 0);
+        v_left := GREATEST(v_units - v_item.quantity, 0);
+
+        product_id := v_item."productId";
+        quantity := v_item.quantity;
+        units_left := v_left;
+        oversold := v_units - func_HeldUnits(v_item."productId", v_venue, v_order_id) < v_item.quantity;
+        v_oversold := v_oversold OR oversold;
+
+        PERFORM func_WriteStock(v_item."productId", v_venue, v_left);
+        INSERT INTO "StockMovements" ("productId", venue, change, "unitsAfter", kind, "orderId")
+        VALUES (v_item."productId", v_venue, -v_item.quantity, v_units - v_item.quantity, 'sale', v_order_id);
+        IF v_units < v_item.quantity THEN
+            INSERT INTO "StockMovements" ("productId", venue, change, "unitsAfter", kind, "orderId")
+            VALUES (v_item."productId", v_venue, v_item.quantity - v_units, v_left, 'oversold', v_order_id);
+        END IF;
+        RETURN NEXT;
+    END LOOP;
+
+    IF v_oversold THEN
+        UPDATE "ShopOrders" SET status = 'oversold' WHERE id = v_order_id;
+    END IF;
+
+    UPDATE "StockReservations" SET status = 'committed' WHERE "orderId" = v_order_id;
+
+    IF v_player_id IS NOT NULL THEN
+        DELETE FROM "Carts" c
+        USING "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId"
+          AND c.quantity <= i.quantity;
+
+        UPDATE "Carts" c SET quantity = c.quantity - i.quantity, "updatedAt" = now()
+        FROM "ShopOrderItems" i
+        WHERE i."orderId" = v_order_id AND c."playerId" = v_player_id AND c."productId" = i."productId";
+    END IF;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION func_RefundShopOrder(p_payment_id TEXT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "ShopOrders" SET status = 'refunded' WHERE "paymentId" = p_payment_id AND status IN ('paid', 'oversold');
+$$;
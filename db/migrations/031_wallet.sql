@@ -0,0 +1,37 @@
+-- credit is positive, what was paid with it negative. the balance of the player is the sum.
+CREATE INDEX IF NOT EXISTS "WalletLedger_player" ON "WalletLedger" ("playerId");
+
+CREATE OR REPLACE FUNCTION func_GetWalletBalance(p_player_id INT)
+RETURNS INT
+LANGUAGE sql STABLE
+AS $$
+    SELECT COALESCE(SUM(amount), 0)::INT FROM "WalletLedger" WHERE "playerId" = p_player_id;
+$$;
+
+CREATE OR REPLACE FUNCTION func_GetWalletLedger(p_player_id INT, p_limit INT)
+RETURNS TABLE (id INT, amount INT, reason TEXT, code TEXT, payment_id TEXT, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, amount, reason, code, "paymentId", "createdAt"
+    FROM "WalletLedger" WHERE "playerId" = p_player_id
+    ORDER BY "createdAt" DESC, id DESC LIMIT p_limit;
+$$;
+
+-- called in the same transaction that saves the code, the lock keeps two purchases from spending the
+-- same credit. returns why the wallet was refused or ''.
+CREATE OR REPLACE FUNCTION func_SpendWallet(p_player_id INT, p_code TEXT, p_reference TEXT, p_amount INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+BEGIN
+    PERFORM pg_advisory_xact_lock(p_player_id);
+
+    IF func_GetWalletBalance(p_player_id) < p_amount THEN
+        RETURN 'not enough credit in the wallet';
+    END IF;
+
+    INSERT INTO "WalletLedger" ("playerId", "paymentId", code, amount, reason)
+    VALUES (p_player_id, p_reference, p_code, -p_amount, 'game');
+    RETURN '';
+END;
+$$;
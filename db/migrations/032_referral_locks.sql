@@ -0,0 +1,43 @@
+DROP FUNCTION IF EXISTS func_ApplyReferral(INT, TEXT, TEXT, INT);
+
+-- the checks the application can't do without racing, the reason is NULL when the referral was recorded.
+-- the device id comes from the app and is only a hint, the checks that hold are on the server side:
+-- one referral per player, before the first purchase, on an account younger than p_window_days, and a
+-- cap per referrer. the referrer and the device are locked so concurrent applies see each other.
+CREATE OR REPLACE FUNCTION func_ApplyReferral(p_player_id INT, p_code TEXT, p_device_id TEXT, p_max_referrals INT,
+                                              p_window_days INT)
+RETURNS TEXT
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_referrer_id INT;
+BEGIN
+    SELECT id INTO v_referrer_id FROM "Players" WHERE "referralCode" = p_code;
+
+    IF v_referrer_id IS NULL THEN
+        RETURN 'unknown referral code';
+    ELSIF v_referrer_id = p_player_id THEN
+        RETURN 'you can''t refer yourself';
+    END IF;
+
+    PERFORM pg_advisory_xact_lock(hashtext('referral-referrer'), v_referrer_id);
+    PERFORM pg_advisory_xact_lock(hashtext('referral-device'), hashtext(p_device_id));
+    PERFORM pg_advisory_xact_lock(hashtext('referral-player'), p_player_id);
+
+    IF EXISTS (SELECT 1 FROM "Referrals" WHERE "referredId" = p_player_id) THEN
+        RETURN 'a referral code was already applied';
+    ELSIF (SELECT "createdAt" FROM "Players" WHERE id = p_player_id) < now() - make_interval(days => p_window_days) THEN
+        RETURN 'referral codes only work for new accounts';
+    ELSIF EXISTS (SELECT 1 FROM "CapturedPayments" WHERE "playerId" = p_player_id) THEN
+        RETURN 'referral codes only work before the first purchase';
+    ELSIF EXISTS (SELECT 1 FROM "PlayerDevices" WHERE "playerId" = v_referrer_id AND "deviceId" = p_device_id)
+       OR EXISTS (SELECT 1 FROM "Referrals" WHERE "deviceId" = p_device_id) THEN
+        RETURN 'this device was already used for a referral';
+    ELSIF (SELECT COUNT(*) FROM "Referrals" WHERE "referrerId" = v_referrer_id) >= p_max_referrals THEN
+        RETURN 'this referral code has been used too many times';
+    END IF;
+
+    INSERT INTO "Referrals" ("referredId", "referrerId", "deviceId") VALUES (p_player_id, v_referrer_id, p_device_id);
+    RETURN NULL;
+END;
+$$;
@@ -0,0 +1,68 @@
+DROP FUNCTION IF EXISTS func_SearchGames(TEXT, TEXT, TEXT, TEXT, INT, INT, TEXT, TEXT, INT, TEXT, BIGINT, INT, INT);
+
+-- the from price is the cheapest tier after the pricing rules in effect, the rules are applied by the
+-- application and their prices given as p_game_ids and p_from_prices. filtering, sorting and the cursor
+-- all use it so the pages follow the prices they show. a game left out falls back to its catalogue price.
+CREATE OR REPLACE FUNCTION func_SearchGames(p_venue TEXT, p_query TEXT, p_system TEXT, p_mode TEXT,
+                                            p_min_price INT, p_max_price INT, p_genre TEXT, p_tag TEXT,
+                                            p_players INT, p_sort TEXT,
+                                            p_cursor_value BIGINT, p_cursor_id INT, p_limit INT,
+                                            p_game_ids INT[], p_from_prices INT[])
+RETURNS TABLE (game_id INT, name TEXT, thumbnail TEXT, system TEXT, from_price INT, plays BIGINT,
+               description TEXT, genres TEXT[], tags TEXT[], min_players INT, max_players INT,
+               release_year INT, controls JSONB, age_rating TEXT)
+LANGUAGE sql STABLE
+AS $$
+    WITH ruled AS (
+        SELECT * FROM unnest(p_game_ids, p_from_prices) AS f(game_id, from_price)
+    ), tiers AS (
+        SELECT p.game_id, MIN(p.price) AS from_price,
+               bool_or(p.item_type = 'time') AS is_timed, bool_or(p.item_type = 'level') AS is_level
+        FROM func_GetGamesPrices(p_venue) p
+        GROUP BY p.game_id
+    ), recent AS (
+        SELECT "gameId" AS game_id, COUNT(*) AS plays
+        FROM "PlaySessions"
+        WHERE "startedAt" > now() - INTERVAL '30 days'
+        GROUP BY "gameId"
+    ), games AS (
+        SELECT g.game_id, g.name, g.thumbnail, gs.system, COALESCE(f.from_price, t.from_price) AS from_price,
+               COALESCE(r.plays, 0) AS plays, t.is_timed, t.is_level, m.description,
+               COALESCE(m.genres, '{}') AS genres, COALESCE(m.tags, '{}') AS tags,
+               m."minPlayers" AS min_players, m."maxPlayers" AS max_players,
+               m."releaseYear" AS release_year, m.controls, m."ageRating" AS age_rating
+        FROM func_GetGamesForUsers(p_venue) g
+        JOIN tiers t ON t.game_id = g.game_id -- games without tiers can't be bought
+        LEFT JOIN ruled f ON f.game_id = g.game_id
+        LEFT JOIN "GameSystems" gs ON gs."gameId" = g.game_id
+        LEFT JOIN "GameMetadata" m ON m."gameId" = g.game_id
+        LEFT JOIN recent r ON r.game_id = g.game_id
+    )
+    SELECT game_id, name, thumbnail, system, from_price, plays, description, genres, tags, min_players,
+           max_players, release_year, controls, age_rating
+    FROM games
+    WHERE (p_query IS NULL
+           OR to_tsvector('simple', name || ' ' || COALESCE(description, '') || ' ' || array_to_string(tags, ' '))
+              @@ websearch_to_tsquery('simple', p_query)
+           OR name ILIKE '%' || p_query || '%')
+      AND (p_system IS NULL OR system = p_system)
+      AND (p_mode IS NULL OR (p_mode = 'timed' AND is_timed) OR (p_mode = 'level' AND is_level))
+      AND (p_min_price IS NULL OR from_price >= p_min_price)
+      AND (p_max_price IS NULL OR from_price <= p_max_price)
+      AND (p_genre IS NULL OR p_genre = ANY(genres))
+      AND (p_tag IS NULL OR p_tag = ANY(tags))
+      AND (p_players IS NULL OR (COALESCE(min_players, 1) <= p_players AND COALESCE(max_players, min_players, 1) >= p_players))
+      AND (p_cursor_id IS NULL OR CASE p_sort
+            WHEN 'newest' THEN game_id < p_cursor_id
+            WHEN 'price' THEN from_price > p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            WHEN 'price_desc' THEN from_price < p_cursor_value OR (from_price = p_cursor_value AND game_id > p_cursor_id)
+            ELSE plays < p_cursor_value OR (plays = p_cursor_value AND game_id > p_cursor_id)
+          END)
+    ORDER BY
+        CASE WHEN p_sort = 'price' THEN from_price END ASC,
+        CASE WHEN p_sort = 'price_desc' THEN from_price END DESC,
+        CASE WHEN p_sort = 'newest' THEN game_id END DESC,
+        CASE WHEN p_sort = 'popular' THEN plays END DESC,
+        game_id ASC
+    LIMIT p_limit;
+$$;
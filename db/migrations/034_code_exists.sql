@@ -0,0 +1,22 @@
+-- func_CheckGameCode only finds codes that can still be played, a played or expired code was being
+-- handed out again. every table generated codes are stored in is looked at directly.
+CREATE OR REPLACE FUNCTION func_GameCodeExists(p_code TEXT)
+RETURNS BOOLEAN
+LANGUAGE sql STABLE
+AS $$
+    SELECT EXISTS (SELECT 1 FROM "GameStatus" gs WHERE gs.code = p_code)
+        OR EXISTS (SELECT 1 FROM "BundleCodes" bc WHERE bc.code = p_code)
+        OR EXISTS (SELECT 1 FROM "VoucherCodes" vc WHERE vc.code = p_code);
+$$;
+
+-- voucher codes come from the same counter, a restore has to move past them too.
+CREATE OR REPLACE FUNCTION func_GetIssuedCodes()
+RETURNS TABLE (code TEXT)
+LANGUAGE sql STABLE
+AS $$
+    SELECT gs.code::TEXT FROM "GameStatus" gs WHERE gs.code IS NOT NULL
+    UNION ALL
+    SELECT bc.code FROM "BundleCodes" bc WHERE bc."isBalance"
+    UNION ALL
+    SELECT vc.code FROM "VoucherCodes" vc;
+$$;
@@ -0,0 +1,11 @@
+-- the scores still on the boards, the leaderboards in redis are rebuilt from them.
+CREATE OR REPLACE FUNCTION func_GetBoardScores()
+RETURNS TABLE (id INT, game_id INT, code TEXT, machine_id TEXT, venue TEXT, player_id INT,
+               name TEXT, score BIGINT, is_removed BOOLEAN, created_at TIMESTAMPTZ)
+LANGUAGE sql STABLE
+AS $$
+    SELECT id, "gameId", code, "machineId", venue, "playerId", name, score, "isRemoved", "createdAt"
+    FROM "Scores"
+    WHERE NOT "isRemoved"
+    ORDER BY id;
+$$;
@@ -0,0 +1,24 @@
+-- only codes paid for through razorpay turn into credit, codes from passes, loyalty points, bundles,
+-- the wallet, referrals and vouchers were never paid for on their own. the credit goes to the player
+-- the code belongs to, a guest's stays claimable by the payment until the code is attached.
+CREATE OR REPLACE FUNCTION func_ExpireCodes()
+RETURNS TABLE (code TEXT, credited INT)
+LANGUAGE sql
+AS $$
+    WITH expired AS (
+        UPDATE "GameStatus" gs
+        SET "isExpired" = TRUE
+        WHERE NOT gs."isPlayed" AND NOT gs."isExpired" AND gs."expiresAt" < now()
+        RETURNING gs.code, gs."gameId", gs.price, gs."paymentId", gs."playerId"
+    ), credits AS (
+        INSERT INTO "WalletLedger" ("playerId", "paymentId", code, amount, reason)
+        SELECT e."playerId", e."paymentId", e.code, e.price, 'expired code'
+        FROM expired e
+        JOIN "CapturedPayments" cp ON cp."paymentId" = e."paymentId"
+        WHERE COALESCE((SELECT p."convertToCredit" FROM "CodeValidityPolicies" p
+                        WHERE p."gameId" = e."gameId" OR p."gameId" IS NULL
+                        ORDER BY p."gameId" NULLS LAST LIMIT 1), FALSE)
+        RETURNING code, amount
+    )
+    SELECT e.code::TEXT, COALESCE(c.amount, 0) FROM expired e LEFT JOIN credits c ON c.code = e.code;
+$$;
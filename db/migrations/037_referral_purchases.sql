@@ -0,0 +1,34 @@
+DROP FUNCTION IF EXISTS func_ConvertReferral(INT, TEXT);
+
+-- the pending referral of the player, no row when there is none. it's converted by the first payment
+-- something was bought with, a code saved against it, a pass, a bundle or a paid shop order, has_purchase
+-- is FALSE while nothing was. the amount is checked by the application.
+CREATE OR REPLACE FUNCTION func_ConvertReferral(p_player_id INT, p_payment_id TEXT)
+RETURNS TABLE (referrer_id INT, has_purchase BOOLEAN)
+LANGUAGE plpgsql
+AS $$
+DECLARE
+    v_referrer_id INT;
+BEGIN
+    SELECT r."referrerId" INTO v_referrer_id
+    FROM "Referrals" r
+    WHERE r."referredId" = p_player_id AND r.status = 'pending'
+    FOR UPDATE;
+
+    IF NOT FOUND THEN
+        RETURN;
+    END IF;
+
+    IF NOT (EXISTS (SELECT 1 FROM "GameStatus" gs WHERE gs."paymentId" = p_payment_id)
+            OR EXISTS (SELECT 1 FROM "PlayerPasses" pp WHERE pp."paymentId" = p_payment_id)
+            OR EXISTS (SELECT 1 FROM "BundlePurchases" bp WHERE bp."paymentId" = p_payment_id)
+            OR EXISTS (SELECT 1 FROM "ShopOrders" so WHERE so."paymentId" = p_payment_id AND so.status = 'paid')) THEN
+        RETURN QUERY SELECT v_referrer_id, FALSE;
+        RETURN;
+    END IF;
+
+    UPDATE "Referrals" SET status = 'converted', "paymentId" = p_payment_id, "convertedAt" = now()
+    WHERE "referredId" = p_player_id AND status = 'pending';
+    RETURN QUERY SELECT v_referrer_id, TRUE;
+END;
+$$;
@@ -0,0 +1,8 @@
+-- undoes func_CancelPassSubscription when razorpay couldn't cancel, the renewals are still coming.
+CREATE OR REPLACE FUNCTION func_ReopenPassSubscription(p_subscription_id TEXT, p_player_id INT)
+RETURNS VOID
+LANGUAGE sql
+AS $$
+    UPDATE "PassSubscriptions" SET "isCancelled" = FALSE
+    WHERE id = p_subscription_id AND "playerId" = p_player_id;
+$$;
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AchievementHandler interface {
+	// admin
+	Achievements(c *gin.Context)
+	AddAchievement(c *gin.Context)
+	UpdateAchievement(c *gin.Context)
+
+	// players
+	Progress(c *gin.Context)
+}
+
+type achievementHandler struct {
+	achievementService services.AchievementService
+}
+
+func NewAchievementHandler(achievementService services.AchievementService) *achievementHandler {
+	return &achievementHandler{achievementService: achievementService}
+}
+
+func (h *achievementHandler) Achievements(c *gin.Context) {
+	achievements, err := h.achievementService.GetAchievements()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"achievements": achievements})
+}
+
+func (h *achievementHandler) AddAchievement(c *gin.Context) {
+	var achievement models.Achievement
+	if err := c.ShouldBindJSON(&achievement); err != nil || isAnyEmpty(achievement.Title, achievement.Description) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title, description, kind and threshold are required"})
+		return
+	}
+	achievement.Id = 0
+
+	h.saveAchievement(c, achievement)
+}
+
+func (h *achievementHandler) UpdateAchievement(c *gin.Context) {
+	achievementId, ok := pathId(c, "achievementId")
+	if !ok {
+		return
+	}
+
+	var achievement models.Achievement
+	if err := c.ShouldBindJSON(&achievement); err != nil || isAnyEmpty(achievement.Title, achievement.Description) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "title, description, kind and threshold are required"})
+		return
+	}
+	achievement.Id = achievementId
+
+	h.saveAchievement(c, achievement)
+}
+
+func (h *achievementHandler) saveAchievement(c *gin.Context, achievement models.Achievement) {
+	achievementId, err := h.achievementService.SaveAchievement(achievement)
+	if err != nil {
+		if errors.Is(err, services.ErrAchievementNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": achievementId})
+}
+
+func (h *achievementHandler) Progress(c *gin.Context) {
+	progress, err := h.achievementService.GetProgress(utils.CheckPlayer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"achievements": progress})
+}
@@ -27,12 +27,14 @@ type AdminConsoleHandler interface {
 
 type adminConsoleHandler struct {
 	adminConsoleService services.AdminConsoleService
+	venueService        services.VenueService
 }
 
 const passwordNotMatched = "existsButPWNotMatched"
 
-func NewAdminConsoleHandler(adminConsoleService services.AdminConsoleService) *adminConsoleHandler {
-	return &adminConsoleHandler{adminConsoleService: adminConsoleService}
+func NewAdminConsoleHandler(adminConsoleService services.AdminConsoleService,
+	venueService services.VenueService) *adminConsoleHandler {
+	return &adminConsoleHandler{adminConsoleService: adminConsoleService, venueService: venueService}
 }
 
 func (h *adminConsoleHandler) SignUp(c *gin.Context) {
@@ -87,7 +89,15 @@ func (h *adminConsoleHandler) Login(c *gin.Context) {
 		return
 	}
 
-	tokenString, err := jwtutil.CreateToken(username, adminId)
+	// the token carries the venues of the admin, so every request is scoped without a lookup.
+	venues, err := h.venueService.AdminVenues(adminId)
+	if err != nil {
+		utils.LogError("Failed to fetch venues of admin %s: %v", username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading your venues, please try again."})
+		return
+	}
+
+	tokenString, err := jwtutil.CreateToken(username, adminId, venues)
 	if err != nil {
 		utils.LogError("Failed to create JWT token for admin %s: %v", username, err)
 		c.String(http.StatusInternalServerError, "Error creating the authentication token, please try again. maybe servers are down.")
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// every report takes ?from and ?to as days and ?venue to look at one venue, venue admins only see theirs.
+type AnalyticsHandler interface {
+	Games(c *gin.Context)
+	GameDays(c *gin.Context)
+	Utilisation(c *gin.Context) // ?machine narrows it to one cabinet
+	PeakHours(c *gin.Context)
+	Rollup(c *gin.Context)
+}
+
+type analyticsHandler struct {
+	analyticsService services.AnalyticsService
+}
+
+func NewAnalyticsHandler(analyticsService services.AnalyticsService) *analyticsHandler {
+	return &analyticsHandler{analyticsService: analyticsService}
+}
+
+func (h *analyticsHandler) Games(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	games, err := h.analyticsService.GetGames(from, to, scope)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"games": games})
+}
+
+func (h *analyticsHandler) GameDays(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	days, err := h.analyticsService.GetGameDays(from, to, uint16(gameId), scope)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"days": days})
+}
+
+func (h *analyticsHandler) Utilisation(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	hours, err := h.analyticsService.GetUtilisation(from, to, c.Query("machine"), scope)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"hours": hours})
+}
+
+func (h *analyticsHandler) PeakHours(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	cells, err := h.analyticsService.GetPeakHours(from, to, scope)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"heatmap": cells})
+}
+
+// Rollup redoes the rollups of the range, for when the data of past days was corrected.
+func (h *analyticsHandler) Rollup(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	days, err := h.analyticsService.Rollup(from, to)
+	if err != nil {
+		analyticsError(c, err)
+		return
+	}
+
+	utils.LogInfo("Analytics of %d days rolled up again by admin ID %d", days, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"days": days})
+}
+
+// analyticsScope is the venue asked for when the admin manages it, otherwise every venue of the admin.
+func analyticsScope(c *gin.Context) ([]string, bool) {
+	venue := c.Query("venue")
+	if venue == "" {
+		return utils.AdminVenues(c), true
+	}
+	if !utils.ManagesVenue(c, venue) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you don't manage venue '%s'", venue)})
+		return nil, false
+	}
+	return []string{venue}, true
+}
+
+func analyticsError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrInvalidRange) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+}
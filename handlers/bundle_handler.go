@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BundleHandler interface {
+	// admin
+	AllBundles(c *gin.Context)
+	AddBundle(c *gin.Context)
+	DeactivateBundle(c *gin.Context)
+
+	// players and cabinets
+	PurchaseCodes(c *gin.Context)
+	Draw(c *gin.Context)
+}
+
+type bundleHandler struct {
+	bundleService services.BundleService
+}
+
+func NewBundleHandler(bundleService services.BundleService) *bundleHandler {
+	return &bundleHandler{bundleService: bundleService}
+}
+
+func (h *bundleHandler) AllBundles(c *gin.Context) {
+	bundles, err := h.bundleService.GetBundles(true)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"bundles": bundles})
+}
+
+func (h *bundleHandler) AddBundle(c *gin.Context) {
+	var bundle models.Bundle
+	if err := c.ShouldBindJSON(&bundle); err != nil || isAnyEmpty(bundle.Name, bundle.Description, bundle.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name, description, kind and price are required"})
+		return
+	}
+
+	bundleId, err := h.bundleService.AddBundle(bundle)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": bundleId})
+}
+
+func (h *bundleHandler) DeactivateBundle(c *gin.Context) {
+	bundleId, ok := pathId(c, "bundleId")
+	if !ok {
+		return
+	}
+
+	if err := h.bundleService.DeactivateBundle(bundleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Bundle is no longer on sale."})
+}
+
+// PurchaseCodes is empty until razorpay's webhook for the payment has come in, the page polls it.
+func (h *bundleHandler) PurchaseCodes(c *gin.Context) {
+	codes, err := h.bundleService.PurchaseCodes(c.Param("paymentId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"codes": codes})
+}
+
+func (h *bundleHandler) Draw(c *gin.Context) {
+	var draw models.BalanceDraw
+	if err := c.ShouldBindJSON(&draw); err != nil || draw.GameId <= 0 || draw.PlayTime <= 0 || isAnyEmpty(draw.BalanceCode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "balance code, game id and play time are required"})
+		return
+	}
+
+	code, left, err := h.bundleService.Draw(draw)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBalanceCodeNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrBalanceTooLow):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrTierNotFound):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": code, "minutesLeft": left})
+}
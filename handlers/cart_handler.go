@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// guests are told their cart session in a cookie, apps without cookies send it back in the header.
+const (
+	cartSessionCookie = "cart_session"
+	cartSessionHeader = "X-Cart-Session"
+	cartSessionMaxAge = 30 * 24 * 60 * 60
+)
+
+// every cart route takes ?venue for the shop the cart is bought from, the central stock without it.
+type CartHandler interface {
+	Cart(c *gin.Context)
+	SetItem(c *gin.Context)
+	RemoveItem(c *gin.Context)
+	ClearCart(c *gin.Context)
+	Checkout(c *gin.Context)
+	Orders(c *gin.Context)
+}
+
+type cartHandler struct {
+	cartService services.CartService
+}
+
+func NewCartHandler(cartService services.CartService) *cartHandler {
+	return &cartHandler{cartService: cartService}
+}
+
+func (h *cartHandler) Cart(c *gin.Context) {
+	cart, err := h.cartService.GetCart(cartOwner(c), c.Query("venue"))
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+// SetItem takes {"quantity": n} for the product of the path, 0 takes it out of the cart.
+func (h *cartHandler) SetItem(c *gin.Context) {
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
+	var req struct {
+		Quantity *int `json:"quantity"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quantity == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "quantity is required"})
+		return
+	}
+
+	owner, ok := h.sessionOwner(c)
+	if !ok {
+		return
+	}
+
+	cart, err := h.cartService.SetItem(owner, c.Query("venue"), models.CartItem{ProductId: productId, Quantity: *req.Quantity})
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (h *cartHandler) RemoveItem(c *gin.Context) {
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
+
+	owner := cartOwner(c)
+	if owner.PlayerId == 0 && owner.Session == "" {
+		c.JSON(http.StatusOK, gin.H{"cart": models.Cart{Items: []models.CartLine{}}})
+		return
+	}
+
+	cart, err := h.cartService.SetItem(owner, c.Query("venue"), models.CartItem{ProductId: productId})
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"cart": cart})
+}
+
+func (h *cartHandler) ClearCart(c *gin.Context) {
+	if err := h.cartService.ClearCart(cartOwner(c)); err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared."})
+}
+
+// Checkout opens the razorpay order for the cart, the cart is emptied once the payment is captured.
+func (h *cartHandler) Checkout(c *gin.Context) {
+	checkout, err := h.cartService.Checkout(cartOwner(c), c.Query("venue"))
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"checkout": checkout})
+}
+
+// Orders are the orders of the player, or of the cart session for guests.
+func (h *cartHandler) Orders(c *gin.Context) {
+	orders, err := h.cartService.GetOrders(cartOwner(c))
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"orders": orders})
+}
+
+// sessionOwner starts a cart session for the guest who doesn't have one yet.
+func (h *cartHandler) sessionOwner(c *gin.Context) (models.CartOwner, bool) {
+	owner := cartOwner(c)
+	if owner.PlayerId > 0 || owner.Session != "" {
+		return owner, true
+	}
+
+	session, err := h.cartService.NewSession()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return owner, false
+	}
+	c.SetCookie(
+		cartSessionCookie,
+		session,
+		cartSessionMaxAge,
+		"/",
+		"localhost",
+		false, //make sure to make it true later in https
+		true)
+	c.Header(cartSessionHeader, session)
+
+	owner.Session = session
+	return owner, true
+}
+
+// cartOwner is the logged in player, with the guest session to move its cart over after the login.
+// a session that isn't one we hand out is ignored.
+func cartOwner(c *gin.Context) models.CartOwner {
+	owner := models.CartOwner{PlayerId: utils.CheckPlayer(c)}
+
+	session, err := c.Cookie(cartSessionCookie)
+	if err != nil {
+		session = c.GetHeader(cartSessionHeader)
+	}
+	if decoded, err := hex.DecodeString(session); err == nil && len(decoded) == 16 {
+		owner.Session = session
+	}
+	return owner
+}
+
+func cartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotEnoughStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrCartEmpty), errors.Is(err, services.ErrInvalidCartItem):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CodeExpiryHandler interface {
+	Policies(c *gin.Context)
+	SavePolicy(c *gin.Context)
+	DeletePolicy(c *gin.Context) // ?game=<id>, without it the default policy is removed
+}
+
+type codeExpiryHandler struct {
+	codeExpiryService services.CodeExpiryService
+}
+
+func NewCodeExpiryHandler(codeExpiryService services.CodeExpiryService) *codeExpiryHandler {
+	return &codeExpiryHandler{codeExpiryService: codeExpiryService}
+}
+
+func (h *codeExpiryHandler) Policies(c *gin.Context) {
+	policies, err := h.codeExpiryService.GetPolicies()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+func (h *codeExpiryHandler) SavePolicy(c *gin.Context) {
+	var policy models.CodeValidityPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		utils.LogError("Invalid code validity policy input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	if err := h.codeExpiryService.SavePolicy(policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Code validity policy saved."})
+}
+
+func (h *codeExpiryHandler) DeletePolicy(c *gin.Context) {
+	var gameId *uint16
+	if game := c.Query("game"); game != "" {
+		id, err := strconv.ParseUint(game, 10, 16)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+			return
+		}
+		parsed := uint16(id)
+		gameId = &parsed
+	}
+
+	if err := h.codeExpiryService.DeletePolicy(gameId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Code validity policy removed."})
+}
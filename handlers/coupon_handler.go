@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CouponHandler interface {
+	Coupons(c *gin.Context)
+	AddCoupon(c *gin.Context)
+	DeactivateCoupon(c *gin.Context)
+}
+
+type couponHandler struct {
+	couponService services.CouponService
+}
+
+func NewCouponHandler(couponService services.CouponService) *couponHandler {
+	return &couponHandler{couponService: couponService}
+}
+
+func (h *couponHandler) Coupons(c *gin.Context) {
+	coupons, err := h.couponService.GetCoupons()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+func (h *couponHandler) AddCoupon(c *gin.Context) {
+	var coupon models.Coupon
+	if err := c.ShouldBindJSON(&coupon); err != nil || isAnyEmpty(coupon.Code, coupon.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code, kind and value are required"})
+		return
+	}
+
+	couponId, err := h.couponService.AddCoupon(coupon)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": couponId})
+}
+
+func (h *couponHandler) DeactivateCoupon(c *gin.Context) {
+	couponId, ok := pathId(c, "couponId")
+	if !ok {
+		return
+	}
+
+	if err := h.couponService.DeactivateCoupon(couponId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deactivated."})
+}
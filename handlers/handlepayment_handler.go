@@ -4,7 +4,10 @@ import (
 	"GameWala-Arcade/config"
 	"GameWala-Arcade/models"
 	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"time"
@@ -16,29 +19,74 @@ import (
 type HandlePaymentHandler interface {
 	CreateOrder(c *gin.Context)
 	SaveOrderDetails(c *gin.Context)
+	Webhook(c *gin.Context) // razorpay calls this on payment events
 }
 
 type handlePaymentHandler struct {
 	handlePaymentService services.HandlePaymentService
+	playGameService      services.PlayGameService
 }
 
-func NewHandlePaymentHandler(paymentService services.HandlePaymentService) *handlePaymentHandler {
-	return &handlePaymentHandler{handlePaymentService: paymentService}
+func NewHandlePaymentHandler(paymentService services.HandlePaymentService,
+	playGameService services.PlayGameService) *handlePaymentHandler {
+	return &handlePaymentHandler{handlePaymentService: paymentService, playGameService: playGameService}
 }
 
+// ?purpose=game|shop|pass|bundle, ?game=<id>, ?pass=<id> and ?bundle=<id> are kept in the order notes, razorpay sends them back with the payment events.
+// ?venue=<venue> picks the pricing rules of the venue. With ?coupon=<code> the game is priced here from ?game and ?time or ?level, the amount in the path is ignored.
 func (h *handlePaymentHandler) CreateOrder(c *gin.Context) {
 	amount := c.Param("amount")
 	amount_inr, _ := strconv.Atoi(amount)
+	if coupon := c.Query("coupon"); coupon != "" {
+		quote, err := h.quote(c, coupon)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		amount_inr = int(quote.Price) * 100 // razorpay takes paise
+	}
+	// a pass is granted to the player who paid, a guest would pay and get nothing.
+	if c.Query("purpose") == models.PaymentPurposePass && utils.CheckPlayer(c) <= 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "log in to buy a pass"})
+		return
+	}
 	client := razorpay.NewClient(config.GetString("key_id"), config.GetString("key_secret"))
 	receipt := fmt.Sprintf("txn_%d", time.Now().Unix())
 
+	notes := map[string]interface{}{"purpose": c.DefaultQuery("purpose", models.PaymentPurposeGame)}
+	if gameId := c.Query("game"); gameId != "" {
+		notes["game_id"] = gameId
+	}
+	if passId := c.Query("pass"); passId != "" {
+		notes["pass_id"] = passId
+	}
+	if bundleId := c.Query("bundle"); bundleId != "" {
+		notes["bundle_id"] = bundleId
+	}
+	if coupon := c.Query("coupon"); coupon != "" {
+		notes["coupon"] = coupon
+	}
+	if playerId := utils.CheckPlayer(c); playerId > 0 {
+		notes["player_id"] = strconv.Itoa(playerId)
+	}
+
 	data := map[string]interface{}{
 		"amount":   amount_inr,
 		"currency": "INR",
-		"receipt":  receipt}
+		"receipt":  receipt,
+		"notes":    notes}
 
 	body, err := client.Order.Create(data, map[string]string{}) // 2nd param optional
 	if err == nil {
+		// the game is priced by the rules in effect now, not when the player gets round to paying.
+		order := models.PaymentOrder{Purpose: notes["purpose"].(string), Amount: int64(amount_inr)}
+		order.OrderId, _ = body["id"].(string)
+		if venue := c.Query("venue"); venue != "" {
+			order.Venue = &venue
+		}
+		if err := h.handlePaymentService.RecordOrder(order); err != nil {
+			utils.LogError("Order %s will be priced at payment time: %v", order.OrderId, err)
+		}
 		c.JSON(http.StatusOK, gin.H{"details": body})
 	} else {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Errorf("razorpay might be down, please try later.").Error()})
@@ -60,3 +108,48 @@ func (h *handlePaymentHandler) SaveOrderDetails(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"Success: ": "Successfully saved order details."})
 	}
 }
+
+func (h *handlePaymentHandler) Webhook(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "couldn't read the request"})
+		return
+	}
+
+	err = h.handlePaymentService.HandleWebhook(body, c.GetHeader("X-Razorpay-Signature"))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidWebhook) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		// razorpay retries on anything but 2xx.
+		utils.LogError("Failed to handle razorpay webhook: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Some error handling the webhook. please check logs."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+func (h *handlePaymentHandler) quote(c *gin.Context, coupon string) (models.PriceQuote, error) {
+	gameId, err := strconv.ParseUint(c.Query("game"), 10, 16)
+	if err != nil || gameId == 0 {
+		return models.PriceQuote{}, fmt.Errorf("game is required with a coupon")
+	}
+
+	status := models.GameStatus{GameId: uint16(gameId), Coupon: coupon, Venue: c.Query("venue")}
+	if playTime, err := strconv.ParseUint(c.Query("time"), 10, 16); err == nil {
+		t := uint16(playTime)
+		status.IsTimed, status.PlayTime = true, &t
+	} else if levels, err := strconv.ParseUint(c.Query("level"), 10, 8); err == nil {
+		l := uint8(levels)
+		status.Levels = &l
+	} else {
+		return models.PriceQuote{}, fmt.Errorf("time or level is required with a coupon")
+	}
+	if playerId := utils.CheckPlayer(c); playerId > 0 {
+		status.PlayerId = &playerId
+	}
+
+	return h.playGameService.Quote(status)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// the stock of a venue is changed by its admins, the central stock only by the admins of every venue.
+type InventoryHandler interface {
+	Restock(c *gin.Context)
+	Adjust(c *gin.Context)
+	LowStock(c *gin.Context)  // ?venue, ?threshold overrides the one of the config
+	Movements(c *gin.Context) // ?product, ?venue, ?from and ?to as days, ?limit
+}
+
+type inventoryHandler struct {
+	inventoryService services.InventoryService
+}
+
+func NewInventoryHandler(inventoryService services.InventoryService) *inventoryHandler {
+	return &inventoryHandler{inventoryService: inventoryService}
+}
+
+// Restock takes {"units": n, "venue": "", "reason": ""}, the venue starts stocking the product if it didn't.
+func (h *inventoryHandler) Restock(c *gin.Context) {
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
+	var req struct {
+		Units  int    `json:"units"`
+		Venue  string `json:"venue"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Units <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "units are required and have to be positive"})
+		return
+	}
+	if !stockVenue(c, req.Venue) {
+		return
+	}
+
+	units, err := h.inventoryService.Restock(models.StockChange{ProductId: productId, Venue: req.Venue,
+		Change: req.Units, Reason: req.Reason}, utils.CheckCookies(c))
+	if err != nil {
+		inventoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"units": units})
+}
+
+// Adjust takes {"change": n, "venue": "", "reason": ""}, change is negative to take units out.
+func (h *inventoryHandler) Adjust(c *gin.Context) {
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
+	var change models.StockChange
+	if err := c.ShouldBindJSON(&change); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "change and reason are required"})
+		return
+	}
+	change.ProductId = productId
+	if !stockVenue(c, change.Venue) {
+		return
+	}
+
+	units, err := h.inventoryService.Adjust(change, utils.CheckCookies(c))
+	if err != nil {
+		inventoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"units": units})
+}
+
+func (h *inventoryHandler) LowStock(c *gin.Context) {
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+	threshold, _ := strconv.Atoi(c.Query("threshold"))
+
+	stock, err := h.inventoryService.GetLowStock(scope, threshold)
+	if err != nil {
+		inventoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": stock})
+}
+
+func (h *inventoryHandler) Movements(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	var productId *int
+	if value := c.Query("product"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid product"})
+			return
+		}
+		productId = &id
+	}
+
+	movements, err := h.inventoryService.GetMovements(productId, scope, from, to, queryLimit(c, 100, 1000))
+	if err != nil {
+		inventoryError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"movements": movements})
+}
+
+// stockVenue checks the admin may change the stock of the venue, empty is the central stock.
+func stockVenue(c *gin.Context, venue string) bool {
+	if utils.ManagesVenue(c, venue) {
+		return true
+	}
+	if venue == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins of every venue can change the central stock"})
+	} else {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you don't manage venue '%s'", venue)})
+	}
+	return false
+}
+
+func inventoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotEnoughStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidStockChange), errors.Is(err, services.ErrInvalidRange):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+	}
+}
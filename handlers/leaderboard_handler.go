@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+type LeaderboardHandler interface {
+	SubmitScore(c *gin.Context) // cabinets only, behind the machine signature
+	Leaderboard(c *gin.Context) // ?period=daily|weekly|all&venue=<venue>&limit=<n>
+	Scores(c *gin.Context)      // admin, ?game=<id>&limit=<n>
+	RemoveScore(c *gin.Context) // admin
+	Rebuild(c *gin.Context)     // global admin
+}
+
+type leaderboardHandler struct {
+	leaderboardService services.LeaderboardService
+}
+
+func NewLeaderboardHandler(leaderboardService services.LeaderboardService) *leaderboardHandler {
+	return &leaderboardHandler{leaderboardService: leaderboardService}
+}
+
+func (h *leaderboardHandler) SubmitScore(c *gin.Context) {
+	var submission models.ScoreSubmission
+	if err := c.ShouldBindJSON(&submission); err != nil || submission.GameId <= 0 || isAnyEmpty(submission.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game id, code and score are required"})
+		return
+	}
+
+	score, err := h.leaderboardService.SubmitScore(submission, c.GetString("machine_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrScoreRejected) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// one score per code, a replayed submission hits the unique code.
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("a score was already submitted for code %s", submission.Code)})
+			return
+		}
+		utils.LogError("Failed to submit score for code %s: %v", submission.Code, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't save the score, please try again later."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"score": score})
+}
+
+func (h *leaderboardHandler) Leaderboard(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	period := c.DefaultQuery("period", models.LeaderboardAllTime)
+	switch period {
+	case models.LeaderboardDaily, models.LeaderboardWeekly, models.LeaderboardAllTime:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Wrong period requested '%s'", period)})
+		return
+	}
+
+	limit := queryLimit(c, defaultLeaderboardSize, maxLeaderboardSize)
+
+	entries, err := h.leaderboardService.Leaderboard(uint16(gameId), c.Query("venue"), period, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"leaderboard": entries})
+}
+
+func (h *leaderboardHandler) Scores(c *gin.Context) {
+	var gameId *uint16
+	if game := c.Query("game"); game != "" {
+		id, err := strconv.ParseUint(game, 10, 16)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+			return
+		}
+		parsed := uint16(id)
+		gameId = &parsed
+	}
+
+	scores, err := h.leaderboardService.GetScores(gameId, queryLimit(c, maxLeaderboardSize, 1000), utils.AdminVenues(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scores": scores})
+}
+
+func (h *leaderboardHandler) RemoveScore(c *gin.Context) {
+	scoreId, err := strconv.Atoi(c.Param("scoreId"))
+	if err != nil || scoreId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid score id"})
+		return
+	}
+
+	if err := h.leaderboardService.RemoveScore(scoreId, utils.AdminVenues(c)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no score with id %d", scoreId)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	utils.LogInfo("Score ID %d removed by admin ID %d", scoreId, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Score removed from the leaderboards."})
+}
+
+func (h *leaderboardHandler) Rebuild(c *gin.Context) {
+	if err := h.leaderboardService.RebuildLeaderboards(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	utils.LogInfo("Leaderboards rebuilt by admin ID %d", utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Leaderboards rebuilt from the saved scores."})
+}
+
+// queryLimit reads ?limit, falling back to the default and capping it at max.
+func queryLimit(c *gin.Context, fallback int, max int) int {
+	limit, err := strconv.Atoi(c.Query("limit"))
+	if err != nil || limit <= 0 {
+		return fallback
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LoyaltyHandler interface {
+	// admin
+	Rules(c *gin.Context)
+	AddRule(c *gin.Context)
+	DeleteRule(c *gin.Context)
+	AddReward(c *gin.Context)
+	DeactivateReward(c *gin.Context)
+
+	// players
+	Rewards(c *gin.Context)
+	Summary(c *gin.Context)
+	Redeem(c *gin.Context)
+}
+
+type loyaltyHandler struct {
+	loyaltyService services.LoyaltyService
+}
+
+func NewLoyaltyHandler(loyaltyService services.LoyaltyService) *loyaltyHandler {
+	return &loyaltyHandler{loyaltyService: loyaltyService}
+}
+
+func (h *loyaltyHandler) Rules(c *gin.Context) {
+	rules, err := h.loyaltyService.GetRules()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *loyaltyHandler) AddRule(c *gin.Context) {
+	var rule models.LoyaltyRule
+	if err := c.ShouldBindJSON(&rule); err != nil || isAnyEmpty(rule.Description) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multiplier and description are required"})
+		return
+	}
+
+	ruleId, err := h.loyaltyService.AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": ruleId})
+}
+
+func (h *loyaltyHandler) DeleteRule(c *gin.Context) {
+	ruleId, ok := pathId(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	if err := h.loyaltyService.DeleteRule(ruleId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted."})
+}
+
+func (h *loyaltyHandler) AddReward(c *gin.Context) {
+	var reward models.LoyaltyReward
+	if err := c.ShouldBindJSON(&reward); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	rewardId, err := h.loyaltyService.AddReward(reward)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": rewardId})
+}
+
+func (h *loyaltyHandler) DeactivateReward(c *gin.Context) {
+	rewardId, ok := pathId(c, "rewardId")
+	if !ok {
+		return
+	}
+
+	if err := h.loyaltyService.DeactivateReward(rewardId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reward is no longer available."})
+}
+
+func (h *loyaltyHandler) Rewards(c *gin.Context) {
+	rewards, err := h.loyaltyService.GetRewards()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rewards": rewards})
+}
+
+func (h *loyaltyHandler) Summary(c *gin.Context) {
+	summary, err := h.loyaltyService.GetSummary(utils.CheckPlayer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"loyalty": summary})
+}
+
+func (h *loyaltyHandler) Redeem(c *gin.Context) {
+	rewardId, ok := pathId(c, "rewardId")
+	if !ok {
+		return
+	}
+
+	redemption, err := h.loyaltyService.Redeem(utils.CheckPlayer(c), rewardId)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrNotEnoughPoints):
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrRewardNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redemption": redemption})
+}
+
+// pathId parses a positive id from the path, answers with 400 when it isn't one.
+func pathId(c *gin.Context, param string) (int, bool) {
+	id, err := strconv.Atoi(c.Param(param))
+	if err != nil || id <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s", param)})
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	timestampHeader = "X-Timestamp"
+	signatureHeader = "X-Signature"
+)
+
+type MachineHandler interface {
+	Register(c *gin.Context)
+	Machines(c *gin.Context)
+	Authenticate(c *gin.Context)   // middleware for the signed cabinet requests
+	ManagedMachine(c *gin.Context) // middleware for admin routes on a :machineId of the admin's venues
+}
+
+type machineHandler struct {
+	machineService services.MachineService
+}
+
+func NewMachineHandler(machineService services.MachineService) *machineHandler {
+	return &machineHandler{machineService: machineService}
+}
+
+func (h *machineHandler) Register(c *gin.Context) {
+	var machine models.Machine
+	if err := c.ShouldBindJSON(&machine); err != nil || isAnyEmpty(machine.Id, machine.Name, machine.Venue) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id, name and venue are required"})
+		return
+	}
+
+	if !utils.ManagesVenue(c, machine.Venue) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you don't manage venue '%s'", machine.Venue)})
+		return
+	}
+
+	machine, err := h.machineService.Register(machine)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("venue '%s' doesn't exist", machine.Venue)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	utils.LogInfo("Machine %s registered", machine.Id)
+	c.JSON(http.StatusOK, gin.H{"machine": machine, "secretKey": machine.SecretKey})
+}
+
+func (h *machineHandler) Machines(c *gin.Context) {
+	machines, err := h.machineService.GetMachines(utils.AdminVenues(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"machines": machines})
+}
+
+func (h *machineHandler) ManagedMachine(c *gin.Context) {
+	machine, err := h.machineService.GetMachine(c.Param("machineId"))
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !utils.ManagesVenue(c, machine.Venue)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no machine with id '%s'", c.Param("machineId"))})
+		c.Abort()
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (h *machineHandler) Authenticate(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "couldn't read the request"})
+		c.Abort()
+		return
+	}
+	// put the body back for the handler to bind.
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	machine, err := h.machineService.Authenticate(c.GetHeader(machineIdHeader), c.Request.Method,
+		c.Request.URL.RequestURI(), c.GetHeader(timestampHeader), c.GetHeader(signatureHeader), body)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		c.Abort()
+		return
+	}
+
+	c.Set("machine_id", machine.Id)
+	c.Set("venue", machine.Venue)
+	c.Next()
+}
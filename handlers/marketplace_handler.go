@@ -3,8 +3,10 @@ package handlers
 import (
 	"GameWala-Arcade/models"
 	"GameWala-Arcade/services"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -12,6 +14,7 @@ import (
 
 type MarketPlaceHandler interface {
 	Products(c *gin.Context)
+	Product(c *gin.Context)
 }
 
 type marketPlaceHandler struct {
@@ -23,22 +26,58 @@ func NewMarketPlaceHandler(marketPlaceService services.MarketPlaceService) *mark
 }
 
 // get the products, type will be defined in queryparam, like ?cards, or ?stickers
-// Note: This API may need pagination in future.
+// ?venue=<id> gives the shop of that venue with its own stock.
+// ?sort=newest|price|price_desc, ?limit and ?offset page through it, total counts the whole listing.
 func (h *marketPlaceHandler) Products(c *gin.Context) {
 	requestedType := c.DefaultQuery("type", "")
 	productType, err := stringToProductType(requestedType)
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Errorf("Wrong product type requested '%s'", requestedType).Error()})
+		return
 	}
 
-	productData, err := h.marketPlaceService.FetchProducts(productType)
+	filter := models.ProductFilter{Type: productType, Venue: c.Query("venue"), Sort: c.Query("sort")}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if offset := c.Query("offset"); offset != "" {
+		if filter.Offset, err = strconv.Atoi(offset); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "offset should be a number"})
+			return
+		}
+	}
+
+	page, err := h.marketPlaceService.FetchProducts(filter)
+
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidProductFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Errorf("Something went wrong '%v',", err).Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"Success": page.Products, "total": page.Total})
+}
+
+// Product gives the product with all its images, ?venue=<id> gives the stock of that venue.
+func (h *marketPlaceHandler) Product(c *gin.Context) {
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
 
+	product, err := h.marketPlaceService.FetchProduct(productId, c.Query("venue"))
 	if err != nil {
+		if errors.Is(err, services.ErrProductNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Errorf("Something went wrong '%v',", err).Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"Success": productData})
+	c.JSON(http.StatusOK, gin.H{"Success": product})
 }
 
 func stringToProductType(s string) (models.ProductType, error) {
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PassHandler interface {
+	// admin
+	AllPasses(c *gin.Context)
+	AddPass(c *gin.Context)
+	DeactivatePass(c *gin.Context)
+
+	// players
+	Passes(c *gin.Context)
+	PlayerPasses(c *gin.Context)
+	Subscribe(c *gin.Context)
+	CancelSubscription(c *gin.Context)
+}
+
+type passHandler struct {
+	passService services.PassService
+}
+
+func NewPassHandler(passService services.PassService) *passHandler {
+	return &passHandler{passService: passService}
+}
+
+func (h *passHandler) AllPasses(c *gin.Context) {
+	h.passes(c, true)
+}
+
+func (h *passHandler) Passes(c *gin.Context) {
+	h.passes(c, false)
+}
+
+func (h *passHandler) passes(c *gin.Context, includeInactive bool) {
+	passes, err := h.passService.GetPassProducts(includeInactive)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passes": passes})
+}
+
+func (h *passHandler) AddPass(c *gin.Context) {
+	var pass models.PassProduct
+	if err := c.ShouldBindJSON(&pass); err != nil || isAnyEmpty(pass.Name, pass.Description) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name, description and price are required"})
+		return
+	}
+
+	passId, err := h.passService.AddPassProduct(pass)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": passId})
+}
+
+func (h *passHandler) DeactivatePass(c *gin.Context) {
+	passId, ok := pathId(c, "passId")
+	if !ok {
+		return
+	}
+
+	if err := h.passService.DeactivatePassProduct(passId); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pass is no longer on sale."})
+}
+
+func (h *passHandler) PlayerPasses(c *gin.Context) {
+	passes, err := h.passService.GetPlayerPasses(utils.CheckPlayer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passes": passes})
+}
+
+func (h *passHandler) Subscribe(c *gin.Context) {
+	passId, ok := pathId(c, "passId")
+	if !ok {
+		return
+	}
+
+	subscription, err := h.passService.Subscribe(utils.CheckPlayer(c), passId)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPassNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, services.ErrPassNotRecurring):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subscription": subscription})
+}
+
+func (h *passHandler) CancelSubscription(c *gin.Context) {
+	err := h.passService.CancelSubscription(utils.CheckPlayer(c), c.Param("subscriptionId"))
+	if err != nil {
+		if errors.Is(err, services.ErrSubscriptionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription won't renew, the current period keeps running."})
+}
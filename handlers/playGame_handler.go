@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -15,19 +16,31 @@ import (
 
 const minPrice = 10
 
+// cabinets identify themselves with this header on their signed requests.
+const machineIdHeader = "X-Machine-Id"
+
 type PlayGameHandler interface {
 	SaveGameStatus(c *gin.Context)
 	GetGamesCatalogue(c *gin.Context)
 	CheckGameCode(c *gin.Context)
-	GenerateCode(c *gin.Context) //this is something logical ughh.
+	CabinetCheckGameCode(c *gin.Context) // also checks the code was bought for the venue of the cabinet
+	GenerateCode(c *gin.Context)         //this is something logical ughh.
+	CodeQR(c *gin.Context)
+	CabinetQR(c *gin.Context)
+	Quote(c *gin.Context) // the price to pay for a tier, with the coupon taken off
+	SetGameSystem(c *gin.Context)
+	SetGameMetadata(c *gin.Context)
+	Genres(c *gin.Context)
+	InvalidateCatalogue(c *gin.Context) // middleware for the admin routes that change the catalogue
 }
 
 type playGameHandler struct {
-	playGameService services.PlayGameService
+	playGameService  services.PlayGameService
+	catalogueService services.CatalogueService
 }
 
-func NewPlayGameHandler(arcadeStoreService services.PlayGameService) *playGameHandler {
-	return &playGameHandler{playGameService: arcadeStoreService}
+func NewPlayGameHandler(arcadeStoreService services.PlayGameService, catalogueService services.CatalogueService) *playGameHandler {
+	return &playGameHandler{playGameService: arcadeStoreService, catalogueService: catalogueService}
 }
 
 func (h *playGameHandler) SaveGameStatus(c *gin.Context) {
@@ -43,21 +56,30 @@ func (h *playGameHandler) SaveGameStatus(c *gin.Context) {
 	if req.GameId <= 0 {
 		utils.LogError("Invalid game ID provided: %d", req.GameId)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
 	}
 
-	if isAnyEmpty(req.PaymentReference) {
+	if isAnyEmpty(req.PaymentReference) && req.PassId == nil && !req.Wallet {
 		utils.LogError("Missing payment reference for game ID: %d", req.GameId)
 		c.JSON(http.StatusPaymentRequired, gin.H{"error": "invalid code, or payment reference id"})
+		return
 	}
 
-	if req.Price < minPrice {
+	// a coupon can legitimately take the price under the minimum, validatePrice checks it against the tier.
+	if req.Price < minPrice && req.Coupon == "" {
 		utils.LogError("Attempt to play with low price: %d (min: %d) for game ID: %d", req.Price, minPrice, req.GameId)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Price is very low, seems like an attempt to play for free or cheap?"})
+		return
 	}
 
 	if req.PlayTime == nil && req.Levels == nil {
 		utils.LogError("Both PlayTime and Levels are null for game ID: %d", req.GameId)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Time and Level, both can't be null"})
+		return
+	}
+
+	if playerId := utils.CheckPlayer(c); playerId > 0 {
+		req.PlayerId = &playerId
 	}
 
 	res, code, err := h.playGameService.SaveGameStatus(req)
@@ -65,7 +87,10 @@ func (h *playGameHandler) SaveGameStatus(c *gin.Context) {
 	if err != nil {
 		utils.LogError("Error saving game status for game ID %d: %v", req.GameId, err)
 		var pqErr *pq.Error
-		if errors.As(err, &pqErr) {
+		if errors.Is(err, services.ErrVenueNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if errors.As(err, &pqErr) {
 			if pqErr.Code == "23505" {
 				utils.LogError("Either code '%s' or paymentId '%s' already exists", req.Code, req.PaymentReference)
 				c.JSON(http.StatusBadRequest, gin.H{
@@ -80,6 +105,12 @@ func (h *playGameHandler) SaveGameStatus(c *gin.Context) {
 			utils.LogError("Given the game: %s , price: %d and time: %d doesn't match.", req.Name, req.Price, *req.PlayTime)
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Given the game: %s , price: %d and time: %d doesn't match.", req.Name, req.Price, *req.PlayTime)})
 			return
+		} else if res == 5 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		} else if res == 4 {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+			return
 		} else if res == 3 {
 			utils.LogError("Given the game: %s , price: %d and level: %d doesn't match.", req.Name, req.Price, *req.Levels)
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Given the game: %s , price: %d and level: %d doesn't match.", req.Name, req.Price, *req.Levels)})
@@ -95,21 +126,204 @@ func (h *playGameHandler) SaveGameStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"Code": code})
 }
 
+func (h *playGameHandler) Quote(c *gin.Context) {
+	var req models.GameStatus
+	if err := c.ShouldBindJSON(&req); err != nil || req.GameId <= 0 || (req.PlayTime == nil && req.Levels == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game id and the time or level are required"})
+		return
+	}
+
+	if playerId := utils.CheckPlayer(c); playerId > 0 {
+		req.PlayerId = &playerId
+	}
+
+	quote, err := h.playGameService.Quote(req)
+	if err != nil {
+		if errors.Is(err, services.ErrTierNotFound) || errors.Is(err, services.ErrCouponRefused) ||
+			errors.Is(err, services.ErrVenueNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quote": quote})
+}
+
+// ?q= searches the names, descriptions and tags, ?system=, ?mode=timed|level, ?minPrice=&maxPrice=, ?genre=,
+// ?tag= and ?players= filter, ?sort=popular|newest|price|price_desc orders and ?cursor= with the nextCursor of the previous page gives the next one.
+// The bundles only come with the first page.
 func (h *playGameHandler) GetGamesCatalogue(c *gin.Context) {
 	utils.LogInfo("Received request to get games catalogue")
-	// var res models.GameResponse
-	res, err := h.playGameService.GetGames()
+
+	filter := models.GameFilter{
+		Venue:  c.Query("venue"),
+		Query:  strings.TrimSpace(c.Query("q")),
+		System: c.Query("system"),
+		Mode:   c.Query("mode"),
+		Genre:  c.Query("genre"),
+		Tag:    c.Query("tag"),
+		Sort:   c.Query("sort"),
+		Cursor: c.Query("cursor"),
+	}
+	filter.Limit, _ = strconv.Atoi(c.Query("limit"))
+
+	var err error
+	if filter.MinPrice, err = queryPrice(c, "minPrice"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if filter.MaxPrice, err = queryPrice(c, "maxPrice"); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if players := c.Query("players"); players != "" {
+		count, err := strconv.ParseUint(players, 10, 8)
+		if err != nil || count == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "players should be a number from 1"})
+			return
+		}
+		parsed := uint8(count)
+		filter.Players = &parsed
+	}
+
+	body, etag, err := h.catalogueService.Catalogue(filter)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidFilter) || errors.Is(err, services.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		utils.LogError("Error fetching games catalogue: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Errorf("Some error occurred: %w", err).Error()})
 		return
 	}
 
+	// browsers revalidate every time, an unchanged catalogue costs them an empty 304.
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
 	utils.LogInfo("Successfully retrieved games catalogue")
-	c.JSON(http.StatusOK, gin.H{"games": res})
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// InvalidateCatalogue runs the admin change first and drops the cached catalogue once it went through.
+func (h *playGameHandler) InvalidateCatalogue(c *gin.Context) {
+	c.Next()
+	if c.Writer.Status() < http.StatusMultipleChoices {
+		h.catalogueService.Invalidate()
+	}
+}
+
+// etagMatches reads If-None-Match, a list of tags that may be weak or "*".
+func etagMatches(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *playGameHandler) SetGameSystem(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	var req struct {
+		System string `json:"system"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "system is required"})
+		return
+	}
+
+	if err := h.playGameService.SetGameSystem(uint16(gameId), req.System); err != nil {
+		if errors.Is(err, services.ErrInvalidMetadata) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("system '%s' isn't registered", req.System)})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "System updated."})
+}
+
+func (h *playGameHandler) SetGameMetadata(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	var metadata models.GameMetadata
+	if err := c.ShouldBindJSON(&metadata); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid metadata"})
+		return
+	}
+
+	if err := h.playGameService.SetGameMetadata(uint16(gameId), metadata); err != nil {
+		if errors.Is(err, services.ErrInvalidMetadata) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	utils.LogInfo("Metadata of game ID %d updated by admin ID %d", gameId, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Game updated."})
+}
+
+// the genres to browse, ?venue= counts the games available there.
+func (h *playGameHandler) Genres(c *gin.Context) {
+	genres, err := h.playGameService.GetGenres(c.Query("venue"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"genres": genres})
+}
+
+// queryPrice reads an optional price from the query, nil when it's not given.
+func queryPrice(c *gin.Context, key string) (*uint16, error) {
+	value := c.Query(key)
+	if value == "" {
+		return nil, nil
+	}
+
+	price, err := strconv.ParseUint(value, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("%s should be a whole number of rupees", key)
+	}
+	parsed := uint16(price)
+	return &parsed, nil
 }
 
 func (h *playGameHandler) CheckGameCode(c *gin.Context) {
+	h.checkGameCode(c, "")
+}
+
+func (h *playGameHandler) CabinetCheckGameCode(c *gin.Context) {
+	h.checkGameCode(c, c.GetString("machine_id"))
+}
+
+// checkGameCode only knows the machine on the signed cabinet path, the venue of the code is checked there.
+func (h *playGameHandler) checkGameCode(c *gin.Context, machineId string) {
 	code := c.Param("gamecode")
 
 	if isAnyEmpty(code) {
@@ -118,9 +332,24 @@ func (h *playGameHandler) CheckGameCode(c *gin.Context) {
 		return
 	}
 
-	details, err := h.playGameService.CheckGameCode(code)
+	details, err := h.playGameService.CheckGameCode(code, machineId)
 	if err != nil {
 
+		if errors.Is(err, services.ErrInvalidCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The code '%s' seems mistyped, please check it again.", code)})
+			return
+		}
+
+		if errors.Is(err, services.ErrCodeOtherVenue) {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("The code '%s' was bought for another venue.", code)})
+			return
+		}
+
+		if errors.Is(err, services.ErrCodeExpired) {
+			c.JSON(http.StatusGone, gin.H{"error": fmt.Sprintf("The code '%s' has expired.", code)})
+			return
+		}
+
 		if strings.Contains(err.Error(), "Scan error") {
 			utils.LogError("scan error occurred (more likely wrong code entered): %v", err)
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Errorf("Wrong error code entered: '%s'", code).Error()})
@@ -154,3 +383,41 @@ func (h *playGameHandler) GenerateCode(c *gin.Context) {
 	utils.LogInfo("Code is Generated Successfully: %s", code)
 	c.JSON(http.StatusOK, gin.H{"success": fmt.Sprintf("Generated code is: %s", code)})
 }
+
+// ?format=png (default) or ?format=svg
+func (h *playGameHandler) CodeQR(c *gin.Context) {
+	code := c.Param("gamecode")
+
+	image, contentType, err := h.playGameService.CodeQR(code, c.DefaultQuery("format", utils.QRFormatPNG))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("The code '%s' seems mistyped, please check it again.", code)})
+			return
+		}
+		utils.LogError("Failed to render QR for code %s: %v", code, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, image)
+}
+
+// sticker for the cabinet, ?game=<id> preselects the game and ?format=png|svg picks the image type.
+func (h *playGameHandler) CabinetQR(c *gin.Context) {
+	machineId := c.Param("machineId")
+
+	gameId, err := strconv.ParseUint(c.DefaultQuery("game", "0"), 10, 16)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	image, contentType, err := h.playGameService.CabinetQR(machineId, uint16(gameId), c.DefaultQuery("format", utils.QRFormatPNG))
+	if err != nil {
+		utils.LogError("Failed to render QR for cabinet %s: %v", machineId, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Data(http.StatusOK, contentType, image)
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PlayerHandler interface {
+	SendOTP(c *gin.Context)
+	VerifyOTP(c *gin.Context) // logs the player in
+	Logout(c *gin.Context)
+	Me(c *gin.Context)
+	UpdateName(c *gin.Context)
+	Codes(c *gin.Context) // ?status=played|unplayed|expired
+	ResendCode(c *gin.Context)
+	Wallet(c *gin.Context) // ?limit of the entries
+}
+
+type playerHandler struct {
+	playerService services.PlayerService
+}
+
+func NewPlayerHandler(playerService services.PlayerService) *playerHandler {
+	return &playerHandler{playerService: playerService}
+}
+
+const playerTokenMaxAge = 30 * 24 * 60 * 60
+
+// the player apps send a stable id of the install, used against referral abuse.
+const deviceIdHeader = "X-Device-Id"
+
+func (h *playerHandler) SendOTP(c *gin.Context) {
+	var req models.OTPRequest
+	if err := c.ShouldBindJSON(&req); err != nil || isAnyEmpty(req.Phone) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone number is required"})
+		return
+	}
+
+	err := h.playerService.SendOTP(req.Phone)
+	switch {
+	case errors.Is(err, services.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrOTPTooSoon), errors.Is(err, services.ErrOTPLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't send the otp, please try again later."})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "OTP sent."})
+	}
+}
+
+func (h *playerHandler) VerifyOTP(c *gin.Context) {
+	var req models.OTPVerification
+	if err := c.ShouldBindJSON(&req); err != nil || isAnyEmpty(req.Phone, req.OTP) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "phone number and otp are required"})
+		return
+	}
+
+	player, token, err := h.playerService.VerifyOTP(req.Phone, req.OTP, c.GetHeader(deviceIdHeader))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidPhone) || errors.Is(err, services.ErrInvalidOTP) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrOTPLocked) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.SetCookie(
+		utils.PlayerTokenCookie,
+		token,
+		playerTokenMaxAge,
+		"/",
+		"localhost",
+		false, //make sure to make it true later in https
+		true)
+	utils.LogInfo("Player login successful: ID %d", player.Id)
+	c.JSON(http.StatusOK, gin.H{"player": player, "token": token})
+}
+
+func (h *playerHandler) Logout(c *gin.Context) {
+	c.SetCookie(utils.PlayerTokenCookie, "", -1, "/", "localhost", false, true)
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out."})
+}
+
+func (h *playerHandler) Me(c *gin.Context) {
+	player, err := h.playerService.GetPlayer(utils.CheckPlayer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"player": player})
+}
+
+func (h *playerHandler) UpdateName(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	if err := h.playerService.UpdateName(utils.CheckPlayer(c), req.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Name updated."})
+}
+
+func (h *playerHandler) Codes(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", models.CodeStatusPlayed, models.CodeStatusUnplayed, models.CodeStatusExpired:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Wrong status requested '%s'", status)})
+		return
+	}
+
+	codes, err := h.playerService.GetCodes(utils.CheckPlayer(c), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"codes": codes})
+}
+
+func (h *playerHandler) Wallet(c *gin.Context) {
+	wallet, err := h.playerService.GetWallet(utils.CheckPlayer(c), queryLimit(c, 50, 500))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wallet": wallet})
+}
+
+func (h *playerHandler) ResendCode(c *gin.Context) {
+	err := h.playerService.ResendCode(utils.CheckPlayer(c), c.Param("code"))
+	if err != nil {
+		if errors.Is(err, services.ErrCodeNotResendable) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, services.ErrResendTooSoon) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Couldn't resend the code, please try again later."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Code sent to your phone."})
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PricingHandler interface {
+	Rules(c *gin.Context)
+	AddRule(c *gin.Context)
+	DeleteRule(c *gin.Context)
+}
+
+type pricingHandler struct {
+	pricingService services.PricingService
+}
+
+func NewPricingHandler(pricingService services.PricingService) *pricingHandler {
+	return &pricingHandler{pricingService: pricingService}
+}
+
+func (h *pricingHandler) Rules(c *gin.Context) {
+	rules, err := h.pricingService.GetRules(utils.AdminVenues(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+func (h *pricingHandler) AddRule(c *gin.Context) {
+	var rule models.PricingRule
+	if err := c.ShouldBindJSON(&rule); err != nil || isAnyEmpty(rule.StartTime, rule.EndTime, rule.Description) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start, end, description and a multiplier or override price are required"})
+		return
+	}
+
+	ruleId, err := h.pricingService.AddRule(rule, utils.AdminVenues(c))
+	if errors.Is(err, services.ErrVenueNotManaged) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"id": ruleId})
+}
+
+func (h *pricingHandler) DeleteRule(c *gin.Context) {
+	ruleId, ok := pathId(c, "ruleId")
+	if !ok {
+		return
+	}
+
+	if err := h.pricingService.DeleteRule(ruleId, utils.AdminVenues(c)); errors.Is(err, services.ErrVenueNotManaged) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Rule deleted."})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const reportDateLayout = "2006-01-02"
+
+type ReferralHandler interface {
+	Stats(c *gin.Context)
+	ApplyReferral(c *gin.Context)
+	Report(c *gin.Context) // admin, ?from=2006-01-02&to=2006-01-02
+}
+
+type referralHandler struct {
+	referralService services.ReferralService
+}
+
+func NewReferralHandler(referralService services.ReferralService) *referralHandler {
+	return &referralHandler{referralService: referralService}
+}
+
+func (h *referralHandler) Stats(c *gin.Context) {
+	stats, err := h.referralService.GetStats(utils.CheckPlayer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"referral": stats})
+}
+
+func (h *referralHandler) ApplyReferral(c *gin.Context) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || isAnyEmpty(req.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "referral code is required"})
+		return
+	}
+
+	err := h.referralService.ApplyReferral(utils.CheckPlayer(c), req.Code, c.GetHeader(deviceIdHeader))
+	if err != nil {
+		if errors.Is(err, services.ErrReferralRefused) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Referral applied, both of you get the reward after your first purchase."})
+}
+
+func (h *referralHandler) Report(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.referralService.GetReport(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"report": report})
+}
+
+// dateRange reads ?from and ?to as days, to is inclusive. defaults to the last 30 days.
+func dateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	from, to := today.AddDate(0, 0, -30), today
+
+	var err error
+	if value := c.Query("from"); value != "" {
+		if from, err = time.ParseInLocation(reportDateLayout, value, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("from should look like %s", reportDateLayout)})
+			return from, to, false
+		}
+	}
+	if value := c.Query("to"); value != "" {
+		if to, err = time.ParseInLocation(reportDateLayout, value, time.Local); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("to should look like %s", reportDateLayout)})
+			return from, to, false
+		}
+	}
+
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to can't be before from"})
+		return from, to, false
+	}
+	return from, to.AddDate(0, 0, 1), true
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportHandler interface {
+	// Revenue takes ?from and ?to as days, ?period=day|week|month, ?venue and ?format=json|csv|xlsx.
+	Revenue(c *gin.Context)
+}
+
+type reportHandler struct {
+	reportService services.ReportService
+}
+
+func NewReportHandler(reportService services.ReportService) *reportHandler {
+	return &reportHandler{reportService: reportService}
+}
+
+func (h *reportHandler) Revenue(c *gin.Context) {
+	from, to, ok := dateRange(c)
+	if !ok {
+		return
+	}
+	scope, ok := analyticsScope(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" && format != "xlsx" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format should be json, csv or xlsx"})
+		return
+	}
+
+	report, err := h.reportService.GetRevenue(from, to, c.Query("period"), scope)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidReport) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	filename := fmt.Sprintf("revenue-%s-%s", report.From, report.To)
+	switch format {
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+		c.Header("Content-Type", "text/csv")
+		err = h.reportService.ExportCSV(report, c.Writer)
+	case "xlsx":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		err = h.reportService.ExportXLSX(report, c.Writer)
+	default:
+		c.JSON(http.StatusOK, gin.H{"report": report})
+	}
+
+	if err != nil {
+		utils.LogError("Failed to export revenue report %s: %v", filename, err)
+		c.Status(http.StatusInternalServerError)
+	}
+}
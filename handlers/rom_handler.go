@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+type RomHandler interface {
+	Systems(c *gin.Context)
+	SaveSystem(c *gin.Context) // adds the system or replaces it when the id exists
+	Roms(c *gin.Context)       // ?game= narrows it to the versions of one game
+	Rom(c *gin.Context)        // the ROM with the machines that installed it
+	Upload(c *gin.Context)
+	SetCurrent(c *gin.Context)
+
+	Download(c *gin.Context)  // for cabinets
+	Installed(c *gin.Context) // for cabinets
+}
+
+type romHandler struct {
+	romService services.RomService
+}
+
+func NewRomHandler(romService services.RomService) *romHandler {
+	return &romHandler{romService: romService}
+}
+
+func (h *romHandler) Systems(c *gin.Context) {
+	systems, err := h.romService.GetSystems()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"systems": systems})
+}
+
+func (h *romHandler) SaveSystem(c *gin.Context) {
+	var system models.EmulatorSystem
+	if err := c.ShouldBindJSON(&system); err != nil || isAnyEmpty(system.Id, system.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id and name are required"})
+		return
+	}
+
+	if err := h.romService.SaveSystem(system); err != nil {
+		romError(c, err)
+		return
+	}
+
+	utils.LogInfo("System %s saved by admin ID %d", system.Id, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "System saved."})
+}
+
+func (h *romHandler) Roms(c *gin.Context) {
+	var gameId *uint16
+	if game := c.Query("game"); game != "" {
+		id, err := strconv.ParseUint(game, 10, 16)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+			return
+		}
+		value := uint16(id)
+		gameId = &value
+	}
+
+	roms, err := h.romService.GetRoms(gameId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roms": roms})
+}
+
+func (h *romHandler) Rom(c *gin.Context) {
+	romId, ok := pathId(c, "romId")
+	if !ok {
+		return
+	}
+
+	rom, err := h.romService.GetRom(romId)
+	if err != nil {
+		romError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rom": rom})
+}
+
+// Upload takes a multipart form with the file in "rom" and gameId, system, version and an optional sha256.
+func (h *romHandler) Upload(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.PostForm("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	upload := models.RomUpload{
+		GameId:   uint16(gameId),
+		SystemId: c.PostForm("system"),
+		Version:  c.PostForm("version"),
+		Sha256:   c.PostForm("sha256"),
+	}
+	if isAnyEmpty(upload.SystemId, upload.Version) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "system and version are required"})
+		return
+	}
+
+	header, err := c.FormFile("rom")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rom file is required"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "couldn't read the rom file"})
+		return
+	}
+	defer file.Close()
+	upload.FileName = header.Filename
+
+	adminId := utils.CheckCookies(c)
+	rom, err := h.romService.Upload(upload, file, adminId)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("version '%s' of game ID %d already exists", upload.Version, upload.GameId)})
+			return
+		}
+		romError(c, err)
+		return
+	}
+
+	utils.LogInfo("ROM ID %d of game ID %d uploaded by admin ID %d", rom.Id, rom.GameId, adminId)
+	c.JSON(http.StatusOK, gin.H{"rom": rom})
+}
+
+func (h *romHandler) SetCurrent(c *gin.Context) {
+	romId, ok := pathId(c, "romId")
+	if !ok {
+		return
+	}
+
+	if err := h.romService.SetCurrent(romId); err != nil {
+		romError(c, err)
+		return
+	}
+
+	utils.LogInfo("ROM ID %d made current by admin ID %d", romId, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "ROM is now the current version."})
+}
+
+func (h *romHandler) Download(c *gin.Context) {
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	download, err := h.romService.Download(uint16(gameId))
+	if err != nil {
+		romError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rom": download})
+}
+
+// Installed takes {"sha256": "..."}, the checksum of the file the cabinet installed.
+func (h *romHandler) Installed(c *gin.Context) {
+	romId, ok := pathId(c, "romId")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Sha256 string `json:"sha256"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || isAnyEmpty(req.Sha256) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sha256 is required"})
+		return
+	}
+
+	machineId := c.GetString("machine_id")
+	if err := h.romService.RecordInstall(machineId, romId, req.Sha256); err != nil {
+		romError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Install recorded."})
+}
+
+func romError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrRomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSystemNotFound), errors.Is(err, services.ErrInvalidRom):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrChecksumMismatch):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+	}
+}
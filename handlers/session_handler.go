@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler interface {
+	Event(c *gin.Context) // cabinets only, behind the machine signature
+}
+
+type sessionHandler struct {
+	sessionService services.SessionService
+}
+
+func NewSessionHandler(sessionService services.SessionService) *sessionHandler {
+	return &sessionHandler{sessionService: sessionService}
+}
+
+func (h *sessionHandler) Event(c *gin.Context) {
+	var event models.SessionEvent
+	if err := c.ShouldBindJSON(&event); err != nil || event.GameId <= 0 || isAnyEmpty(event.Type, event.Code) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type, game id and code are required"})
+		return
+	}
+
+	if err := h.sessionService.HandleEvent(event, c.GetString("machine_id")); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Event recorded."})
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VenueHandler interface {
+	Venues(c *gin.Context) // venue admins only get their own venues
+	AddVenue(c *gin.Context)
+	Venue(c *gin.Context) // the venue with its game, price and stock changes
+	UpdateVenue(c *gin.Context)
+	SetAdminVenues(c *gin.Context)
+	SetGame(c *gin.Context)
+	SetPrice(c *gin.Context)
+	SetStock(c *gin.Context)
+}
+
+type venueHandler struct {
+	venueService services.VenueService
+}
+
+func NewVenueHandler(venueService services.VenueService) *venueHandler {
+	return &venueHandler{venueService: venueService}
+}
+
+func (h *venueHandler) Venues(c *gin.Context) {
+	venues, err := h.venueService.GetVenues(utils.AdminVenues(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"venues": venues})
+}
+
+func (h *venueHandler) AddVenue(c *gin.Context) {
+	var venue models.Venue
+	if err := c.ShouldBindJSON(&venue); err != nil || isAnyEmpty(venue.Id, venue.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "id and name are required"})
+		return
+	}
+
+	if err := h.venueService.AddVenue(venue); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	utils.LogInfo("Venue %s added by admin ID %d", venue.Id, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"id": venue.Id})
+}
+
+func (h *venueHandler) Venue(c *gin.Context) {
+	venueId, ok := managedVenue(c)
+	if !ok {
+		return
+	}
+
+	details, err := h.venueService.GetDetails(venueId)
+	if err != nil {
+		venueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"venue": details})
+}
+
+func (h *venueHandler) UpdateVenue(c *gin.Context) {
+	var venue models.Venue
+	if err := c.ShouldBindJSON(&venue); err != nil || isAnyEmpty(venue.Name) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+	venue.Id = c.Param("venueId")
+
+	if err := h.venueService.UpdateVenue(venue); err != nil {
+		venueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Venue updated."})
+}
+
+// SetAdminVenues takes {"venues": [...]}, an empty list makes the admin manage every venue.
+func (h *venueHandler) SetAdminVenues(c *gin.Context) {
+	adminId, ok := pathId(c, "adminId")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Venues []string `json:"venues"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "venues are required, empty for every venue"})
+		return
+	}
+
+	if err := h.venueService.SetAdminVenues(adminId, req.Venues); err != nil {
+		venueError(c, err)
+		return
+	}
+
+	utils.LogInfo("Venues of admin ID %d set to %v by admin ID %d", adminId, req.Venues, utils.CheckCookies(c))
+	c.JSON(http.StatusOK, gin.H{"message": "Venues updated, they apply from the next login."})
+}
+
+func (h *venueHandler) SetGame(c *gin.Context) {
+	venueId, ok := managedVenue(c)
+	if !ok {
+		return
+	}
+
+	gameId, err := strconv.ParseUint(c.Param("gameId"), 10, 16)
+	if err != nil || gameId == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid game id provided"})
+		return
+	}
+
+	game := models.VenueGame{GameId: uint16(gameId)}
+	if err := c.ShouldBindJSON(&game); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "isAvailable is required"})
+		return
+	}
+	game.GameId = uint16(gameId)
+
+	if err := h.venueService.SetGame(venueId, game); err != nil {
+		venueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Game availability updated."})
+}
+
+func (h *venueHandler) SetPrice(c *gin.Context) {
+	venueId, ok := managedVenue(c)
+	if !ok {
+		return
+	}
+
+	var price models.VenuePrice
+	if err := c.ShouldBindJSON(&price); err != nil || price.GameId == 0 || price.Label == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "game id, item type and label are required"})
+		return
+	}
+
+	if err := h.venueService.SetPrice(venueId, price); err != nil {
+		venueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Price updated."})
+}
+
+func (h *venueHandler) SetStock(c *gin.Context) {
+	venueId, ok := managedVenue(c)
+	if !ok {
+		return
+	}
+
+	productId, ok := pathId(c, "productId")
+	if !ok {
+		return
+	}
+
+	var stock models.VenueStock
+	if err := c.ShouldBindJSON(&stock); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "units are required"})
+		return
+	}
+	stock.ProductId = int32(productId)
+
+	if err := h.venueService.SetStock(venueId, stock); err != nil {
+		venueError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Stock updated."})
+}
+
+// managedVenue reads the venue from the path, answering for the handler when the admin doesn't manage it.
+func managedVenue(c *gin.Context) (string, bool) {
+	venueId := c.Param("venueId")
+	if !utils.ManagesVenue(c, venueId) {
+		c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("you don't manage venue '%s'", venueId)})
+		return "", false
+	}
+	return venueId, true
+}
+
+func venueError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrVenueNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/services"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type VoucherHandler interface {
+	IssueBatch(c *gin.Context)
+	Batches(c *gin.Context)
+	Batch(c *gin.Context)
+	ExportBatch(c *gin.Context) // ?format=csv or ?format=qr
+}
+
+type voucherHandler struct {
+	voucherService services.VoucherService
+}
+
+func NewVoucherHandler(voucherService services.VoucherService) *voucherHandler {
+	return &voucherHandler{voucherService: voucherService}
+}
+
+func (h *voucherHandler) IssueBatch(c *gin.Context) {
+	utils.LogInfo("Received voucher batch request")
+	adminId := utils.CheckCookies(c)
+	if adminId == 0 {
+		return
+	}
+
+	var req models.VoucherBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.LogError("Invalid voucher batch input: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid input"})
+		return
+	}
+
+	if isAnyEmpty(req.Label, req.Name) || req.GameId <= 0 || (req.PlayTime == nil && req.Levels == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "label, name, game id and either time or levels are required"})
+		return
+	}
+
+	batch, err := h.voucherService.IssueBatch(req, adminId)
+	if err != nil {
+		utils.LogError("Failed to issue voucher batch '%s': %v", req.Label, err)
+		if errors.Is(err, services.ErrInvalidVoucherBatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	utils.LogInfo("Voucher batch ID %d issued by admin ID %d", batch.Id, adminId)
+	c.JSON(http.StatusOK, gin.H{"batch": batch})
+}
+
+func (h *voucherHandler) Batches(c *gin.Context) {
+	batches, err := h.voucherService.GetBatches()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"batches": batches})
+}
+
+func (h *voucherHandler) Batch(c *gin.Context) {
+	batch, ok := h.fetchBatch(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"batch": batch})
+}
+
+func (h *voucherHandler) ExportBatch(c *gin.Context) {
+	batch, ok := h.fetchBatch(c)
+	if !ok {
+		return
+	}
+
+	var err error
+	switch c.DefaultQuery("format", "csv") {
+	case "csv":
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=vouchers-%d.csv", batch.Id))
+		c.Header("Content-Type", "text/csv")
+		err = h.voucherService.ExportCSV(batch, c.Writer)
+	case "qr":
+		c.Header("Content-Type", "text/html; charset=utf-8")
+		err = h.voucherService.ExportQRSheet(batch, c.Writer)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format should be either csv or qr"})
+		return
+	}
+
+	if err != nil {
+		utils.LogError("Failed to export voucher batch ID %d: %v", batch.Id, err)
+		c.Status(http.StatusInternalServerError)
+	}
+}
+
+func (h *voucherHandler) fetchBatch(c *gin.Context) (models.VoucherBatch, bool) {
+	batchId, err := strconv.Atoi(c.Param("batchId"))
+	if err != nil || batchId <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch id"})
+		return models.VoucherBatch{}, false
+	}
+
+	batch, err := h.voucherService.GetBatch(batchId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": fmt.Sprintf("no voucher batch with id %d", batchId)})
+			return batch, false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Some error occurred: %v", err)})
+		return batch, false
+	}
+
+	return batch, true
+}
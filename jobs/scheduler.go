@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"GameWala-Arcade/utils"
+	"time"
+)
+
+// Every runs the job in the background at the given interval, errors are only logged
+// so a failing run doesn't stop the next ones.
+func Every(interval time.Duration, name string, job func() error) {
+	utils.LogInfo("Scheduling job '%s' every %v", name, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			run(name, job)
+		}
+	}()
+}
+
+func run(name string, job func() error) {
+	start := time.Now()
+	if err := job(); err != nil {
+		utils.LogError("Job '%s' failed after %v: %v", name, time.Since(start), err)
+		return
+	}
+	utils.LogInfo("Job '%s' finished in %v", name, time.Since(start))
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"GameWala-Arcade/db"
 	"GameWala-Arcade/handlers"
+	"GameWala-Arcade/jobs"
 	"GameWala-Arcade/repositories"
 	"GameWala-Arcade/routes"
 	"GameWala-Arcade/services"
 	"context"
 	"log"
+	"time"
 
 	"GameWala-Arcade/config"
 	"GameWala-Arcade/utils"
@@ -49,28 +51,178 @@ func main() {
 		AllowCredentials: true, // Allow cookies to be sent with cross-origin requests
 	}))
 
+	venueRepository := repositories.NewVenueRepository(db.DB)
+	venueService := services.NewVenueService(venueRepository)
+	venueHandler := handlers.NewVenueHandler(venueService)
+
 	adminConsoleRepository := repositories.NewAdminConsoleRepository(db.DB)
 	adminConsoleService := services.NewAdminConsoleService(adminConsoleRepository)
-	adminConsoleHandler := handlers.NewAdminConsoleHandler(adminConsoleService)
+	adminConsoleHandler := handlers.NewAdminConsoleHandler(adminConsoleService, venueService)
+
+	couponRepository := repositories.NewCouponRepository(db.DB)
+	couponService := services.NewCouponService(couponRepository)
+	couponHandler := handlers.NewCouponHandler(couponService)
+
+	pricingRepository := repositories.NewPricingRepository(db.DB)
+	pricingService := services.NewPricingService(pricingRepository)
+	pricingHandler := handlers.NewPricingHandler(pricingService)
+
+	codeFormat, err := utils.LoadCodeFormat()
+	if err != nil {
+		utils.LogError("could not load the game code format, error: %v", err)
+		log.Fatalf("Could not load the game code format: %v", err)
+	}
 
 	playGameRespository := repositories.NewPlayGameReposiory(db.DB)
-	playGameService := services.NewPlayGameService(playGameRespository, redisStore)
-	playGameHandler := handlers.NewPlayGameHandler(playGameService)
+	playGameService := services.NewPlayGameService(playGameRespository, couponRepository, pricingRepository, redisStore, codeFormat)
+
+	bundleRepository := repositories.NewBundleRepository(db.DB)
+	bundleService := services.NewBundleService(bundleRepository, playGameService)
+	bundleHandler := handlers.NewBundleHandler(bundleService)
+
+	catalogueService := services.NewCatalogueService(playGameService, bundleService, redisStore)
+	playGameHandler := handlers.NewPlayGameHandler(playGameService, catalogueService)
+
+	if err := playGameService.RestoreCodeCounter(); err != nil {
+		utils.LogError("could not restore the game code counter, error: %v", err)
+		log.Fatalf("Could not restore the game code counter: %v", err)
+	}
 
 	handlePaymentRepository := repositories.NewHandlePaymentReposiory(db.DB)
 	handlePaymentService := services.NewHandlePaymentService(handlePaymentRepository)
-	handlePaymentHandler := handlers.NewHandlePaymentHandler(handlePaymentService)
+	handlePaymentHandler := handlers.NewHandlePaymentHandler(handlePaymentService, playGameService)
 
 	marketPlaceRepository := repositories.NewMarketPlaceReposiory(db.DB)
 	marketPlaceService := services.NewMarketPlaceService(marketPlaceRepository)
 	marketPlaceHandler := handlers.NewMarketPlaceHandler(marketPlaceService)
 
+	voucherRepository := repositories.NewVoucherRepository(db.DB)
+	voucherService := services.NewVoucherService(voucherRepository, playGameService)
+	voucherHandler := handlers.NewVoucherHandler(voucherService)
+
+	codeExpiryRepository := repositories.NewCodeExpiryRepository(db.DB)
+	codeExpiryService := services.NewCodeExpiryService(codeExpiryRepository)
+	codeExpiryHandler := handlers.NewCodeExpiryHandler(codeExpiryService)
+
+	jobs.Every(jobInterval("codeExpiryJobMinutes", 60), "expire codes", codeExpiryService.ExpireCodes)
+
+	smsSender, err := utils.NewSMSSender()
+	if err != nil {
+		utils.LogError("could not set up text messages, error: %v", err)
+		log.Fatalf("Could not set up text messages: %v", err)
+	}
+
+	otpSecret := config.GetString("otpSecret")
+	if otpSecret == "" {
+		utils.LogError("otpSecret is missing from the config")
+		log.Fatalf("otpSecret is missing from the config, the otps can't be stored without it")
+	}
+
+	playerRepository := repositories.NewPlayerRepository(db.DB)
+	playerService := services.NewPlayerService(playerRepository, redisStore, smsSender, []byte(otpSecret))
+	playerHandler := handlers.NewPlayerHandler(playerService)
+
+	machineRepository := repositories.NewMachineRepository(db.DB)
+	machineService := services.NewMachineService(machineRepository)
+	machineHandler := handlers.NewMachineHandler(machineService)
+
+	scoreRepository := repositories.NewScoreRepository(db.DB)
+	leaderboardService := services.NewLeaderboardService(scoreRepository, redisStore)
+	leaderboardHandler := handlers.NewLeaderboardHandler(leaderboardService)
+
+	// the boards can still be read while broken, a failed rebuild only leaves them as they were.
+	if err := leaderboardService.RebuildLeaderboards(); err != nil {
+		utils.LogError("could not rebuild the leaderboards, error: %v", err)
+	}
+
+	loyaltyRepository := repositories.NewLoyaltyRepository(db.DB)
+	loyaltyService := services.NewLoyaltyService(loyaltyRepository, playGameService)
+	loyaltyHandler := handlers.NewLoyaltyHandler(loyaltyService)
+
+	handlePaymentService.AddListener(bundleService)
+	handlePaymentService.AddListener(loyaltyService)
+	jobs.Every(jobInterval("loyaltyExpiryJobMinutes", 24*60), "expire loyalty points", loyaltyService.ExpirePoints)
+
+	referralRepository := repositories.NewReferralRepository(db.DB)
+	referralService := services.NewReferralService(referralRepository, playGameService)
+	referralHandler := handlers.NewReferralHandler(referralService)
+
+	sessionRepository := repositories.NewSessionRepository(db.DB)
+	sessionService := services.NewSessionService(sessionRepository)
+	sessionHandler := handlers.NewSessionHandler(sessionService)
+
+	achievementRepository := repositories.NewAchievementRepository(db.DB)
+	achievementService := services.NewAchievementService(achievementRepository)
+	achievementHandler := handlers.NewAchievementHandler(achievementService)
+
+	sessionService.AddListener(achievementService)
+
+	passRepository := repositories.NewPassRepository(db.DB)
+	passService := services.NewPassService(passRepository)
+	passHandler := handlers.NewPassHandler(passService)
+
+	handlePaymentService.AddListener(passService)
+	sessionService.AddListener(passService)
+
+	romRepository := repositories.NewRomRepository(db.DB)
+	romService := services.NewRomService(romRepository)
+	romHandler := handlers.NewRomHandler(romService)
+
+	analyticsRepository := repositories.NewAnalyticsRepository(db.DB)
+	analyticsService := services.NewAnalyticsService(analyticsRepository)
+	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
+
+	reportService := services.NewReportService(handlePaymentRepository, smsSender)
+	reportHandler := handlers.NewReportHandler(reportService)
+
+	jobs.Every(jobInterval("revenueSummaryJobMinutes", 60), "end of day revenue summary", reportService.SendDailySummary)
+
+	// only finished days are rolled up, so checking hourly rolls a day up shortly after midnight.
+	jobs.Every(jobInterval("analyticsRollupJobMinutes", 60), "roll up analytics", analyticsService.RollupPending)
+
+	inventoryRepository := repositories.NewInventoryRepository(db.DB)
+	inventoryService := services.NewInventoryService(inventoryRepository, smsSender)
+	inventoryHandler := handlers.NewInventoryHandler(inventoryService)
+
+	// an unpaid checkout stops holding its units when the hold runs out, the job only marks it.
+	jobs.Every(jobInterval("stockReservationJobMinutes", 5), "expire stock reservations", inventoryService.ExpireReservations)
+
+	cartRepository := repositories.NewCartRepository(db.DB)
+	cartService := services.NewCartService(cartRepository, marketPlaceRepository, handlePaymentRepository,
+		inventoryService, redisStore)
+	cartHandler := handlers.NewCartHandler(cartService)
+
+	handlePaymentService.AddListener(cartService)
+	handlePaymentService.AddFailureListener(cartService)
+
+	// last, the referral converts on what the other listeners saved for the payment.
+	handlePaymentService.AddListener(referralService)
+
 	routes.SetupRoutes(
 		router,
 		adminConsoleHandler,
 		playGameHandler,
 		handlePaymentHandler,
-		marketPlaceHandler)
+		marketPlaceHandler,
+		voucherHandler,
+		codeExpiryHandler,
+		playerHandler,
+		machineHandler,
+		leaderboardHandler,
+		loyaltyHandler,
+		referralHandler,
+		sessionHandler,
+		achievementHandler,
+		passHandler,
+		couponHandler,
+		pricingHandler,
+		bundleHandler,
+		venueHandler,
+		romHandler,
+		analyticsHandler,
+		reportHandler,
+		cartHandler,
+		inventoryHandler)
 
 	utils.LogInfo("Server starting on 0.0.0.0:8080")
 	if err := router.Run("0.0.0.0:8080"); err != nil {
@@ -78,3 +230,12 @@ func main() {
 		panic(err)
 	}
 }
+
+// jobInterval reads the interval in minutes from the config, fallback is used when it's not set.
+func jobInterval(key string, fallback int) time.Duration {
+	minutes := config.GetInt(key)
+	if minutes <= 0 {
+		minutes = fallback
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -1,8 +1,51 @@
 package models
 
+import "time"
+
 type PaymentStatus struct {
 	OrderCreationId   string
 	RazorpayPaymentId string
 	RazorpayOrderId   string
 	RazorpaySignature string
 }
+
+// purposes a payment order can be created for, sent to razorpay in the order notes.
+const (
+	PaymentPurposeGame   = "game"
+	PaymentPurposeShop   = "shop"
+	PaymentPurposePass   = "pass"
+	PaymentPurposeBundle = "bundle"
+)
+
+// CapturedPayment amounts are in paise, as razorpay sends them.
+type CapturedPayment struct {
+	PaymentId  string
+	OrderId    string
+	Amount     int64
+	Method     string
+	Purpose    string
+	PlayerId   *int
+	GameId     *uint16
+	PassId     *int
+	BundleId   *int
+	CapturedAt time.Time
+
+	SubscriptionId *string // set for the renewals of a pass subscription
+}
+
+// FailedPayment is one failed attempt, the order stays open for the player to try again.
+type FailedPayment struct {
+	PaymentId string
+	OrderId   string
+	Purpose   string
+	Reason    string
+}
+
+type Refund struct {
+	RefundId      string
+	PaymentId     string
+	Amount        int64
+	PaymentAmount int64 // of the refunded payment, for partial refunds
+	Purpose       string
+	PlayerId      *int
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	AchievementGamesPlayed   = "games_played"   // first play is games_played with threshold 1
+	AchievementAllLevels     = "all_levels"     // level bound games with every level cleared
+	AchievementSystemsPlayed = "systems_played" // distinct emulator systems
+)
+
+type Achievement struct {
+	Id          int     `json:"id"`
+	Title       string  `json:"title"`
+	Description string  `json:"description"`
+	Kind        string  `json:"kind"`
+	Threshold   int     `json:"threshold"`
+	GameId      *uint16 `json:"gameId"`
+	WindowDays  *int    `json:"windowDays"`
+	Badge       *string `json:"badge"`
+	IsActive    bool    `json:"active"`
+}
+
+type AchievementProgress struct {
+	Achievement
+	Progress int        `json:"progress"`
+	EarnedAt *time.Time `json:"earnedAt"`
+}
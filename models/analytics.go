@@ -0,0 +1,42 @@
+package models
+
+// the analytics are read from the nightly rollups, so they cover the days up to yesterday.
+
+// GameAnalytics is how a game did over the range, the minutes are the average of its finished timed sessions.
+type GameAnalytics struct {
+	GameId              uint16  `json:"gameId"`
+	Name                string  `json:"name"`
+	Plays               int64   `json:"plays"`
+	PlaysPerDay         float64 `json:"playsPerDay"`
+	CodesSold           int64   `json:"codesSold"`
+	Revenue             int64   `json:"revenue"`
+	AvgSessionMinutes   float64 `json:"avgSessionMinutes"`
+	AvgPurchasedMinutes float64 `json:"avgPurchasedMinutes"`
+}
+
+type GameDay struct {
+	Day                 string  `json:"day"` // 2006-01-02
+	Plays               int64   `json:"plays"`
+	CodesSold           int64   `json:"codesSold"`
+	Revenue             int64   `json:"revenue"`
+	AvgSessionMinutes   float64 `json:"avgSessionMinutes"`
+	AvgPurchasedMinutes float64 `json:"avgPurchasedMinutes"`
+}
+
+// MachineHour is one hour of a cabinet, Utilisation is the percentage of the hour a session was running.
+type MachineHour struct {
+	MachineId   string  `json:"machineId"`
+	Venue       string  `json:"venue"`
+	Day         string  `json:"day"`
+	Hour        int     `json:"hour"`
+	Sessions    int     `json:"sessions"`
+	Utilisation float64 `json:"utilisation"`
+}
+
+// PeakHour is a cell of the heatmap, Weekday 0 is sunday.
+type PeakHour struct {
+	Weekday     int     `json:"weekday"`
+	Hour        int     `json:"hour"`
+	Sessions    int64   `json:"sessions"`
+	Utilisation float64 `json:"utilisation"`
+}
@@ -0,0 +1,40 @@
+package models
+
+const (
+	BundleKindCodes   = "codes"   // one game code per item and quantity
+	BundleKindBalance = "balance" // one code worth Minutes on any timed game
+)
+
+type Bundle struct {
+	Id          int          `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Price       int          `json:"price"`
+	Kind        string       `json:"kind"`
+	Minutes     *int         `json:"minutes,omitempty"`
+	Items       []BundleItem `json:"items,omitempty"`
+	IsActive    bool         `json:"active"`
+}
+
+type BundleItem struct {
+	GameId   uint16  `json:"gameId"`
+	IsTimed  bool    `json:"isTimed"`
+	PlayTime *uint16 `json:"playTime"`
+	Levels   *uint8  `json:"levels"`
+	Quantity int     `json:"quantity"`
+}
+
+// BundleCode is a code of a purchase, MinutesLeft is only there for balance codes.
+type BundleCode struct {
+	Code        string `json:"code"`
+	IsBalance   bool   `json:"isBalance"`
+	Minutes     *int   `json:"minutes,omitempty"`
+	MinutesLeft *int   `json:"minutesLeft,omitempty"`
+}
+
+// BalanceDraw asks for a game code paid from a balance code.
+type BalanceDraw struct {
+	BalanceCode string `json:"balanceCode"`
+	GameId      uint16 `json:"gameId"`
+	PlayTime    uint16 `json:"playTime"`
+}
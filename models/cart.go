@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// CartOwner is the player when logged in, otherwise the guest cart session.
+type CartOwner struct {
+	PlayerId int
+	Session  string
+}
+
+type CartItem struct {
+	ProductId int `json:"productId"`
+	Quantity  int `json:"quantity"`
+}
+
+// CartLine is a cart item priced and checked against the stock of the venue, Available is false when
+// the product went out of stock or the venue stopped stocking it.
+type CartLine struct {
+	ProductId  int    `json:"productId"`
+	Title      string `json:"title"`
+	Price      int32  `json:"price"`
+	Quantity   int    `json:"quantity"`
+	TotalUnits int32  `json:"totalunits"`
+	Available  bool   `json:"available"`
+}
+
+// Cart total is in rupees and only counts the available lines.
+type Cart struct {
+	Items []CartLine `json:"items"`
+	Total int64      `json:"total"`
+}
+
+const (
+	ShopOrderPending  = "pending"
+	ShopOrderPaid     = "paid"
+	ShopOrderOversold = "oversold" // paid after the units ran out, waiting for the refund
+	ShopOrderRefunded = "refunded"
+)
+
+type ShopOrderItem struct {
+	ProductId int    `json:"productId"`
+	Title     string `json:"title"`
+	Price     int32  `json:"price"`
+	Quantity  int    `json:"quantity"`
+}
+
+// ShopOrder amount is in paise, what razorpay is asked for. OrderId is the razorpay order.
+type ShopOrder struct {
+	Id        int             `json:"id"`
+	OrderId   string          `json:"orderId"`
+	Venue     *string         `json:"venue"`
+	Amount    int64           `json:"amount"`
+	Status    string          `json:"status"`
+	Items     []ShopOrderItem `json:"items"`
+	CreatedAt time.Time       `json:"createdAt"`
+	PaidAt    *time.Time      `json:"paidAt"`
+
+	PlayerId     *int    `json:"-"`
+	GuestSession *string `json:"-"`
+}
+
+// ShopCheckout is what the razorpay checkout is opened with, the units are held until HeldUntil.
+type ShopCheckout struct {
+	OrderId   string          `json:"orderId"`
+	Amount    int64           `json:"amount"`
+	Currency  string          `json:"currency"`
+	Items     []ShopOrderItem `json:"items"`
+	HeldUntil time.Time       `json:"heldUntil"`
+}
@@ -0,0 +1,13 @@
+package models
+
+// GameId nil is the default policy used by every game without its own.
+type CodeValidityPolicy struct {
+	GameId          *uint16 `json:"gameId"`
+	ValidDays       int     `json:"validDays"`
+	ConvertToCredit bool    `json:"convertToCredit"`
+}
+
+type ExpiredCode struct {
+	Code     string
+	Credited int
+}
@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+const (
+	CouponPercent = "percent"
+	CouponFlat    = "flat" // rupees off
+)
+
+// Coupon restrictions left nil match every game, tier and product type.
+type Coupon struct {
+	Id               int        `json:"id"`
+	Code             string     `json:"code"`
+	Kind             string     `json:"kind"`
+	Value            int        `json:"value"`
+	StartsAt         *time.Time `json:"startsAt"`
+	EndsAt           *time.Time `json:"endsAt"`
+	MaxUses          *int       `json:"maxUses"`
+	MaxUsesPerPlayer *int       `json:"maxUsesPerPlayer"`
+	GameId           *uint16    `json:"gameId"`
+	TierLabel        *uint16    `json:"tierLabel"`   // minutes or levels of the tier
+	ProductType      *string    `json:"productType"` // game, shop or pass
+	IsActive         bool       `json:"active"`
+	Uses             int        `json:"uses"`
+	PlayerUses       int        `json:"-"`
+}
+
+// CouponPurchase is what the coupon is checked against.
+type CouponPurchase struct {
+	ProductType string
+	GameId      uint16
+	TierLabel   uint16
+	Amount      uint16
+	PlayerId    *int
+}
+
+type PriceQuote struct {
+	BasePrice uint16 `json:"basePrice"` // from the catalogue, or the venue price when it has one
+	ListPrice uint16 `json:"listPrice"` // with the pricing rules applied
+	Discount  uint16 `json:"discount"`
+	Price     uint16 `json:"price"`
+	Coupon    string `json:"coupon,omitempty"`
+	Venue     string `json:"venue,omitempty"`
+}
@@ -13,22 +13,78 @@ type GameData struct {
 
 type GameStatus struct {
 	Code             string
-	Name             string    `json:"name"`
-	GameId           uint16    `json:"gameId"`
-	IsTimed          bool      `json:"isTimed"`
-	Price            uint16    `json:"price"`
-	PlayTime         *uint16   `json:"playTime"`
-	Levels           *uint8    `json:"levels"`
-	TimeStamp        time.Time `json:"currentTime"`
-	IsPlayed         bool      `json:"played"`
-	PaymentReference string    `json:"paymentId"`
+	Name             string     `json:"name"`
+	GameId           uint16     `json:"gameId"`
+	IsTimed          bool       `json:"isTimed"`
+	Price            uint16     `json:"price"`
+	PlayTime         *uint16    `json:"playTime"`
+	Levels           *uint8     `json:"levels"`
+	TimeStamp        time.Time  `json:"currentTime"`
+	IsPlayed         bool       `json:"played"`
+	PaymentReference string     `json:"paymentId"`
+	ExpiresAt        *time.Time `json:"-"`      // set by the server, nil falls back to the validity policy
+	PlayerId         *int       `json:"-"`      // set when the purchase is made while logged in
+	PassId           *int       `json:"passId"` // player pass to draw from instead of a payment
+	PassMinutes      uint16     `json:"-"`      // held on the pass, set by the server
+	Wallet           bool       `json:"wallet"` // paid with the wallet credit of the player instead of a payment
+	Coupon           string     `json:"coupon"`
+	OrderId          string     `json:"orderId"` // razorpay order, the price is the one in effect when it was created
+	Venue            string     `json:"venue"`
+	Discount         uint16     `json:"-"` // taken off by the coupon, set by the server
 }
 
 type GameResponse struct {
-	Name      string
-	GameId    uint16
-	Price     Price
-	Thumbnail *string
+	Name            string
+	GameId          uint16
+	Price           Price // in effect right now
+	Thumbnail       *string
+	System          *string
+	Metadata        *GameMetadata // nil until the admins describe the game
+	NextPriceChange *time.Time
+}
+
+// sort orders of the catalogue, popular counts the sessions of the last 30 days.
+const (
+	GameSortPopular   = "popular"
+	GameSortNewest    = "newest"
+	GameSortPrice     = "price"
+	GameSortPriceDesc = "price_desc"
+)
+
+// GameFilter narrows the catalogue, empty fields don't filter. Prices are the cheapest tier at the
+// venue before the pricing rules.
+type GameFilter struct {
+	Venue    string
+	Query    string // searched in the name
+	System   string
+	Mode     string // timed or level
+	MinPrice *uint16
+	MaxPrice *uint16
+	Genre    string
+	Tag      string
+	Players  *uint8 // games this many can play together
+	Sort     string
+	Cursor   string // NextCursor of the previous page
+	Limit    int
+}
+
+// GameListing is a game of a catalogue page with the values it was sorted on.
+type GameListing struct {
+	Game      GameResponse
+	FromPrice uint16
+	Plays     int64
+}
+
+type GamePage struct {
+	Games      []GameResponse `json:"games"`
+	NextCursor string         `json:"nextCursor,omitempty"` // empty on the last page
+}
+
+// Catalogue is what GET /games serves, the bundles come with the first page only.
+type Catalogue struct {
+	Games      []GameResponse `json:"games"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Bundles    []Bundle       `json:"bundles,omitempty"`
 }
 
 type GamePrice struct {
@@ -59,10 +115,15 @@ type LevelPrice struct {
 }
 
 type GameDetails struct {
-	IsPlayed   bool    `json:"is_played"`
-	IsTimed    bool    `json:"is_Timed"`
-	Time       uint16  `json:"time"`
-	SystemName *string `json:"system"`
-	Rom        *string `json:"rom"`
-	Level      uint16  `json:"level"`
+	IsPlayed   bool       `json:"is_played"`
+	IsTimed    bool       `json:"is_Timed"`
+	Time       uint16     `json:"time"`
+	SystemName *string    `json:"system"`
+	Rom        *string    `json:"rom"`
+	Level      uint16     `json:"level"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	RomId      *int       `json:"romId"` // current ROM of the game, nil until one is uploaded
+	RomSha256  *string    `json:"romSha256"`
+	RomVersion *string    `json:"romVersion"`
+	Core       *string    `json:"core"`
 }
@@ -0,0 +1,21 @@
+package models
+
+// GameMetadata is what players browse the catalogue by, every field is optional.
+type GameMetadata struct {
+	Description *string           `json:"description"`
+	Genres      []string          `json:"genres"` // lowercase
+	Tags        []string          `json:"tags"`   // lowercase
+	MinPlayers  *uint8            `json:"minPlayers"`
+	MaxPlayers  *uint8            `json:"maxPlayers"`
+	ReleaseYear *int              `json:"releaseYear"`
+	Controls    map[string]string `json:"controls"` // button to what it does, e.g. "A": "jump"
+	AgeRating   *string           `json:"ageRating"`
+}
+
+// age ratings a game can carry, from the youngest.
+var AgeRatings = []string{"all", "7+", "12+", "16+", "18+"}
+
+type Genre struct {
+	Genre string `json:"genre"`
+	Games int    `json:"games"` // available at the venue
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// kinds of stock movements, count is the venue stock being set to what was counted. oversold follows
+// a sale of more units than there were and gives back the missing ones.
+const (
+	StockRestock    = "restock"
+	StockAdjustment = "adjustment"
+	StockCount      = "count"
+	StockSale       = "sale"
+	StockOversold   = "oversold"
+)
+
+// StockChange adds Change units to the stock of the venue, empty venue is the central stock.
+// Change is negative to take units out.
+type StockChange struct {
+	ProductId int    `json:"-"`
+	Venue     string `json:"venue"`
+	Change    int    `json:"change"`
+	Reason    string `json:"reason"`
+}
+
+type StockMovement struct {
+	Id         int64     `json:"id"`
+	ProductId  int       `json:"productId"`
+	Venue      *string   `json:"venue"`
+	Change     int       `json:"change"`
+	UnitsAfter int       `json:"unitsAfter"`
+	Kind       string    `json:"kind"`
+	Reason     *string   `json:"reason"`
+	OrderId    *int      `json:"orderId"`
+	AdminId    *int      `json:"adminId"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// LowStock units are what is on the shelf, Held of them are held for orders not paid yet.
+type LowStock struct {
+	ProductId int     `json:"productId"`
+	Title     string  `json:"title"`
+	Venue     *string `json:"venue"`
+	Units     int     `json:"units"`
+	Held      int     `json:"held"`
+}
+
+// SoldStock is what a paid shop order took of a product, Oversold when the units were gone by then.
+type SoldStock struct {
+	ProductId int
+	Quantity  int
+	UnitsLeft int
+	Oversold  bool
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// LoyaltyRule multiplies the points earned, GameId and Weekday nil match every game and day.
+type LoyaltyRule struct {
+	Id          int     `json:"id"`
+	GameId      *uint16 `json:"gameId"`
+	Weekday     *int    `json:"weekday"` // 0 is sunday
+	Multiplier  float64 `json:"multiplier"`
+	Description string  `json:"description"`
+}
+
+const (
+	RewardKindGame    = "game"
+	RewardKindProduct = "product"
+)
+
+// LoyaltyReward is either a game tier (GameId, Name, Price, PlayTime/Levels) or a shop product.
+type LoyaltyReward struct {
+	Id        int     `json:"id"`
+	Title     string  `json:"title"`
+	Points    int     `json:"points"`
+	Kind      string  `json:"kind"`
+	GameId    *uint16 `json:"gameId"`
+	Name      *string `json:"name"`
+	Price     *uint16 `json:"price"`
+	PlayTime  *uint16 `json:"playTime"`
+	Levels    *uint8  `json:"levels"`
+	ProductId *int32  `json:"productId"`
+}
+
+type LoyaltyEntry struct {
+	Id        int        `json:"id"`
+	Points    int        `json:"points"`
+	Kind      string     `json:"kind"`
+	PaymentId *string    `json:"paymentId"`
+	RewardId  *int       `json:"rewardId"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type LoyaltyTier struct {
+	Name           string   `json:"name"`
+	MinPoints      int      `json:"minPoints"` // earned in the last year
+	EarnMultiplier float64  `json:"earnMultiplier"`
+	Perks          []string `json:"perks"`
+}
+
+type LoyaltySummary struct {
+	Balance      int            `json:"balance"`
+	YearlyEarned int            `json:"yearlyEarned"`
+	Tier         LoyaltyTier    `json:"tier"`
+	NextTier     *LoyaltyTier   `json:"nextTier"`
+	Entries      []LoyaltyEntry `json:"entries"`
+}
+
+// Redemption has the game code for game rewards, product rewards are collected at the counter with the claim id.
+type Redemption struct {
+	ClaimId int    `json:"claimId"`
+	Code    string `json:"code,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type Machine struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	Venue     string    `json:"venue"`
+	SecretKey string    `json:"-"` // signs the cabinet requests, only shown once when registering
+	CreatedAt time.Time `json:"createdAt"`
+}
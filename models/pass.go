@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PassProduct is a monthly pass, Minutes nil is unlimited play.
+type PassProduct struct {
+	Id             int     `json:"id"`
+	Name           string  `json:"name"`
+	Description    string  `json:"description"`
+	Price          int     `json:"price"`
+	Minutes        *int    `json:"minutes"`
+	WeekdaysOnly   bool    `json:"weekdaysOnly"`
+	PeriodDays     int     `json:"periodDays"`
+	RazorpayPlanId *string `json:"razorpayPlanId,omitempty"`
+	IsActive       bool    `json:"active"`
+}
+
+// PlayerPass is one paid period of a pass, Id is what SaveGameStatus takes as passId.
+type PlayerPass struct {
+	Id             int       `json:"id"`
+	PassId         int       `json:"passId"`
+	Name           string    `json:"name"`
+	Minutes        *int      `json:"minutes"`
+	MinutesUsed    int       `json:"minutesUsed"`
+	WeekdaysOnly   bool      `json:"weekdaysOnly"`
+	StartsAt       time.Time `json:"startsAt"`
+	EndsAt         time.Time `json:"endsAt"`
+	SubscriptionId *string   `json:"subscriptionId"`
+}
+
+type PassSubscription struct {
+	Id       string `json:"id"`
+	ShortURL string `json:"shortUrl"` // razorpay page where the player authorises the mandate
+}
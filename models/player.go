@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type Player struct {
+	Id        int       `json:"id"`
+	Phone     string    `json:"phone"`
+	Name      *string   `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type OTPRequest struct {
+	Phone string `json:"phone"`
+}
+
+type OTPVerification struct {
+	Phone string `json:"phone"`
+	OTP   string `json:"otp"`
+}
+
+const (
+	CodeStatusPlayed   = "played"
+	CodeStatusUnplayed = "unplayed"
+	CodeStatusExpired  = "expired"
+)
+
+// Wallet is the credit of the player in rupees, from expired codes and referrals, spent on games.
+type Wallet struct {
+	Balance int           `json:"balance"`
+	Entries []WalletEntry `json:"entries"`
+}
+
+// WalletEntry amount is negative when the credit was spent.
+type WalletEntry struct {
+	Id        int       `json:"id"`
+	Amount    int       `json:"amount"`
+	Reason    string    `json:"reason"`
+	Code      *string   `json:"code"`
+	PaymentId string    `json:"paymentId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type PlayerCode struct {
+	Code        string     `json:"code"`
+	GameId      uint16     `json:"gameId"`
+	Name        string     `json:"name"`
+	PlayTime    *uint16    `json:"playTime"`
+	Levels      *uint8     `json:"levels"`
+	Price       uint16     `json:"price"`
+	PurchasedAt time.Time  `json:"purchasedAt"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	Status      string     `json:"status"`
+	MachineId   *string    `json:"machineId"` // cabinet the code was used on
+}
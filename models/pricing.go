@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PricingRule changes the catalogue price in a time window, either by Multiplier or with
+// OverridePrice for one tier (GameId and TierLabel). Nil Weekday, Venue and GameId match everything.
+type PricingRule struct {
+	Id            int      `json:"id"`
+	Weekday       *int     `json:"weekday"` // 0 is sunday
+	StartTime     string   `json:"start"`   // HH:MM, local time
+	EndTime       string   `json:"end"`     // before the start runs past midnight
+	Venue         *string  `json:"venue"`
+	GameId        *uint16  `json:"gameId"`
+	TierLabel     *uint16  `json:"tierLabel"` // minutes or levels of the tier
+	Multiplier    *float64 `json:"multiplier"`
+	OverridePrice *uint16  `json:"overridePrice"`
+	Description   string   `json:"description"`
+}
+
+// PaymentOrder is the razorpay order as it was created, the game is priced at CreatedAt.
+type PaymentOrder struct {
+	OrderId   string
+	Purpose   string
+	Amount    int64
+	Venue     *string
+	CreatedAt time.Time
+}
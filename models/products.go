@@ -1,13 +1,14 @@
 package models
 
 type Product struct {
-	ProductId   int32    `json:"productid"`
-	Price       int32    `json:"price"`
-	Description string   `json:"description"`
-	TotalUnits  int8     `json:"totalunits"`
-	Title       string   `json:"title"`
-	CoverImage  string   `json:"coverImage"`
-	Images      []string `json:"images"`
+	ProductId   int32       `json:"productid"`
+	Price       int32       `json:"price"`
+	Description string      `json:"description"`
+	TotalUnits  int32       `json:"totalunits"`
+	Title       string      `json:"title"`
+	CoverImage  string      `json:"coverImage"`
+	Images      []string    `json:"images"`
+	Type        ProductType `json:"type,omitempty"` // only filled on the product details
 }
 
 type ProductType int
@@ -16,3 +17,23 @@ const (
 	Sticker ProductType = iota + 1
 	Card
 )
+
+// sort orders of the shop, newest is the product added last.
+const (
+	ProductSortNewest    = "newest"
+	ProductSortPrice     = "price"
+	ProductSortPriceDesc = "price_desc"
+)
+
+type ProductFilter struct {
+	Type   ProductType
+	Venue  string // empty gives the central stock
+	Sort   string // empty keeps the products in the order they were added
+	Limit  int
+	Offset int
+}
+
+type ProductPage struct {
+	Products []Product `json:"products"`
+	Total    int64     `json:"total"` // products of the listing across every page
+}
@@ -0,0 +1,23 @@
+package models
+
+type ReferralStats struct {
+	ReferralCode string `json:"referralCode"`
+	Referred     int    `json:"referred"`
+	Converted    int    `json:"converted"`
+}
+
+type ReferrerReport struct {
+	ReferrerId int    `json:"referrerId"`
+	Phone      string `json:"phone"`
+	Referred   int    `json:"referred"`
+	Converted  int    `json:"converted"`
+	Revenue    int64  `json:"revenue"` // first purchases of the converted friends, in paise
+}
+
+type ReferralReport struct {
+	Referred       int              `json:"referred"`
+	Converted      int              `json:"converted"`
+	ConversionRate float64          `json:"conversionRate"`
+	Revenue        int64            `json:"revenue"`
+	Referrers      []ReferrerReport `json:"referrers"`
+}
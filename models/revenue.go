@@ -0,0 +1,40 @@
+package models
+
+// periods the revenue report can be split by, weeks start on monday.
+const (
+	RevenueDaily   = "day"
+	RevenueWeekly  = "week"
+	RevenueMonthly = "month"
+)
+
+// RevenueRow amounts are in paise, Net is Gross less the refunds made in the period.
+type RevenueRow struct {
+	Period   string `json:"period"` // first day of the period
+	Purpose  string `json:"purpose"`
+	Method   string `json:"method"`
+	Venue    string `json:"venue"` // empty for payments made without one
+	Payments int64  `json:"payments"`
+	Gross    int64  `json:"gross"`
+	Refunded int64  `json:"refunded"`
+	Net      int64  `json:"net"`
+}
+
+// RevenueTotal adds up the rows sharing a purpose, method or venue.
+type RevenueTotal struct {
+	Key      string `json:"key"`
+	Payments int64  `json:"payments"`
+	Gross    int64  `json:"gross"`
+	Refunded int64  `json:"refunded"`
+	Net      int64  `json:"net"`
+}
+
+type RevenueReport struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"` // inclusive
+	Period    string         `json:"period"`
+	Rows      []RevenueRow   `json:"rows"`
+	ByPurpose []RevenueTotal `json:"byPurpose"`
+	ByMethod  []RevenueTotal `json:"byMethod"`
+	ByVenue   []RevenueTotal `json:"byVenue"`
+	Total     RevenueTotal   `json:"total"`
+}
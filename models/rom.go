@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// EmulatorSystem is an emulated console or board, Core is the libretro core the cabinets load.
+type EmulatorSystem struct {
+	Id        string     `json:"id"` // e.g. nes, snes, mame
+	Name      string     `json:"name"`
+	Core      string     `json:"core"`
+	Bios      []BiosFile `json:"bios"` // files the core needs next to the ROM
+	IsActive  bool       `json:"isActive"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+type BiosFile struct {
+	File   string `json:"file"`
+	Sha256 string `json:"sha256"`
+}
+
+// Rom is one uploaded version of the ROM of a game, the current one is what the cabinets install.
+type Rom struct {
+	Id         int       `json:"id"`
+	GameId     uint16    `json:"gameId"`
+	SystemId   string    `json:"systemId"`
+	FileName   string    `json:"fileName"`
+	ObjectKey  string    `json:"-"`
+	Sha256     string    `json:"sha256"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	Version    string    `json:"version"`
+	IsCurrent  bool      `json:"isCurrent"`
+	UploadedAt time.Time `json:"uploadedAt"`
+	Machines   []string  `json:"machines"` // machines that reported it installed
+}
+
+// RomUpload describes the uploaded file, Sha256 is optional and checked against the file when given.
+type RomUpload struct {
+	GameId   uint16
+	SystemId string
+	Version  string
+	FileName string
+	Sha256   string
+}
+
+// RomDownload is what a cabinet needs to fetch and run the ROM, the file has to hash to Sha256.
+type RomDownload struct {
+	Rom
+	URL       string     `json:"url"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	Core      string     `json:"core"`
+	Bios      []BiosFile `json:"bios"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+type ScoreSubmission struct {
+	GameId uint16 `json:"gameId"`
+	Code   string `json:"code"`
+	Score  int64  `json:"score"`
+	Name   string `json:"name"` // initials typed at game over, the player name wins if logged in
+}
+
+type Score struct {
+	Id        int       `json:"id"`
+	GameId    uint16    `json:"gameId"`
+	Code      string    `json:"code"`
+	MachineId string    `json:"machineId"`
+	Venue     string    `json:"venue"`
+	PlayerId  *int      `json:"playerId"`
+	Name      string    `json:"name"`
+	Score     int64     `json:"score"`
+	IsRemoved bool      `json:"removed"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type LeaderboardEntry struct {
+	Rank    int    `json:"rank"`
+	ScoreId int    `json:"scoreId"`
+	Name    string `json:"name"`
+	Score   int64  `json:"score"`
+	Venue   string `json:"venue"`
+}
+
+const (
+	LeaderboardDaily   = "daily"
+	LeaderboardWeekly  = "weekly"
+	LeaderboardAllTime = "all"
+)
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	SessionEventStart = "start"
+	SessionEventLevel = "level"
+	SessionEventEnd   = "end"
+)
+
+// SessionEvent is sent by the cabinet, Level is the level reached so far.
+type SessionEvent struct {
+	Type      string `json:"type"`
+	Code      string `json:"code"`
+	GameId    uint16 `json:"gameId"`
+	Level     int    `json:"level"`
+	Completed bool   `json:"completed"` // the game was finished, not just the purchased levels
+}
+
+type PlaySession struct {
+	Id          int        `json:"id"`
+	Code        string     `json:"code"`
+	GameId      uint16     `json:"gameId"`
+	MachineId   string     `json:"machineId"`
+	PlayerId    *int       `json:"playerId"`
+	System      *string    `json:"system"`
+	MaxLevel    int        `json:"maxLevel"`
+	IsCompleted bool       `json:"completed"`
+	StartedAt   time.Time  `json:"startedAt"`
+	EndedAt     *time.Time `json:"endedAt"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Venue is one location of the arcade, Id is the venue machines and scores carry.
+type Venue struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	City      string    `json:"city"`
+	IsActive  bool      `json:"isActive"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// VenueGame turns a catalogue game on or off at the venue, games are on unless turned off.
+type VenueGame struct {
+	GameId      uint16 `json:"gameId"`
+	IsAvailable bool   `json:"isAvailable"`
+}
+
+// VenuePrice replaces the catalogue price of one tier at the venue, a nil Price goes back to the catalogue.
+type VenuePrice struct {
+	GameId   uint16  `json:"gameId"`
+	ItemType string  `json:"itemType"` // time or level
+	Label    uint16  `json:"label"`    // minutes or levels of the tier
+	Price    *uint16 `json:"price"`
+}
+
+// VenueStock is what the venue has of a product, the venue shop only lists products it has stock rows for.
+type VenueStock struct {
+	ProductId int32 `json:"productId"`
+	Units     int   `json:"units"`
+}
+
+// VenueDetails is what the venue changes from the catalogue.
+type VenueDetails struct {
+	Venue
+	Games     []VenueGame  `json:"games"`
+	Prices    []VenuePrice `json:"prices"`
+	Inventory []VenueStock `json:"inventory"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+type VoucherBatchRequest struct {
+	Label     string     `json:"label"`
+	GameId    uint16     `json:"gameId"`
+	Name      string     `json:"name"`
+	IsTimed   bool       `json:"isTimed"`
+	Price     uint16     `json:"price"`
+	PlayTime  *uint16    `json:"playTime"`
+	Levels    *uint8     `json:"levels"`
+	Count     int        `json:"count"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+type VoucherBatch struct {
+	Id         int           `json:"id"`
+	Label      string        `json:"label"`
+	GameId     uint16        `json:"gameId"`
+	Name       string        `json:"name"`
+	Price      uint16        `json:"price"`
+	PlayTime   *uint16       `json:"playTime"`
+	Levels     *uint8        `json:"levels"`
+	TotalCodes int           `json:"totalCodes"`
+	Redeemed   int           `json:"redeemed"`
+	ExpiresAt  *time.Time    `json:"expiresAt"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	Codes      []VoucherCode `json:"codes"`
+}
+
+type VoucherCode struct {
+	Code     string `json:"code"`
+	IsPlayed bool   `json:"played"`
+}
@@ -0,0 +1,108 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type AchievementRepository interface {
+	FetchAchievements(includeInactive bool) ([]models.Achievement, error)
+	SaveAchievement(achievement models.Achievement) (int, error) // creates it when the id is 0
+	FetchProgress(playerId int) ([]models.AchievementProgress, error)
+	AwardAchievement(playerId int, achievementId int) (bool, error) // false when it was already earned
+}
+
+type achievementRepository struct {
+	db *sql.DB
+}
+
+func NewAchievementRepository(db *sql.DB) *achievementRepository {
+	return &achievementRepository{db: db}
+}
+
+func (r *achievementRepository) FetchAchievements(includeInactive bool) ([]models.Achievement, error) {
+	rows, err := r.db.Query(`SELECT id, title, description, kind, threshold, game_id, window_days, badge, is_active
+		FROM func_GetAchievements($1)`, includeInactive)
+	if err != nil {
+		utils.LogError("Failed to fetch achievements: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var achievements []models.Achievement
+	for rows.Next() {
+		var achievement models.Achievement
+		if err := rows.Scan(&achievement.Id, &achievement.Title, &achievement.Description, &achievement.Kind,
+			&achievement.Threshold, &achievement.GameId, &achievement.WindowDays, &achievement.Badge,
+			&achievement.IsActive); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		achievements = append(achievements, achievement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return achievements, nil
+}
+
+func (r *achievementRepository) SaveAchievement(achievement models.Achievement) (int, error) {
+	var id *int
+	if achievement.Id > 0 {
+		id = &achievement.Id
+	}
+
+	var achievementId sql.NullInt64
+	err := r.db.QueryRow("SELECT func_UpsertAchievement($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		id, achievement.Title, achievement.Description, achievement.Kind, achievement.Threshold,
+		achievement.GameId, achievement.WindowDays, achievement.Badge, achievement.IsActive).Scan(&achievementId)
+
+	if err != nil {
+		utils.LogError("Failed to save achievement %s: %v", achievement.Title, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	if !achievementId.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return int(achievementId.Int64), nil
+}
+
+func (r *achievementRepository) FetchProgress(playerId int) ([]models.AchievementProgress, error) {
+	rows, err := r.db.Query(`SELECT id, title, description, kind, threshold, game_id, window_days, badge, is_active,
+		progress, earned_at FROM func_GetAchievementProgress($1)`, playerId)
+	if err != nil {
+		utils.LogError("Failed to fetch achievement progress of player ID %d: %v", playerId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var progress []models.AchievementProgress
+	for rows.Next() {
+		var p models.AchievementProgress
+		if err := rows.Scan(&p.Id, &p.Title, &p.Description, &p.Kind, &p.Threshold, &p.GameId, &p.WindowDays,
+			&p.Badge, &p.IsActive, &p.Progress, &p.EarnedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		progress = append(progress, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return progress, nil
+}
+
+func (r *achievementRepository) AwardAchievement(playerId int, achievementId int) (bool, error) {
+	var awarded bool
+	err := r.db.QueryRow("SELECT func_AwardAchievement($1, $2)", playerId, achievementId).Scan(&awarded)
+
+	if err != nil {
+		utils.LogError("Failed to award achievement ID %d to player ID %d: %v", achievementId, playerId, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return awarded, nil
+}
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const analyticsDayLayout = "2006-01-02"
+
+type AnalyticsRepository interface {
+	RollupPending() (int, error)
+	Rollup(day time.Time) error
+
+	// to is exclusive, venues nil for every venue.
+	FetchGames(from time.Time, to time.Time, venues []string) ([]models.GameAnalytics, error)
+	FetchGameDays(from time.Time, to time.Time, gameId uint16, venues []string) ([]models.GameDay, error)
+	FetchMachineHours(from time.Time, to time.Time, machineId string, venues []string) ([]models.MachineHour, error)
+	FetchPeakHours(from time.Time, to time.Time, venues []string) ([]models.PeakHour, error)
+}
+
+type analyticsRepository struct {
+	db *sql.DB
+}
+
+func NewAnalyticsRepository(db *sql.DB) *analyticsRepository {
+	return &analyticsRepository{db: db}
+}
+
+func (r *analyticsRepository) RollupPending() (int, error) {
+	var days int
+	if err := r.db.QueryRow("SELECT func_RollupPendingAnalytics()").Scan(&days); err != nil {
+		utils.LogError("Failed to roll up analytics: %v", err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return days, nil
+}
+
+func (r *analyticsRepository) Rollup(day time.Time) error {
+	if _, err := r.db.Exec("SELECT func_RollupAnalytics($1)", day.Format(analyticsDayLayout)); err != nil {
+		utils.LogError("Failed to roll up analytics of %s: %v", day.Format(analyticsDayLayout), err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *analyticsRepository) FetchGames(from time.Time, to time.Time, venues []string) ([]models.GameAnalytics, error) {
+	rows, err := r.db.Query(`SELECT game_id, name, plays, codes_sold, revenue, avg_played_minutes, avg_purchased_minutes
+		FROM func_GetGameAnalytics($1, $2, $3)`, from.Format(analyticsDayLayout), to.Format(analyticsDayLayout), pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch game analytics: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	games := []models.GameAnalytics{}
+	for rows.Next() {
+		var game models.GameAnalytics
+		if err := rows.Scan(&game.GameId, &game.Name, &game.Plays, &game.CodesSold, &game.Revenue,
+			&game.AvgSessionMinutes, &game.AvgPurchasedMinutes); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		games = append(games, game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return games, nil
+}
+
+func (r *analyticsRepository) FetchGameDays(from time.Time, to time.Time, gameId uint16, venues []string) ([]models.GameDay, error) {
+	rows, err := r.db.Query(`SELECT day, plays, codes_sold, revenue, avg_played_minutes, avg_purchased_minutes
+		FROM func_GetGameDailyAnalytics($1, $2, $3, $4)`,
+		from.Format(analyticsDayLayout), to.Format(analyticsDayLayout), gameId, pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch daily analytics of game ID %d: %v", gameId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	days := []models.GameDay{}
+	for rows.Next() {
+		var day models.GameDay
+		var date time.Time
+		if err := rows.Scan(&date, &day.Plays, &day.CodesSold, &day.Revenue,
+			&day.AvgSessionMinutes, &day.AvgPurchasedMinutes); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		day.Day = date.Format(analyticsDayLayout)
+		days = append(days, day)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return days, nil
+}
+
+func (r *analyticsRepository) FetchMachineHours(from time.Time, to time.Time, machineId string, venues []string) ([]models.MachineHour, error) {
+	rows, err := r.db.Query(`SELECT machine_id, venue, day, hour, sessions, busy_seconds
+		FROM func_GetMachineUtilisation($1, $2, $3, $4)`,
+		from.Format(analyticsDayLayout), to.Format(analyticsDayLayout), optional(machineId), pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch machine utilisation: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	hours := []models.MachineHour{}
+	for rows.Next() {
+		var hour models.MachineHour
+		var date time.Time
+		var busySeconds int
+		if err := rows.Scan(&hour.MachineId, &hour.Venue, &date, &hour.Hour, &hour.Sessions, &busySeconds); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		hour.Day = date.Format(analyticsDayLayout)
+		hour.Utilisation = float64(busySeconds) / 36 // percent of 3600 seconds
+		hours = append(hours, hour)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return hours, nil
+}
+
+func (r *analyticsRepository) FetchPeakHours(from time.Time, to time.Time, venues []string) ([]models.PeakHour, error) {
+	rows, err := r.db.Query(`SELECT weekday, hour, sessions, busy_seconds, machine_hours FROM func_GetPeakHours($1, $2, $3)`,
+		from.Format(analyticsDayLayout), to.Format(analyticsDayLayout), pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch peak hours: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	cells := []models.PeakHour{}
+	for rows.Next() {
+		var cell models.PeakHour
+		var busySeconds, machineHours int64
+		if err := rows.Scan(&cell.Weekday, &cell.Hour, &cell.Sessions, &busySeconds, &machineHours); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if machineHours > 0 {
+			cell.Utilisation = float64(busySeconds) / float64(machineHours*36)
+		}
+		cells = append(cells, cell)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return cells, nil
+}
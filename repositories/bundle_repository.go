@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type BundleRepository interface {
+	FetchBundles(includeInactive bool) ([]models.Bundle, error)
+	CreateBundle(bundle models.Bundle) (int, error)
+	DeactivateBundle(bundleId int) error
+
+	CreatePurchase(bundleId int, paymentId string, playerId *int) (int, error) // 0 when already fulfilled
+	AddCode(purchaseId int, code string, isBalance bool, minutes *int) error
+	FinishPurchase(purchaseId int) error
+	FetchCodeByReference(reference string) (string, error) // empty when nothing was issued under it
+	FetchPurchaseCodes(paymentId string) ([]models.BundleCode, error)
+
+	DrawBalance(balanceCode string, gameCode string, minutes uint16) (int, error) // minutes left, -1 when too low
+	ReleaseDraw(gameCode string) error
+}
+
+type bundleRepository struct {
+	db *sql.DB
+}
+
+func NewBundleRepository(db *sql.DB) *bundleRepository {
+	return &bundleRepository{db: db}
+}
+
+// FetchBundles gets a row per item, they are folded into their bundles here.
+func (r *bundleRepository) FetchBundles(includeInactive bool) ([]models.Bundle, error) {
+	rows, err := r.db.Query(`SELECT id, name, description, price, kind, minutes, is_active, item_id, game_id,
+		is_timed, play_time, levels, quantity FROM func_GetBundles($1)`, includeInactive)
+	if err != nil {
+		utils.LogError("Failed to fetch bundles: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var bundles []models.Bundle
+	for rows.Next() {
+		var bundle models.Bundle
+		var itemId sql.NullInt64
+		var item models.BundleItem
+		var gameId, quantity sql.NullInt64
+		var isTimed sql.NullBool
+		if err := rows.Scan(&bundle.Id, &bundle.Name, &bundle.Description, &bundle.Price, &bundle.Kind,
+			&bundle.Minutes, &bundle.IsActive, &itemId, &gameId, &isTimed, &item.PlayTime, &item.Levels,
+			&quantity); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		if len(bundles) == 0 || bundles[len(bundles)-1].Id != bundle.Id {
+			bundles = append(bundles, bundle)
+		}
+		if itemId.Valid {
+			item.GameId, item.IsTimed, item.Quantity = uint16(gameId.Int64), isTimed.Bool, int(quantity.Int64)
+			last := &bundles[len(bundles)-1]
+			last.Items = append(last.Items, item)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return bundles, nil
+}
+
+func (r *bundleRepository) CreateBundle(bundle models.Bundle) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		utils.LogError("Failed to begin create bundle transaction: %v", err)
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var bundleId int
+	err = tx.QueryRow("SELECT func_InsertBundle($1, $2, $3, $4, $5)",
+		bundle.Name, bundle.Description, bundle.Price, bundle.Kind, bundle.Minutes).Scan(&bundleId)
+	if err != nil {
+		utils.LogError("Failed to create bundle %s: %v", bundle.Name, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+
+	for _, item := range bundle.Items {
+		if _, err := tx.Exec("SELECT func_InsertBundleItem($1, $2, $3, $4, $5, $6)",
+			bundleId, item.GameId, item.IsTimed, item.PlayTime, item.Levels, item.Quantity); err != nil {
+			utils.LogError("Failed to add game ID %d to bundle %s: %v", item.GameId, bundle.Name, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		utils.LogError("Failed to commit bundle %s: %v", bundle.Name, err)
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+	return bundleId, nil
+}
+
+func (r *bundleRepository) DeactivateBundle(bundleId int) error {
+	if _, err := r.db.Exec("SELECT func_DeactivateBundle($1)", bundleId); err != nil {
+		utils.LogError("Failed to deactivate bundle ID %d: %v", bundleId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *bundleRepository) CreatePurchase(bundleId int, paymentId string, playerId *int) (int, error) {
+	var purchaseId sql.NullInt64
+	err := r.db.QueryRow("SELECT func_InsertBundlePurchase($1, $2, $3)", bundleId, paymentId, playerId).Scan(&purchaseId)
+
+	if err != nil {
+		utils.LogError("Failed to record purchase of bundle ID %d, payment ID %s: %v", bundleId, paymentId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return int(purchaseId.Int64), nil
+}
+
+func (r *bundleRepository) AddCode(purchaseId int, code string, isBalance bool, minutes *int) error {
+	if _, err := r.db.Exec("SELECT func_InsertBundleCode($1, $2, $3, $4)", purchaseId, code, isBalance, minutes); err != nil {
+		utils.LogError("Failed to add code %s to bundle purchase ID %d: %v", code, purchaseId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *bundleRepository) FinishPurchase(purchaseId int) error {
+	if _, err := r.db.Exec("SELECT func_FinishBundlePurchase($1)", purchaseId); err != nil {
+		utils.LogError("Failed to finish bundle purchase ID %d: %v", purchaseId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *bundleRepository) FetchCodeByReference(reference string) (string, error) {
+	var code sql.NullString
+	if err := r.db.QueryRow("SELECT func_GetCodeByReference($1)", reference).Scan(&code); err != nil {
+		utils.LogError("Failed to fetch the code of reference %s: %v", reference, err)
+		return "", fmt.Errorf("error executing function: %w", err)
+	}
+	return code.String, nil
+}
+
+func (r *bundleRepository) FetchPurchaseCodes(paymentId string) ([]models.BundleCode, error) {
+	rows, err := r.db.Query("SELECT code, is_balance, minutes, minutes_left FROM func_GetBundlePurchaseCodes($1)", paymentId)
+	if err != nil {
+		utils.LogError("Failed to fetch bundle codes of payment ID %s: %v", paymentId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []models.BundleCode
+	for rows.Next() {
+		var code models.BundleCode
+		if err := rows.Scan(&code.Code, &code.IsBalance, &code.Minutes, &code.MinutesLeft); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return codes, nil
+}
+
+// DrawBalance returns sql.ErrNoRows when the code isn't a balance code.
+func (r *bundleRepository) DrawBalance(balanceCode string, gameCode string, minutes uint16) (int, error) {
+	var left sql.NullInt64
+	err := r.db.QueryRow("SELECT func_DrawBalance($1, $2, $3)", balanceCode, gameCode, minutes).Scan(&left)
+
+	if err != nil {
+		utils.LogError("Failed to draw %d minutes from balance code %s: %v", minutes, balanceCode, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	if !left.Valid {
+		return 0, sql.ErrNoRows
+	}
+	return int(left.Int64), nil
+}
+
+func (r *bundleRepository) ReleaseDraw(gameCode string) error {
+	if _, err := r.db.Exec("SELECT func_ReleaseBalanceDraw($1)", gameCode); err != nil {
+		utils.LogError("Failed to release balance draw of code %s: %v", gameCode, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
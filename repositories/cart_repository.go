@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// CartRepository keeps the carts of the players, the guest carts are kept in redis by the service.
+type CartRepository interface {
+	FetchCart(playerId int) ([]models.CartItem, error)
+	SetCartItem(playerId int, productId int, quantity int) error // quantity 0 takes it out
+	MergeCart(playerId int, items []models.CartItem) error
+	ClearCart(playerId int) error
+
+	// CreateShopOrder holds the units of the order for the minutes, nothing is created when a product
+	// doesn't have enough units left and shortProductId names it.
+	CreateShopOrder(order models.ShopOrder, holdMinutes int) (orderId int, shortProductId int, unitsLeft int, err error)
+	FetchShopOrder(orderId string) (models.ShopOrder, error)
+	FetchShopOrders(playerId int, guestSession string) ([]models.ShopOrder, error)
+	PayShopOrder(orderId string, paymentId string) ([]models.SoldStock, error) // nothing when it was already paid
+	ReleaseShopOrder(orderId string) (int, error)
+	RefundShopOrder(paymentId string) error
+}
+
+type cartRepository struct {
+	db *sql.DB
+}
+
+func NewCartRepository(db *sql.DB) *cartRepository {
+	return &cartRepository{db: db}
+}
+
+func (r *cartRepository) FetchCart(playerId int) ([]models.CartItem, error) {
+	rows, err := r.db.Query("SELECT product_id, quantity FROM func_GetCart($1)", playerId)
+	if err != nil {
+		utils.LogError("Failed to fetch cart of player ID %d: %v", playerId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.CartItem{}
+	for rows.Next() {
+		var item models.CartItem
+		if err := rows.Scan(&item.ProductId, &item.Quantity); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return items, nil
+}
+
+func (r *cartRepository) SetCartItem(playerId int, productId int, quantity int) error {
+	if _, err := r.db.Exec("SELECT func_SetCartItem($1, $2, $3)", playerId, productId, quantity); err != nil {
+		utils.LogError("Failed to set product ID %d in cart of player ID %d: %v", productId, playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *cartRepository) MergeCart(playerId int, items []models.CartItem) error {
+	productIds := make([]int64, len(items))
+	quantities := make([]int64, len(items))
+	for i, item := range items {
+		productIds[i] = int64(item.ProductId)
+		quantities[i] = int64(item.Quantity)
+	}
+
+	if _, err := r.db.Exec("SELECT func_MergeCart($1, $2, $3)", playerId, pq.Array(productIds), pq.Array(quantities)); err != nil {
+		utils.LogError("Failed to merge guest cart into cart of player ID %d: %v", playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *cartRepository) ClearCart(playerId int) error {
+	if _, err := r.db.Exec("SELECT func_ClearCart($1)", playerId); err != nil {
+		utils.LogError("Failed to clear cart of player ID %d: %v", playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *cartRepository) CreateShopOrder(order models.ShopOrder, holdMinutes int) (int, int, int, error) {
+	productIds := make([]int64, len(order.Items))
+	titles := make([]string, len(order.Items))
+	prices := make([]int64, len(order.Items))
+	quantities := make([]int64, len(order.Items))
+	for i, item := range order.Items {
+		productIds[i] = int64(item.ProductId)
+		titles[i] = item.Title
+		prices[i] = int64(item.Price)
+		quantities[i] = int64(item.Quantity)
+	}
+
+	var id, shortProductId, unitsLeft sql.NullInt32
+	err := r.db.QueryRow(`SELECT order_id, short_product_id, units_left
+		FROM func_CreateShopOrder($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		order.OrderId, order.PlayerId, order.GuestSession, order.Venue, order.Amount,
+		pq.Array(productIds), pq.Array(titles), pq.Array(prices), pq.Array(quantities), holdMinutes).
+		Scan(&id, &shortProductId, &unitsLeft)
+	if err != nil {
+		utils.LogError("Failed to create shop order for razorpay order %s: %v", order.OrderId, err)
+		return 0, 0, 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return int(id.Int32), int(shortProductId.Int32), int(unitsLeft.Int32), nil
+}
+
+// FetchShopOrder returns sql.ErrNoRows when the razorpay order wasn't a checkout.
+func (r *cartRepository) FetchShopOrder(orderId string) (models.ShopOrder, error) {
+	order, err := scanShopOrder(r.db.QueryRow(`SELECT id, razorpay_order_id, player_id, guest_session, venue, amount,
+		status, created_at, paid_at, items FROM func_GetShopOrder($1)`, orderId))
+	if err == sql.ErrNoRows {
+		return order, err
+	} else if err != nil {
+		utils.LogError("Failed to fetch shop order of razorpay order %s: %v", orderId, err)
+		return order, fmt.Errorf("error executing function: %w", err)
+	}
+	return order, nil
+}
+
+func (r *cartRepository) FetchShopOrders(playerId int, guestSession string) ([]models.ShopOrder, error) {
+	var player *int
+	if playerId > 0 {
+		player = &playerId
+	}
+
+	rows, err := r.db.Query(`SELECT id, razorpay_order_id, player_id, guest_session, venue, amount,
+		status, created_at, paid_at, items FROM func_GetShopOrders($1, $2)`, player, optional(guestSession))
+	if err != nil {
+		utils.LogError("Failed to fetch shop orders: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	orders := []models.ShopOrder{}
+	for rows.Next() {
+		order, err := scanShopOrder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		orders = append(orders, order)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return orders, nil
+}
+
+func (r *cartRepository) PayShopOrder(orderId string, paymentId string) ([]models.SoldStock, error) {
+	rows, err := r.db.Query("SELECT product_id, quantity, units_left, oversold FROM func_PayShopOrder($1, $2)", orderId, paymentId)
+	if err != nil {
+		utils.LogError("Failed to mark shop order of razorpay order %s paid: %v", orderId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	sold := []models.SoldStock{}
+	for rows.Next() {
+		var stock models.SoldStock
+		if err := rows.Scan(&stock.ProductId, &stock.Quantity, &stock.UnitsLeft, &stock.Oversold); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		sold = append(sold, stock)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return sold, nil
+}
+
+func (r *cartRepository) ReleaseShopOrder(orderId string) (int, error) {
+	var released int
+	if err := r.db.QueryRow("SELECT func_ReleaseShopOrder($1)", orderId).Scan(&released); err != nil {
+		utils.LogError("Failed to release the stock held for razorpay order %s: %v", orderId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return released, nil
+}
+
+func (r *cartRepository) RefundShopOrder(paymentId string) error {
+	if _, err := r.db.Exec("SELECT func_RefundShopOrder($1)", paymentId); err != nil {
+		utils.LogError("Failed to mark shop order of payment ID %s refunded: %v", paymentId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func scanShopOrder(row interface{ Scan(...any) error }) (models.ShopOrder, error) {
+	var order models.ShopOrder
+	var items []byte
+	if err := row.Scan(&order.Id, &order.OrderId, &order.PlayerId, &order.GuestSession, &order.Venue, &order.Amount,
+		&order.Status, &order.CreatedAt, &order.PaidAt, &items); err != nil {
+		return order, err
+	}
+
+	order.Items = []models.ShopOrderItem{}
+	if items != nil {
+		if err := json.Unmarshal(items, &order.Items); err != nil {
+			return order, fmt.Errorf("error reading items of shop order %d: %w", order.Id, err)
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type CodeExpiryRepository interface {
+	FetchPolicies() ([]models.CodeValidityPolicy, error)
+	SavePolicy(policy models.CodeValidityPolicy) error
+	DeletePolicy(gameId *uint16) error
+	ExpireCodes() ([]models.ExpiredCode, error)
+}
+
+type codeExpiryRepository struct {
+	db *sql.DB
+}
+
+func NewCodeExpiryRepository(db *sql.DB) *codeExpiryRepository {
+	return &codeExpiryRepository{db: db}
+}
+
+func (r *codeExpiryRepository) FetchPolicies() ([]models.CodeValidityPolicy, error) {
+	rows, err := r.db.Query("SELECT game_id, valid_days, convert_to_credit FROM func_GetCodeValidityPolicies()")
+	if err != nil {
+		utils.LogError("Failed to fetch code validity policies: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var policies []models.CodeValidityPolicy
+	for rows.Next() {
+		var policy models.CodeValidityPolicy
+		if err := rows.Scan(&policy.GameId, &policy.ValidDays, &policy.ConvertToCredit); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		policies = append(policies, policy)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return policies, nil
+}
+
+func (r *codeExpiryRepository) SavePolicy(policy models.CodeValidityPolicy) error {
+	_, err := r.db.Exec("SELECT func_UpsertCodeValidityPolicy($1, $2, $3)",
+		policy.GameId, policy.ValidDays, policy.ConvertToCredit)
+
+	if err != nil {
+		utils.LogError("Failed to save code validity policy: %v", err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *codeExpiryRepository) DeletePolicy(gameId *uint16) error {
+	if _, err := r.db.Exec("SELECT func_DeleteCodeValidityPolicy($1)", gameId); err != nil {
+		utils.LogError("Failed to delete code validity policy: %v", err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *codeExpiryRepository) ExpireCodes() ([]models.ExpiredCode, error) {
+	rows, err := r.db.Query("SELECT code, credited FROM func_ExpireCodes()")
+	if err != nil {
+		utils.LogError("Failed to expire codes: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var expired []models.ExpiredCode
+	for rows.Next() {
+		var code models.ExpiredCode
+		if err := rows.Scan(&code.Code, &code.Credited); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		expired = append(expired, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return expired, nil
+}
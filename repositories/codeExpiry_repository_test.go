@@ -0,0 +1,51 @@
+package repositories
+
+import "testing"
+
+func TestExpireCodesCreditsOnlyPaidCodes(t *testing.T) {
+	tx := testTx(t)
+	gameId := testGameId(t, tx)
+
+	var playerId int
+	if err := tx.QueryRow("SELECT func_UpsertPlayer($1)", "+910000000030").Scan(&playerId); err != nil {
+		t.Fatalf("creating the player: %v", err)
+	}
+	mustExec(t, tx, "SELECT func_UpsertCodeValidityPolicy($1, $2, $3)", gameId, 1, true)
+	mustExec(t, tx, `INSERT INTO "CapturedPayments" ("paymentId", "orderId", amount, method, purpose, "playerId", "gameId")
+		VALUES ('pay_test030', 'order_test030', 5000, 'upi', 'game', $1, $2)`, playerId, gameId)
+
+	// one code paid through razorpay, one taken from an unlimited pass.
+	for code, reference := range map[string]string{"TSTPAID": "pay_test030", "TSTPASS": "pass-1"} {
+		mustExec(t, tx, "SELECT func_InsertGameStatus($1, $2, $3, $4, $5, $6, $7, $8)",
+			gameId, "test game", false, 50, 10, nil, reference, code)
+		mustExec(t, tx, "SELECT func_SetCodeExpiry($1, now() - INTERVAL '1 minute')", code)
+		mustExec(t, tx, "SELECT func_AttachCodeToPlayer($1, $2)", code, playerId)
+	}
+
+	rows, err := tx.Query("SELECT code, credited FROM func_ExpireCodes() WHERE code IN ('TSTPAID', 'TSTPASS')")
+	if err != nil {
+		t.Fatalf("expiring codes: %v", err)
+	}
+	credited := map[string]int{}
+	for rows.Next() {
+		var code string
+		var amount int
+		if err := rows.Scan(&code, &amount); err != nil {
+			t.Fatalf("scanning expired code: %v", err)
+		}
+		credited[code] = amount
+	}
+	rows.Close()
+
+	if len(credited) != 2 || credited["TSTPAID"] != 50 || credited["TSTPASS"] != 0 {
+		t.Fatalf("credited %v, want 50 for the paid code and nothing for the pass code", credited)
+	}
+
+	var balance int
+	if err := tx.QueryRow("SELECT func_GetWalletBalance($1)", playerId).Scan(&balance); err != nil {
+		t.Fatalf("reading the balance: %v", err)
+	}
+	if balance != 50 {
+		t.Errorf("wallet balance is %d, want 50", balance)
+	}
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+const couponColumns = `id, code, kind, value, starts_at, ends_at, max_uses, max_uses_per_player, game_id, tier_label,
+	product_type, is_active, uses, player_uses`
+
+type CouponRepository interface {
+	FetchCoupons() ([]models.Coupon, error)
+	FetchCoupon(code string, playerId *int) (models.Coupon, error) // sql.ErrNoRows when there is no such code
+	CreateCoupon(coupon models.Coupon) (int, error)
+	DeactivateCoupon(couponId int) error
+}
+
+type couponRepository struct {
+	db *sql.DB
+}
+
+func NewCouponRepository(db *sql.DB) *couponRepository {
+	return &couponRepository{db: db}
+}
+
+func (r *couponRepository) FetchCoupons() ([]models.Coupon, error) {
+	rows, err := r.db.Query("SELECT " + couponColumns + " FROM func_GetCoupons()")
+	if err != nil {
+		utils.LogError("Failed to fetch coupons: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var coupons []models.Coupon
+	for rows.Next() {
+		coupon, err := scanCoupon(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		coupons = append(coupons, coupon)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return coupons, nil
+}
+
+func (r *couponRepository) FetchCoupon(code string, playerId *int) (models.Coupon, error) {
+	coupon, err := scanCoupon(r.db.QueryRow("SELECT "+couponColumns+" FROM func_GetCoupon($1, $2)", code, playerId))
+
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("Failed to fetch coupon %s: %v", code, err)
+		return coupon, fmt.Errorf("error executing function: %w", err)
+	}
+	return coupon, err
+}
+
+func (r *couponRepository) CreateCoupon(coupon models.Coupon) (int, error) {
+	var couponId int
+	err := r.db.QueryRow("SELECT func_InsertCoupon($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		coupon.Code, coupon.Kind, coupon.Value, coupon.StartsAt, coupon.EndsAt, coupon.MaxUses,
+		coupon.MaxUsesPerPlayer, coupon.GameId, coupon.TierLabel, coupon.ProductType).Scan(&couponId)
+
+	if err != nil {
+		utils.LogError("Failed to create coupon %s: %v", coupon.Code, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return couponId, nil
+}
+
+func (r *couponRepository) DeactivateCoupon(couponId int) error {
+	if _, err := r.db.Exec("SELECT func_DeactivateCoupon($1)", couponId); err != nil {
+		utils.LogError("Failed to deactivate coupon ID %d: %v", couponId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func scanCoupon(row rowScanner) (models.Coupon, error) {
+	var coupon models.Coupon
+	err := row.Scan(&coupon.Id, &coupon.Code, &coupon.Kind, &coupon.Value, &coupon.StartsAt, &coupon.EndsAt,
+		&coupon.MaxUses, &coupon.MaxUsesPerPlayer, &coupon.GameId, &coupon.TierLabel, &coupon.ProductType,
+		&coupon.IsActive, &coupon.Uses, &coupon.PlayerUses)
+	return coupon, err
+}
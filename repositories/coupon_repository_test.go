@@ -0,0 +1,44 @@
+package repositories
+
+import "testing"
+
+func TestRedeemCouponLimits(t *testing.T) {
+	tx := testTx(t)
+
+	var playerId int
+	if err := tx.QueryRow("SELECT func_UpsertPlayer($1)", "+910000000038").Scan(&playerId); err != nil {
+		t.Fatalf("creating the player: %v", err)
+	}
+	// once per player, three times in all.
+	mustExec(t, tx, "SELECT func_InsertCoupon($1, 'flat', 20, NULL, NULL, 3, 1, NULL, NULL, NULL)", "TESTLIMIT038")
+
+	redeem := func(player *int, reference string) string {
+		t.Helper()
+		var reason string
+		if err := tx.QueryRow("SELECT func_RedeemCoupon($1, $2, $3, 20)", "testlimit038", player, reference).
+			Scan(&reason); err != nil {
+			t.Fatalf("redeeming: %v", err)
+		}
+		return reason
+	}
+
+	if reason := redeem(nil, "guest-038"); reason != "coupon needs a logged in player" {
+		t.Errorf("guest got %q, want the coupon to need a logged in player", reason)
+	}
+	if reason := redeem(&playerId, "pay-038-1"); reason != "" {
+		t.Fatalf("first redemption refused: %q", reason)
+	}
+	if reason := redeem(&playerId, "pay-038-2"); reason != "coupon already used" {
+		t.Errorf("second redemption got %q, want the coupon already used", reason)
+	}
+
+	var uses int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM "CouponRedemptions" r JOIN "Coupons" c ON c.id = r."couponId"
+		WHERE c.code = 'TESTLIMIT038'`).Scan(&uses)
+	if err != nil {
+		t.Fatalf("counting redemptions: %v", err)
+	}
+	if uses != 1 {
+		t.Errorf("coupon redeemed %d times, want 1", uses)
+	}
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// testTx is a transaction on the database at DATABASE_URL with the migrations applied, rolled back
+// when the test ends. Tests that need it are skipped without one.
+func testTx(t *testing.T) *sql.Tx {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL isn't set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("opening the database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("starting a transaction: %v", err)
+	}
+	t.Cleanup(func() { tx.Rollback() })
+	return tx
+}
+
+// testGameId is any game of the catalogue, the tables of the game codes point to one.
+func testGameId(t *testing.T, tx *sql.Tx) int {
+	var gameId int
+	if err := tx.QueryRow("SELECT game_id FROM func_GetGamesForUsers(NULL) LIMIT 1").Scan(&gameId); err != nil {
+		t.Skipf("no game to issue codes for: %v", err)
+	}
+	return gameId
+}
+
+func mustExec(t *testing.T, tx *sql.Tx, query string, args ...any) {
+	t.Helper()
+	if _, err := tx.Exec(query, args...); err != nil {
+		t.Fatalf("running %q: %v", query, err)
+	}
+}
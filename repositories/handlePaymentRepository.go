@@ -5,10 +5,21 @@ import (
 	"GameWala-Arcade/utils"
 	"database/sql"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type HandlePaymentRepository interface {
 	SaveOrderDetails(models.PaymentStatus) error
+	RecordOrder(order models.PaymentOrder) error
+	RecordCapture(payment models.CapturedPayment) (bool, error)
+	RecordRefund(refund models.Refund) (models.Refund, bool, error)
+	FinishCapture(paymentId string) error
+	FinishRefund(refundId string) error
+	ListenerDone(eventId string, listener string) (bool, error)
+	MarkListenerDone(eventId string, listener string) error
+	FetchRevenue(from time.Time, to time.Time, period string, venues []string) ([]models.RevenueRow, error)
 }
 
 type handlePaymentRepository struct {
@@ -36,3 +47,109 @@ func (r *handlePaymentRepository) SaveOrderDetails(details models.PaymentStatus)
 	utils.LogInfo("Successfully saved payment status for order ID %s", details.OrderCreationId)
 	return nil
 }
+
+func (r *handlePaymentRepository) RecordOrder(order models.PaymentOrder) error {
+	_, err := r.db.Exec("SELECT func_InsertPaymentOrder($1, $2, $3, $4)", order.OrderId, order.Purpose, order.Amount, order.Venue)
+
+	if err != nil {
+		utils.LogError("Failed to record order ID %s: %v", order.OrderId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+// RecordCapture returns false when the capture was already processed by an earlier webhook.
+func (r *handlePaymentRepository) RecordCapture(payment models.CapturedPayment) (bool, error) {
+	utils.LogInfo("Recording capture of payment ID %s", payment.PaymentId)
+
+	var recorded bool
+	err := r.db.QueryRow("SELECT func_RecordPaymentCapture($1, $2, $3, $4, $5, $6, $7)",
+		payment.PaymentId, payment.OrderId, payment.Amount, payment.Method,
+		payment.Purpose, payment.PlayerId, payment.GameId).Scan(&recorded)
+
+	if err != nil {
+		utils.LogError("Failed to record capture of payment ID %s: %v", payment.PaymentId, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return recorded, nil
+}
+
+// RecordRefund fills in the refunded payment details, false when the refund was already processed.
+func (r *handlePaymentRepository) RecordRefund(refund models.Refund) (models.Refund, bool, error) {
+	utils.LogInfo("Recording refund ID %s of payment ID %s", refund.RefundId, refund.PaymentId)
+
+	err := r.db.QueryRow("SELECT payment_amount, player_id, purpose FROM func_RecordRefund($1, $2, $3)",
+		refund.RefundId, refund.PaymentId, refund.Amount).Scan(&refund.PaymentAmount, &refund.PlayerId, &refund.Purpose)
+
+	if err == sql.ErrNoRows {
+		return refund, false, nil
+	} else if err != nil {
+		utils.LogError("Failed to record refund ID %s: %v", refund.RefundId, err)
+		return refund, false, fmt.Errorf("error executing function: %w", err)
+	}
+	return refund, true, nil
+}
+
+// FinishCapture marks the capture processed once every listener handled it.
+func (r *handlePaymentRepository) FinishCapture(paymentId string) error {
+	if _, err := r.db.Exec("SELECT func_FinishCapture($1)", paymentId); err != nil {
+		utils.LogError("Failed to mark capture of payment ID %s processed: %v", paymentId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *handlePaymentRepository) FinishRefund(refundId string) error {
+	if _, err := r.db.Exec("SELECT func_FinishRefund($1)", refundId); err != nil {
+		utils.LogError("Failed to mark refund ID %s processed: %v", refundId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *handlePaymentRepository) ListenerDone(eventId string, listener string) (bool, error) {
+	var done bool
+	if err := r.db.QueryRow("SELECT func_IsListenerDone($1, $2)", eventId, listener).Scan(&done); err != nil {
+		utils.LogError("Failed to check listener %s of payment event %s: %v", listener, eventId, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return done, nil
+}
+
+func (r *handlePaymentRepository) MarkListenerDone(eventId string, listener string) error {
+	if _, err := r.db.Exec("SELECT func_MarkListenerDone($1, $2)", eventId, listener); err != nil {
+		utils.LogError("Failed to mark listener %s of payment event %s done: %v", listener, eventId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+// FetchRevenue splits the payments of the range by period, purpose, method and venue. to is exclusive,
+// venues nil for every venue.
+func (r *handlePaymentRepository) FetchRevenue(from time.Time, to time.Time, period string, venues []string) ([]models.RevenueRow, error) {
+	rows, err := r.db.Query(`SELECT period, purpose, method, venue, payments, gross, refunded
+		FROM func_GetRevenue($1, $2, $3, $4)`, from, to, period, pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch revenue: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	revenue := []models.RevenueRow{}
+	for rows.Next() {
+		var row models.RevenueRow
+		var start time.Time
+		if err := rows.Scan(&start, &row.Purpose, &row.Method, &row.Venue, &row.Payments, &row.Gross, &row.Refunded); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		row.Period = start.Format("2006-01-02")
+		row.Net = row.Gross - row.Refunded
+		revenue = append(revenue, row)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return revenue, nil
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type InventoryRepository interface {
+	// ChangeStock returns sql.ErrNoRows when the product doesn't exist or the venue doesn't stock it,
+	// applied is false when the stock would go under what is held for orders.
+	ChangeStock(change models.StockChange, kind string, adminId int) (unitsAfter int, applied bool, err error)
+	ExpireReservations() (int, error)
+
+	// venues nil for every venue and the central stock.
+	FetchLowStock(venues []string, threshold int) ([]models.LowStock, error)
+	FetchMovements(productId *int, venues []string, from time.Time, to time.Time, limit int) ([]models.StockMovement, error)
+}
+
+type inventoryRepository struct {
+	db *sql.DB
+}
+
+func NewInventoryRepository(db *sql.DB) *inventoryRepository {
+	return &inventoryRepository{db: db}
+}
+
+func (r *inventoryRepository) ChangeStock(change models.StockChange, kind string, adminId int) (int, bool, error) {
+	var unitsAfter int
+	var applied bool
+	err := r.db.QueryRow("SELECT units_after, applied FROM func_ChangeStock($1, $2, $3, $4, $5, $6)",
+		change.ProductId, venueParam(change.Venue), change.Change, kind, optional(change.Reason), adminId).
+		Scan(&unitsAfter, &applied)
+
+	if err == sql.ErrNoRows {
+		return 0, false, err
+	} else if err != nil {
+		utils.LogError("Failed to change stock of product ID %d: %v", change.ProductId, err)
+		return 0, false, fmt.Errorf("error executing function: %w", err)
+	}
+	return unitsAfter, applied, nil
+}
+
+func (r *inventoryRepository) ExpireReservations() (int, error) {
+	var expired int
+	if err := r.db.QueryRow("SELECT func_ExpireStockReservations()").Scan(&expired); err != nil {
+		utils.LogError("Failed to expire stock reservations: %v", err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return expired, nil
+}
+
+func (r *inventoryRepository) FetchLowStock(venues []string, threshold int) ([]models.LowStock, error) {
+	rows, err := r.db.Query("SELECT product_id, title, venue, units, held FROM func_GetLowStock($1, $2)",
+		pq.Array(venues), threshold)
+	if err != nil {
+		utils.LogError("Failed to fetch low stock: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	stock := []models.LowStock{}
+	for rows.Next() {
+		var low models.LowStock
+		if err := rows.Scan(&low.ProductId, &low.Title, &low.Venue, &low.Units, &low.Held); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		stock = append(stock, low)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return stock, nil
+}
+
+func (r *inventoryRepository) FetchMovements(productId *int, venues []string, from time.Time, to time.Time, limit int) ([]models.StockMovement, error) {
+	rows, err := r.db.Query(`SELECT id, product_id, venue, change, units_after, kind, reason, order_id, admin_id, created_at
+		FROM func_GetStockMovements($1, $2, $3, $4, $5)`,
+		productId, pq.Array(venues), from.Format(analyticsDayLayout), to.Format(analyticsDayLayout), limit)
+	if err != nil {
+		utils.LogError("Failed to fetch stock movements: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	movements := []models.StockMovement{}
+	for rows.Next() {
+		var movement models.StockMovement
+		if err := rows.Scan(&movement.Id, &movement.ProductId, &movement.Venue, &movement.Change, &movement.UnitsAfter,
+			&movement.Kind, &movement.Reason, &movement.OrderId, &movement.AdminId, &movement.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		movements = append(movements, movement)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return movements, nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"database/sql"
+	"testing"
+)
+
+func TestReservationExpiryAndOversell(t *testing.T) {
+	tx := testTx(t)
+
+	var productId int
+	if err := tx.QueryRow(`SELECT id FROM "Products" LIMIT 1`).Scan(&productId); err != nil {
+		t.Skipf("no product to sell: %v", err)
+	}
+	// two units in the central stock and nothing else held on them.
+	mustExec(t, tx, `UPDATE "Products" SET units = 2 WHERE id = $1`, productId)
+	mustExec(t, tx, `UPDATE "StockReservations" SET status = 'released' WHERE "productId" = $1 AND status = 'held'`, productId)
+
+	checkout := func(razorpayOrderId string, quantity int) (sql.NullInt32, sql.NullInt32) {
+		t.Helper()
+		var orderId, shortProductId, unitsLeft sql.NullInt32
+		err := tx.QueryRow(`SELECT order_id, short_product_id, units_left FROM func_CreateShopOrder($1, NULL,
+			'guest-050', NULL, $2, ARRAY[$3::INT], ARRAY['test product'], ARRAY[1], ARRAY[$4::INT], 15)`,
+			razorpayOrderId, quantity*100, productId, quantity).Scan(&orderId, &shortProductId, &unitsLeft)
+		if err != nil {
+			t.Fatalf("checking out %s: %v", razorpayOrderId, err)
+		}
+		return orderId, shortProductId
+	}
+	pay := func(razorpayOrderId string) []bool {
+		t.Helper()
+		rows, err := tx.Query("SELECT oversold FROM func_PayShopOrder($1, $2)", razorpayOrderId, "pay_"+razorpayOrderId)
+		if err != nil {
+			t.Fatalf("paying %s: %v", razorpayOrderId, err)
+		}
+		defer rows.Close()
+		var oversold []bool
+		for rows.Next() {
+			var o bool
+			if err := rows.Scan(&o); err != nil {
+				t.Fatalf("scanning the sale of %s: %v", razorpayOrderId, err)
+			}
+			oversold = append(oversold, o)
+		}
+		return oversold
+	}
+
+	first, _ := checkout("order_test_050_a", 2)
+	if !first.Valid {
+		t.Fatal("first checkout wasn't created")
+	}
+	if _, short := checkout("order_test_050_b", 1); int(short.Int32) != productId {
+		t.Fatalf("second checkout got short product %v, want the held product %d", short, productId)
+	}
+
+	// the hold of the first checkout runs out and the job marks it.
+	mustExec(t, tx, `UPDATE "StockReservations" SET "expiresAt" = now() - interval '1 minute' WHERE "orderId" = $1`, first.Int32)
+	var expired int
+	if err := tx.QueryRow("SELECT func_ExpireStockReservations()").Scan(&expired); err != nil {
+		t.Fatalf("expiring the holds: %v", err)
+	}
+	if expired < 1 {
+		t.Fatalf("expired %d holds, want the one of the first checkout", expired)
+	}
+
+	if second, _ := checkout("order_test_050_c", 2); !second.Valid {
+		t.Fatal("checkout after the expiry wasn't created")
+	}
+	if oversold := pay("order_test_050_c"); len(oversold) != 1 || oversold[0] {
+		t.Fatalf("paying the held order gave %v, want one sale that isn't oversold", oversold)
+	}
+
+	// the first checkout is paid late, the units went to the other order.
+	if oversold := pay("order_test_050_a"); len(oversold) != 1 || !oversold[0] {
+		t.Fatalf("paying the expired order gave %v, want one oversold sale", oversold)
+	}
+	var status string
+	var units int
+	if err := tx.QueryRow(`SELECT status FROM "ShopOrders" WHERE id = $1`, first.Int32).Scan(&status); err != nil {
+		t.Fatalf("reading the order: %v", err)
+	}
+	if err := tx.QueryRow(`SELECT units FROM "Products" WHERE id = $1`, productId).Scan(&units); err != nil {
+		t.Fatalf("reading the stock: %v", err)
+	}
+	if status != "oversold" || units != 0 {
+		t.Errorf("late order is %s with %d units left, want oversold with 0", status, units)
+	}
+
+	if sold := pay("order_test_050_a"); len(sold) != 0 {
+		t.Errorf("paying the order again sold %d products, want none", len(sold))
+	}
+}
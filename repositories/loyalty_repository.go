@@ -0,0 +1,217 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type LoyaltyRepository interface {
+	FetchRules() ([]models.LoyaltyRule, error)
+	CreateRule(rule models.LoyaltyRule) (int, error)
+	DeleteRule(ruleId int) error
+
+	FetchRewards() ([]models.LoyaltyReward, error)
+	CreateReward(reward models.LoyaltyReward) (int, error)
+	DeactivateReward(rewardId int) error
+
+	AddEntry(playerId int, points int, kind string, paymentId *string, rewardId *int, expiresAt *time.Time) (int, error)
+	FetchPaymentPoints(paymentId string) (int, int, int, error) // player, earned and already reversed
+	FetchSummary(playerId int) (int, int, error)                // balance and points earned in the last year
+	FetchLedger(playerId int, limit int) ([]models.LoyaltyEntry, error)
+	Redeem(playerId int, rewardId int) (int, error) // 0 when the balance is too low
+	ExpirePoints() (int, error)                     // the number of earn entries that expired
+}
+
+type loyaltyRepository struct {
+	db *sql.DB
+}
+
+func NewLoyaltyRepository(db *sql.DB) *loyaltyRepository {
+	return &loyaltyRepository{db: db}
+}
+
+func (r *loyaltyRepository) FetchRules() ([]models.LoyaltyRule, error) {
+	rows, err := r.db.Query("SELECT id, game_id, weekday, multiplier, description FROM func_GetLoyaltyRules()")
+	if err != nil {
+		utils.LogError("Failed to fetch loyalty rules: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.LoyaltyRule
+	for rows.Next() {
+		var rule models.LoyaltyRule
+		if err := rows.Scan(&rule.Id, &rule.GameId, &rule.Weekday, &rule.Multiplier, &rule.Description); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *loyaltyRepository) CreateRule(rule models.LoyaltyRule) (int, error) {
+	var ruleId int
+	err := r.db.QueryRow("SELECT func_InsertLoyaltyRule($1, $2, $3, $4)",
+		rule.GameId, rule.Weekday, rule.Multiplier, rule.Description).Scan(&ruleId)
+
+	if err != nil {
+		utils.LogError("Failed to create loyalty rule: %v", err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return ruleId, nil
+}
+
+func (r *loyaltyRepository) DeleteRule(ruleId int) error {
+	if _, err := r.db.Exec("SELECT func_DeleteLoyaltyRule($1)", ruleId); err != nil {
+		utils.LogError("Failed to delete loyalty rule ID %d: %v", ruleId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyRepository) FetchRewards() ([]models.LoyaltyReward, error) {
+	rows, err := r.db.Query(`SELECT id, title, points, kind, game_id, name, price, play_time, levels, product_id
+		FROM func_GetLoyaltyRewards()`)
+	if err != nil {
+		utils.LogError("Failed to fetch loyalty rewards: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var rewards []models.LoyaltyReward
+	for rows.Next() {
+		var reward models.LoyaltyReward
+		err := rows.Scan(&reward.Id, &reward.Title, &reward.Points, &reward.Kind, &reward.GameId, &reward.Name,
+			&reward.Price, &reward.PlayTime, &reward.Levels, &reward.ProductId)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		rewards = append(rewards, reward)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return rewards, nil
+}
+
+func (r *loyaltyRepository) CreateReward(reward models.LoyaltyReward) (int, error) {
+	var rewardId int
+	err := r.db.QueryRow("SELECT func_InsertLoyaltyReward($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		reward.Title, reward.Points, reward.Kind, reward.GameId, reward.Name, reward.Price,
+		reward.PlayTime, reward.Levels, reward.ProductId).Scan(&rewardId)
+
+	if err != nil {
+		utils.LogError("Failed to create loyalty reward '%s': %v", reward.Title, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return rewardId, nil
+}
+
+func (r *loyaltyRepository) DeactivateReward(rewardId int) error {
+	if _, err := r.db.Exec("SELECT func_DeactivateLoyaltyReward($1)", rewardId); err != nil {
+		utils.LogError("Failed to deactivate loyalty reward ID %d: %v", rewardId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *loyaltyRepository) AddEntry(playerId int, points int, kind string, paymentId *string, rewardId *int, expiresAt *time.Time) (int, error) {
+	var entryId int
+	err := r.db.QueryRow("SELECT func_InsertLoyaltyEntry($1, $2, $3, $4, $5, $6)",
+		playerId, points, kind, paymentId, rewardId, expiresAt).Scan(&entryId)
+
+	if err != nil {
+		utils.LogError("Failed to add %d %s points for player ID %d: %v", points, kind, playerId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+
+	utils.LogInfo("Added %d %s points for player ID %d", points, kind, playerId)
+	return entryId, nil
+}
+
+func (r *loyaltyRepository) FetchPaymentPoints(paymentId string) (int, int, int, error) {
+	var playerId, earned, reversed int
+	err := r.db.QueryRow("SELECT player_id, earned, reversed FROM func_GetPaymentPoints($1)", paymentId).
+		Scan(&playerId, &earned, &reversed)
+
+	if err == sql.ErrNoRows {
+		return 0, 0, 0, nil
+	} else if err != nil {
+		utils.LogError("Failed to fetch points of payment ID %s: %v", paymentId, err)
+		return 0, 0, 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return playerId, earned, reversed, nil
+}
+
+func (r *loyaltyRepository) FetchSummary(playerId int) (int, int, error) {
+	var balance, yearlyEarned int
+	err := r.db.QueryRow("SELECT balance, yearly_earned FROM func_GetLoyaltySummary($1)", playerId).
+		Scan(&balance, &yearlyEarned)
+
+	if err != nil {
+		utils.LogError("Failed to fetch loyalty summary of player ID %d: %v", playerId, err)
+		return 0, 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return balance, yearlyEarned, nil
+}
+
+func (r *loyaltyRepository) FetchLedger(playerId int, limit int) ([]models.LoyaltyEntry, error) {
+	rows, err := r.db.Query(`SELECT id, points, kind, payment_id, reward_id, expires_at, created_at
+		FROM func_GetLoyaltyLedger($1, $2)`, playerId, limit)
+	if err != nil {
+		utils.LogError("Failed to fetch loyalty ledger of player ID %d: %v", playerId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.LoyaltyEntry{}
+	for rows.Next() {
+		var entry models.LoyaltyEntry
+		var expiresAt sql.NullTime
+		err := rows.Scan(&entry.Id, &entry.Points, &entry.Kind, &entry.PaymentId, &entry.RewardId, &expiresAt, &entry.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		if expiresAt.Valid {
+			entry.ExpiresAt = &expiresAt.Time
+		}
+		entries = append(entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (r *loyaltyRepository) Redeem(playerId int, rewardId int) (int, error) {
+	var entryId sql.NullInt64
+	err := r.db.QueryRow("SELECT func_RedeemLoyaltyPoints($1, $2)", playerId, rewardId).Scan(&entryId)
+
+	if err != nil {
+		utils.LogError("Failed to redeem reward ID %d for player ID %d: %v", rewardId, playerId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return int(entryId.Int64), nil
+}
+
+func (r *loyaltyRepository) ExpirePoints() (int, error) {
+	var lots int
+	if err := r.db.QueryRow("SELECT func_ExpireLoyaltyPoints()").Scan(&lots); err != nil {
+		utils.LogError("Failed to expire loyalty points: %v", err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return lots, nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type MachineRepository interface {
+	CreateMachine(machine models.Machine) error
+	FetchMachines(venues []string) ([]models.Machine, error) // nil venues for every venue
+	FetchMachine(machineId string) (models.Machine, error)
+}
+
+type machineRepository struct {
+	db *sql.DB
+}
+
+func NewMachineRepository(db *sql.DB) *machineRepository {
+	return &machineRepository{db: db}
+}
+
+func (r *machineRepository) CreateMachine(machine models.Machine) error {
+	utils.LogInfo("Registering machine %s at %s", machine.Id, machine.Venue)
+
+	_, err := r.db.Exec("SELECT func_InsertMachine($1, $2, $3, $4)",
+		machine.Id, machine.Name, machine.Venue, machine.SecretKey)
+	if err != nil {
+		utils.LogError("Failed to register machine %s: %v", machine.Id, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *machineRepository) FetchMachines(venues []string) ([]models.Machine, error) {
+	rows, err := r.db.Query("SELECT id, name, venue, secret_key, created_at FROM func_GetMachines($1)", pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch machines: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var machines []models.Machine
+	for rows.Next() {
+		var machine models.Machine
+		if err := rows.Scan(&machine.Id, &machine.Name, &machine.Venue, &machine.SecretKey, &machine.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		machines = append(machines, machine)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return machines, nil
+}
+
+func (r *machineRepository) FetchMachine(machineId string) (models.Machine, error) {
+	var machine models.Machine
+	err := r.db.QueryRow("SELECT id, name, venue, secret_key, created_at FROM func_GetMachines(NULL) WHERE id = $1", machineId).
+		Scan(&machine.Id, &machine.Name, &machine.Venue, &machine.SecretKey, &machine.CreatedAt)
+
+	if err != nil {
+		utils.LogError("Failed to fetch machine %s: %v", machineId, err)
+		if err == sql.ErrNoRows {
+			return machine, err
+		}
+		return machine, fmt.Errorf("error executing function: %w", err)
+	}
+	return machine, nil
+}
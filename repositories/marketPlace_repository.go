@@ -8,7 +8,8 @@ import (
 )
 
 type MarketPlaceRepository interface {
-	FetchProducts(productType models.ProductType) ([]models.Product, error)
+	FetchProducts(filter models.ProductFilter) (models.ProductPage, error) // empty venue gives the central stock
+	FetchProduct(productId int, venue string) (models.Product, error)
 }
 
 type marketPlaceRepository struct {
@@ -19,32 +20,56 @@ func NewMarketPlaceReposiory(db *sql.DB) *marketPlaceRepository {
 	return &marketPlaceRepository{db: db}
 }
 
-func (r *marketPlaceRepository) FetchProducts(productType models.ProductType) ([]models.Product, error) {
-	utils.LogInfo("Getting products for type: %d", productType)
+func (r *marketPlaceRepository) FetchProducts(filter models.ProductFilter) (models.ProductPage, error) {
+	utils.LogInfo("Getting products for type: %d at venue '%s'", filter.Type, filter.Venue)
+	page := models.ProductPage{Products: []models.Product{}}
 
-	rows, err := r.db.Query(`SELECT id, "productName", price, description, units FROM "Products" WHERE "productType" = $1`, productType)
+	rows, err := r.db.Query("SELECT id, product_name, price, description, units, total FROM func_GetProductsPage($1, $2, $3, $4, $5)",
+		filter.Type, venueParam(filter.Venue), filter.Sort, filter.Limit, filter.Offset)
 	if err != nil {
 		utils.LogError("some error occured while querying db: %v", err)
-		return nil, fmt.Errorf("error querying database: %v", err)
+		return page, fmt.Errorf("error querying database: %v", err)
 	}
 	defer rows.Close()
 
-	// Slice to hold the result set
-	var products []models.Product
-
 	// Iterate through the rows and map to Product struct
 	for rows.Next() {
 		var product models.Product
-		if err := rows.Scan(&product.ProductId, &product.Title, &product.Price, &product.Description, &product.TotalUnits); err != nil {
-			return nil, fmt.Errorf("error scanning row: %v", err)
+		if err := rows.Scan(&product.ProductId, &product.Title, &product.Price, &product.Description, &product.TotalUnits, &page.Total); err != nil {
+			return page, fmt.Errorf("error scanning row: %v", err)
 		}
-		products = append(products, product)
+		page.Products = append(page.Products, product)
 	}
 
 	// Check for errors after iteration
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error with row iteration: %v", err)
+		return page, fmt.Errorf("error with row iteration: %v", err)
+	}
+
+	// a page past the end has no row to carry the total.
+	if len(page.Products) == 0 && filter.Offset > 0 {
+		err := r.db.QueryRow("SELECT func_CountProducts($1, $2)", filter.Type, venueParam(filter.Venue)).Scan(&page.Total)
+		if err != nil {
+			utils.LogError("Failed to count products: %v", err)
+			return page, fmt.Errorf("error executing function: %w", err)
+		}
 	}
 
-	return products, nil
+	return page, nil
+}
+
+// FetchProduct returns sql.ErrNoRows when the product doesn't exist or the venue doesn't stock it.
+func (r *marketPlaceRepository) FetchProduct(productId int, venue string) (models.Product, error) {
+	var product models.Product
+	err := r.db.QueryRow("SELECT id, product_name, price, description, units, product_type FROM func_GetProduct($1, $2)",
+		productId, venueParam(venue)).Scan(&product.ProductId, &product.Title, &product.Price, &product.Description,
+		&product.TotalUnits, &product.Type)
+
+	if err == sql.ErrNoRows {
+		return product, err
+	} else if err != nil {
+		utils.LogError("Failed to fetch product ID %d: %v", productId, err)
+		return product, fmt.Errorf("error executing function: %w", err)
+	}
+	return product, nil
 }
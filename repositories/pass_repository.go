@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type PassRepository interface {
+	FetchPassProducts(includeInactive bool) ([]models.PassProduct, error)
+	CreatePassProduct(pass models.PassProduct) (int, error)
+	DeactivatePassProduct(passId int) error
+
+	CreateSubscription(subscriptionId string, playerId int, passId int) error
+	CancelSubscription(subscriptionId string, playerId int) (bool, error)
+	ReopenSubscription(subscriptionId string, playerId int) error
+
+	GrantPass(playerId int, passId int, paymentId string, subscriptionId *string) (int, error) // 0 when already granted
+	RevokePass(paymentId string) error
+	FetchPlayerPasses(playerId int) ([]models.PlayerPass, error)
+	SettleUsage(code string, minutes int) error
+}
+
+type passRepository struct {
+	db *sql.DB
+}
+
+func NewPassRepository(db *sql.DB) *passRepository {
+	return &passRepository{db: db}
+}
+
+func (r *passRepository) FetchPassProducts(includeInactive bool) ([]models.PassProduct, error) {
+	rows, err := r.db.Query(`SELECT id, name, description, price, minutes, weekdays_only, period_days, razorpay_plan_id, is_active
+		FROM func_GetPassProducts($1)`, includeInactive)
+	if err != nil {
+		utils.LogError("Failed to fetch pass products: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var passes []models.PassProduct
+	for rows.Next() {
+		var pass models.PassProduct
+		if err := rows.Scan(&pass.Id, &pass.Name, &pass.Description, &pass.Price, &pass.Minutes,
+			&pass.WeekdaysOnly, &pass.PeriodDays, &pass.RazorpayPlanId, &pass.IsActive); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		passes = append(passes, pass)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return passes, nil
+}
+
+func (r *passRepository) CreatePassProduct(pass models.PassProduct) (int, error) {
+	var passId int
+	err := r.db.QueryRow("SELECT func_InsertPassProduct($1, $2, $3, $4, $5, $6, $7)",
+		pass.Name, pass.Description, pass.Price, pass.Minutes, pass.WeekdaysOnly, pass.PeriodDays,
+		pass.RazorpayPlanId).Scan(&passId)
+
+	if err != nil {
+		utils.LogError("Failed to create pass product %s: %v", pass.Name, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return passId, nil
+}
+
+func (r *passRepository) DeactivatePassProduct(passId int) error {
+	if _, err := r.db.Exec("SELECT func_DeactivatePassProduct($1)", passId); err != nil {
+		utils.LogError("Failed to deactivate pass product ID %d: %v", passId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *passRepository) CreateSubscription(subscriptionId string, playerId int, passId int) error {
+	if _, err := r.db.Exec("SELECT func_InsertPassSubscription($1, $2, $3)", subscriptionId, playerId, passId); err != nil {
+		utils.LogError("Failed to save subscription %s of player ID %d: %v", subscriptionId, playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *passRepository) CancelSubscription(subscriptionId string, playerId int) (bool, error) {
+	var cancelled bool
+	err := r.db.QueryRow("SELECT func_CancelPassSubscription($1, $2)", subscriptionId, playerId).Scan(&cancelled)
+
+	if err != nil {
+		utils.LogError("Failed to cancel subscription %s of player ID %d: %v", subscriptionId, playerId, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return cancelled, nil
+}
+
+func (r *passRepository) ReopenSubscription(subscriptionId string, playerId int) error {
+	if _, err := r.db.Exec("SELECT func_ReopenPassSubscription($1, $2)", subscriptionId, playerId); err != nil {
+		utils.LogError("Failed to reopen subscription %s of player ID %d: %v", subscriptionId, playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *passRepository) GrantPass(playerId int, passId int, paymentId string, subscriptionId *string) (int, error) {
+	var playerPassId sql.NullInt64
+	err := r.db.QueryRow("SELECT func_GrantPass($1, $2, $3, $4)",
+		playerId, passId, paymentId, subscriptionId).Scan(&playerPassId)
+
+	if err != nil {
+		utils.LogError("Failed to grant pass ID %d to player ID %d: %v", passId, playerId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return int(playerPassId.Int64), nil
+}
+
+func (r *passRepository) RevokePass(paymentId string) error {
+	if _, err := r.db.Exec("SELECT func_RevokePass($1)", paymentId); err != nil {
+		utils.LogError("Failed to revoke pass of payment ID %s: %v", paymentId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *passRepository) FetchPlayerPasses(playerId int) ([]models.PlayerPass, error) {
+	rows, err := r.db.Query(`SELECT id, pass_id, name, minutes, minutes_used, weekdays_only, starts_at, ends_at, subscription_id
+		FROM func_GetPlayerPasses($1)`, playerId)
+	if err != nil {
+		utils.LogError("Failed to fetch passes of player ID %d: %v", playerId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var passes []models.PlayerPass
+	for rows.Next() {
+		var pass models.PlayerPass
+		if err := rows.Scan(&pass.Id, &pass.PassId, &pass.Name, &pass.Minutes, &pass.MinutesUsed,
+			&pass.WeekdaysOnly, &pass.StartsAt, &pass.EndsAt, &pass.SubscriptionId); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		passes = append(passes, pass)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return passes, nil
+}
+
+func (r *passRepository) SettleUsage(code string, minutes int) error {
+	if _, err := r.db.Exec("SELECT func_SettlePassUsage($1, $2)", code, minutes); err != nil {
+		utils.LogError("Failed to settle pass usage of code %s: %v", code, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
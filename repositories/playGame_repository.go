@@ -5,14 +5,30 @@ import (
 	"GameWala-Arcade/models"
 	"GameWala-Arcade/utils"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 type PlayGameRepository interface {
 	SaveGameStatus(status models.GameStatus) (int, error)
-	GetGames() ([]models.GameResponse, error)
-	FetchPrices() (models.PriceMap, error)
+	GetGames(venue string) ([]models.GameResponse, error) // empty venue gives the whole catalogue
+	FetchPrices(venue string) (models.PriceMap, error)
+	SearchGames(filter models.GameFilter, fromPrices map[uint16]uint16, cursorValue *int64, cursorId *int) ([]models.GameListing, error)
+	SetGameSystem(gameId uint16, system string) error
+	SetGameMetadata(gameId uint16, metadata models.GameMetadata) error
+	FetchGenres(venue string) ([]models.Genre, error)
 	CheckGameCode(code string) (models.GameDetails, error)
+	FetchCodeVenue(code string, machineId string) (*string, *string, error)
+	VenueActive(venue string) (bool, error)
+	FetchCodeRom(code string, details *models.GameDetails) error
+	CodeExists(code string) (bool, error)
+	FetchIssuedCodes() ([]string, error)
+	FetchCodeExpiry(code string) (*time.Time, bool, error)
+	RecordCodeMachine(code string, machineId string) error
 	ValidateTimeAndPrice(gameId uint16, price uint16, playTime *uint16) error
 	ValidateLevelsAndPrice(gameId uint16, price uint16, levels *uint8) error
 }
@@ -28,15 +44,15 @@ func NewPlayGameReposiory(db *sql.DB) *playGameRepository {
 func (r *playGameRepository) SaveGameStatus(status models.GameStatus) (int, error) {
 	utils.LogInfo("Saving game status to database for game ID %d", status.GameId)
 
-	// Prepare the call to the stored procedure
-	stmt, err := r.db.Prepare("SELECT func_InsertGameStatus($1, $2, $3, $4, $5, $6, $7, $8)")
+	tx, err := r.db.Begin()
 	if err != nil {
-		utils.LogError("Failed to prepare save game status statement: %v", err)
-		return 0, fmt.Errorf("error preparing statement: %w", err)
+		utils.LogError("Failed to begin save game status transaction: %v", err)
+		return 0, fmt.Errorf("error starting transaction: %w", err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.Exec(status.GameId, status.Name, status.IsPlayed, status.Price,
+	_, err = tx.Exec("SELECT func_InsertGameStatus($1, $2, $3, $4, $5, $6, $7, $8)",
+		status.GameId, status.Name, status.IsPlayed, status.Price,
 		status.PlayTime, status.Levels, status.PaymentReference, status.Code)
 
 	if err != nil {
@@ -44,6 +60,73 @@ func (r *playGameRepository) SaveGameStatus(status models.GameStatus) (int, erro
 		return 0, fmt.Errorf("error executing function: %w", err)
 	}
 
+	if _, err = tx.Exec("SELECT func_SetCodeExpiry($1, $2)", status.Code, status.ExpiresAt); err != nil {
+		utils.LogError("Failed to set expiry of code %s: %v", status.Code, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+
+	if status.Venue != "" {
+		if _, err = tx.Exec("SELECT func_SetCodeVenue($1, $2)", status.Code, status.Venue); err != nil {
+			utils.LogError("Failed to tie code %s to venue %s: %v", status.Code, status.Venue, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+	}
+
+	if status.PlayerId != nil {
+		if _, err = tx.Exec("SELECT func_AttachCodeToPlayer($1, $2)", status.Code, *status.PlayerId); err != nil {
+			utils.LogError("Failed to attach code %s to player ID %d: %v", status.Code, *status.PlayerId, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+	}
+
+	if status.Coupon != "" {
+		var reason string
+		err = tx.QueryRow("SELECT func_RedeemCoupon($1, $2, $3, $4)",
+			status.Coupon, status.PlayerId, status.PaymentReference, status.Discount).Scan(&reason)
+		if err != nil {
+			utils.LogError("Failed to redeem coupon %s for code %s: %v", status.Coupon, status.Code, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+		if reason != "" {
+			utils.LogError("Coupon %s refused for game ID %d: %s", status.Coupon, status.GameId, reason)
+			return 5, errors.New(reason) // 5 means, the coupon can't be used
+		}
+	}
+
+	// the pass is locked until the commit, two codes can't both take the last minutes.
+	if status.PassId != nil && status.PlayerId != nil {
+		var reason string
+		err = tx.QueryRow("SELECT func_UsePass($1, $2, $3, $4)",
+			*status.PassId, *status.PlayerId, status.Code, status.PassMinutes).Scan(&reason)
+		if err != nil {
+			utils.LogError("Failed to use pass ID %d for code %s: %v", *status.PassId, status.Code, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+		if reason != "" {
+			utils.LogError("Pass ID %d refused for game ID %d: %s", *status.PassId, status.GameId, reason)
+			return 4, errors.New(reason) // 4 means, the pass doesn't cover this game
+		}
+	}
+
+	if status.Wallet && status.PlayerId != nil {
+		var reason string
+		err = tx.QueryRow("SELECT func_SpendWallet($1, $2, $3, $4)",
+			*status.PlayerId, status.Code, status.PaymentReference, status.Price).Scan(&reason)
+		if err != nil {
+			utils.LogError("Failed to pay code %s with the wallet: %v", status.Code, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+		if reason != "" {
+			utils.LogError("Wallet of player ID %d refused for game ID %d: %s", *status.PlayerId, status.GameId, reason)
+			return 4, errors.New(reason) // 4 means, the wallet can't pay for it
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		utils.LogError("Failed to commit game status for game ID %d: %v", status.GameId, err)
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
 	utils.LogInfo("Successfully saved game status for game ID %d", status.GameId)
 	return 1, nil
 }
@@ -98,10 +181,10 @@ func (r *playGameRepository) ValidateLevelsAndPrice(gameId uint16, price uint16,
 	return nil
 }
 
-func (r *playGameRepository) GetGames() ([]models.GameResponse, error) {
-	utils.LogInfo("Fetching all games from database")
+func (r *playGameRepository) GetGames(venue string) ([]models.GameResponse, error) {
+	utils.LogInfo("Fetching all games from database for venue '%s'", venue)
 
-	rows, err := r.db.Query("Select * from func_GetGamesForUsers()")
+	rows, err := r.db.Query("Select * from func_GetGamesForUsers($1)", venueParam(venue))
 
 	if err != nil {
 		utils.LogError("Failed to fetch games from database: %v", err)
@@ -124,11 +207,119 @@ func (r *playGameRepository) GetGames() ([]models.GameResponse, error) {
 	return games, nil
 }
 
-func (r *playGameRepository) FetchPrices() (models.PriceMap, error) {
+// SearchGames gives up to filter.Limit games after the cursor, nil cursor for the first page.
+// fromPrices are the cheapest tiers of the games after the pricing rules, they are filtered and sorted on.
+func (r *playGameRepository) SearchGames(filter models.GameFilter, fromPrices map[uint16]uint16, cursorValue *int64, cursorId *int) ([]models.GameListing, error) {
+	utils.LogInfo("Searching games with filter %+v", filter)
+
+	gameIds := make([]int64, 0, len(fromPrices))
+	prices := make([]int64, 0, len(fromPrices))
+	for gameId, price := range fromPrices {
+		gameIds = append(gameIds, int64(gameId))
+		prices = append(prices, int64(price))
+	}
+
+	rows, err := r.db.Query(`SELECT game_id, name, thumbnail, system, from_price, plays, description, genres, tags,
+		min_players, max_players, release_year, controls, age_rating
+		FROM func_SearchGames($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)`,
+		venueParam(filter.Venue), optional(filter.Query), optional(filter.System), optional(filter.Mode),
+		filter.MinPrice, filter.MaxPrice, optional(filter.Genre), optional(filter.Tag), filter.Players,
+		filter.Sort, cursorValue, cursorId, filter.Limit, pq.Array(gameIds), pq.Array(prices))
+	if err != nil {
+		utils.LogError("Failed to search games: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var games []models.GameListing
+	for rows.Next() {
+		var game models.GameListing
+		var metadata models.GameMetadata
+		var controls []byte
+		if err := rows.Scan(&game.Game.GameId, &game.Game.Name, &game.Game.Thumbnail, &game.Game.System,
+			&game.FromPrice, &game.Plays, &metadata.Description, pq.Array(&metadata.Genres), pq.Array(&metadata.Tags),
+			&metadata.MinPlayers, &metadata.MaxPlayers, &metadata.ReleaseYear, &controls, &metadata.AgeRating); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		if controls != nil {
+			if err := json.Unmarshal(controls, &metadata.Controls); err != nil {
+				return nil, fmt.Errorf("error reading controls of game ID %d: %w", game.Game.GameId, err)
+			}
+		}
+		if metadata.Description != nil || len(metadata.Genres) > 0 || len(metadata.Tags) > 0 ||
+			metadata.MinPlayers != nil || metadata.ReleaseYear != nil || controls != nil || metadata.AgeRating != nil {
+			game.Game.Metadata = &metadata
+		}
+		games = append(games, game)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return games, nil
+}
+
+func (r *playGameRepository) SetGameSystem(gameId uint16, system string) error {
+	if _, err := r.db.Exec("SELECT func_SetGameSystem($1, $2)", gameId, system); err != nil {
+		utils.LogError("Failed to set system of game ID %d: %v", gameId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *playGameRepository) SetGameMetadata(gameId uint16, metadata models.GameMetadata) error {
+	// sent as text, lib/pq would send bytes as bytea.
+	var controls *string
+	if metadata.Controls != nil {
+		encoded, err := json.Marshal(metadata.Controls)
+		if err != nil {
+			return fmt.Errorf("error encoding controls: %w", err)
+		}
+		text := string(encoded)
+		controls = &text
+	}
+
+	_, err := r.db.Exec("SELECT func_UpsertGameMetadata($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		gameId, metadata.Description, pq.Array(metadata.Genres), pq.Array(metadata.Tags), metadata.MinPlayers,
+		metadata.MaxPlayers, metadata.ReleaseYear, controls, metadata.AgeRating)
+	if err != nil {
+		utils.LogError("Failed to save metadata of game ID %d: %v", gameId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *playGameRepository) FetchGenres(venue string) ([]models.Genre, error) {
+	rows, err := r.db.Query("SELECT genre, games FROM func_GetGenres($1)", venueParam(venue))
+	if err != nil {
+		utils.LogError("Failed to fetch genres: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var genres []models.Genre
+	for rows.Next() {
+		var genre models.Genre
+		if err := rows.Scan(&genre.Genre, &genre.Games); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		genres = append(genres, genre)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return genres, nil
+}
+
+func (r *playGameRepository) FetchPrices(venue string) (models.PriceMap, error) {
 
 	var price models.PriceMap
 
-	rows, err := r.db.Query("SELECT * FROM func_GetGamesPrices()")
+	rows, err := r.db.Query("SELECT * FROM func_GetGamesPrices($1)", venueParam(venue))
 
 	if err != nil {
 		return price, fmt.Errorf("query error: %w", err)
@@ -188,3 +379,99 @@ func (r *playGameRepository) CheckGameCode(code string) (models.GameDetails, err
 
 	return gamedetails, err //if true, then need to implement redis queue to make it false after the time, if timebounded.
 }
+
+// FetchCodeVenue gives the venue the code was bought for and the venue of the machine, nil when unknown.
+func (r *playGameRepository) FetchCodeVenue(code string, machineId string) (*string, *string, error) {
+	var codeVenue, machineVenue *string
+	err := r.db.QueryRow("SELECT code_venue, machine_venue FROM func_GetCodeVenue($1, $2)", code, machineId).
+		Scan(&codeVenue, &machineVenue)
+
+	if err != nil {
+		utils.LogError("Failed to fetch venue of code %s: %v", code, err)
+		return nil, nil, fmt.Errorf("error executing function: %w", err)
+	}
+	return codeVenue, machineVenue, nil
+}
+
+// FetchCodeRom fills the current ROM of the game of the code, left nil when the game has none yet.
+func (r *playGameRepository) FetchCodeRom(code string, details *models.GameDetails) error {
+	err := r.db.QueryRow("SELECT rom_id, sha256, version, core FROM func_GetCodeRom($1)", code).
+		Scan(&details.RomId, &details.RomSha256, &details.RomVersion, &details.Core)
+
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("Failed to fetch ROM of code %s: %v", code, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *playGameRepository) VenueActive(venue string) (bool, error) {
+	var active bool
+	if err := r.db.QueryRow("SELECT func_IsVenueActive($1)", venue).Scan(&active); err != nil {
+		utils.LogError("Failed to check venue %s: %v", venue, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return active, nil
+}
+
+func (r *playGameRepository) CodeExists(code string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT func_GameCodeExists($1)", code).Scan(&exists)
+
+	if err != nil {
+		utils.LogError("Failed to check if code %s already exists: %v", code, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+
+	return exists, nil
+}
+
+func (r *playGameRepository) FetchIssuedCodes() ([]string, error) {
+	utils.LogInfo("Fetching all issued codes from database")
+
+	rows, err := r.db.Query("SELECT code FROM func_GetIssuedCodes()")
+	if err != nil {
+		utils.LogError("Failed to fetch issued codes: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []string
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (r *playGameRepository) FetchCodeExpiry(code string) (*time.Time, bool, error) {
+	var expiresAt sql.NullTime
+	var isExpired bool
+	err := r.db.QueryRow("SELECT expires_at, is_expired FROM func_GetCodeExpiry($1)", code).Scan(&expiresAt, &isExpired)
+
+	if err != nil {
+		utils.LogError("Failed to fetch expiry for code %s: %v", code, err)
+		return nil, false, fmt.Errorf("error executing function: %w", err)
+	}
+
+	if !expiresAt.Valid {
+		return nil, isExpired, nil
+	}
+	return &expiresAt.Time, isExpired, nil
+}
+
+func (r *playGameRepository) RecordCodeMachine(code string, machineId string) error {
+	if _, err := r.db.Exec("SELECT func_RecordCodeMachine($1, $2)", code, machineId); err != nil {
+		utils.LogError("Failed to record machine %s for code %s: %v", machineId, code, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type PlayerRepository interface {
+	UpsertPlayer(phone string) (int, error)
+	FetchPlayer(playerId int) (models.Player, error)
+	UpdateName(playerId int, name string) error
+	FetchPlayerCodes(playerId int) ([]models.PlayerCode, error)
+	RecordDevice(playerId int, deviceId string) error
+	FetchWallet(playerId int, limit int) (models.Wallet, error)
+}
+
+type playerRepository struct {
+	db *sql.DB
+}
+
+func NewPlayerRepository(db *sql.DB) *playerRepository {
+	return &playerRepository{db: db}
+}
+
+func (r *playerRepository) UpsertPlayer(phone string) (int, error) {
+	var playerId int
+	err := r.db.QueryRow("SELECT func_UpsertPlayer($1)", phone).Scan(&playerId)
+
+	if err != nil {
+		utils.LogError("Failed to upsert player %s: %v", phone, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+
+	utils.LogInfo("Player %s logged in with ID %d", phone, playerId)
+	return playerId, nil
+}
+
+func (r *playerRepository) FetchPlayer(playerId int) (models.Player, error) {
+	var player models.Player
+	err := r.db.QueryRow("SELECT id, phone, name, created_at FROM func_GetPlayer($1)", playerId).
+		Scan(&player.Id, &player.Phone, &player.Name, &player.CreatedAt)
+
+	if err != nil {
+		utils.LogError("Failed to fetch player ID %d: %v", playerId, err)
+		if err == sql.ErrNoRows {
+			return player, err
+		}
+		return player, fmt.Errorf("error executing function: %w", err)
+	}
+
+	return player, nil
+}
+
+func (r *playerRepository) UpdateName(playerId int, name string) error {
+	if _, err := r.db.Exec("SELECT func_UpdatePlayerName($1, $2)", playerId, name); err != nil {
+		utils.LogError("Failed to update name of player ID %d: %v", playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *playerRepository) FetchPlayerCodes(playerId int) ([]models.PlayerCode, error) {
+	utils.LogInfo("Fetching codes of player ID %d", playerId)
+
+	rows, err := r.db.Query(`SELECT code, game_id, name, play_time, levels, price, purchased_at, expires_at, status, machine_id
+		FROM func_GetPlayerCodes($1)`, playerId)
+	if err != nil {
+		utils.LogError("Failed to fetch codes of player ID %d: %v", playerId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []models.PlayerCode
+	for rows.Next() {
+		var code models.PlayerCode
+		var expiresAt sql.NullTime
+		err := rows.Scan(&code.Code, &code.GameId, &code.Name, &code.PlayTime, &code.Levels, &code.Price,
+			&code.PurchasedAt, &expiresAt, &code.Status, &code.MachineId)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+
+		if expiresAt.Valid {
+			code.ExpiresAt = &expiresAt.Time
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (r *playerRepository) RecordDevice(playerId int, deviceId string) error {
+	if _, err := r.db.Exec("SELECT func_RecordPlayerDevice($1, $2)", playerId, deviceId); err != nil {
+		utils.LogError("Failed to record device of player ID %d: %v", playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *playerRepository) FetchWallet(playerId int, limit int) (models.Wallet, error) {
+	wallet := models.Wallet{Entries: []models.WalletEntry{}}
+	if err := r.db.QueryRow("SELECT func_GetWalletBalance($1)", playerId).Scan(&wallet.Balance); err != nil {
+		utils.LogError("Failed to fetch wallet balance of player ID %d: %v", playerId, err)
+		return wallet, fmt.Errorf("error executing function: %w", err)
+	}
+
+	rows, err := r.db.Query("SELECT id, amount, reason, code, payment_id, created_at FROM func_GetWalletLedger($1, $2)",
+		playerId, limit)
+	if err != nil {
+		utils.LogError("Failed to fetch wallet ledger of player ID %d: %v", playerId, err)
+		return wallet, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry models.WalletEntry
+		if err := rows.Scan(&entry.Id, &entry.Amount, &entry.Reason, &entry.Code, &entry.PaymentId, &entry.CreatedAt); err != nil {
+			return wallet, fmt.Errorf("error scanning row: %w", err)
+		}
+		wallet.Entries = append(wallet.Entries, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return wallet, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return wallet, nil
+}
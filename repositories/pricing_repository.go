@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type PricingRepository interface {
+	FetchRules() ([]models.PricingRule, error)
+	CreateRule(rule models.PricingRule) (int, error)
+	DeleteRule(ruleId int) error
+	FetchOrder(orderId string) (models.PaymentOrder, error) // sql.ErrNoRows when it wasn't created here
+}
+
+type pricingRepository struct {
+	db *sql.DB
+}
+
+func NewPricingRepository(db *sql.DB) *pricingRepository {
+	return &pricingRepository{db: db}
+}
+
+func (r *pricingRepository) FetchRules() ([]models.PricingRule, error) {
+	rows, err := r.db.Query(`SELECT id, weekday, start_time, end_time, venue, game_id, tier_label, multiplier,
+		override_price, description FROM func_GetPricingRules()`)
+	if err != nil {
+		utils.LogError("Failed to fetch pricing rules: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []models.PricingRule
+	for rows.Next() {
+		var rule models.PricingRule
+		if err := rows.Scan(&rule.Id, &rule.Weekday, &rule.StartTime, &rule.EndTime, &rule.Venue, &rule.GameId,
+			&rule.TierLabel, &rule.Multiplier, &rule.OverridePrice, &rule.Description); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return rules, nil
+}
+
+func (r *pricingRepository) CreateRule(rule models.PricingRule) (int, error) {
+	var ruleId int
+	err := r.db.QueryRow("SELECT func_InsertPricingRule($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		rule.Weekday, rule.StartTime, rule.EndTime, rule.Venue, rule.GameId, rule.TierLabel, rule.Multiplier,
+		rule.OverridePrice, rule.Description).Scan(&ruleId)
+
+	if err != nil {
+		utils.LogError("Failed to create pricing rule: %v", err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return ruleId, nil
+}
+
+func (r *pricingRepository) DeleteRule(ruleId int) error {
+	if _, err := r.db.Exec("SELECT func_DeletePricingRule($1)", ruleId); err != nil {
+		utils.LogError("Failed to delete pricing rule ID %d: %v", ruleId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *pricingRepository) FetchOrder(orderId string) (models.PaymentOrder, error) {
+	order := models.PaymentOrder{OrderId: orderId}
+	err := r.db.QueryRow("SELECT created_at, venue FROM func_GetPaymentOrder($1)", orderId).Scan(&order.CreatedAt, &order.Venue)
+
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("Failed to fetch payment order %s: %v", orderId, err)
+		return order, fmt.Errorf("error executing function: %w", err)
+	}
+	return order, err
+}
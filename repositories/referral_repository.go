@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type ReferralRepository interface {
+	SetReferralCode(playerId int, code string) (string, error)
+	FetchStats(playerId int) (models.ReferralStats, error)
+	ApplyReferral(playerId int, code string, deviceId string, maxReferrals int, windowDays int) (string, error) // reason when refused
+	ConvertReferral(playerId int, paymentId string) (int, bool, error)                                          // referrer, 0 without a pending referral. false while nothing was bought with the payment
+	AddWalletCredit(playerId int, reference string, amount int, reason string) error
+	FetchReport(from time.Time, to time.Time) ([]models.ReferrerReport, error)
+}
+
+type referralRepository struct {
+	db *sql.DB
+}
+
+func NewReferralRepository(db *sql.DB) *referralRepository {
+	return &referralRepository{db: db}
+}
+
+func (r *referralRepository) SetReferralCode(playerId int, code string) (string, error) {
+	var referralCode string
+	err := r.db.QueryRow("SELECT func_SetReferralCode($1, $2)", playerId, code).Scan(&referralCode)
+
+	if err != nil {
+		utils.LogError("Failed to set referral code of player ID %d: %v", playerId, err)
+		return "", fmt.Errorf("error executing function: %w", err)
+	}
+	return referralCode, nil
+}
+
+func (r *referralRepository) FetchStats(playerId int) (models.ReferralStats, error) {
+	var stats models.ReferralStats
+	var code sql.NullString
+	err := r.db.QueryRow("SELECT referral_code, referred, converted FROM func_GetReferralStats($1)", playerId).
+		Scan(&code, &stats.Referred, &stats.Converted)
+
+	if err != nil {
+		utils.LogError("Failed to fetch referral stats of player ID %d: %v", playerId, err)
+		return stats, fmt.Errorf("error executing function: %w", err)
+	}
+
+	stats.ReferralCode = code.String
+	return stats, nil
+}
+
+func (r *referralRepository) ApplyReferral(playerId int, code string, deviceId string, maxReferrals int,
+	windowDays int) (string, error) {
+	var reason sql.NullString
+	err := r.db.QueryRow("SELECT func_ApplyReferral($1, $2, $3, $4, $5)", playerId, code, deviceId, maxReferrals,
+		windowDays).Scan(&reason)
+
+	if err != nil {
+		utils.LogError("Failed to apply referral code %s for player ID %d: %v", code, playerId, err)
+		return "", fmt.Errorf("error executing function: %w", err)
+	}
+	return reason.String, nil
+}
+
+func (r *referralRepository) ConvertReferral(playerId int, paymentId string) (int, bool, error) {
+	var referrerId int
+	var converted bool
+	err := r.db.QueryRow("SELECT referrer_id, has_purchase FROM func_ConvertReferral($1, $2)", playerId, paymentId).
+		Scan(&referrerId, &converted)
+
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	} else if err != nil {
+		utils.LogError("Failed to convert referral of player ID %d: %v", playerId, err)
+		return 0, false, fmt.Errorf("error executing function: %w", err)
+	}
+	return referrerId, converted, nil
+}
+
+func (r *referralRepository) AddWalletCredit(playerId int, reference string, amount int, reason string) error {
+	_, err := r.db.Exec("SELECT func_InsertWalletCredit($1, $2, $3, $4)", playerId, reference, amount, reason)
+
+	if err != nil {
+		utils.LogError("Failed to credit %d to player ID %d: %v", amount, playerId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *referralRepository) FetchReport(from time.Time, to time.Time) ([]models.ReferrerReport, error) {
+	rows, err := r.db.Query("SELECT referrer_id, phone, referred, converted, revenue FROM func_GetReferralReport($1, $2)", from, to)
+	if err != nil {
+		utils.LogError("Failed to fetch referral report: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	referrers := []models.ReferrerReport{}
+	for rows.Next() {
+		var referrer models.ReferrerReport
+		err := rows.Scan(&referrer.ReferrerId, &referrer.Phone, &referrer.Referred, &referrer.Converted, &referrer.Revenue)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		referrers = append(referrers, referrer)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return referrers, nil
+}
@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type RomRepository interface {
+	FetchSystems() ([]models.EmulatorSystem, error)
+	SaveSystem(system models.EmulatorSystem) error
+
+	CreateRom(upload models.RomUpload, objectKey string, sha256 string, size int64, adminId int) (int, error)
+	SetCurrentRom(romId int) (bool, error)
+	FetchRoms(gameId *uint16, romId *int) ([]models.Rom, error)
+	RecordInstall(machineId string, romId int) error
+}
+
+type romRepository struct {
+	db *sql.DB
+}
+
+func NewRomRepository(db *sql.DB) *romRepository {
+	return &romRepository{db: db}
+}
+
+func (r *romRepository) FetchSystems() ([]models.EmulatorSystem, error) {
+	rows, err := r.db.Query("SELECT id, name, core, bios, is_active, created_at FROM func_GetEmulatorSystems()")
+	if err != nil {
+		utils.LogError("Failed to fetch emulator systems: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var systems []models.EmulatorSystem
+	for rows.Next() {
+		var system models.EmulatorSystem
+		var bios []byte
+		if err := rows.Scan(&system.Id, &system.Name, &system.Core, &bios, &system.IsActive, &system.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		if err := json.Unmarshal(bios, &system.Bios); err != nil {
+			return nil, fmt.Errorf("error reading bios of system %s: %w", system.Id, err)
+		}
+		systems = append(systems, system)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return systems, nil
+}
+
+func (r *romRepository) SaveSystem(system models.EmulatorSystem) error {
+	bios, err := json.Marshal(system.Bios)
+	if err != nil {
+		return fmt.Errorf("error encoding bios: %w", err)
+	}
+
+	// the bios goes as text, lib/pq would send bytes as bytea.
+	_, err = r.db.Exec("SELECT func_UpsertEmulatorSystem($1, $2, $3, $4, $5)",
+		system.Id, system.Name, system.Core, string(bios), system.IsActive)
+	if err != nil {
+		utils.LogError("Failed to save emulator system %s: %v", system.Id, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *romRepository) CreateRom(upload models.RomUpload, objectKey string, sha256 string, size int64, adminId int) (int, error) {
+	utils.LogInfo("Recording ROM %s version %s of game ID %d", upload.FileName, upload.Version, upload.GameId)
+
+	var romId int
+	err := r.db.QueryRow("SELECT func_InsertRom($1, $2, $3, $4, $5, $6, $7, $8)",
+		upload.GameId, upload.SystemId, upload.FileName, objectKey, sha256, size, upload.Version, adminId).Scan(&romId)
+
+	if err != nil {
+		utils.LogError("Failed to record ROM of game ID %d: %v", upload.GameId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return romId, nil
+}
+
+func (r *romRepository) SetCurrentRom(romId int) (bool, error) {
+	var found bool
+	if err := r.db.QueryRow("SELECT func_SetCurrentRom($1)", romId).Scan(&found); err != nil {
+		utils.LogError("Failed to make ROM ID %d current: %v", romId, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return found, nil
+}
+
+func (r *romRepository) FetchRoms(gameId *uint16, romId *int) ([]models.Rom, error) {
+	rows, err := r.db.Query(`SELECT id, game_id, system_id, file_name, object_key, sha256, size_bytes, version,
+		is_current, uploaded_at, machines FROM func_GetRoms($1, $2)`, gameId, romId)
+	if err != nil {
+		utils.LogError("Failed to fetch ROMs: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var roms []models.Rom
+	for rows.Next() {
+		var rom models.Rom
+		if err := rows.Scan(&rom.Id, &rom.GameId, &rom.SystemId, &rom.FileName, &rom.ObjectKey, &rom.Sha256,
+			&rom.SizeBytes, &rom.Version, &rom.IsCurrent, &rom.UploadedAt, pq.Array(&rom.Machines)); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		roms = append(roms, rom)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return roms, nil
+}
+
+func (r *romRepository) RecordInstall(machineId string, romId int) error {
+	if _, err := r.db.Exec("SELECT func_RecordRomInstall($1, $2)", machineId, romId); err != nil {
+		utils.LogError("Failed to record ROM ID %d on machine %s: %v", romId, machineId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type ScoreRepository interface {
+	SaveScore(submission models.ScoreSubmission, machineId string) (models.Score, error)
+	FetchScores(gameId *uint16, limit int, venues []string) ([]models.Score, error) // nil venues for every venue
+	RemoveScore(scoreId int, venues []string) (models.Score, error)                 // sql.ErrNoRows outside the venues
+	FetchBoardScores() ([]models.Score, error)
+}
+
+type scoreRepository struct {
+	db *sql.DB
+}
+
+func NewScoreRepository(db *sql.DB) *scoreRepository {
+	return &scoreRepository{db: db}
+}
+
+const scoreColumns = "id, game_id, code, machine_id, venue, player_id, name, score, is_removed, created_at"
+
+// SaveScore returns sql.ErrNoRows when the code doesn't exist or belongs to another game.
+func (r *scoreRepository) SaveScore(submission models.ScoreSubmission, machineId string) (models.Score, error) {
+	utils.LogInfo("Saving score %d for code %s from machine %s", submission.Score, submission.Code, machineId)
+
+	score := models.Score{
+		GameId:    submission.GameId,
+		Code:      submission.Code,
+		MachineId: machineId,
+		Score:     submission.Score,
+	}
+
+	err := r.db.QueryRow("SELECT id, player_id, name, venue, created_at FROM func_InsertScore($1, $2, $3, $4, $5)",
+		submission.GameId, submission.Code, machineId, submission.Name, submission.Score).
+		Scan(&score.Id, &score.PlayerId, &score.Name, &score.Venue, &score.CreatedAt)
+
+	if err != nil {
+		utils.LogError("Failed to save score for code %s: %v", submission.Code, err)
+		if err == sql.ErrNoRows {
+			return score, err
+		}
+		return score, fmt.Errorf("error executing function: %w", err)
+	}
+
+	return score, nil
+}
+
+func (r *scoreRepository) FetchScores(gameId *uint16, limit int, venues []string) ([]models.Score, error) {
+	rows, err := r.db.Query("SELECT "+scoreColumns+" FROM func_GetScores($1, $2, $3)", gameId, limit, pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch scores: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []models.Score
+	for rows.Next() {
+		score, err := scanScore(rows)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return scores, nil
+}
+
+// FetchBoardScores gives every score that wasn't removed, oldest first.
+func (r *scoreRepository) FetchBoardScores() ([]models.Score, error) {
+	rows, err := r.db.Query("SELECT " + scoreColumns + " FROM func_GetBoardScores()")
+	if err != nil {
+		utils.LogError("Failed to fetch board scores: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var scores []models.Score
+	for rows.Next() {
+		score, err := scanScore(rows)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, score)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return scores, nil
+}
+
+func (r *scoreRepository) RemoveScore(scoreId int, venues []string) (models.Score, error) {
+	utils.LogInfo("Removing score ID %d", scoreId)
+
+	score, err := scanScore(r.db.QueryRow("SELECT "+scoreColumns+" FROM func_RemoveScore($1, $2)", scoreId, pq.Array(venues)))
+	if err != nil {
+		utils.LogError("Failed to remove score ID %d: %v", scoreId, err)
+	}
+	return score, err
+}
+
+func scanScore(row rowScanner) (models.Score, error) {
+	var score models.Score
+	err := row.Scan(&score.Id, &score.GameId, &score.Code, &score.MachineId, &score.Venue,
+		&score.PlayerId, &score.Name, &score.Score, &score.IsRemoved, &score.CreatedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		return score, fmt.Errorf("error scanning row: %w", err)
+	}
+	return score, err
+}
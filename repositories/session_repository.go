@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type SessionRepository interface {
+	StartSession(code string, gameId uint16, machineId string) (int, error)
+	RecordLevel(code string, level int) error
+	EndSession(code string, level int, completed bool) (models.PlaySession, error)
+}
+
+type sessionRepository struct {
+	db *sql.DB
+}
+
+func NewSessionRepository(db *sql.DB) *sessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) StartSession(code string, gameId uint16, machineId string) (int, error) {
+	var sessionId int
+	err := r.db.QueryRow("SELECT func_StartSession($1, $2, $3)", code, gameId, machineId).Scan(&sessionId)
+
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("Failed to start session for code %s on machine %s: %v", code, machineId, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+	return sessionId, err
+}
+
+func (r *sessionRepository) RecordLevel(code string, level int) error {
+	if _, err := r.db.Exec("SELECT func_RecordSessionLevel($1, $2)", code, level); err != nil {
+		utils.LogError("Failed to record level %d for code %s: %v", level, code, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+// EndSession returns sql.ErrNoRows when there is no open session for the code.
+func (r *sessionRepository) EndSession(code string, level int, completed bool) (models.PlaySession, error) {
+	var session models.PlaySession
+	err := r.db.QueryRow(`SELECT id, code, game_id, machine_id, player_id, system, max_level, is_completed, started_at, ended_at
+		FROM func_EndSession($1, $2, $3)`, code, level, completed).
+		Scan(&session.Id, &session.Code, &session.GameId, &session.MachineId, &session.PlayerId, &session.System,
+			&session.MaxLevel, &session.IsCompleted, &session.StartedAt, &session.EndedAt)
+
+	if err != nil && err != sql.ErrNoRows {
+		utils.LogError("Failed to end session for code %s: %v", code, err)
+		return session, fmt.Errorf("error executing function: %w", err)
+	}
+	return session, err
+}
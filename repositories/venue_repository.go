@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+type VenueRepository interface {
+	FetchVenues(venues []string) ([]models.Venue, error) // nil venues gives every venue
+	CreateVenue(venue models.Venue) error
+	UpdateVenue(venue models.Venue) (bool, error)
+	FetchDetails(venue models.Venue) (models.VenueDetails, error)
+	FetchAdminVenues(adminId int) ([]string, error)
+	SetAdminVenues(adminId int, venues []string) error
+	SetGame(venueId string, game models.VenueGame) error
+	SetPrice(venueId string, price models.VenuePrice) error
+	SetStock(venueId string, stock models.VenueStock) error
+}
+
+type venueRepository struct {
+	db *sql.DB
+}
+
+func NewVenueRepository(db *sql.DB) *venueRepository {
+	return &venueRepository{db: db}
+}
+
+func (r *venueRepository) FetchVenues(venues []string) ([]models.Venue, error) {
+	rows, err := r.db.Query("SELECT id, name, city, is_active, created_at FROM func_GetVenues($1)", pq.Array(venues))
+	if err != nil {
+		utils.LogError("Failed to fetch venues: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.Venue
+	for rows.Next() {
+		var venue models.Venue
+		if err := rows.Scan(&venue.Id, &venue.Name, &venue.City, &venue.IsActive, &venue.CreatedAt); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		result = append(result, venue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return result, nil
+}
+
+func (r *venueRepository) CreateVenue(venue models.Venue) error {
+	utils.LogInfo("Creating venue %s", venue.Id)
+
+	if _, err := r.db.Exec("SELECT func_InsertVenue($1, $2, $3)", venue.Id, venue.Name, venue.City); err != nil {
+		utils.LogError("Failed to create venue %s: %v", venue.Id, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *venueRepository) UpdateVenue(venue models.Venue) (bool, error) {
+	var updated bool
+	err := r.db.QueryRow("SELECT func_UpdateVenue($1, $2, $3, $4)",
+		venue.Id, venue.Name, venue.City, venue.IsActive).Scan(&updated)
+
+	if err != nil {
+		utils.LogError("Failed to update venue %s: %v", venue.Id, err)
+		return false, fmt.Errorf("error executing function: %w", err)
+	}
+	return updated, nil
+}
+
+func (r *venueRepository) FetchDetails(venue models.Venue) (models.VenueDetails, error) {
+	details := models.VenueDetails{Venue: venue}
+
+	games, err := r.db.Query("SELECT game_id, is_available FROM func_GetVenueGames($1)", venue.Id)
+	if err != nil {
+		utils.LogError("Failed to fetch games of venue %s: %v", venue.Id, err)
+		return details, fmt.Errorf("error executing function: %w", err)
+	}
+	defer games.Close()
+	for games.Next() {
+		var game models.VenueGame
+		if err := games.Scan(&game.GameId, &game.IsAvailable); err != nil {
+			return details, fmt.Errorf("error scanning row: %w", err)
+		}
+		details.Games = append(details.Games, game)
+	}
+	if err := games.Err(); err != nil {
+		return details, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	prices, err := r.db.Query("SELECT game_id, item_type, label, price FROM func_GetVenuePrices($1)", venue.Id)
+	if err != nil {
+		utils.LogError("Failed to fetch prices of venue %s: %v", venue.Id, err)
+		return details, fmt.Errorf("error executing function: %w", err)
+	}
+	defer prices.Close()
+	for prices.Next() {
+		var price models.VenuePrice
+		if err := prices.Scan(&price.GameId, &price.ItemType, &price.Label, &price.Price); err != nil {
+			return details, fmt.Errorf("error scanning row: %w", err)
+		}
+		details.Prices = append(details.Prices, price)
+	}
+	if err := prices.Err(); err != nil {
+		return details, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	stock, err := r.db.Query("SELECT product_id, units FROM func_GetVenueInventory($1)", venue.Id)
+	if err != nil {
+		utils.LogError("Failed to fetch inventory of venue %s: %v", venue.Id, err)
+		return details, fmt.Errorf("error executing function: %w", err)
+	}
+	defer stock.Close()
+	for stock.Next() {
+		var item models.VenueStock
+		if err := stock.Scan(&item.ProductId, &item.Units); err != nil {
+			return details, fmt.Errorf("error scanning row: %w", err)
+		}
+		details.Inventory = append(details.Inventory, item)
+	}
+	if err := stock.Err(); err != nil {
+		return details, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return details, nil
+}
+
+func (r *venueRepository) FetchAdminVenues(adminId int) ([]string, error) {
+	rows, err := r.db.Query("SELECT venue_id FROM func_GetAdminVenues($1)", adminId)
+	if err != nil {
+		utils.LogError("Failed to fetch venues of admin ID %d: %v", adminId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var venues []string
+	for rows.Next() {
+		var venue string
+		if err := rows.Scan(&venue); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		venues = append(venues, venue)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return venues, nil
+}
+
+func (r *venueRepository) SetAdminVenues(adminId int, venues []string) error {
+	utils.LogInfo("Setting venues of admin ID %d to %v", adminId, venues)
+
+	if _, err := r.db.Exec("SELECT func_SetAdminVenues($1, $2)", adminId, pq.Array(venues)); err != nil {
+		utils.LogError("Failed to set venues of admin ID %d: %v", adminId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *venueRepository) SetGame(venueId string, game models.VenueGame) error {
+	if _, err := r.db.Exec("SELECT func_SetVenueGame($1, $2, $3)", venueId, game.GameId, game.IsAvailable); err != nil {
+		utils.LogError("Failed to set game ID %d at venue %s: %v", game.GameId, venueId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *venueRepository) SetPrice(venueId string, price models.VenuePrice) error {
+	_, err := r.db.Exec("SELECT func_SetVenuePrice($1, $2, $3, $4, $5)",
+		venueId, price.GameId, price.ItemType, price.Label, price.Price)
+	if err != nil {
+		utils.LogError("Failed to set price of game ID %d at venue %s: %v", price.GameId, venueId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+func (r *venueRepository) SetStock(venueId string, stock models.VenueStock) error {
+	if _, err := r.db.Exec("SELECT func_SetVenueInventory($1, $2, $3)", venueId, stock.ProductId, stock.Units); err != nil {
+		utils.LogError("Failed to set stock of product ID %d at venue %s: %v", stock.ProductId, venueId, err)
+		return fmt.Errorf("error executing function: %w", err)
+	}
+	return nil
+}
+
+// venueParam passes an empty venue as NULL, which the functions read as every venue.
+func venueParam(venue string) *string {
+	return optional(venue)
+}
+
+// optional passes an empty string as NULL.
+func optional(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
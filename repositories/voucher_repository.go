@@ -0,0 +1,158 @@
+package repositories
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+)
+
+type VoucherRepository interface {
+	CreateBatch(req models.VoucherBatchRequest, adminId int, codes []string) (int, error)
+	FetchBatches() ([]models.VoucherBatch, error)
+	FetchBatch(batchId int) (models.VoucherBatch, error)
+	FetchVoucherCodes(batchId int) ([]models.VoucherCode, error)
+}
+
+type voucherRepository struct {
+	db *sql.DB
+}
+
+func NewVoucherRepository(db *sql.DB) *voucherRepository {
+	return &voucherRepository{db: db}
+}
+
+const voucherBatchColumns = "id, label, game_id, name, price, play_time, levels, total_codes, redeemed, expires_at, created_at"
+
+// CreateBatch saves the batch with a prepaid game status per code in one transaction, a batch is
+// either issued whole or not at all.
+func (r *voucherRepository) CreateBatch(req models.VoucherBatchRequest, adminId int, codes []string) (int, error) {
+	utils.LogInfo("Creating voucher batch '%s' of %d codes for game ID %d", req.Label, len(codes), req.GameId)
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		utils.LogError("Failed to begin voucher batch transaction: %v", err)
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var batchId int
+	err = tx.QueryRow("SELECT func_InsertVoucherBatch($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		req.Label, req.GameId, req.Name, req.Price, req.PlayTime, req.Levels, len(codes), req.ExpiresAt, adminId).Scan(&batchId)
+	if err != nil {
+		utils.LogError("Failed to create voucher batch '%s': %v", req.Label, err)
+		return 0, fmt.Errorf("error executing function: %w", err)
+	}
+
+	for i, code := range codes {
+		// the payment reference points back to the batch, no payment is needed.
+		reference := fmt.Sprintf("voucher-%d-%d", batchId, i+1)
+		_, err = tx.Exec("SELECT func_InsertGameStatus($1, $2, $3, $4, $5, $6, $7, $8)",
+			req.GameId, req.Name, false, req.Price, req.PlayTime, req.Levels, reference, code)
+		if err != nil {
+			utils.LogError("Failed to save voucher code %s for batch ID %d: %v", code, batchId, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+		if _, err = tx.Exec("SELECT func_SetCodeExpiry($1, $2)", code, req.ExpiresAt); err != nil {
+			utils.LogError("Failed to set expiry of voucher code %s: %v", code, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+		if _, err = tx.Exec("SELECT func_InsertVoucherCode($1, $2)", batchId, code); err != nil {
+			utils.LogError("Failed to save voucher code %s for batch ID %d: %v", code, batchId, err)
+			return 0, fmt.Errorf("error executing function: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		utils.LogError("Failed to commit voucher batch '%s': %v", req.Label, err)
+		return 0, fmt.Errorf("error committing transaction: %w", err)
+	}
+
+	utils.LogInfo("Successfully created voucher batch with ID %d", batchId)
+	return batchId, nil
+}
+
+func (r *voucherRepository) FetchBatches() ([]models.VoucherBatch, error) {
+	utils.LogInfo("Fetching all voucher batches from database")
+
+	rows, err := r.db.Query("SELECT " + voucherBatchColumns + " FROM func_GetVoucherBatches()")
+	if err != nil {
+		utils.LogError("Failed to fetch voucher batches: %v", err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var batches []models.VoucherBatch
+	for rows.Next() {
+		batch, err := scanVoucherBatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return batches, nil
+}
+
+func (r *voucherRepository) FetchBatch(batchId int) (models.VoucherBatch, error) {
+	row := r.db.QueryRow("SELECT "+voucherBatchColumns+" FROM func_GetVoucherBatches() WHERE id = $1", batchId)
+
+	batch, err := scanVoucherBatch(row)
+	if err != nil {
+		utils.LogError("Failed to fetch voucher batch ID %d: %v", batchId, err)
+		return batch, err
+	}
+	return batch, nil
+}
+
+func (r *voucherRepository) FetchVoucherCodes(batchId int) ([]models.VoucherCode, error) {
+	rows, err := r.db.Query("SELECT code, is_played FROM func_GetVoucherCodes($1)", batchId)
+	if err != nil {
+		utils.LogError("Failed to fetch codes of voucher batch ID %d: %v", batchId, err)
+		return nil, fmt.Errorf("error executing function: %w", err)
+	}
+	defer rows.Close()
+
+	var codes []models.VoucherCode
+	for rows.Next() {
+		var code models.VoucherCode
+		if err := rows.Scan(&code.Code, &code.IsPlayed); err != nil {
+			return nil, fmt.Errorf("error scanning row: %w", err)
+		}
+		codes = append(codes, code)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error with row iteration: %w", err)
+	}
+
+	return codes, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanVoucherBatch(row rowScanner) (models.VoucherBatch, error) {
+	var batch models.VoucherBatch
+	var expiresAt sql.NullTime
+
+	err := row.Scan(&batch.Id, &batch.Label, &batch.GameId, &batch.Name, &batch.Price, &batch.PlayTime,
+		&batch.Levels, &batch.TotalCodes, &batch.Redeemed, &expiresAt, &batch.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return batch, err
+		}
+		return batch, fmt.Errorf("error scanning row: %w", err)
+	}
+
+	if expiresAt.Valid {
+		batch.ExpiresAt = &expiresAt.Time
+	}
+	return batch, nil
+}
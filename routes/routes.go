@@ -2,6 +2,7 @@ package routes
 
 import (
 	"GameWala-Arcade/handlers"
+	"GameWala-Arcade/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -10,7 +11,26 @@ func SetupRoutes(router *gin.Engine,
 	adminConsoleHandler handlers.AdminConsoleHandler,
 	playGameHandler handlers.PlayGameHandler,
 	handlePaymentHandler handlers.HandlePaymentHandler,
-	marketPlaceHandler handlers.MarketPlaceHandler) {
+	marketPlaceHandler handlers.MarketPlaceHandler,
+	voucherHandler handlers.VoucherHandler,
+	codeExpiryHandler handlers.CodeExpiryHandler,
+	playerHandler handlers.PlayerHandler,
+	machineHandler handlers.MachineHandler,
+	leaderboardHandler handlers.LeaderboardHandler,
+	loyaltyHandler handlers.LoyaltyHandler,
+	referralHandler handlers.ReferralHandler,
+	sessionHandler handlers.SessionHandler,
+	achievementHandler handlers.AchievementHandler,
+	passHandler handlers.PassHandler,
+	couponHandler handlers.CouponHandler,
+	pricingHandler handlers.PricingHandler,
+	bundleHandler handlers.BundleHandler,
+	venueHandler handlers.VenueHandler,
+	romHandler handlers.RomHandler,
+	analyticsHandler handlers.AnalyticsHandler,
+	reportHandler handlers.ReportHandler,
+	cartHandler handlers.CartHandler,
+	inventoryHandler handlers.InventoryHandler) {
 	v1 := router.Group("/api/v1")
 	{
 		admin := v1.Group("/restricted")
@@ -22,25 +42,212 @@ func SetupRoutes(router *gin.Engine,
 			// admin.POST("/", adminConsoleHandler.AddGames)
 			// admin.PUT("/", adminConsoleHandler.UpdateGames)
 			// admin.DELETE("/", adminConsoleHandler.DeleteGames)
+
+			// venue admins only reach the venue scoped routes, the settings shared by every venue
+			// are for the admins of every venue.
+			venues := admin.Group("/venues", utils.AuthenticateMiddleware)
+			{
+				venues.GET("", venueHandler.Venues)
+				venues.POST("", utils.GlobalAdminMiddleware, venueHandler.AddVenue)
+				venues.GET("/:venueId", venueHandler.Venue)
+				venues.PUT("/:venueId", utils.GlobalAdminMiddleware, playGameHandler.InvalidateCatalogue,
+					venueHandler.UpdateVenue)
+				venues.PUT("/:venueId/games/:gameId", playGameHandler.InvalidateCatalogue, venueHandler.SetGame)
+				venues.PUT("/:venueId/prices", playGameHandler.InvalidateCatalogue, venueHandler.SetPrice)
+				venues.PUT("/:venueId/inventory/:productId", venueHandler.SetStock)
+			}
+
+			admin.PUT("/games/:gameId/system", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware,
+				playGameHandler.InvalidateCatalogue, playGameHandler.SetGameSystem)
+			admin.PUT("/games/:gameId/metadata", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware,
+				playGameHandler.InvalidateCatalogue, playGameHandler.SetGameMetadata)
+
+			admin.PUT("/admins/:adminId/venues", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware,
+				venueHandler.SetAdminVenues)
+
+			vouchers := admin.Group("/vouchers", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				vouchers.POST("", voucherHandler.IssueBatch)
+				vouchers.GET("", voucherHandler.Batches)
+				vouchers.GET("/:batchId", voucherHandler.Batch)
+				vouchers.GET("/:batchId/export", voucherHandler.ExportBatch)
+			}
+
+			admin.GET("/cabinets/:machineId/qr", utils.AuthenticateMiddleware, machineHandler.ManagedMachine,
+				playGameHandler.CabinetQR)
+
+			machines := admin.Group("/machines", utils.AuthenticateMiddleware)
+			{
+				machines.POST("", machineHandler.Register)
+				machines.GET("", machineHandler.Machines)
+			}
+
+			scores := admin.Group("/scores", utils.AuthenticateMiddleware)
+			{
+				scores.GET("", leaderboardHandler.Scores)
+				scores.DELETE("/:scoreId", leaderboardHandler.RemoveScore)
+				scores.POST("/rebuild", utils.GlobalAdminMiddleware, leaderboardHandler.Rebuild)
+			}
+
+			loyalty := admin.Group("/loyalty", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				loyalty.GET("/rules", loyaltyHandler.Rules)
+				loyalty.POST("/rules", loyaltyHandler.AddRule)
+				loyalty.DELETE("/rules/:ruleId", loyaltyHandler.DeleteRule)
+				loyalty.POST("/rewards", loyaltyHandler.AddReward)
+				loyalty.DELETE("/rewards/:rewardId", loyaltyHandler.DeactivateReward)
+			}
+
+			admin.GET("/referrals/report", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware, referralHandler.Report)
+
+			achievements := admin.Group("/achievements", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				achievements.GET("", achievementHandler.Achievements)
+				achievements.POST("", achievementHandler.AddAchievement)
+				achievements.PUT("/:achievementId", achievementHandler.UpdateAchievement)
+			}
+
+			passes := admin.Group("/passes", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				passes.GET("", passHandler.AllPasses)
+				passes.POST("", passHandler.AddPass)
+				passes.DELETE("/:passId", passHandler.DeactivatePass)
+			}
+
+			coupons := admin.Group("/coupons", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				coupons.GET("", couponHandler.Coupons)
+				coupons.POST("", couponHandler.AddCoupon)
+				coupons.DELETE("/:couponId", couponHandler.DeactivateCoupon)
+			}
+
+			pricing := admin.Group("/pricing-rules", utils.AuthenticateMiddleware)
+			{
+				pricing.GET("", pricingHandler.Rules)
+				pricing.POST("", playGameHandler.InvalidateCatalogue, pricingHandler.AddRule)
+				pricing.DELETE("/:ruleId", playGameHandler.InvalidateCatalogue, pricingHandler.DeleteRule)
+			}
+
+			bundles := admin.Group("/bundles", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				bundles.GET("", bundleHandler.AllBundles)
+				bundles.POST("", playGameHandler.InvalidateCatalogue, bundleHandler.AddBundle)
+				bundles.DELETE("/:bundleId", playGameHandler.InvalidateCatalogue, bundleHandler.DeactivateBundle)
+			}
+
+			codeValidity := admin.Group("/code-validity", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				codeValidity.GET("", codeExpiryHandler.Policies)
+				codeValidity.PUT("", codeExpiryHandler.SavePolicy)
+				codeValidity.DELETE("", codeExpiryHandler.DeletePolicy)
+			}
+
+			systems := admin.Group("/systems", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				systems.GET("", romHandler.Systems)
+				systems.PUT("", romHandler.SaveSystem)
+			}
+
+			roms := admin.Group("/roms", utils.AuthenticateMiddleware, utils.GlobalAdminMiddleware)
+			{
+				roms.GET("", romHandler.Roms)
+				roms.POST("", playGameHandler.InvalidateCatalogue, romHandler.Upload) // sets the system of the game
+				roms.GET("/:romId", romHandler.Rom)
+				roms.PUT("/:romId/current", romHandler.SetCurrent)
+			}
+
+			analytics := admin.Group("/analytics", utils.AuthenticateMiddleware)
+			{
+				analytics.GET("/games", analyticsHandler.Games)
+				analytics.GET("/games/:gameId/daily", analyticsHandler.GameDays)
+				analytics.GET("/machines/utilisation", analyticsHandler.Utilisation)
+				analytics.GET("/peak-hours", analyticsHandler.PeakHours)
+				analytics.POST("/rollup", utils.GlobalAdminMiddleware, analyticsHandler.Rollup)
+			}
+
+			admin.GET("/reports/revenue", utils.AuthenticateMiddleware, reportHandler.Revenue)
+
+			inventory := admin.Group("/inventory", utils.AuthenticateMiddleware)
+			{
+				inventory.GET("/low-stock", inventoryHandler.LowStock)
+				inventory.GET("/movements", inventoryHandler.Movements)
+				inventory.POST("/:productId/restock", inventoryHandler.Restock)
+				inventory.POST("/:productId/adjust", inventoryHandler.Adjust)
+			}
 		}
 
 		users := v1.Group("")
 		{
 			users.GET("/games", playGameHandler.GetGamesCatalogue)
-			users.POST("/games/status", playGameHandler.SaveGameStatus)
+			users.GET("/games/genres", playGameHandler.Genres)
+			users.POST("/games/status", utils.OptionalPlayerMiddleware, playGameHandler.SaveGameStatus)
+			users.POST("/games/quote", utils.OptionalPlayerMiddleware, playGameHandler.Quote)
 			users.GET("/code-check/:gamecode", playGameHandler.CheckGameCode)
+			users.GET("/code-qr/:gamecode", playGameHandler.CodeQR)
+			users.GET("/leaderboards/:gameId", leaderboardHandler.Leaderboard)
+			users.GET("/loyalty/rewards", loyaltyHandler.Rewards)
+			users.GET("/passes", passHandler.Passes)
+			users.GET("/bundles/purchases/:paymentId", bundleHandler.PurchaseCodes)
+			users.POST("/bundles/draw", bundleHandler.Draw)
 			// users.GET("code-generate", playGameHandler.GenerateCode) // unexposed, not needed
 		}
 
+		cabinets := v1.Group("/cabinets", machineHandler.Authenticate)
+		{
+			cabinets.GET("/code-check/:gamecode", playGameHandler.CabinetCheckGameCode)
+			cabinets.POST("/scores", leaderboardHandler.SubmitScore)
+			cabinets.POST("/sessions/events", sessionHandler.Event)
+			cabinets.POST("/bundles/draw", bundleHandler.Draw)
+			cabinets.GET("/games/:gameId/rom", romHandler.Download)
+			cabinets.POST("/roms/:romId/installed", romHandler.Installed)
+		}
+
+		players := v1.Group("/players")
+		{
+			players.POST("/otp", playerHandler.SendOTP)
+			players.POST("/otp/verify", playerHandler.VerifyOTP)
+			players.POST("/logout", playerHandler.Logout)
+
+			me := players.Group("/me", utils.PlayerAuthMiddleware)
+			{
+				me.GET("", playerHandler.Me)
+				me.PUT("/name", playerHandler.UpdateName)
+				me.GET("/codes", playerHandler.Codes)
+				me.POST("/codes/:code/resend", playerHandler.ResendCode)
+				me.GET("/wallet", playerHandler.Wallet)
+				me.GET("/loyalty", loyaltyHandler.Summary)
+				me.POST("/loyalty/rewards/:rewardId/redeem", loyaltyHandler.Redeem)
+				me.GET("/referral", referralHandler.Stats)
+				me.POST("/referral", referralHandler.ApplyReferral)
+				me.GET("/achievements", achievementHandler.Progress)
+				me.GET("/passes", passHandler.PlayerPasses)
+				me.POST("/passes/:passId/subscribe", passHandler.Subscribe)
+				me.POST("/subscriptions/:subscriptionId/cancel", passHandler.CancelSubscription)
+			}
+		}
+
 		payment := v1.Group("payment")
 		{
-			payment.GET("/order/:amount", handlePaymentHandler.CreateOrder)
+			payment.GET("/order/:amount", utils.OptionalPlayerMiddleware, handlePaymentHandler.CreateOrder)
 			payment.POST("/order/details", handlePaymentHandler.SaveOrderDetails)
+			payment.POST("/webhook", handlePaymentHandler.Webhook)
 		}
 
 		shop := v1.Group("/shop")
 		{
 			shop.GET("/products", marketPlaceHandler.Products)
+			shop.GET("/products/:productId", marketPlaceHandler.Product)
+
+			// guests get a cart session, it's moved into the cart of the player on login.
+			cart := shop.Group("/cart", utils.OptionalPlayerMiddleware)
+			{
+				cart.GET("", cartHandler.Cart)
+				cart.DELETE("", cartHandler.ClearCart)
+				cart.PUT("/items/:productId", cartHandler.SetItem)
+				cart.DELETE("/items/:productId", cartHandler.RemoveItem)
+			}
+			shop.POST("/checkout", utils.OptionalPlayerMiddleware, cartHandler.Checkout)
+			shop.GET("/orders", utils.OptionalPlayerMiddleware, cartHandler.Orders)
 		}
 	}
 }
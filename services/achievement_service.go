@@ -0,0 +1,83 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrAchievementNotFound = errors.New("achievement not found")
+
+type AchievementService interface {
+	SessionListener
+
+	GetAchievements() ([]models.Achievement, error)
+	SaveAchievement(achievement models.Achievement) (int, error)
+	GetProgress(playerId int) ([]models.AchievementProgress, error)
+}
+
+type achievementService struct {
+	achievementRepository repositories.AchievementRepository
+}
+
+func NewAchievementService(achievementRepository repositories.AchievementRepository) *achievementService {
+	return &achievementService{achievementRepository: achievementRepository}
+}
+
+func (s *achievementService) GetAchievements() ([]models.Achievement, error) {
+	return s.achievementRepository.FetchAchievements(true)
+}
+
+func (s *achievementService) SaveAchievement(achievement models.Achievement) (int, error) {
+	switch achievement.Kind {
+	case models.AchievementGamesPlayed, models.AchievementAllLevels, models.AchievementSystemsPlayed:
+	default:
+		return 0, fmt.Errorf("unknown achievement kind '%s'", achievement.Kind)
+	}
+	if achievement.Threshold <= 0 {
+		return 0, fmt.Errorf("threshold has to be positive")
+	}
+	if achievement.WindowDays != nil && *achievement.WindowDays <= 0 {
+		return 0, fmt.Errorf("window has to be at least a day")
+	}
+
+	achievementId, err := s.achievementRepository.SaveAchievement(achievement)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrAchievementNotFound
+	}
+	return achievementId, err
+}
+
+func (s *achievementService) GetProgress(playerId int) ([]models.AchievementProgress, error) {
+	return s.achievementRepository.FetchProgress(playerId)
+}
+
+// SessionEnded awards whatever the player's sessions now add up to, anonymous sessions don't count.
+func (s *achievementService) SessionEnded(session models.PlaySession) error {
+	if session.PlayerId == nil {
+		return nil
+	}
+
+	progress, err := s.achievementRepository.FetchProgress(*session.PlayerId)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range progress {
+		if p.EarnedAt != nil || !p.IsActive || p.Progress < p.Threshold {
+			continue
+		}
+
+		awarded, err := s.achievementRepository.AwardAchievement(*session.PlayerId, p.Id)
+		if err != nil {
+			return err
+		}
+		if awarded {
+			utils.LogInfo("Player ID %d earned achievement %s", *session.PlayerId, p.Title)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// a year of hourly rows of every cabinet is already more than a screen can show.
+const maxAnalyticsDays = 366
+
+var ErrInvalidRange = errors.New("invalid date range")
+
+type AnalyticsService interface {
+	// RollupPending is the nightly job, it rolls up the finished days not rolled up yet.
+	RollupPending() error
+	// Rollup redoes the finished days of the range, from and to are days and to is exclusive.
+	Rollup(from time.Time, to time.Time) (int, error)
+
+	// the reports take the scope of the admin, nil for every venue.
+	GetGames(from time.Time, to time.Time, scope []string) ([]models.GameAnalytics, error)
+	GetGameDays(from time.Time, to time.Time, gameId uint16, scope []string) ([]models.GameDay, error)
+	GetUtilisation(from time.Time, to time.Time, machineId string, scope []string) ([]models.MachineHour, error)
+	GetPeakHours(from time.Time, to time.Time, scope []string) ([]models.PeakHour, error)
+}
+
+type analyticsService struct {
+	analyticsRepository repositories.AnalyticsRepository
+}
+
+func NewAnalyticsService(analyticsRepository repositories.AnalyticsRepository) *analyticsService {
+	return &analyticsService{analyticsRepository: analyticsRepository}
+}
+
+func (s *analyticsService) RollupPending() error {
+	days, err := s.analyticsRepository.RollupPending()
+	if err != nil {
+		return err
+	}
+	if days > 0 {
+		utils.LogInfo("Rolled up analytics of %d days", days)
+	}
+	return nil
+}
+
+func (s *analyticsService) Rollup(from time.Time, to time.Time) (int, error) {
+	to = finishedBy(to)
+	if err := checkRange(from, to); err != nil {
+		return 0, err
+	}
+
+	days := 0
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if err := s.analyticsRepository.Rollup(day); err != nil {
+			return days, err
+		}
+		days++
+	}
+	return days, nil
+}
+
+func (s *analyticsService) GetGames(from time.Time, to time.Time, scope []string) ([]models.GameAnalytics, error) {
+	to = finishedBy(to)
+	if err := checkRange(from, to); err != nil {
+		return nil, err
+	}
+
+	games, err := s.analyticsRepository.FetchGames(from, to, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	days := rangeDays(from, to)
+	for i := range games {
+		if days > 0 {
+			games[i].PlaysPerDay = float64(games[i].Plays) / float64(days)
+		}
+	}
+	return games, nil
+}
+
+func (s *analyticsService) GetGameDays(from time.Time, to time.Time, gameId uint16, scope []string) ([]models.GameDay, error) {
+	to = finishedBy(to)
+	if err := checkRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.analyticsRepository.FetchGameDays(from, to, gameId, scope)
+}
+
+func (s *analyticsService) GetUtilisation(from time.Time, to time.Time, machineId string, scope []string) ([]models.MachineHour, error) {
+	to = finishedBy(to)
+	if err := checkRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.analyticsRepository.FetchMachineHours(from, to, machineId, scope)
+}
+
+func (s *analyticsService) GetPeakHours(from time.Time, to time.Time, scope []string) ([]models.PeakHour, error) {
+	to = finishedBy(to)
+	if err := checkRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.analyticsRepository.FetchPeakHours(from, to, scope)
+}
+
+// finishedBy caps the end of the range at today, the days after yesterday aren't rolled up yet.
+func finishedBy(to time.Time) time.Time {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, to.Location())
+	if to.After(today) {
+		return today
+	}
+	return to
+}
+
+func checkRange(from time.Time, to time.Time) error {
+	if !from.Before(to) {
+		return fmt.Errorf("%w: the range has no finished day, today is rolled up tonight", ErrInvalidRange)
+	}
+	if rangeDays(from, to) > maxAnalyticsDays {
+		return fmt.Errorf("%w: the range can't be longer than %d days", ErrInvalidRange, maxAnalyticsDays)
+	}
+	return nil
+}
+
+// rangeDays rounds, the days around a DST change are an hour short or long.
+func rangeDays(from time.Time, to time.Time) int {
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrBundleNotFound = errors.New("bundle not found")
+var ErrBalanceCodeNotFound = errors.New("balance code not found")
+var ErrBalanceTooLow = errors.New("not enough minutes left on the balance code")
+
+type BundleService interface {
+	PaymentListener
+
+	GetBundles(includeInactive bool) ([]models.Bundle, error)
+	AddBundle(bundle models.Bundle) (int, error)
+	DeactivateBundle(bundleId int) error
+
+	PurchaseCodes(paymentId string) ([]models.BundleCode, error)
+	Draw(draw models.BalanceDraw) (string, int, error) // game code and the minutes left
+}
+
+type bundleService struct {
+	bundleRepository repositories.BundleRepository
+	playGameService  PlayGameService
+}
+
+func NewBundleService(bundleRepository repositories.BundleRepository, playGameService PlayGameService) *bundleService {
+	return &bundleService{bundleRepository: bundleRepository, playGameService: playGameService}
+}
+
+func (s *bundleService) GetBundles(includeInactive bool) ([]models.Bundle, error) {
+	return s.bundleRepository.FetchBundles(includeInactive)
+}
+
+func (s *bundleService) AddBundle(bundle models.Bundle) (int, error) {
+	if bundle.Price <= 0 {
+		return 0, fmt.Errorf("price has to be positive")
+	}
+
+	switch bundle.Kind {
+	case models.BundleKindCodes:
+		if len(bundle.Items) == 0 || bundle.Minutes != nil {
+			return 0, fmt.Errorf("a codes bundle needs items and no minutes")
+		}
+		for _, item := range bundle.Items {
+			if item.Quantity <= 0 {
+				return 0, fmt.Errorf("quantity of game ID %d has to be positive", item.GameId)
+			}
+			if _, err := s.playGameService.Quote(itemGameStatus(item)); err != nil {
+				return 0, fmt.Errorf("game ID %d: %w", item.GameId, err)
+			}
+		}
+	case models.BundleKindBalance:
+		if bundle.Minutes == nil || *bundle.Minutes <= 0 || len(bundle.Items) > 0 {
+			return 0, fmt.Errorf("a balance bundle needs minutes and no items")
+		}
+	default:
+		return 0, fmt.Errorf("unknown bundle kind '%s'", bundle.Kind)
+	}
+
+	return s.bundleRepository.CreateBundle(bundle)
+}
+
+func (s *bundleService) DeactivateBundle(bundleId int) error {
+	return s.bundleRepository.DeactivateBundle(bundleId)
+}
+
+func (s *bundleService) PurchaseCodes(paymentId string) ([]models.BundleCode, error) {
+	return s.bundleRepository.FetchPurchaseCodes(paymentId)
+}
+
+// PaymentCaptured issues the codes of the bundle, guests pick them up with the payment id.
+func (s *bundleService) PaymentCaptured(payment models.CapturedPayment) error {
+	if payment.Purpose != models.PaymentPurposeBundle {
+		return nil
+	}
+	if payment.BundleId == nil {
+		utils.LogError("Bundle payment ID %s is missing the bundle", payment.PaymentId)
+		return nil
+	}
+
+	bundle, err := s.findBundle(*payment.BundleId)
+	if err != nil {
+		return err
+	}
+	if payment.Amount < int64(bundle.Price)*100 {
+		utils.LogError("Bundle payment ID %s paid %d paise for bundle ID %d priced at %d", payment.PaymentId,
+			payment.Amount, bundle.Id, bundle.Price)
+		return nil
+	}
+
+	// a purchase that failed part way comes back on the retry, the codes it has are kept.
+	purchaseId, err := s.bundleRepository.CreatePurchase(bundle.Id, payment.PaymentId, payment.PlayerId)
+	if err != nil || purchaseId == 0 {
+		return err
+	}
+
+	if bundle.Kind == models.BundleKindBalance {
+		codes, err := s.bundleRepository.FetchPurchaseCodes(payment.PaymentId)
+		if err != nil {
+			return err
+		}
+		if len(codes) == 0 {
+			code, err := s.playGameService.GenerateCode()
+			if err != nil {
+				return err
+			}
+			if err := s.bundleRepository.AddCode(purchaseId, code, true, bundle.Minutes); err != nil {
+				return err
+			}
+		}
+		return s.bundleRepository.FinishPurchase(purchaseId)
+	}
+
+	issued := 0
+	for _, item := range bundle.Items {
+		for i := 0; i < item.Quantity; i++ {
+			issued++
+			reference := fmt.Sprintf("bundle-%d-%d", purchaseId, issued)
+			code, err := s.bundleRepository.FetchCodeByReference(reference)
+			if err != nil {
+				return err
+			}
+			if code != "" {
+				if err := s.bundleRepository.AddCode(purchaseId, code, false, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			status := itemGameStatus(item)
+			quote, err := s.playGameService.Quote(status)
+			if err != nil {
+				return err
+			}
+			if status.Name, err = s.gameName(item.GameId); err != nil {
+				return err
+			}
+			status.Price = quote.BasePrice
+			status.PaymentReference = reference
+			status.PlayerId = payment.PlayerId
+
+			_, code, err = s.playGameService.IssueCode(status)
+			if err != nil {
+				utils.LogError("Failed to issue code %d of bundle purchase ID %d: %v", issued, purchaseId, err)
+				return err
+			}
+			if err := s.bundleRepository.AddCode(purchaseId, code, false, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	utils.LogInfo("Issued %d codes for bundle purchase ID %d", issued, purchaseId)
+	return s.bundleRepository.FinishPurchase(purchaseId)
+}
+
+func (s *bundleService) PaymentRefunded(refund models.Refund) error {
+	return nil // codes already handed out stay valid, refunds are settled at the counter
+}
+
+// Draw issues a code for a timed tier of the game, paid with minutes from the balance code.
+func (s *bundleService) Draw(draw models.BalanceDraw) (string, int, error) {
+	playTime := draw.PlayTime
+	status := models.GameStatus{GameId: draw.GameId, IsTimed: true, PlayTime: &playTime}
+	quote, err := s.playGameService.Quote(status)
+	if err != nil {
+		return "", 0, err
+	}
+	if status.Name, err = s.gameName(draw.GameId); err != nil {
+		return "", 0, err
+	}
+
+	code, err := s.playGameService.GenerateCode()
+	if err != nil {
+		return "", 0, err
+	}
+
+	left, err := s.bundleRepository.DrawBalance(draw.BalanceCode, code, draw.PlayTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", 0, ErrBalanceCodeNotFound
+	} else if err != nil {
+		return "", 0, err
+	}
+	if left < 0 {
+		return "", 0, ErrBalanceTooLow
+	}
+
+	status.Code = code
+	status.Price = quote.BasePrice
+	status.PaymentReference = fmt.Sprintf("balance-%s-%s", draw.BalanceCode, code)
+	if _, _, err := s.playGameService.IssueCode(status); err != nil {
+		utils.LogError("Failed to issue code from balance code %s, giving the minutes back: %v", draw.BalanceCode, err)
+		s.bundleRepository.ReleaseDraw(code)
+		return "", 0, err
+	}
+
+	return code, left, nil
+}
+
+func (s *bundleService) findBundle(bundleId int) (models.Bundle, error) {
+	// bought before it was taken off sale still counts.
+	bundles, err := s.bundleRepository.FetchBundles(true)
+	if err != nil {
+		return models.Bundle{}, err
+	}
+
+	for _, bundle := range bundles {
+		if bundle.Id == bundleId {
+			return bundle, nil
+		}
+	}
+	return models.Bundle{}, ErrBundleNotFound
+}
+
+func (s *bundleService) gameName(gameId uint16) (string, error) {
+	games, err := s.playGameService.GetGames("")
+	if err != nil {
+		return "", err
+	}
+
+	for _, game := range games {
+		if game.GameId == gameId {
+			return game.Name, nil
+		}
+	}
+	return "", fmt.Errorf("game ID %d not found", gameId)
+}
+
+func itemGameStatus(item models.BundleItem) models.GameStatus {
+	return models.GameStatus{
+		GameId:   item.GameId,
+		IsTimed:  item.IsTimed,
+		PlayTime: item.PlayTime,
+		Levels:   item.Levels,
+	}
+}
@@ -0,0 +1,183 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+var errIssue = errors.New("issue failed")
+
+// issuingGames stands in for the play game service, codes are kept by their payment reference
+// the way GameStatus keeps them.
+type issuingGames struct {
+	PlayGameService
+	issued    map[string]string
+	failAfter int // IssueCode fails once this many codes were issued, -1 never
+	generated int
+}
+
+func (s *issuingGames) Quote(status models.GameStatus) (models.PriceQuote, error) {
+	return models.PriceQuote{BasePrice: 40, ListPrice: 40, Price: 40}, nil
+}
+
+func (s *issuingGames) GetGames(venue string) ([]models.GameResponse, error) {
+	return []models.GameResponse{{GameId: 1, Name: "Pacman"}, {GameId: 2, Name: "Tekken"}}, nil
+}
+
+func (s *issuingGames) GenerateCode() (string, error) {
+	s.generated++
+	return fmt.Sprintf("CODE%02d", s.generated), nil
+}
+
+func (s *issuingGames) IssueCode(status models.GameStatus) (int, string, error) {
+	if s.failAfter >= 0 && len(s.issued) >= s.failAfter {
+		return 0, "", errIssue
+	}
+	code := status.Code
+	if code == "" {
+		code, _ = s.GenerateCode()
+	}
+	s.issued[status.PaymentReference] = code
+	return len(s.issued), code, nil
+}
+
+// boughtBundles keeps a single purchase in memory.
+type boughtBundles struct {
+	repositories.BundleRepository
+	games    *issuingGames
+	bundles  []models.Bundle
+	codes    []string
+	finished bool
+	left     int
+	drawErr  error
+}
+
+func (r *boughtBundles) FetchBundles(includeInactive bool) ([]models.Bundle, error) {
+	return r.bundles, nil
+}
+
+func (r *boughtBundles) CreatePurchase(bundleId int, paymentId string, playerId *int) (int, error) {
+	if r.finished {
+		return 0, nil
+	}
+	return 7, nil
+}
+
+func (r *boughtBundles) AddCode(purchaseId int, code string, isBalance bool, minutes *int) error {
+	for _, added := range r.codes {
+		if added == code {
+			return nil
+		}
+	}
+	r.codes = append(r.codes, code)
+	return nil
+}
+
+func (r *boughtBundles) FinishPurchase(purchaseId int) error {
+	r.finished = true
+	return nil
+}
+
+func (r *boughtBundles) FetchCodeByReference(reference string) (string, error) {
+	return r.games.issued[reference], nil
+}
+
+func (r *boughtBundles) DrawBalance(balanceCode string, gameCode string, minutes uint16) (int, error) {
+	return r.left, r.drawErr
+}
+
+func (r *boughtBundles) ReleaseDraw(gameCode string) error {
+	return nil
+}
+
+func TestBundlePaymentResumesIssuing(t *testing.T) {
+	games := &issuingGames{issued: map[string]string{}, failAfter: 2}
+	repo := &boughtBundles{games: games, bundles: []models.Bundle{{
+		Id: 3, Price: 100, Kind: models.BundleKindCodes,
+		Items: []models.BundleItem{{GameId: 1, Quantity: 2}, {GameId: 2, Quantity: 1}},
+	}}}
+	service := NewBundleService(repo, games)
+
+	bundleId := 3
+	payment := models.CapturedPayment{PaymentId: "pay_1", Amount: 10000, Purpose: models.PaymentPurposeBundle,
+		BundleId: &bundleId}
+
+	if err := service.PaymentCaptured(payment); !errors.Is(err, errIssue) {
+		t.Fatalf("first delivery: got %v, want the issue error", err)
+	}
+	if repo.finished || len(repo.codes) != 2 {
+		t.Fatalf("after the failure: finished %v with %d codes, want unfinished with 2", repo.finished, len(repo.codes))
+	}
+
+	// the retry picks up the codes already issued under their references.
+	games.failAfter = -1
+	if err := service.PaymentCaptured(payment); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if !repo.finished || len(repo.codes) != 3 || len(games.issued) != 3 {
+		t.Fatalf("after the retry: finished %v with %d codes and %d issued, want finished with 3 of each",
+			repo.finished, len(repo.codes), len(games.issued))
+	}
+
+	// a late duplicate of the webhook doesn't issue anything.
+	if err := service.PaymentCaptured(payment); err != nil || len(games.issued) != 3 {
+		t.Fatalf("duplicate delivery: %v with %d issued, want no error and 3", err, len(games.issued))
+	}
+}
+
+func TestBundlePaymentTooLow(t *testing.T) {
+	games := &issuingGames{issued: map[string]string{}, failAfter: -1}
+	repo := &boughtBundles{games: games, bundles: []models.Bundle{{
+		Id: 3, Price: 100, Kind: models.BundleKindCodes, Items: []models.BundleItem{{GameId: 1, Quantity: 1}},
+	}}}
+
+	bundleId := 3
+	payment := models.CapturedPayment{PaymentId: "pay_1", Amount: 9999, Purpose: models.PaymentPurposeBundle,
+		BundleId: &bundleId}
+	if err := NewBundleService(repo, games).PaymentCaptured(payment); err != nil {
+		t.Fatal(err)
+	}
+	if len(games.issued) != 0 || repo.finished {
+		t.Fatalf("underpaid bundle issued %d codes, want none", len(games.issued))
+	}
+}
+
+func TestDraw(t *testing.T) {
+	tests := []struct {
+		name    string
+		left    int
+		drawErr error
+		wantErr error
+	}{
+		{name: "enough minutes", left: 20},
+		{name: "last minutes", left: 0},
+		{name: "too low", left: -1, wantErr: ErrBalanceTooLow},
+		{name: "not a balance code", drawErr: sql.ErrNoRows, wantErr: ErrBalanceCodeNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			games := &issuingGames{issued: map[string]string{}, failAfter: -1}
+			repo := &boughtBundles{games: games, left: tt.left, drawErr: tt.drawErr}
+			draw := models.BalanceDraw{BalanceCode: "BAL123", GameId: 1, PlayTime: 10}
+
+			code, left, err := NewBundleService(repo, games).Draw(draw)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(games.issued) != 0 {
+					t.Fatalf("refused draw issued %d codes", len(games.issued))
+				}
+				return
+			}
+			if code == "" || left != tt.left || games.issued["balance-BAL123-"+code] != code {
+				t.Fatalf("got code %q with %d left, want an issued code with %d left", code, left, tt.left)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	razorpay "github.com/razorpay/razorpay-go"
+	"github.com/redis/go-redis/v9"
+)
+
+// a guest cart is forgotten a week after it was last touched unless the config says otherwise.
+const defaultGuestCartDays = 7
+
+// how long the units of a checkout are held for its payment unless the config says otherwise.
+const defaultStockHoldMinutes = 15
+
+var ErrCartEmpty = errors.New("cart is empty")
+var ErrNotEnoughStock = errors.New("not enough units in stock")
+var ErrInvalidCartItem = errors.New("invalid cart item")
+
+type CartService interface {
+	PaymentListener
+	PaymentFailureListener
+
+	NewSession() (string, error)
+
+	// the venue is the shop the cart is bought from, empty for the central stock.
+	GetCart(owner models.CartOwner, venue string) (models.Cart, error)
+	SetItem(owner models.CartOwner, venue string, item models.CartItem) (models.Cart, error)
+	ClearCart(owner models.CartOwner) error
+
+	// Checkout prices the cart as it is now, holds the units and opens a razorpay order for it, the
+	// order is paid once razorpay reports the capture.
+	Checkout(owner models.CartOwner, venue string) (models.ShopCheckout, error)
+	GetOrders(owner models.CartOwner) ([]models.ShopOrder, error)
+}
+
+type cartService struct {
+	cartRepository          repositories.CartRepository
+	marketPlaceRepository   repositories.MarketPlaceRepository
+	handlePaymentRepository repositories.HandlePaymentRepository
+	inventoryService        InventoryService
+	redisClient             *redis.Client
+}
+
+func NewCartService(cartRepository repositories.CartRepository, marketPlaceRepository repositories.MarketPlaceRepository,
+	handlePaymentRepository repositories.HandlePaymentRepository, inventoryService InventoryService,
+	redisClient *redis.Client) *cartService {
+	return &cartService{cartRepository: cartRepository, marketPlaceRepository: marketPlaceRepository,
+		handlePaymentRepository: handlePaymentRepository, inventoryService: inventoryService, redisClient: redisClient}
+}
+
+func (s *cartService) NewSession() (string, error) {
+	session := make([]byte, 16)
+	if _, err := rand.Read(session); err != nil {
+		return "", fmt.Errorf("error reading random bytes: %w", err)
+	}
+	return hex.EncodeToString(session), nil
+}
+
+func (s *cartService) GetCart(owner models.CartOwner, venue string) (models.Cart, error) {
+	items, err := s.items(owner)
+	if err != nil {
+		return models.Cart{}, err
+	}
+	return s.price(items, venue)
+}
+
+// SetItem sets the quantity of the product in the cart, 0 takes it out. More units than the venue has
+// in stock can't be put in the cart.
+func (s *cartService) SetItem(owner models.CartOwner, venue string, item models.CartItem) (models.Cart, error) {
+	if item.Quantity < 0 {
+		return models.Cart{}, fmt.Errorf("%w: quantity can't be negative", ErrInvalidCartItem)
+	}
+
+	if item.Quantity > 0 {
+		product, err := s.marketPlaceRepository.FetchProduct(item.ProductId, venue)
+		if err == sql.ErrNoRows {
+			return models.Cart{}, ErrProductNotFound
+		} else if err != nil {
+			return models.Cart{}, err
+		}
+		if item.Quantity > int(product.TotalUnits) {
+			return models.Cart{}, fmt.Errorf("%w: only %d of '%s' left", ErrNotEnoughStock, product.TotalUnits, product.Title)
+		}
+	}
+
+	if owner.PlayerId > 0 {
+		if err := s.cartRepository.SetCartItem(owner.PlayerId, item.ProductId, item.Quantity); err != nil {
+			return models.Cart{}, err
+		}
+	} else if err := s.setGuestItem(owner.Session, item); err != nil {
+		return models.Cart{}, err
+	}
+
+	return s.GetCart(owner, venue)
+}
+
+func (s *cartService) ClearCart(owner models.CartOwner) error {
+	if owner.PlayerId > 0 {
+		return s.cartRepository.ClearCart(owner.PlayerId)
+	}
+	if owner.Session == "" {
+		return nil
+	}
+	if err := s.redisClient.Del(context.Background(), guestCartKey(owner.Session)).Err(); err != nil {
+		return fmt.Errorf("error reaching redis: %w", err)
+	}
+	return nil
+}
+
+func (s *cartService) Checkout(owner models.CartOwner, venue string) (models.ShopCheckout, error) {
+	items, err := s.items(owner)
+	if err != nil {
+		return models.ShopCheckout{}, err
+	}
+	if len(items) == 0 {
+		return models.ShopCheckout{}, ErrCartEmpty
+	}
+
+	order, err := s.shopOrder(items, venue)
+	if err != nil {
+		return models.ShopCheckout{}, err
+	}
+
+	notes := map[string]interface{}{"purpose": models.PaymentPurposeShop}
+	if owner.PlayerId > 0 {
+		notes["player_id"] = strconv.Itoa(owner.PlayerId)
+	}
+
+	client := razorpay.NewClient(config.GetString("key_id"), config.GetString("key_secret"))
+	body, err := client.Order.Create(map[string]interface{}{
+		"amount":   order.Amount,
+		"currency": "INR",
+		"receipt":  fmt.Sprintf("shop_%d", time.Now().Unix()),
+		"notes":    notes,
+	}, map[string]string{})
+	if err != nil {
+		utils.LogError("Failed to create razorpay order for shop checkout: %v", err)
+		return models.ShopCheckout{}, fmt.Errorf("razorpay might be down, please try later")
+	}
+	order.OrderId, _ = body["id"].(string)
+
+	if owner.PlayerId > 0 {
+		order.PlayerId = &owner.PlayerId
+	} else {
+		order.GuestSession = &owner.Session
+	}
+	if venue != "" {
+		order.Venue = &venue
+	}
+	// the units are checked again under a lock, another checkout may have taken them since.
+	holdMinutes := stockHoldMinutes()
+	orderId, shortProductId, unitsLeft, err := s.cartRepository.CreateShopOrder(order, holdMinutes)
+	if err != nil {
+		return models.ShopCheckout{}, err
+	}
+	if shortProductId > 0 {
+		return models.ShopCheckout{}, fmt.Errorf("%w: only %d of product ID %d left", ErrNotEnoughStock, unitsLeft, shortProductId)
+	}
+	order.Id = orderId
+
+	paymentOrder := models.PaymentOrder{OrderId: order.OrderId, Purpose: models.PaymentPurposeShop,
+		Amount: order.Amount, Venue: order.Venue}
+	if err := s.handlePaymentRepository.RecordOrder(paymentOrder); err != nil {
+		utils.LogError("Order %s will be priced at payment time: %v", order.OrderId, err)
+	}
+
+	utils.LogInfo("Shop order ID %d opened with razorpay order %s for %d paise", order.Id, order.OrderId, order.Amount)
+	return models.ShopCheckout{OrderId: order.OrderId, Amount: order.Amount, Currency: "INR", Items: order.Items,
+		HeldUntil: time.Now().Add(time.Duration(holdMinutes) * time.Minute)}, nil
+}
+
+func (s *cartService) GetOrders(owner models.CartOwner) ([]models.ShopOrder, error) {
+	if owner.PlayerId == 0 && owner.Session == "" {
+		return []models.ShopOrder{}, nil
+	}
+	return s.cartRepository.FetchShopOrders(owner.PlayerId, owner.Session)
+}
+
+// PaymentCaptured pays the shop order of the razorpay order, takes the units out of the stock and what
+// was bought out of the cart. shop payments of orders not opened by a checkout are left alone.
+func (s *cartService) PaymentCaptured(payment models.CapturedPayment) error {
+	if payment.Purpose != models.PaymentPurposeShop || payment.OrderId == "" {
+		return nil
+	}
+
+	order, err := s.cartRepository.FetchShopOrder(payment.OrderId)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if payment.Amount < order.Amount {
+		utils.LogError("Shop payment ID %s paid %d paise for shop order ID %d of %d paise", payment.PaymentId,
+			payment.Amount, order.Id, order.Amount)
+		return nil
+	}
+
+	sold, err := s.cartRepository.PayShopOrder(payment.OrderId, payment.PaymentId)
+	if err != nil || len(sold) == 0 {
+		return err
+	}
+	utils.LogInfo("Shop order ID %d paid with payment ID %s", order.Id, payment.PaymentId)
+	s.inventoryService.StockSold(order, sold)
+
+	// the carts of players are emptied by the database along with the payment.
+	if order.GuestSession != nil {
+		return s.removeGuestItems(*order.GuestSession, order.Items)
+	}
+	return nil
+}
+
+// PaymentFailed gives the units held for the order back, a later attempt that goes through still
+// takes them if they are left.
+func (s *cartService) PaymentFailed(payment models.FailedPayment) error {
+	if payment.Purpose != models.PaymentPurposeShop || payment.OrderId == "" {
+		return nil
+	}
+
+	released, err := s.cartRepository.ReleaseShopOrder(payment.OrderId)
+	if err != nil {
+		return err
+	}
+	if released > 0 {
+		utils.LogInfo("Released the stock held for razorpay order %s, payment ID %s failed: %s", payment.OrderId,
+			payment.PaymentId, payment.Reason)
+	}
+	return nil
+}
+
+// PaymentRefunded marks the order refunded on a full refund, a partial refund is for part of the items.
+func (s *cartService) PaymentRefunded(refund models.Refund) error {
+	if refund.Purpose != models.PaymentPurposeShop || refund.Amount < refund.PaymentAmount {
+		return nil
+	}
+	return s.cartRepository.RefundShopOrder(refund.PaymentId)
+}
+
+// items of the cart of the owner, the guest cart is moved into the cart of the player on the first
+// request after the login.
+func (s *cartService) items(owner models.CartOwner) ([]models.CartItem, error) {
+	if owner.PlayerId == 0 {
+		return s.guestItems(owner.Session)
+	}
+
+	if owner.Session != "" {
+		guestItems, err := s.guestItems(owner.Session)
+		if err != nil {
+			return nil, err
+		}
+		if len(guestItems) > 0 {
+			if err := s.cartRepository.MergeCart(owner.PlayerId, guestItems); err != nil {
+				return nil, err
+			}
+			utils.LogInfo("Guest cart moved into the cart of player ID %d", owner.PlayerId)
+		}
+		if err := s.redisClient.Del(context.Background(), guestCartKey(owner.Session)).Err(); err != nil {
+			return nil, fmt.Errorf("error reaching redis: %w", err)
+		}
+	}
+
+	return s.cartRepository.FetchCart(owner.PlayerId)
+}
+
+// shopOrder prices the items for the checkout, the prices come from the products, never from what the
+// client says the cart costs.
+func (s *cartService) shopOrder(items []models.CartItem, venue string) (models.ShopOrder, error) {
+	order := models.ShopOrder{Items: []models.ShopOrderItem{}}
+	for _, item := range items {
+		product, err := s.marketPlaceRepository.FetchProduct(item.ProductId, venue)
+		if err == sql.ErrNoRows {
+			return models.ShopOrder{}, fmt.Errorf("%w: product ID %d is no longer sold here", ErrNotEnoughStock, item.ProductId)
+		} else if err != nil {
+			return models.ShopOrder{}, err
+		}
+		if item.Quantity > int(product.TotalUnits) {
+			return models.ShopOrder{}, fmt.Errorf("%w: only %d of '%s' left", ErrNotEnoughStock, product.TotalUnits, product.Title)
+		}
+
+		order.Items = append(order.Items, models.ShopOrderItem{ProductId: item.ProductId, Title: product.Title,
+			Price: product.Price, Quantity: item.Quantity})
+		order.Amount += int64(product.Price) * int64(item.Quantity) * 100 // razorpay takes paise
+	}
+	return order, nil
+}
+
+// price fills the cart lines from the products, lines the venue can't sell anymore are kept
+// but left out of the total.
+func (s *cartService) price(items []models.CartItem, venue string) (models.Cart, error) {
+	cart := models.Cart{Items: []models.CartLine{}}
+	for _, item := range items {
+		line := models.CartLine{ProductId: item.ProductId, Quantity: item.Quantity}
+
+		product, err := s.marketPlaceRepository.FetchProduct(item.ProductId, venue)
+		if err != nil && err != sql.ErrNoRows {
+			return cart, err
+		}
+		if err == nil {
+			line.Title = product.Title
+			line.Price = product.Price
+			line.TotalUnits = product.TotalUnits
+			line.Available = item.Quantity <= int(product.TotalUnits)
+		}
+		if line.Available {
+			cart.Total += int64(line.Price) * int64(line.Quantity)
+		}
+		cart.Items = append(cart.Items, line)
+	}
+	return cart, nil
+}
+
+func (s *cartService) guestItems(session string) ([]models.CartItem, error) {
+	items := []models.CartItem{}
+	if session == "" {
+		return items, nil
+	}
+
+	fields, err := s.redisClient.HGetAll(context.Background(), guestCartKey(session)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reaching redis: %w", err)
+	}
+
+	for field, value := range fields {
+		productId, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		quantity, err := strconv.Atoi(value)
+		if err != nil || quantity <= 0 {
+			continue
+		}
+		items = append(items, models.CartItem{ProductId: productId, Quantity: quantity})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductId < items[j].ProductId })
+	return items, nil
+}
+
+func (s *cartService) setGuestItem(session string, item models.CartItem) error {
+	if session == "" {
+		return fmt.Errorf("%w: the cart session is missing", ErrInvalidCartItem)
+	}
+
+	ctx := context.Background()
+	key := guestCartKey(session)
+	field := strconv.Itoa(item.ProductId)
+
+	pipe := s.redisClient.TxPipeline()
+	if item.Quantity == 0 {
+		pipe.HDel(ctx, key, field)
+	} else {
+		pipe.HSet(ctx, key, field, item.Quantity)
+	}
+	pipe.Expire(ctx, key, guestCartTTL())
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("error reaching redis: %w", err)
+	}
+	return nil
+}
+
+// removeGuestItems takes the bought units out of the guest cart, products added after the checkout stay.
+func (s *cartService) removeGuestItems(session string, bought []models.ShopOrderItem) error {
+	ctx := context.Background()
+	key := guestCartKey(session)
+
+	for _, item := range bought {
+		field := strconv.Itoa(item.ProductId)
+		left, err := s.redisClient.HIncrBy(ctx, key, field, -int64(item.Quantity)).Result()
+		if err != nil {
+			return fmt.Errorf("error reaching redis: %w", err)
+		}
+		if left <= 0 {
+			if err := s.redisClient.HDel(ctx, key, field).Err(); err != nil {
+				return fmt.Errorf("error reaching redis: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+func guestCartTTL() time.Duration {
+	days := config.GetInt("guestCartDays")
+	if days <= 0 {
+		days = defaultGuestCartDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+func stockHoldMinutes() int {
+	minutes := config.GetInt("stockHoldMinutes")
+	if minutes <= 0 {
+		minutes = defaultStockHoldMinutes
+	}
+	return minutes
+}
+
+func guestCartKey(session string) string { return "cart:" + session }
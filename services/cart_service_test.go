@@ -0,0 +1,234 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"testing"
+)
+
+// stockedProducts are the products of the venue, missing ones aren't sold there.
+type stockedProducts struct {
+	repositories.MarketPlaceRepository
+	products map[int]models.Product
+}
+
+func (r *stockedProducts) FetchProduct(productId int, venue string) (models.Product, error) {
+	product, ok := r.products[productId]
+	if !ok {
+		return models.Product{}, sql.ErrNoRows
+	}
+	return product, nil
+}
+
+// playerCarts merges like func_MergeCart, quantities of products already in the cart are added up.
+type playerCarts struct {
+	repositories.CartRepository
+	carts  map[int]map[int]int
+	merges int
+}
+
+func (r *playerCarts) FetchCart(playerId int) ([]models.CartItem, error) {
+	items := []models.CartItem{}
+	for productId, quantity := range r.carts[playerId] {
+		items = append(items, models.CartItem{ProductId: productId, Quantity: quantity})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ProductId < items[j].ProductId })
+	return items, nil
+}
+
+func (r *playerCarts) MergeCart(playerId int, items []models.CartItem) error {
+	r.merges++
+	if r.carts[playerId] == nil {
+		r.carts[playerId] = map[int]int{}
+	}
+	for _, item := range items {
+		r.carts[playerId][item.ProductId] += item.Quantity
+	}
+	return nil
+}
+
+func testProducts() *stockedProducts {
+	return &stockedProducts{products: map[int]models.Product{
+		1: {ProductId: 1, Title: "Sticker", Price: 30, TotalUnits: 10},
+		2: {ProductId: 2, Title: "Card", Price: 120, TotalUnits: 2},
+	}}
+}
+
+func TestCartMergesGuestCartOnLogin(t *testing.T) {
+	client := testRedis(t)
+	carts := &playerCarts{carts: map[int]map[int]int{7: {1: 2}}}
+	service := NewCartService(carts, testProducts(), nil, nil, client)
+
+	guest := models.CartOwner{Session: "guest-session"}
+	if _, err := service.SetItem(guest, "", models.CartItem{ProductId: 1, Quantity: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.SetItem(guest, "", models.CartItem{ProductId: 2, Quantity: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	player := models.CartOwner{PlayerId: 7, Session: guest.Session}
+	cart, err := service.GetCart(player, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 3 || cart.Items[1].Quantity != 2 {
+		t.Fatalf("merged cart is %+v, want 3 stickers and 2 cards", cart.Items)
+	}
+	if cart.Total != 3*30+2*120 {
+		t.Errorf("total is %d, want %d", cart.Total, 3*30+2*120)
+	}
+
+	if exists, _ := client.Exists(context.Background(), guestCartKey(guest.Session)).Result(); exists != 0 {
+		t.Error("guest cart is still there after the merge")
+	}
+	// the next request of the player doesn't merge the same items again.
+	if _, err := service.GetCart(player, ""); err != nil {
+		t.Fatal(err)
+	}
+	if carts.merges != 1 || carts.carts[7][1] != 3 {
+		t.Errorf("merged %d times with %d stickers, want once with 3", carts.merges, carts.carts[7][1])
+	}
+}
+
+func TestGetCartLeavesUnavailableLinesOut(t *testing.T) {
+	client := testRedis(t)
+	service := NewCartService(&playerCarts{}, testProducts(), nil, nil, client)
+
+	guest := models.CartOwner{Session: "guest-session"}
+	// the stock ran out after the cards went in the cart, and product 3 isn't sold anymore.
+	for _, item := range []models.CartItem{{ProductId: 1, Quantity: 2}, {ProductId: 2, Quantity: 3}, {ProductId: 3, Quantity: 1}} {
+		if err := service.setGuestItem(guest.Session, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cart, err := service.GetCart(guest, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cart.Items) != 3 || !cart.Items[0].Available || cart.Items[1].Available || cart.Items[2].Available {
+		t.Fatalf("cart lines are %+v, want only the stickers available", cart.Items)
+	}
+	if cart.Total != 2*30 {
+		t.Errorf("total is %d, want %d", cart.Total, 2*30)
+	}
+}
+
+func TestShopOrderPricing(t *testing.T) {
+	service := NewCartService(&playerCarts{}, testProducts(), nil, nil, nil)
+
+	tests := []struct {
+		name       string
+		items      []models.CartItem
+		wantAmount int64
+		wantErr    error
+	}{
+		{name: "priced from the products in paise", items: []models.CartItem{{ProductId: 1, Quantity: 3}, {ProductId: 2, Quantity: 2}},
+			wantAmount: (3*30 + 2*120) * 100},
+		{name: "more than in stock", items: []models.CartItem{{ProductId: 2, Quantity: 3}}, wantErr: ErrNotEnoughStock},
+		{name: "no longer sold", items: []models.CartItem{{ProductId: 1, Quantity: 1}, {ProductId: 3, Quantity: 1}},
+			wantErr: ErrNotEnoughStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := service.shopOrder(tt.items, "")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %v, want %v", err, tt.wantErr)
+			}
+			if order.Amount != tt.wantAmount {
+				t.Errorf("amount is %d, want %d", order.Amount, tt.wantAmount)
+			}
+			if tt.wantErr == nil && len(order.Items) != len(tt.items) {
+				t.Errorf("order has %d items, want %d", len(order.Items), len(tt.items))
+			}
+		})
+	}
+}
+
+// paidOrders is a checkout waiting for its payment, the sale is what func_PayShopOrder gives back.
+type paidOrders struct {
+	repositories.CartRepository
+	order models.ShopOrder
+	sale  []models.SoldStock
+	paid  bool
+}
+
+func (r *paidOrders) FetchShopOrder(orderId string) (models.ShopOrder, error) {
+	if orderId != r.order.OrderId {
+		return models.ShopOrder{}, sql.ErrNoRows
+	}
+	return r.order, nil
+}
+
+func (r *paidOrders) PayShopOrder(orderId string, paymentId string) ([]models.SoldStock, error) {
+	if r.paid {
+		return []models.SoldStock{}, nil
+	}
+	r.paid = true
+	return r.sale, nil
+}
+
+type soldStock struct {
+	InventoryService
+	sold []models.SoldStock
+}
+
+func (s *soldStock) StockSold(order models.ShopOrder, sold []models.SoldStock) {
+	s.sold = append(s.sold, sold...)
+}
+
+func TestOversoldPaymentReportedOnce(t *testing.T) {
+	client := testRedis(t)
+	session := "guest-session"
+	orders := &paidOrders{
+		order: models.ShopOrder{Id: 4, OrderId: "order_1", Amount: 6000, GuestSession: &session,
+			Items: []models.ShopOrderItem{{ProductId: 1, Price: 30, Quantity: 2}}},
+		sale: []models.SoldStock{{ProductId: 1, Quantity: 2, UnitsLeft: 0, Oversold: true}},
+	}
+	inventory := &soldStock{}
+	service := NewCartService(orders, testProducts(), nil, inventory, client)
+
+	// a sticker was added to the guest cart after the checkout, it stays.
+	if err := service.setGuestItem(session, models.CartItem{ProductId: 1, Quantity: 3}); err != nil {
+		t.Fatal(err)
+	}
+
+	payment := models.CapturedPayment{PaymentId: "pay_1", OrderId: "order_1", Amount: 6000, Purpose: models.PaymentPurposeShop}
+	if err := service.PaymentCaptured(payment); err != nil {
+		t.Fatal(err)
+	}
+	// the webhook comes again, the order is paid already.
+	if err := service.PaymentCaptured(payment); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(inventory.sold) != 1 || !inventory.sold[0].Oversold {
+		t.Fatalf("inventory was told of %+v, want the oversold sale once", inventory.sold)
+	}
+	items, err := service.guestItems(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Quantity != 1 {
+		t.Errorf("guest cart is %+v, want the sticker added after the checkout", items)
+	}
+}
+
+func TestUnderpaidShopOrderIsLeftAlone(t *testing.T) {
+	orders := &paidOrders{order: models.ShopOrder{Id: 4, OrderId: "order_1", Amount: 6000}}
+	service := NewCartService(orders, testProducts(), nil, &soldStock{}, testRedis(t))
+
+	payment := models.CapturedPayment{PaymentId: "pay_1", OrderId: "order_1", Amount: 5999, Purpose: models.PaymentPurposeShop}
+	if err := service.PaymentCaptured(payment); err != nil {
+		t.Fatal(err)
+	}
+	if orders.paid {
+		t.Error("underpaid shop order was marked paid")
+	}
+}
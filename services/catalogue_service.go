@@ -0,0 +1,125 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// bumped on every admin change, the pages cached under an older version are never read again and expire.
+const catalogueVersionKey = "catalogue:version"
+
+const defaultCatalogueCache = 5 * time.Minute
+
+type CatalogueService interface {
+	// Catalogue is the JSON of the catalogue page with its ETag, served from redis until something changes.
+	Catalogue(filter models.GameFilter) ([]byte, string, error)
+	Invalidate()
+}
+
+type catalogueService struct {
+	playGameService PlayGameService
+	bundleService   BundleService
+	redisClient     *redis.Client
+}
+
+func NewCatalogueService(playGameService PlayGameService, bundleService BundleService,
+	redisClient *redis.Client) *catalogueService {
+	return &catalogueService{playGameService: playGameService, bundleService: bundleService, redisClient: redisClient}
+}
+
+func (s *catalogueService) Catalogue(filter models.GameFilter) ([]byte, string, error) {
+	ctx := context.Background()
+
+	// a redis outage only costs the cache, the catalogue is built from the DB as before.
+	version, err := s.redisClient.Get(ctx, catalogueVersionKey).Result()
+	if err != nil && err != redis.Nil {
+		utils.LogError("Failed to read the catalogue version, skipping the cache: %v", err)
+		body, _, err := s.build(filter)
+		return body, catalogueETag(body), err
+	}
+	key, err := catalogueKey(version, filter)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if body, err := s.redisClient.Get(ctx, key).Bytes(); err == nil {
+		return body, catalogueETag(body), nil
+	} else if err != redis.Nil {
+		utils.LogError("Failed to read the cached catalogue: %v", err)
+	}
+
+	body, ttl, err := s.build(filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if ttl > 0 {
+		if err := s.redisClient.Set(ctx, key, body, ttl).Err(); err != nil {
+			utils.LogError("Failed to cache the catalogue: %v", err)
+		}
+	}
+	return body, catalogueETag(body), nil
+}
+
+func (s *catalogueService) Invalidate() {
+	if err := s.redisClient.Incr(context.Background(), catalogueVersionKey).Err(); err != nil {
+		utils.LogError("Failed to invalidate the catalogue cache: %v", err)
+		return
+	}
+	utils.LogInfo("Catalogue cache invalidated")
+}
+
+// build assembles the page and tells how long it can be cached, the prices are only good until the
+// next pricing rule starts or ends.
+func (s *catalogueService) build(filter models.GameFilter) ([]byte, time.Duration, error) {
+	page, err := s.playGameService.SearchGames(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	catalogue := models.Catalogue{Games: page.Games, NextCursor: page.NextCursor}
+	if filter.Cursor == "" {
+		if catalogue.Bundles, err = s.bundleService.GetBundles(false); err != nil {
+			utils.LogError("Error fetching bundles for the catalogue: %v", err)
+			return nil, 0, err
+		}
+	}
+
+	body, err := json.Marshal(catalogue)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error encoding catalogue: %w", err)
+	}
+
+	ttl := time.Duration(config.GetInt("catalogueCacheSeconds")) * time.Second
+	if ttl <= 0 {
+		ttl = defaultCatalogueCache
+	}
+	for _, game := range page.Games {
+		if game.NextPriceChange != nil {
+			ttl = min(ttl, time.Until(*game.NextPriceChange))
+		}
+	}
+	return body, ttl, nil
+}
+
+func catalogueKey(version string, filter models.GameFilter) (string, error) {
+	raw, err := json.Marshal(filter)
+	if err != nil {
+		return "", fmt.Errorf("error encoding catalogue filter: %w", err)
+	}
+	sum := sha256.Sum256(raw)
+	return fmt.Sprintf("catalogue:%s:%s", version, hex.EncodeToString(sum[:16])), nil
+}
+
+func catalogueETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
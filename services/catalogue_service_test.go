@@ -0,0 +1,98 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	_ "github.com/lib/pq"
+)
+
+// catalogueSource stands in for the DB side of the catalogue and counts how often the page is built.
+type catalogueSource struct {
+	PlayGameService
+	BundleService
+	page    models.GamePage
+	bundles []models.Bundle
+	builds  atomic.Int64
+}
+
+func (s *catalogueSource) SearchGames(filter models.GameFilter) (models.GamePage, error) {
+	s.builds.Add(1)
+	return s.page, nil
+}
+
+func (s *catalogueSource) GetBundles(includeInactive bool) ([]models.Bundle, error) {
+	return s.bundles, nil
+}
+
+func newCatalogueSource(games int) *catalogueSource {
+	source := &catalogueSource{}
+	for i := 1; i <= games; i++ {
+		system := "arcade"
+		source.page.Games = append(source.page.Games, models.GameResponse{
+			Name:   fmt.Sprintf("Game %d", i),
+			GameId: uint16(i),
+			System: &system,
+			Price: models.Price{
+				ByTime:  []models.TimePrice{{Time: 10, Price: 50}, {Time: 30, Price: 120}},
+				ByLevel: []models.LevelPrice{{Level: 3, Price: 40}},
+			},
+		})
+	}
+	minutes := 60
+	source.bundles = []models.Bundle{{Id: 1, Name: "Hour pack", Price: 200, Kind: "balance", Minutes: &minutes, IsActive: true}}
+	return source
+}
+
+func BenchmarkCatalogue(b *testing.B) {
+	filter := models.GameFilter{Sort: "popular", Limit: defaultCataloguePage}
+
+	b.Run("cached", func(b *testing.B) {
+		source := newCatalogueSource(defaultCataloguePage)
+		service := NewCatalogueService(source, source, testRedis(b))
+		if _, _, err := service.Catalogue(filter); err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := service.Catalogue(filter); err != nil {
+				b.Fatal(err)
+			}
+		}
+		b.ReportMetric(float64(source.builds.Load()-1)/float64(b.N), "builds/op")
+	})
+
+	// what every request cost before the cache, the page built from the DB at DATABASE_URL.
+	b.Run("database", func(b *testing.B) {
+		dsn := os.Getenv("DATABASE_URL")
+		if dsn == "" {
+			b.Skip("DATABASE_URL isn't set")
+		}
+		db, err := sql.Open("postgres", dsn)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { db.Close() })
+
+		playGameService := NewPlayGameService(repositories.NewPlayGameReposiory(db), nil,
+			repositories.NewPricingRepository(db), nil, utils.CodeFormat{})
+		bundleService := NewBundleService(repositories.NewBundleRepository(db), playGameService)
+		service := NewCatalogueService(playGameService, bundleService, testRedis(b))
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if _, _, err := service.build(filter); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"fmt"
+)
+
+type CodeExpiryService interface {
+	GetPolicies() ([]models.CodeValidityPolicy, error)
+	SavePolicy(policy models.CodeValidityPolicy) error
+	DeletePolicy(gameId *uint16) error
+	ExpireCodes() error // run by the scheduler
+}
+
+type codeExpiryService struct {
+	codeExpiryRepository repositories.CodeExpiryRepository
+}
+
+func NewCodeExpiryService(codeExpiryRepository repositories.CodeExpiryRepository) *codeExpiryService {
+	return &codeExpiryService{codeExpiryRepository: codeExpiryRepository}
+}
+
+func (s *codeExpiryService) GetPolicies() ([]models.CodeValidityPolicy, error) {
+	return s.codeExpiryRepository.FetchPolicies()
+}
+
+// SavePolicy only affects codes bought afterwards, the expiry is fixed at purchase.
+func (s *codeExpiryService) SavePolicy(policy models.CodeValidityPolicy) error {
+	if policy.ValidDays <= 0 {
+		return fmt.Errorf("validDays should be more than 0")
+	}
+	return s.codeExpiryRepository.SavePolicy(policy)
+}
+
+func (s *codeExpiryService) DeletePolicy(gameId *uint16) error {
+	return s.codeExpiryRepository.DeletePolicy(gameId)
+}
+
+func (s *codeExpiryService) ExpireCodes() error {
+	expired, err := s.codeExpiryRepository.ExpireCodes()
+	if err != nil {
+		return err
+	}
+
+	credited := 0
+	for _, code := range expired {
+		credited += code.Credited
+	}
+
+	utils.LogInfo("Marked %d codes as expired, %d converted to wallet credit", len(expired), credited)
+	return nil
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrCouponRefused = errors.New("coupon can't be used")
+
+type CouponService interface {
+	GetCoupons() ([]models.Coupon, error)
+	AddCoupon(coupon models.Coupon) (int, error)
+	DeactivateCoupon(couponId int) error
+}
+
+type couponService struct {
+	couponRepository repositories.CouponRepository
+}
+
+func NewCouponService(couponRepository repositories.CouponRepository) *couponService {
+	return &couponService{couponRepository: couponRepository}
+}
+
+func (s *couponService) GetCoupons() ([]models.Coupon, error) {
+	return s.couponRepository.FetchCoupons()
+}
+
+func (s *couponService) AddCoupon(coupon models.Coupon) (int, error) {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+
+	switch coupon.Kind {
+	case models.CouponPercent:
+		if coupon.Value > 100 {
+			return 0, fmt.Errorf("percentage can't be more than 100")
+		}
+	case models.CouponFlat:
+	default:
+		return 0, fmt.Errorf("unknown coupon kind '%s'", coupon.Kind)
+	}
+	if coupon.Value <= 0 {
+		return 0, fmt.Errorf("value has to be positive")
+	}
+	if coupon.StartsAt != nil && coupon.EndsAt != nil && !coupon.EndsAt.After(*coupon.StartsAt) {
+		return 0, fmt.Errorf("coupon has to end after it starts")
+	}
+	if coupon.ProductType != nil {
+		switch *coupon.ProductType {
+		case models.PaymentPurposeGame, models.PaymentPurposeShop, models.PaymentPurposePass:
+		default:
+			return 0, fmt.Errorf("unknown product type '%s'", *coupon.ProductType)
+		}
+	}
+
+	return s.couponRepository.CreateCoupon(coupon)
+}
+
+func (s *couponService) DeactivateCoupon(couponId int) error {
+	return s.couponRepository.DeactivateCoupon(couponId)
+}
+
+// applyCoupon returns the discount the coupon gives on the purchase. The usage limits are checked
+// again when the redemption is saved, this only keeps the player from paying for a coupon that won't hold.
+func applyCoupon(coupon models.Coupon, purchase models.CouponPurchase, now time.Time) (uint16, error) {
+	switch {
+	case !coupon.IsActive:
+		return 0, fmt.Errorf("%w: it's no longer active", ErrCouponRefused)
+	case coupon.StartsAt != nil && now.Before(*coupon.StartsAt):
+		return 0, fmt.Errorf("%w: it isn't valid yet", ErrCouponRefused)
+	case coupon.EndsAt != nil && !now.Before(*coupon.EndsAt):
+		return 0, fmt.Errorf("%w: it has expired", ErrCouponRefused)
+	case coupon.MaxUses != nil && coupon.Uses >= *coupon.MaxUses:
+		return 0, fmt.Errorf("%w: it has been used up", ErrCouponRefused)
+	case coupon.MaxUsesPerPlayer != nil && purchase.PlayerId == nil:
+		return 0, fmt.Errorf("%w: log in to use it", ErrCouponRefused)
+	case coupon.MaxUsesPerPlayer != nil && coupon.PlayerUses >= *coupon.MaxUsesPerPlayer:
+		return 0, fmt.Errorf("%w: you have already used it", ErrCouponRefused)
+	case coupon.ProductType != nil && *coupon.ProductType != purchase.ProductType:
+		return 0, fmt.Errorf("%w: it's only for %s purchases", ErrCouponRefused, *coupon.ProductType)
+	case coupon.GameId != nil && *coupon.GameId != purchase.GameId:
+		return 0, fmt.Errorf("%w: it's for another game", ErrCouponRefused)
+	case coupon.TierLabel != nil && *coupon.TierLabel != purchase.TierLabel:
+		return 0, fmt.Errorf("%w: it's for another time or level", ErrCouponRefused)
+	}
+
+	if coupon.Kind == models.CouponPercent {
+		return uint16(int(purchase.Amount) * coupon.Value / 100), nil
+	}
+	return uint16(min(coupon.Value, int(purchase.Amount))), nil
+}
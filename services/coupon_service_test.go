@@ -0,0 +1,75 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestApplyCoupon(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.Local)
+	earlier, later := now.Add(-time.Hour), now.Add(time.Hour)
+	one, two := 1, 2
+	game, otherGame := uint16(7), uint16(8)
+	tier := uint16(30)
+	shop := models.PaymentPurposeShop
+	player := 11
+
+	percent := models.Coupon{Code: "HALF", Kind: models.CouponPercent, Value: 50, IsActive: true}
+	flat := models.Coupon{Code: "TWENTY", Kind: models.CouponFlat, Value: 20, IsActive: true}
+	purchase := models.CouponPurchase{ProductType: models.PaymentPurposeGame, GameId: game, TierLabel: tier,
+		Amount: 120, PlayerId: &player}
+	guest := purchase
+	guest.PlayerId = nil
+
+	with := func(coupon models.Coupon, change func(*models.Coupon)) models.Coupon {
+		change(&coupon)
+		return coupon
+	}
+
+	tests := []struct {
+		name     string
+		coupon   models.Coupon
+		purchase models.CouponPurchase
+		discount uint16
+		refused  bool
+	}{
+		{"percent of the price", percent, purchase, 60, false},
+		{"flat amount", flat, purchase, 20, false},
+		{"flat amount above the price", with(flat, func(c *models.Coupon) { c.Value = 500 }), purchase, 120, false},
+		{"inactive", with(percent, func(c *models.Coupon) { c.IsActive = false }), purchase, 0, true},
+		{"not started", with(percent, func(c *models.Coupon) { c.StartsAt = &later }), purchase, 0, true},
+		{"started", with(percent, func(c *models.Coupon) { c.StartsAt = &earlier }), purchase, 60, false},
+		{"ended", with(percent, func(c *models.Coupon) { c.EndsAt = &earlier }), purchase, 0, true},
+		{"ends exactly now", with(percent, func(c *models.Coupon) { c.EndsAt = &now }), purchase, 0, true},
+		{"used up", with(percent, func(c *models.Coupon) { c.MaxUses, c.Uses = &two, 2 }), purchase, 0, true},
+		{"uses left", with(percent, func(c *models.Coupon) { c.MaxUses, c.Uses = &two, 1 }), purchase, 60, false},
+		{"per player limit for a guest", with(percent, func(c *models.Coupon) { c.MaxUsesPerPlayer = &one }), guest, 0, true},
+		{"per player limit reached", with(percent, func(c *models.Coupon) { c.MaxUsesPerPlayer, c.PlayerUses = &one, 1 }), purchase, 0, true},
+		{"per player limit not reached", with(percent, func(c *models.Coupon) { c.MaxUsesPerPlayer = &one }), purchase, 60, false},
+		{"guest without a per player limit", percent, guest, 60, false},
+		{"other product type", with(percent, func(c *models.Coupon) { c.ProductType = &shop }), purchase, 0, true},
+		{"other game", with(percent, func(c *models.Coupon) { c.GameId = &otherGame }), purchase, 0, true},
+		{"same game", with(percent, func(c *models.Coupon) { c.GameId = &game }), purchase, 60, false},
+		{"other tier", with(percent, func(c *models.Coupon) { other := uint16(10); c.TierLabel = &other }), purchase, 0, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discount, err := applyCoupon(test.coupon, test.purchase, now)
+			if test.refused {
+				if !errors.Is(err, ErrCouponRefused) {
+					t.Fatalf("got %v, want ErrCouponRefused", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if discount != test.discount {
+				t.Fatalf("discount is %d, want %d", discount, test.discount)
+			}
+		})
+	}
+}
@@ -1,16 +1,47 @@
 package services
 
 import (
+	"GameWala-Arcade/config"
 	"GameWala-Arcade/models"
 	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
 )
 
+var ErrInvalidWebhook = errors.New("webhook signature doesn't match")
+
+// PaymentListener is told about every captured and refunded payment. A listener returning an error is
+// told again on the razorpay retry, the listeners that succeeded aren't.
+type PaymentListener interface {
+	PaymentCaptured(payment models.CapturedPayment) error
+	PaymentRefunded(refund models.Refund) error
+}
+
+// PaymentFailureListener is told about failed payment attempts, retried webhooks are told again.
+type PaymentFailureListener interface {
+	PaymentFailed(payment models.FailedPayment) error
+}
+
 type HandlePaymentService interface {
 	SaveOrderDetails(models.PaymentStatus) error
+	RecordOrder(order models.PaymentOrder) error
+	HandleWebhook(body []byte, signature string) error
+	AddListener(listener PaymentListener)
+	AddFailureListener(listener PaymentFailureListener)
 }
 
 type handlePaymentService struct {
 	handlePaymentRepository repositories.HandlePaymentRepository
+	listeners               []PaymentListener
+	failureListeners        []PaymentFailureListener
 }
 
 func NewHandlePaymentService(handlePaymentRepository repositories.HandlePaymentRepository) *handlePaymentService {
@@ -21,3 +52,193 @@ func (s *handlePaymentService) SaveOrderDetails(details models.PaymentStatus) er
 	err := s.handlePaymentRepository.SaveOrderDetails(details)
 	return err
 }
+
+func (s *handlePaymentService) RecordOrder(order models.PaymentOrder) error {
+	return s.handlePaymentRepository.RecordOrder(order)
+}
+
+func (s *handlePaymentService) AddListener(listener PaymentListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *handlePaymentService) AddFailureListener(listener PaymentFailureListener) {
+	s.failureListeners = append(s.failureListeners, listener)
+}
+
+// razorpayWebhook only has the parts of the payload we use.
+type razorpayWebhook struct {
+	Event   string `json:"event"`
+	Payload struct {
+		Payment struct {
+			Entity struct {
+				Id        string            `json:"id"`
+				OrderId   string            `json:"order_id"`
+				Amount    int64             `json:"amount"`
+				Method    string            `json:"method"`
+				ErrorDesc string            `json:"error_description"` // set for failed payments
+				InvoiceId string            `json:"invoice_id"`        // set for subscription charges
+				Notes     map[string]string `json:"notes"`
+				CreatedAt int64             `json:"created_at"`
+			} `json:"entity"`
+		} `json:"payment"`
+		Refund struct {
+			Entity struct {
+				Id        string `json:"id"`
+				PaymentId string `json:"payment_id"`
+				Amount    int64  `json:"amount"`
+			} `json:"entity"`
+		} `json:"refund"`
+		Subscription struct {
+			Entity struct {
+				Id    string            `json:"id"`
+				Notes map[string]string `json:"notes"`
+			} `json:"entity"`
+		} `json:"subscription"`
+	} `json:"payload"`
+}
+
+// HandleWebhook verifies the razorpay signature and passes new captures and refunds to the listeners,
+// retried webhooks are recognised by the payment and refund ids and only finish what failed before.
+func (s *handlePaymentService) HandleWebhook(body []byte, signature string) error {
+	// without a secret anyone could sign a webhook, so none is trusted.
+	secret := config.GetString("webhookSecret")
+	if secret == "" {
+		utils.LogError("Razorpay webhook received but webhookSecret isn't configured")
+		return ErrInvalidWebhook
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, mac.Sum(nil)) {
+		utils.LogError("Razorpay webhook with an invalid signature")
+		return ErrInvalidWebhook
+	}
+
+	var webhook razorpayWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return fmt.Errorf("error parsing webhook: %w", err)
+	}
+
+	utils.LogInfo("Received razorpay webhook '%s'", webhook.Event)
+	entity := webhook.Payload.Payment.Entity
+
+	switch webhook.Event {
+	case "payment.captured", "subscription.charged":
+		if webhook.Event == "subscription.charged" {
+			// the renewal payments don't carry notes of their own, the subscription has them.
+			entity.Notes = webhook.Payload.Subscription.Entity.Notes
+		} else if entity.InvoiceId != "" && entity.Notes["purpose"] == "" {
+			utils.LogInfo("Payment ID %s is a subscription charge, waiting for subscription.charged", entity.Id)
+			return nil
+		}
+
+		payment := models.CapturedPayment{
+			PaymentId:  entity.Id,
+			OrderId:    entity.OrderId,
+			Amount:     entity.Amount,
+			Method:     entity.Method,
+			Purpose:    entity.Notes["purpose"],
+			PlayerId:   noteInt(entity.Notes, "player_id"),
+			PassId:     noteInt(entity.Notes, "pass_id"),
+			BundleId:   noteInt(entity.Notes, "bundle_id"),
+			CapturedAt: time.Unix(entity.CreatedAt, 0),
+		}
+		if subscriptionId := webhook.Payload.Subscription.Entity.Id; subscriptionId != "" {
+			payment.SubscriptionId = &subscriptionId
+		}
+		if gameId := noteInt(entity.Notes, "game_id"); gameId != nil {
+			id := uint16(*gameId)
+			payment.GameId = &id
+		}
+
+		pending, err := s.handlePaymentRepository.RecordCapture(payment)
+		if err != nil || !pending {
+			return err
+		}
+
+		err = s.notify("capture:"+payment.PaymentId, func(listener PaymentListener) error {
+			return listener.PaymentCaptured(payment)
+		})
+		if err != nil {
+			return err
+		}
+		return s.handlePaymentRepository.FinishCapture(payment.PaymentId)
+
+	case "payment.failed":
+		payment := models.FailedPayment{
+			PaymentId: entity.Id,
+			OrderId:   entity.OrderId,
+			Purpose:   entity.Notes["purpose"],
+			Reason:    entity.ErrorDesc,
+		}
+
+		// the failure listeners only give back what was held, running them again does no harm.
+		for _, listener := range s.failureListeners {
+			if err := listener.PaymentFailed(payment); err != nil {
+				utils.LogError("Payment listener failed for failed payment ID %s: %v", payment.PaymentId, err)
+				return err
+			}
+		}
+
+	case "refund.processed":
+		refund := models.Refund{
+			RefundId:  webhook.Payload.Refund.Entity.Id,
+			PaymentId: webhook.Payload.Refund.Entity.PaymentId,
+			Amount:    webhook.Payload.Refund.Entity.Amount,
+		}
+
+		refund, pending, err := s.handlePaymentRepository.RecordRefund(refund)
+		if err != nil || !pending {
+			return err
+		}
+
+		err = s.notify("refund:"+refund.RefundId, func(listener PaymentListener) error {
+			return listener.PaymentRefunded(refund)
+		})
+		if err != nil {
+			return err
+		}
+		return s.handlePaymentRepository.FinishRefund(refund.RefundId)
+	}
+
+	return nil
+}
+
+// notify runs the listeners that didn't handle the event yet. A failing listener fails the webhook so
+// razorpay retries it, the others are marked done and skipped on the retry.
+func (s *handlePaymentService) notify(eventId string, handle func(PaymentListener) error) error {
+	var failed []string
+	for _, listener := range s.listeners {
+		name := fmt.Sprintf("%T", listener)
+		done, err := s.handlePaymentRepository.ListenerDone(eventId, name)
+		if err != nil {
+			return err
+		}
+		if done {
+			continue
+		}
+
+		if err := handle(listener); err != nil {
+			utils.LogError("Payment listener %s failed for payment event %s: %v", name, eventId, err)
+			failed = append(failed, name)
+			continue
+		}
+		if err := s.handlePaymentRepository.MarkListenerDone(eventId, name); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("payment listeners failed for %s: %s", eventId, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func noteInt(notes map[string]string, key string) *int {
+	value, err := strconv.Atoi(notes[key])
+	if err != nil || value <= 0 {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// a product is low on stock at 5 units or less unless the config says otherwise.
+const defaultLowStockThreshold = 5
+
+var ErrInvalidStockChange = errors.New("invalid stock change")
+
+type InventoryService interface {
+	Restock(change models.StockChange, adminId int) (int, error)
+	// Adjust corrects the stock by the change for a reason, breakage or a miscount.
+	Adjust(change models.StockChange, adminId int) (int, error)
+
+	// scope nil for every venue and the central stock, threshold 0 for the one of the config.
+	GetLowStock(scope []string, threshold int) ([]models.LowStock, error)
+	GetMovements(productId *int, scope []string, from time.Time, to time.Time, limit int) ([]models.StockMovement, error)
+
+	// ExpireReservations is the job marking the holds of unpaid checkouts that ran out.
+	ExpireReservations() error
+	// StockSold raises the alerts for what a paid shop order took, oversold orders and low stock.
+	StockSold(order models.ShopOrder, sold []models.SoldStock)
+}
+
+type inventoryService struct {
+	inventoryRepository repositories.InventoryRepository
+	smsSender           utils.SMSSender
+}
+
+func NewInventoryService(inventoryRepository repositories.InventoryRepository, smsSender utils.SMSSender) *inventoryService {
+	return &inventoryService{inventoryRepository: inventoryRepository, smsSender: smsSender}
+}
+
+func (s *inventoryService) Restock(change models.StockChange, adminId int) (int, error) {
+	if change.Change <= 0 {
+		return 0, fmt.Errorf("%w: units to restock have to be positive", ErrInvalidStockChange)
+	}
+	return s.changeStock(change, models.StockRestock, adminId)
+}
+
+func (s *inventoryService) Adjust(change models.StockChange, adminId int) (int, error) {
+	if change.Change == 0 {
+		return 0, fmt.Errorf("%w: the change can't be 0", ErrInvalidStockChange)
+	}
+	if change.Reason == "" {
+		return 0, fmt.Errorf("%w: adjustments need a reason", ErrInvalidStockChange)
+	}
+	return s.changeStock(change, models.StockAdjustment, adminId)
+}
+
+func (s *inventoryService) GetLowStock(scope []string, threshold int) ([]models.LowStock, error) {
+	if threshold <= 0 {
+		threshold = lowStockThreshold()
+	}
+	return s.inventoryRepository.FetchLowStock(scope, threshold)
+}
+
+func (s *inventoryService) GetMovements(productId *int, scope []string, from time.Time, to time.Time, limit int) ([]models.StockMovement, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: to can't be before from", ErrInvalidRange)
+	}
+	return s.inventoryRepository.FetchMovements(productId, scope, from, to, limit)
+}
+
+func (s *inventoryService) ExpireReservations() error {
+	expired, err := s.inventoryRepository.ExpireReservations()
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		utils.LogInfo("Released %d stock holds of checkouts that weren't paid in time", expired)
+	}
+	return nil
+}
+
+func (s *inventoryService) StockSold(order models.ShopOrder, sold []models.SoldStock) {
+	venue := "the central stock"
+	if order.Venue != nil {
+		venue = *order.Venue
+	}
+	threshold := lowStockThreshold()
+
+	for _, stock := range sold {
+		var message string
+		switch {
+		case stock.Oversold:
+			utils.LogError("Shop order ID %d was paid after its hold ran out, product ID %d at %s is oversold and needs a refund",
+				order.Id, stock.ProductId, venue)
+			message = fmt.Sprintf("GameWala shop: order %d is oversold, product %d at %s ran out. Please refund.",
+				order.Id, stock.ProductId, venue)
+		case stock.UnitsLeft <= threshold && stock.UnitsLeft+stock.Quantity > threshold:
+			// only the sale that crosses the threshold alerts, the ones after it would repeat it.
+			message = fmt.Sprintf("GameWala shop: product %d at %s is low, %d left.", stock.ProductId, venue, stock.UnitsLeft)
+		default:
+			continue
+		}
+
+		utils.LogInfo("%s", message)
+		if phone := config.GetString("stockAlertPhone"); phone != "" {
+			if err := s.smsSender.Send(phone, message); err != nil {
+				utils.LogError("Failed to send stock alert: %v", err)
+			}
+		}
+	}
+}
+
+func (s *inventoryService) changeStock(change models.StockChange, kind string, adminId int) (int, error) {
+	units, applied, err := s.inventoryRepository.ChangeStock(change, kind, adminId)
+	if err == sql.ErrNoRows {
+		if change.Venue != "" {
+			return 0, fmt.Errorf("%w: or venue '%s' doesn't stock it", ErrProductNotFound, change.Venue)
+		}
+		return 0, ErrProductNotFound
+	} else if err != nil {
+		return 0, err
+	}
+	if !applied {
+		return units, fmt.Errorf("%w: %d units are there, some may be held for orders not paid yet", ErrNotEnoughStock, units)
+	}
+
+	utils.LogInfo("Stock of product ID %d changed by %d to %d by admin ID %d (%s)", change.ProductId, change.Change, units, adminId, kind)
+	return units, nil
+}
+
+func lowStockThreshold() int {
+	threshold := config.GetInt("lowStockThreshold")
+	if threshold <= 0 {
+		threshold = defaultLowStockThreshold
+	}
+	return threshold
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const leaderboardEntriesKey = "leaderboard:entries"
+
+// a rebuild writes the new boards under this prefix before they replace the live ones.
+const (
+	leaderboardRebuildPrefix = "rebuild:"
+	leaderboardRebuildTTL    = 10 * time.Minute
+)
+
+// daily and weekly boards are only read for the current period, they clean themselves up.
+const (
+	dailyBoardTTL  = 48 * time.Hour
+	weeklyBoardTTL = 8 * 24 * time.Hour
+)
+
+var ErrScoreRejected = errors.New("score doesn't match any code bought for this game")
+
+type LeaderboardService interface {
+	SubmitScore(submission models.ScoreSubmission, machineId string) (models.Score, error)
+	Leaderboard(gameId uint16, venue string, period string, limit int) ([]models.LeaderboardEntry, error)
+	GetScores(gameId *uint16, limit int, venues []string) ([]models.Score, error) // venues of the admin, nil for all
+	RemoveScore(scoreId int, venues []string) error
+	RebuildLeaderboards() error
+}
+
+type leaderboardService struct {
+	scoreRepository repositories.ScoreRepository
+	redisClient     *redis.Client
+}
+
+func NewLeaderboardService(scoreRepository repositories.ScoreRepository,
+	redisClient *redis.Client) *leaderboardService {
+	return &leaderboardService{scoreRepository: scoreRepository, redisClient: redisClient}
+}
+
+// leaderboardEntry is what the sorted sets point to, they only hold the score id.
+type leaderboardEntry struct {
+	Name  string `json:"name"`
+	Venue string `json:"venue"`
+}
+
+func (s *leaderboardService) SubmitScore(submission models.ScoreSubmission, machineId string) (models.Score, error) {
+	if submission.Score < 0 {
+		return models.Score{}, fmt.Errorf("score can't be negative")
+	}
+
+	score, err := s.scoreRepository.SaveScore(submission, machineId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return score, ErrScoreRejected
+		}
+		return score, err
+	}
+
+	ctx := context.Background()
+	pipe := s.redisClient.TxPipeline()
+	addToBoards(ctx, pipe, "", score, time.Now())
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError("Failed to add score ID %d to the leaderboards: %v", score.Id, err)
+		return score, fmt.Errorf("error updating leaderboards: %w", err)
+	}
+
+	utils.LogInfo("Score ID %d added to the leaderboards of game ID %d", score.Id, score.GameId)
+	return score, nil
+}
+
+// Leaderboard gives the top scores of the current day, week or all time, empty venue means every venue.
+func (s *leaderboardService) Leaderboard(gameId uint16, venue string, period string, limit int) ([]models.LeaderboardEntry, error) {
+	ctx := context.Background()
+
+	top, err := s.redisClient.ZRevRangeWithScores(ctx, boardKey(gameId, venue, period, time.Now()), 0, int64(limit-1)).Result()
+	if err != nil {
+		utils.LogError("Failed to read leaderboard of game ID %d: %v", gameId, err)
+		return nil, fmt.Errorf("error reading leaderboard: %w", err)
+	}
+
+	entries := []models.LeaderboardEntry{}
+	if len(top) == 0 {
+		return entries, nil
+	}
+
+	members := make([]string, len(top))
+	for i, z := range top {
+		members[i] = z.Member.(string)
+	}
+
+	details, err := s.redisClient.HMGet(ctx, leaderboardEntriesKey, members...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error reading leaderboard entries: %w", err)
+	}
+
+	for i, z := range top {
+		var detail leaderboardEntry
+		if raw, ok := details[i].(string); ok {
+			json.Unmarshal([]byte(raw), &detail)
+		}
+
+		scoreId, _ := strconv.Atoi(members[i])
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:    i + 1,
+			ScoreId: scoreId,
+			Name:    detail.Name,
+			Score:   int64(z.Score),
+			Venue:   detail.Venue,
+		})
+	}
+
+	return entries, nil
+}
+
+func (s *leaderboardService) GetScores(gameId *uint16, limit int, venues []string) ([]models.Score, error) {
+	return s.scoreRepository.FetchScores(gameId, limit, venues)
+}
+
+// RemoveScore keeps the row for the audit trail but takes it off every board it was on.
+func (s *leaderboardService) RemoveScore(scoreId int, venues []string) error {
+	score, err := s.scoreRepository.RemoveScore(scoreId, venues)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	pipe := s.redisClient.TxPipeline()
+	removeFromBoards(ctx, pipe, score)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError("Failed to remove score ID %d from the leaderboards: %v", scoreId, err)
+		return fmt.Errorf("error updating leaderboards: %w", err)
+	}
+
+	utils.LogInfo("Score ID %d removed from the leaderboards", scoreId)
+	return nil
+}
+
+// RebuildLeaderboards replaces every board with what the scores in the DB say, for when redis lost
+// them or a score was saved but never made it onto the boards. The boards are built under temporary
+// keys and renamed over the live ones at once, the scores submitted or removed meanwhile are caught up after.
+func (s *leaderboardService) RebuildLeaderboards() error {
+	scores, err := s.scoreRepository.FetchBoardScores()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	now := time.Now()
+
+	// the live key of every board built with its ttl, 0 for the ones that don't expire.
+	built := map[string]time.Duration{}
+	pipe := s.redisClient.Pipeline()
+	for _, score := range scores {
+		addToBoards(ctx, pipe, leaderboardRebuildPrefix, score, now)
+		built[leaderboardEntriesKey] = 0
+		for _, key := range liveBoardKeys(score, now) {
+			built[key.name] = key.ttl
+		}
+	}
+	// a rebuild dying halfway leaves nothing behind for long.
+	for key, ttl := range built {
+		if ttl == 0 {
+			pipe.Expire(ctx, leaderboardRebuildPrefix+key, leaderboardRebuildTTL)
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError("Failed to build the new leaderboards: %v", err)
+		return fmt.Errorf("error updating leaderboards: %w", err)
+	}
+
+	var stale []string
+	iter := s.redisClient.Scan(ctx, 0, "leaderboard:*", 1000).Iterator()
+	for iter.Next(ctx) {
+		if _, ok := built[iter.Val()]; !ok {
+			stale = append(stale, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		utils.LogError("Failed to list the leaderboards: %v", err)
+		return fmt.Errorf("error reading leaderboards: %w", err)
+	}
+
+	swap := s.redisClient.TxPipeline()
+	if len(stale) > 0 {
+		swap.Del(ctx, stale...)
+	}
+	for key, ttl := range built {
+		swap.Rename(ctx, leaderboardRebuildPrefix+key, key)
+		if ttl == 0 {
+			swap.Persist(ctx, key)
+		}
+	}
+	if _, err := swap.Exec(ctx); err != nil {
+		utils.LogError("Failed to swap in the new leaderboards: %v", err)
+		return fmt.Errorf("error updating leaderboards: %w", err)
+	}
+
+	if err := s.catchUp(ctx, scores, now); err != nil {
+		return err
+	}
+
+	utils.LogInfo("Leaderboards rebuilt from %d scores", len(scores))
+	return nil
+}
+
+// catchUp puts back what changed on the live boards while the rebuilt ones were built from before.
+func (s *leaderboardService) catchUp(ctx context.Context, before []models.Score, now time.Time) error {
+	after, err := s.scoreRepository.FetchBoardScores()
+	if err != nil {
+		return err
+	}
+
+	rebuilt := make(map[int]bool, len(before))
+	for _, score := range before {
+		rebuilt[score.Id] = true
+	}
+
+	pipe := s.redisClient.TxPipeline()
+	for _, score := range after {
+		if !rebuilt[score.Id] {
+			addToBoards(ctx, pipe, "", score, now)
+		}
+		delete(rebuilt, score.Id)
+	}
+	for _, score := range before {
+		if rebuilt[score.Id] {
+			removeFromBoards(ctx, pipe, score)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		utils.LogError("Failed to catch up the rebuilt leaderboards: %v", err)
+		return fmt.Errorf("error updating leaderboards: %w", err)
+	}
+	return nil
+}
+
+// addToBoards queues the score onto its boards under the key prefix, daily and weekly boards that
+// already ran out are skipped.
+func addToBoards(ctx context.Context, pipe redis.Pipeliner, prefix string, score models.Score, now time.Time) {
+	entry, _ := json.Marshal(leaderboardEntry{Name: score.Name, Venue: score.Venue})
+	member := strconv.Itoa(score.Id)
+
+	pipe.HSet(ctx, prefix+leaderboardEntriesKey, member, entry)
+	for _, key := range liveBoardKeys(score, now) {
+		pipe.ZAdd(ctx, prefix+key.name, redis.Z{Score: float64(score.Score), Member: member})
+		if key.ttl > 0 {
+			pipe.Expire(ctx, prefix+key.name, key.ttl)
+		}
+	}
+}
+
+func removeFromBoards(ctx context.Context, pipe redis.Pipeliner, score models.Score) {
+	member := strconv.Itoa(score.Id)
+	for _, key := range scoreBoardKeys(score) {
+		pipe.ZRem(ctx, key.name, member)
+	}
+	pipe.HDel(ctx, leaderboardEntriesKey, member)
+}
+
+// liveBoardKeys are the boards of the score that haven't run out yet.
+func liveBoardKeys(score models.Score, now time.Time) []boardKeyTTL {
+	var keys []boardKeyTTL
+	for _, key := range scoreBoardKeys(score) {
+		if key.ttl == 0 || now.Sub(score.CreatedAt) < key.ttl {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+type boardKeyTTL struct {
+	name string
+	ttl  time.Duration
+}
+
+// scoreBoardKeys are the boards a score belongs to, global and venue ones for every period.
+func scoreBoardKeys(score models.Score) []boardKeyTTL {
+	var keys []boardKeyTTL
+	for _, venue := range []string{"", score.Venue} {
+		keys = append(keys,
+			boardKeyTTL{boardKey(score.GameId, venue, models.LeaderboardDaily, score.CreatedAt), dailyBoardTTL},
+			boardKeyTTL{boardKey(score.GameId, venue, models.LeaderboardWeekly, score.CreatedAt), weeklyBoardTTL},
+			boardKeyTTL{boardKey(score.GameId, venue, models.LeaderboardAllTime, score.CreatedAt), 0},
+		)
+	}
+	return keys
+}
+
+func boardKey(gameId uint16, venue string, period string, at time.Time) string {
+	key := fmt.Sprintf("leaderboard:game:%d", gameId)
+	if venue != "" {
+		key += ":venue:" + venue
+	}
+
+	at = at.Local()
+	switch period {
+	case models.LeaderboardDaily:
+		return key + ":daily:" + at.Format("20060102")
+	case models.LeaderboardWeekly:
+		year, week := at.ISOWeek()
+		return fmt.Sprintf("%s:weekly:%d-%02d", key, year, week)
+	default:
+		return key + ":all"
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// savedScores stands in for the Scores table, only what is still on the boards. later, when set, is
+// what the table holds from the second read on, as if scores were submitted or removed meanwhile.
+type savedScores struct {
+	repositories.ScoreRepository
+	scores []models.Score
+	later  []models.Score
+	reads  int
+}
+
+func (r *savedScores) FetchBoardScores() ([]models.Score, error) {
+	r.reads++
+	if r.reads > 1 && r.later != nil {
+		return r.later, nil
+	}
+	return r.scores, nil
+}
+
+func TestRebuildLeaderboards(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	repo := &savedScores{scores: []models.Score{
+		{Id: 1, GameId: 7, Venue: "north", Name: "ada", Score: 300, CreatedAt: now},
+		{Id: 2, GameId: 7, Venue: "south", Name: "lin", Score: 500, CreatedAt: now.Add(-30 * 24 * time.Hour)},
+	}}
+	service := NewLeaderboardService(repo, client)
+
+	// a removed score left behind on a board, the rebuild drops it.
+	client.ZAdd(ctx, boardKey(7, "", models.LeaderboardAllTime, now), redis.Z{Score: 900, Member: "3"})
+
+	if err := service.RebuildLeaderboards(); err != nil {
+		t.Fatalf("rebuilding the leaderboards: %v", err)
+	}
+
+	all, err := service.Leaderboard(7, "", models.LeaderboardAllTime, 10)
+	if err != nil {
+		t.Fatalf("reading the leaderboard: %v", err)
+	}
+	if len(all) != 2 || all[0].ScoreId != 2 || all[1].ScoreId != 1 || all[1].Name != "ada" {
+		t.Fatalf("all time board is %+v, want scores 2 then 1", all)
+	}
+
+	// the old score is only on the all time boards, its week is long gone.
+	weekly, err := service.Leaderboard(7, "", models.LeaderboardWeekly, 10)
+	if err != nil {
+		t.Fatalf("reading the leaderboard: %v", err)
+	}
+	if len(weekly) != 1 || weekly[0].ScoreId != 1 {
+		t.Fatalf("weekly board is %+v, want only score 1", weekly)
+	}
+	if exists, _ := client.Exists(ctx, boardKey(7, "south", models.LeaderboardWeekly, repo.scores[1].CreatedAt)).Result(); exists != 0 {
+		t.Errorf("weekly board of an old score was rebuilt")
+	}
+}
+
+func TestRebuildLeaderboardsCatchesUp(t *testing.T) {
+	client := testRedis(t)
+	now := time.Now()
+
+	kept := models.Score{Id: 1, GameId: 7, Venue: "north", Name: "ada", Score: 300, CreatedAt: now}
+	removed := models.Score{Id: 2, GameId: 7, Venue: "north", Name: "lin", Score: 500, CreatedAt: now}
+	submitted := models.Score{Id: 3, GameId: 7, Venue: "south", Name: "max", Score: 400, CreatedAt: now}
+
+	// score 3 is submitted and score 2 removed while the rebuild runs.
+	repo := &savedScores{scores: []models.Score{kept, removed}, later: []models.Score{kept, submitted}}
+	service := NewLeaderboardService(repo, client)
+
+	if err := service.RebuildLeaderboards(); err != nil {
+		t.Fatalf("rebuilding the leaderboards: %v", err)
+	}
+
+	board, err := service.Leaderboard(7, "", models.LeaderboardAllTime, 10)
+	if err != nil {
+		t.Fatalf("reading the leaderboard: %v", err)
+	}
+	if len(board) != 2 || board[0].ScoreId != 3 || board[1].ScoreId != 1 {
+		t.Fatalf("board is %+v, want scores 3 then 1", board)
+	}
+
+	// nothing is left under the rebuild keys and the all time board doesn't expire.
+	if keys, _ := client.Keys(context.Background(), leaderboardRebuildPrefix+"*").Result(); len(keys) != 0 {
+		t.Errorf("rebuild keys left behind: %v", keys)
+	}
+	if ttl, _ := client.TTL(context.Background(), boardKey(7, "", models.LeaderboardAllTime, now)).Result(); ttl != -1 {
+		t.Errorf("all time board expires in %v", ttl)
+	}
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const defaultPointsValidityDays = 365
+
+const loyaltyLedgerSize = 50
+
+var ErrNotEnoughPoints = errors.New("not enough points for this reward")
+var ErrRewardNotFound = errors.New("reward doesn't exist or isn't available anymore")
+
+// ordered by MinPoints, the last tier the player qualifies for wins.
+var loyaltyTiers = []models.LoyaltyTier{
+	{Name: "bronze", MinPoints: 0, EarnMultiplier: 1, Perks: []string{"1 point for every rupee spent"}},
+	{Name: "silver", MinPoints: 1000, EarnMultiplier: 1.25, Perks: []string{"25% bonus points", "early access to new games"}},
+	{Name: "gold", MinPoints: 5000, EarnMultiplier: 1.5, Perks: []string{"50% bonus points", "early access to new games", "free birthday game"}},
+}
+
+type LoyaltyService interface {
+	PaymentListener
+
+	GetRules() ([]models.LoyaltyRule, error)
+	AddRule(rule models.LoyaltyRule) (int, error)
+	DeleteRule(ruleId int) error
+
+	GetRewards() ([]models.LoyaltyReward, error)
+	AddReward(reward models.LoyaltyReward) (int, error)
+	DeactivateReward(rewardId int) error
+
+	GetSummary(playerId int) (models.LoyaltySummary, error)
+	Redeem(playerId int, rewardId int) (models.Redemption, error)
+	ExpirePoints() error // run by the scheduler
+}
+
+type loyaltyService struct {
+	loyaltyRepository repositories.LoyaltyRepository
+	playGameService   PlayGameService
+}
+
+func NewLoyaltyService(loyaltyRepository repositories.LoyaltyRepository,
+	playGameService PlayGameService) *loyaltyService {
+	return &loyaltyService{loyaltyRepository: loyaltyRepository, playGameService: playGameService}
+}
+
+// PaymentCaptured credits the points of a logged in player, amounts come in paise.
+func (s *loyaltyService) PaymentCaptured(payment models.CapturedPayment) error {
+	if payment.PlayerId == nil {
+		return nil
+	}
+
+	points, err := s.pointsFor(*payment.PlayerId, payment)
+	if err != nil || points <= 0 {
+		return err
+	}
+
+	expiresAt := time.Now().AddDate(0, 0, pointsValidityDays())
+	_, err = s.loyaltyRepository.AddEntry(*payment.PlayerId, points, "earn", &payment.PaymentId, nil, &expiresAt)
+	return err
+}
+
+// PaymentRefunded takes back the share of the points matching the refunded share of the payment.
+func (s *loyaltyService) PaymentRefunded(refund models.Refund) error {
+	playerId, earned, reversed, err := s.loyaltyRepository.FetchPaymentPoints(refund.PaymentId)
+	if err != nil || earned == 0 || refund.PaymentAmount <= 0 {
+		return err
+	}
+
+	points := int(math.Ceil(float64(earned) * float64(refund.Amount) / float64(refund.PaymentAmount)))
+	if points > earned-reversed {
+		points = earned - reversed
+	}
+	if points <= 0 {
+		return nil
+	}
+
+	_, err = s.loyaltyRepository.AddEntry(playerId, -points, "reversal", &refund.PaymentId, nil, nil)
+	return err
+}
+
+func (s *loyaltyService) pointsFor(playerId int, payment models.CapturedPayment) (int, error) {
+	rules, err := s.loyaltyRepository.FetchRules()
+	if err != nil {
+		return 0, err
+	}
+
+	_, yearlyEarned, err := s.loyaltyRepository.FetchSummary(playerId)
+	if err != nil {
+		return 0, err
+	}
+
+	tier, _ := tierFor(yearlyEarned)
+	multiplier := tier.EarnMultiplier
+	weekday := int(payment.CapturedAt.Local().Weekday())
+
+	for _, rule := range rules {
+		if rule.GameId != nil && (payment.GameId == nil || *rule.GameId != *payment.GameId) {
+			continue
+		}
+		if rule.Weekday != nil && *rule.Weekday != weekday {
+			continue
+		}
+		multiplier *= rule.Multiplier
+	}
+
+	rupees := float64(payment.Amount) / 100
+	return int(math.Floor(rupees * pointsPerRupee() * multiplier)), nil
+}
+
+func (s *loyaltyService) GetRules() ([]models.LoyaltyRule, error) {
+	return s.loyaltyRepository.FetchRules()
+}
+
+func (s *loyaltyService) AddRule(rule models.LoyaltyRule) (int, error) {
+	if rule.Multiplier <= 0 {
+		return 0, fmt.Errorf("multiplier should be more than 0")
+	}
+	if rule.Weekday != nil && (*rule.Weekday < 0 || *rule.Weekday > 6) {
+		return 0, fmt.Errorf("weekday should be between 0 (sunday) and 6 (saturday)")
+	}
+	return s.loyaltyRepository.CreateRule(rule)
+}
+
+func (s *loyaltyService) DeleteRule(ruleId int) error {
+	return s.loyaltyRepository.DeleteRule(ruleId)
+}
+
+func (s *loyaltyService) GetRewards() ([]models.LoyaltyReward, error) {
+	return s.loyaltyRepository.FetchRewards()
+}
+
+func (s *loyaltyService) AddReward(reward models.LoyaltyReward) (int, error) {
+	if reward.Title == "" || reward.Points <= 0 {
+		return 0, fmt.Errorf("title and points are required")
+	}
+
+	switch reward.Kind {
+	case models.RewardKindGame:
+		if reward.GameId == nil || reward.Name == nil || reward.Price == nil || (reward.PlayTime == nil && reward.Levels == nil) {
+			return 0, fmt.Errorf("game rewards need the game id, name, price and either time or levels")
+		}
+
+		// the tier has to exist, otherwise the redemption would fail later on.
+		if _, err := s.playGameService.ValidatePrice(rewardGameStatus(reward)); err != nil {
+			return 0, fmt.Errorf("price doesn't match the game tier")
+		}
+	case models.RewardKindProduct:
+		if reward.ProductId == nil {
+			return 0, fmt.Errorf("product rewards need the product id")
+		}
+	default:
+		return 0, fmt.Errorf("kind should be either game or product")
+	}
+
+	return s.loyaltyRepository.CreateReward(reward)
+}
+
+func (s *loyaltyService) DeactivateReward(rewardId int) error {
+	return s.loyaltyRepository.DeactivateReward(rewardId)
+}
+
+func (s *loyaltyService) GetSummary(playerId int) (models.LoyaltySummary, error) {
+	var summary models.LoyaltySummary
+	var err error
+
+	summary.Balance, summary.YearlyEarned, err = s.loyaltyRepository.FetchSummary(playerId)
+	if err != nil {
+		return summary, err
+	}
+
+	summary.Tier, summary.NextTier = tierFor(summary.YearlyEarned)
+	summary.Entries, err = s.loyaltyRepository.FetchLedger(playerId, loyaltyLedgerSize)
+	return summary, err
+}
+
+// Redeem takes the points off first, game rewards then get a code paid by the ledger entry.
+func (s *loyaltyService) Redeem(playerId int, rewardId int) (models.Redemption, error) {
+	rewards, err := s.loyaltyRepository.FetchRewards()
+	if err != nil {
+		return models.Redemption{}, err
+	}
+
+	var reward *models.LoyaltyReward
+	for i := range rewards {
+		if rewards[i].Id == rewardId {
+			reward = &rewards[i]
+		}
+	}
+	if reward == nil {
+		return models.Redemption{}, ErrRewardNotFound
+	}
+
+	entryId, err := s.loyaltyRepository.Redeem(playerId, rewardId)
+	if err != nil {
+		return models.Redemption{}, err
+	}
+	if entryId == 0 {
+		return models.Redemption{}, ErrNotEnoughPoints
+	}
+
+	redemption := models.Redemption{ClaimId: entryId}
+	if reward.Kind != models.RewardKindGame {
+		utils.LogInfo("Player ID %d redeemed product reward ID %d, claim ID %d", playerId, rewardId, entryId)
+		return redemption, nil
+	}
+
+	status := rewardGameStatus(*reward)
+	status.PaymentReference = fmt.Sprintf("loyalty-%d", entryId)
+	status.PlayerId = &playerId
+
+	_, code, err := s.playGameService.IssueCode(status)
+	if err != nil {
+		utils.LogError("Failed to issue code for claim ID %d, giving the points back: %v", entryId, err)
+		s.loyaltyRepository.AddEntry(playerId, reward.Points, "redeem-cancel", nil, &rewardId, nil)
+		return models.Redemption{}, err
+	}
+
+	redemption.Code = code
+	utils.LogInfo("Player ID %d redeemed reward ID %d for code %s", playerId, rewardId, code)
+	return redemption, nil
+}
+
+func (s *loyaltyService) ExpirePoints() error {
+	lots, err := s.loyaltyRepository.ExpirePoints()
+	if err != nil {
+		return err
+	}
+
+	utils.LogInfo("Expired loyalty points of %d earn entries", lots)
+	return nil
+}
+
+// tierFor gives the current tier and the next one, nil when already at the top.
+func tierFor(yearlyEarned int) (models.LoyaltyTier, *models.LoyaltyTier) {
+	current := 0
+	for i, tier := range loyaltyTiers {
+		if yearlyEarned >= tier.MinPoints {
+			current = i
+		}
+	}
+
+	if current+1 < len(loyaltyTiers) {
+		return loyaltyTiers[current], &loyaltyTiers[current+1]
+	}
+	return loyaltyTiers[current], nil
+}
+
+func rewardGameStatus(reward models.LoyaltyReward) models.GameStatus {
+	return models.GameStatus{
+		Name:     *reward.Name,
+		GameId:   *reward.GameId,
+		IsTimed:  reward.PlayTime != nil,
+		Price:    *reward.Price,
+		PlayTime: reward.PlayTime,
+		Levels:   reward.Levels,
+	}
+}
+
+func pointsPerRupee() float64 {
+	if rate := config.GetFloat("pointsPerRupee"); rate > 0 {
+		return rate
+	}
+	return 1
+}
+
+func pointsValidityDays() int {
+	if days := config.GetInt("pointsValidityDays"); days > 0 {
+		return days
+	}
+	return defaultPointsValidityDays
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// how far the cabinet clock may drift, older signed requests are refused as replays.
+const maxSignatureSkew = 5 * time.Minute
+
+var ErrInvalidSignature = errors.New("request signature is invalid or too old")
+
+type MachineService interface {
+	Register(machine models.Machine) (models.Machine, error)
+	GetMachines(venues []string) ([]models.Machine, error) // venues of the admin, nil for all
+	GetMachine(machineId string) (models.Machine, error)
+	Authenticate(machineId string, method string, target string, timestamp string, signature string, body []byte) (models.Machine, error)
+}
+
+type machineService struct {
+	machineRepository repositories.MachineRepository
+}
+
+func NewMachineService(machineRepository repositories.MachineRepository) *machineService {
+	return &machineService{machineRepository: machineRepository}
+}
+
+// Register creates the machine with a fresh secret key, the key has to be copied to the cabinet.
+func (s *machineService) Register(machine models.Machine) (models.Machine, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return machine, fmt.Errorf("error reading random bytes: %w", err)
+	}
+	machine.SecretKey = hex.EncodeToString(key)
+
+	if err := s.machineRepository.CreateMachine(machine); err != nil {
+		return machine, err
+	}
+	return machine, nil
+}
+
+func (s *machineService) GetMachines(venues []string) ([]models.Machine, error) {
+	return s.machineRepository.FetchMachines(venues)
+}
+
+func (s *machineService) GetMachine(machineId string) (models.Machine, error) {
+	return s.machineRepository.FetchMachine(machineId)
+}
+
+// Authenticate checks that signature is hex(HMAC-SHA256(machine key, method \n target \n timestamp \n body)),
+// target is the path with the query as it was requested. A signature only fits the request it was made for.
+func (s *machineService) Authenticate(machineId string, method string, target string, timestamp string, signature string, body []byte) (models.Machine, error) {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return models.Machine{}, ErrInvalidSignature
+	}
+
+	if skew := time.Since(time.Unix(unix, 0)); skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		utils.LogError("Signed request from machine %s is %v off", machineId, skew)
+		return models.Machine{}, ErrInvalidSignature
+	}
+
+	machine, err := s.machineRepository.FetchMachine(machineId)
+	if err != nil {
+		return machine, ErrInvalidSignature
+	}
+
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return models.Machine{}, ErrInvalidSignature
+	}
+
+	if !hmac.Equal(given, signRequest(machine.SecretKey, method, target, timestamp, body)) {
+		utils.LogError("Signature mismatch for machine %s", machineId)
+		return models.Machine{}, ErrInvalidSignature
+	}
+
+	return machine, nil
+}
+
+func signRequest(key string, method string, target string, timestamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(method + "\n" + target + "\n" + timestamp + "\n"))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type registeredMachine struct {
+	repositories.MachineRepository
+	machine models.Machine
+}
+
+func (r *registeredMachine) FetchMachine(machineId string) (models.Machine, error) {
+	return r.machine, nil
+}
+
+func TestAuthenticateBindsTheRequest(t *testing.T) {
+	machine := models.Machine{Id: "cab-1", Venue: "north", SecretKey: "secret"}
+	service := NewMachineService(&registeredMachine{machine: machine})
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := hex.EncodeToString(signRequest(machine.SecretKey, "GET", "/v1/cabinets/code-check/ABCDEF", timestamp, nil))
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   []byte
+		valid  bool
+	}{
+		{"the signed request", "GET", "/v1/cabinets/code-check/ABCDEF", nil, true},
+		{"another path", "GET", "/v1/cabinets/games/7/rom", nil, false},
+		{"another code", "GET", "/v1/cabinets/code-check/XYZXYZ", nil, false},
+		{"a query added", "GET", "/v1/cabinets/code-check/ABCDEF?x=1", nil, false},
+		{"another method", "POST", "/v1/cabinets/code-check/ABCDEF", nil, false},
+		{"a body added", "GET", "/v1/cabinets/code-check/ABCDEF", []byte("{}"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := service.Authenticate(machine.Id, test.method, test.target, timestamp, signature, test.body)
+			if test.valid && err != nil {
+				t.Fatalf("refused: %v", err)
+			}
+			if !test.valid && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
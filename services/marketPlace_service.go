@@ -6,6 +6,8 @@ import (
 	"GameWala-Arcade/repositories"
 	"GameWala-Arcade/utils"
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
@@ -25,7 +27,8 @@ type S3BucketInfo struct {
 }
 
 type MarketPlaceService interface {
-	FetchProducts(productType models.ProductType) ([]models.Product, error)
+	FetchProducts(filter models.ProductFilter) (models.ProductPage, error)
+	FetchProduct(productId int, venue string) (models.Product, error)
 }
 
 type marketPlaceService struct {
@@ -38,14 +41,66 @@ func NewMarketPlaceService(marketPlaceRepository repositories.MarketPlaceReposit
 
 const s3BaseUrl string = "https://%s.storage.supabase.co/storage/v1/s3"
 
-func (s *marketPlaceService) FetchProducts(productType models.ProductType) ([]models.Product, error) {
-	products, err := s.marketPlaceRepository.FetchProducts(productType)
+const (
+	defaultShopPage = 20
+	maxShopPage     = 100
+)
+
+var ErrProductNotFound = errors.New("product doesn't exist")
+var ErrInvalidProductFilter = errors.New("invalid product filter")
+
+func (s *marketPlaceService) FetchProducts(filter models.ProductFilter) (models.ProductPage, error) {
+	switch filter.Sort {
+	case "", models.ProductSortNewest, models.ProductSortPrice, models.ProductSortPriceDesc:
+	default:
+		return models.ProductPage{}, fmt.Errorf("%w: sort should be newest, price or price_desc", ErrInvalidProductFilter)
+	}
+	if filter.Offset < 0 {
+		return models.ProductPage{}, fmt.Errorf("%w: offset can't be negative", ErrInvalidProductFilter)
+	}
+	if filter.Limit <= 0 || filter.Limit > maxShopPage {
+		filter.Limit = defaultShopPage
+	}
+
+	page, err := s.marketPlaceRepository.FetchProducts(filter)
 
 	if err != nil {
 		utils.LogError("Some error occured while fetching data from DB: %v", err)
-		return nil, err
+		return page, err
 	}
 
+	storageInfo := productStorage()
+	for i := range page.Products {
+		productImages(storageInfo, &page.Products[i])
+	}
+	return page, nil
+}
+
+// FetchProduct gives the product with every image, the stock is the one of the venue when given.
+func (s *marketPlaceService) FetchProduct(productId int, venue string) (models.Product, error) {
+	product, err := s.marketPlaceRepository.FetchProduct(productId, venue)
+	if err == sql.ErrNoRows {
+		return product, ErrProductNotFound
+	} else if err != nil {
+		return product, err
+	}
+
+	productImages(productStorage(), &product)
+	return product, nil
+}
+
+// productImages fills the cover and the other images, the first image of the folder of the product is the cover.
+// a product without images is still listed.
+func productImages(storageInfo S3BucketInfo, product *models.Product) {
+	storageInfo.Prefix = fmt.Sprintf("%s%d/", storageInfo.Prefix, product.ProductId)
+	images, err := getImageLinks(context.TODO(), storageInfo)
+	if err == nil && len(images) > 0 {
+		product.CoverImage = images[0]
+		product.Images = images[1:]
+	}
+}
+
+func productStorage() S3BucketInfo {
 	// aws stuff
 	supabaseURL := fmt.Sprintf(s3BaseUrl, config.GetString("supabaseProjectID"))
 
@@ -72,28 +127,12 @@ func (s *marketPlaceService) FetchProducts(productType models.ProductType) ([]mo
 	// This is the base URL for constructing the final public links.
 	publicURLBase := fmt.Sprintf(s3BaseUrl, config.GetString("supabaseProjectID"))
 
-	storageInfo := S3BucketInfo{
+	return S3BucketInfo{
 		Client:        s3Client,
 		BucketName:    config.GetString("bucketName"),
 		Prefix:        config.GetString("prefix"),
 		PublicURLBase: publicURLBase,
 	}
-
-	for i := range products {
-		// cover image
-		coverImageDir := fmt.Sprintf("%s%d/", storageInfo.Prefix, products[i].ProductId)
-		storageInfo.Prefix = coverImageDir
-		images, err := getImageLinks(context.TODO(), storageInfo)
-		if err == nil {
-			products[i].CoverImage = images[0]
-			products[i].Images = images[1:]
-		}
-	}
-	for _, product := range products {
-		fmt.Print(product.CoverImage)
-		fmt.Print(product.Images)
-	}
-	return products, nil
 }
 
 func getImageLinks(ctx context.Context, info S3BucketInfo) ([]string, error) {
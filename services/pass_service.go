@@ -0,0 +1,197 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	razorpay "github.com/razorpay/razorpay-go"
+)
+
+// a year of monthly renewals unless the config says otherwise.
+const defaultPassSubscriptionCycles = 12
+
+var ErrPassNotFound = errors.New("pass not found")
+var ErrPassNotRecurring = errors.New("pass can't be bought as a subscription")
+var ErrSubscriptionNotFound = errors.New("subscription not found")
+
+type PassService interface {
+	PaymentListener
+	SessionListener
+
+	GetPassProducts(includeInactive bool) ([]models.PassProduct, error)
+	AddPassProduct(pass models.PassProduct) (int, error)
+	DeactivatePassProduct(passId int) error
+
+	GetPlayerPasses(playerId int) ([]models.PlayerPass, error)
+	Subscribe(playerId int, passId int) (models.PassSubscription, error)
+	CancelSubscription(playerId int, subscriptionId string) error
+}
+
+type passService struct {
+	passRepository repositories.PassRepository
+}
+
+func NewPassService(passRepository repositories.PassRepository) *passService {
+	return &passService{passRepository: passRepository}
+}
+
+func (s *passService) GetPassProducts(includeInactive bool) ([]models.PassProduct, error) {
+	return s.passRepository.FetchPassProducts(includeInactive)
+}
+
+func (s *passService) AddPassProduct(pass models.PassProduct) (int, error) {
+	if pass.Price <= 0 {
+		return 0, fmt.Errorf("price has to be positive")
+	}
+	if pass.Minutes != nil && *pass.Minutes <= 0 {
+		return 0, fmt.Errorf("minutes have to be positive, leave them out for unlimited play")
+	}
+	if pass.PeriodDays <= 0 {
+		pass.PeriodDays = 30
+	}
+
+	return s.passRepository.CreatePassProduct(pass)
+}
+
+func (s *passService) DeactivatePassProduct(passId int) error {
+	return s.passRepository.DeactivatePassProduct(passId)
+}
+
+func (s *passService) GetPlayerPasses(playerId int) ([]models.PlayerPass, error) {
+	return s.passRepository.FetchPlayerPasses(playerId)
+}
+
+// Subscribe creates the razorpay subscription, every charge on it comes back as subscription.charged
+// with these notes and renews the pass.
+func (s *passService) Subscribe(playerId int, passId int) (models.PassSubscription, error) {
+	pass, err := s.findPass(passId, false)
+	if err != nil {
+		return models.PassSubscription{}, err
+	}
+	if pass.RazorpayPlanId == nil || *pass.RazorpayPlanId == "" {
+		return models.PassSubscription{}, ErrPassNotRecurring
+	}
+
+	cycles := config.GetInt("passSubscriptionCycles")
+	if cycles <= 0 {
+		cycles = defaultPassSubscriptionCycles
+	}
+
+	client := razorpay.NewClient(config.GetString("key_id"), config.GetString("key_secret"))
+	body, err := client.Subscription.Create(map[string]interface{}{
+		"plan_id":         *pass.RazorpayPlanId,
+		"total_count":     cycles,
+		"customer_notify": 1,
+		"notes": map[string]interface{}{
+			"purpose":   models.PaymentPurposePass,
+			"pass_id":   strconv.Itoa(passId),
+			"player_id": strconv.Itoa(playerId),
+		},
+	}, map[string]string{})
+	if err != nil {
+		utils.LogError("Failed to create razorpay subscription for pass ID %d: %v", passId, err)
+		return models.PassSubscription{}, fmt.Errorf("razorpay might be down, please try later")
+	}
+
+	subscription := models.PassSubscription{}
+	subscription.Id, _ = body["id"].(string)
+	subscription.ShortURL, _ = body["short_url"].(string)
+
+	if err := s.passRepository.CreateSubscription(subscription.Id, playerId, passId); err != nil {
+		return subscription, err
+	}
+
+	utils.LogInfo("Player ID %d subscribed to pass ID %d with subscription %s", playerId, passId, subscription.Id)
+	return subscription, nil
+}
+
+// CancelSubscription stops the renewals, the periods already paid for keep running. The row is marked
+// first so only the owner can cancel, it's reopened when razorpay refuses so the cancel can be retried.
+func (s *passService) CancelSubscription(playerId int, subscriptionId string) error {
+	cancelled, err := s.passRepository.CancelSubscription(subscriptionId, playerId)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return ErrSubscriptionNotFound
+	}
+
+	client := razorpay.NewClient(config.GetString("key_id"), config.GetString("key_secret"))
+	_, err = client.Subscription.Cancel(subscriptionId, map[string]interface{}{"cancel_at_cycle_end": 1}, map[string]string{})
+	if err != nil {
+		utils.LogError("Failed to cancel razorpay subscription %s: %v", subscriptionId, err)
+		if err := s.passRepository.ReopenSubscription(subscriptionId, playerId); err != nil {
+			utils.LogError("Subscription %s is marked cancelled but razorpay still renews it", subscriptionId)
+		}
+		return fmt.Errorf("razorpay might be down, please try later")
+	}
+	return nil
+}
+
+// PaymentCaptured grants a period of the pass, the first purchase and every renewal alike.
+func (s *passService) PaymentCaptured(payment models.CapturedPayment) error {
+	if payment.Purpose != models.PaymentPurposePass {
+		return nil
+	}
+	if payment.PlayerId == nil || payment.PassId == nil {
+		utils.LogError("Pass payment ID %s is missing the player or the pass", payment.PaymentId)
+		return nil
+	}
+
+	// running subscriptions keep renewing after the pass is taken off sale.
+	pass, err := s.findPass(*payment.PassId, true)
+	if err != nil {
+		return err
+	}
+	if payment.Amount < int64(pass.Price)*100 {
+		utils.LogError("Pass payment ID %s paid %d paise for pass ID %d priced at %d", payment.PaymentId,
+			payment.Amount, pass.Id, pass.Price)
+		return nil
+	}
+
+	playerPassId, err := s.passRepository.GrantPass(*payment.PlayerId, pass.Id, payment.PaymentId, payment.SubscriptionId)
+	if err != nil {
+		return err
+	}
+	if playerPassId > 0 {
+		utils.LogInfo("Player ID %d got pass ID %d from payment ID %s", *payment.PlayerId, pass.Id, payment.PaymentId)
+	}
+	return nil
+}
+
+// PaymentRefunded takes the period back on a full refund, partial refunds are goodwill and keep it.
+func (s *passService) PaymentRefunded(refund models.Refund) error {
+	if refund.Purpose != models.PaymentPurposePass || refund.Amount < refund.PaymentAmount {
+		return nil
+	}
+	return s.passRepository.RevokePass(refund.PaymentId)
+}
+
+// SessionEnded meters the pass by the real session length, codes not bought with a pass are left alone.
+func (s *passService) SessionEnded(session models.PlaySession) error {
+	if session.EndedAt == nil {
+		return nil
+	}
+	minutes := int(math.Ceil(session.EndedAt.Sub(session.StartedAt).Minutes()))
+	return s.passRepository.SettleUsage(session.Code, minutes)
+}
+
+func (s *passService) findPass(passId int, includeInactive bool) (models.PassProduct, error) {
+	passes, err := s.passRepository.FetchPassProducts(includeInactive)
+	if err != nil {
+		return models.PassProduct{}, err
+	}
+
+	for _, pass := range passes {
+		if pass.Id == passId {
+			return pass, nil
+		}
+	}
+	return models.PassProduct{}, ErrPassNotFound
+}
@@ -1,101 +1,583 @@
 package services
 
 import (
+	"GameWala-Arcade/config"
 	"GameWala-Arcade/models"
 	"GameWala-Arcade/repositories"
 	"GameWala-Arcade/utils"
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
 var maxTimeForLevelBoundedGame = uint16(120)
 
-const staticStartingCode = "ABXYSO"
+const qrSize = 512
+
+// how many sequence numbers to try if the code is already taken (issued by an older generator).
+const maxCodeAttempts = 10
+
+const codeCounterKey = "arcade_code_counter"
+
+const (
+	defaultCataloguePage = 50
+	maxCataloguePage     = 200
+)
+
+// how long the price of an order holds after it's created, happy hour may end before the player pays.
+const defaultOrderPriceHold = 30 * time.Minute
+
+// returns -1 instead of starting from 1 when the counter is gone, so a flushed redis
+// never hands out sequence numbers that were already used.
+var nextCodeScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return -1
+end
+return redis.call('INCR', KEYS[1])
+`)
+
+// only ever moves the counter forward, another instance may have restored it already.
+// a missing counter is always set, even to 0 when no code was issued yet.
+var restoreCodeCounterScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '-1')
+if current < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+	return tonumber(ARGV[1])
+end
+return current
+`)
+
+var ErrInvalidCode = errors.New("code is mistyped, check character doesn't match")
+var ErrCodeExpired = errors.New("code has expired")
+var ErrPassNeedsPlayer = errors.New("log in to play with a pass")
+var ErrWalletNeedsPlayer = errors.New("log in to pay with the wallet")
+var ErrTierNotFound = errors.New("game has no such time or level tier")
+var ErrCodeOtherVenue = errors.New("code was bought for another venue")
+var ErrInvalidFilter = errors.New("invalid catalogue filter")
+var ErrInvalidMetadata = errors.New("invalid game details")
+var ErrInvalidCursor = errors.New("cursor is invalid, start again from the first page")
 
 type PlayGameService interface {
 	SaveGameStatus(status models.GameStatus) (int, string, error)
-	GetGames() ([]models.GameResponse, error)
-	CheckGameCode(code string) (models.GameDetails, error) // arcade will hit this api
+	IssueCode(status models.GameStatus) (int, string, error)
+	ValidatePrice(status models.GameStatus) (int, error)
+	Quote(status models.GameStatus) (models.PriceQuote, error)
+	GetGames(venue string) ([]models.GameResponse, error)
+	SearchGames(filter models.GameFilter) (models.GamePage, error)
+	SetGameSystem(gameId uint16, system string) error
+	SetGameMetadata(gameId uint16, metadata models.GameMetadata) error
+	GetGenres(venue string) ([]models.Genre, error)
+	CheckGameCode(code string, machineId string) (models.GameDetails, error) // arcade will hit this api
 	GenerateCode() (string, error)
+	RestoreCodeCounter() error
+	CodeQR(code string, format string) ([]byte, string, error)
+	CabinetQR(machineId string, gameId uint16, format string) ([]byte, string, error)
 }
 
 type playGameService struct {
 	playGameRepository repositories.PlayGameRepository
+	couponRepository   repositories.CouponRepository
+	pricingRepository  repositories.PricingRepository
 	redisClient        *redis.Client
+	codeFormat         utils.CodeFormat
 }
 
 func NewPlayGameService(playGameRepository repositories.PlayGameRepository,
-	redisClient *redis.Client) *playGameService {
-	return &playGameService{playGameRepository: playGameRepository, redisClient: redisClient}
+	couponRepository repositories.CouponRepository, pricingRepository repositories.PricingRepository,
+	redisClient *redis.Client, codeFormat utils.CodeFormat) *playGameService {
+	return &playGameService{
+		playGameRepository: playGameRepository,
+		couponRepository:   couponRepository,
+		pricingRepository:  pricingRepository,
+		redisClient:        redisClient,
+		codeFormat:         codeFormat,
+	}
 }
 
 func (s *playGameService) SaveGameStatus(status models.GameStatus) (int, string, error) {
 	utils.LogInfo("Processing save game status for game ID %d", status.GameId)
 
+	status.Code = ""  // bound from the request, a purchase never picks its own code
+	status.Venue = "" // so is the venue, only the recorded order names it
+	if status.PassId != nil {
+		status.Coupon = "" // nothing is paid, nothing to take off
+		status.Wallet = false
+	}
+
+	res, quote, err := s.validatePrice(status)
+	if err != nil {
+		return res, "", err
+	}
+	status.Discount = quote.Discount
+	status.Venue = quote.Venue
+
+	return s.saveGameStatus(status)
+}
+
+// IssueCode is for the codes the server gives away (vouchers, rewards), they are checked against the
+// catalogue tier since pricing rules and coupons only apply to purchases.
+func (s *playGameService) IssueCode(status models.GameStatus) (int, string, error) {
+	utils.LogInfo("Issuing code for game ID %d", status.GameId)
+
+	status.Coupon, status.PassId, status.Wallet = "", nil, false
+	if res, err := s.ValidatePrice(status); err != nil {
+		return res, "", err
+	}
+
+	return s.saveGameStatus(status)
+}
+
+func (s *playGameService) saveGameStatus(status models.GameStatus) (int, string, error) {
+
+	if status.Wallet && status.PlayerId == nil {
+		return 4, "", ErrWalletNeedsPlayer
+	}
+	if status.PassId != nil {
+		if status.PlayerId == nil {
+			return 4, "", ErrPassNeedsPlayer
+		}
+		// level bound games hold the longest they can run, the session brings it down to what was played.
+		status.PassMinutes = maxTimeForLevelBoundedGame
+		if status.IsTimed && status.PlayTime != nil {
+			status.PassMinutes = *status.PlayTime
+		}
+	}
+
+	// the code may be generated ahead, when something has to be tied to it before it's saved.
+	code := status.Code
+	if code == "" {
+		var err error
+		if code, err = s.GenerateCode(); err != nil {
+			utils.LogError("Failed to generate code for game ID %d: %v", status.GameId, err)
+			return 0, "", err
+		}
+	}
+
+	status.Code = code
+	if status.PassId != nil {
+		status.PaymentReference = fmt.Sprintf("pass-%d-%s", *status.PassId, code)
+	} else if status.Wallet {
+		status.PaymentReference = "wallet-" + code
+	}
+	res, err := s.playGameRepository.SaveGameStatus(status)
+
+	if err != nil {
+		utils.LogError("Failed to save game status for game ID %d: %v", status.GameId, err)
+		return res, "", err // 4 when the pass or the wallet refused it, 5 when the coupon did
+	}
+
+	if res == 1 {
+		utils.LogInfo("Successfully saved game status for game ID %d", status.GameId)
+		return 1, code, err
+	}
+	return 0, "", err
+}
+
+// ValidatePrice checks the price against the time or level tier in the catalogue, pricing rules and coupons
+// only apply to purchases. Returns 2 for a time mismatch and 3 for a level mismatch like SaveGameStatus.
+func (s *playGameService) ValidatePrice(status models.GameStatus) (int, error) {
+	return s.validateTier(status)
+}
+
+// validatePrice quotes the purchase, the price paid has to match the quote after the pricing rules
+// and the coupon, and the tier is then checked against the catalogue price. Venue prices aren't in the
+// catalogue, for a venue the quote having found the tier in its price list is the check.
+func (s *playGameService) validatePrice(status models.GameStatus) (int, models.PriceQuote, error) {
+	mismatch := 3
+	if status.IsTimed && status.PlayTime != nil {
+		mismatch = 2
+	}
+
+	quote, err := s.Quote(status)
+	if err != nil {
+		utils.LogError("Failed to quote game ID %d: %v", status.GameId, err)
+		return mismatch, quote, err
+	}
+	if quote.Price != status.Price {
+		utils.LogError("Price %d for game ID %d doesn't match the quoted price %d", status.Price, status.GameId, quote.Price)
+		return mismatch, quote, fmt.Errorf("price %d doesn't match the price in effect %d", status.Price, quote.Price)
+	}
+
+	if quote.Venue != "" {
+		return 1, quote, nil
+	}
+
+	status.Price = quote.BasePrice
+	res, err := s.validateTier(status)
+	return res, quote, err
+}
+
+func (s *playGameService) validateTier(status models.GameStatus) (int, error) {
 	if status.IsTimed && status.PlayTime != nil {
 		err := s.validateTimeAndPrice(status.GameId, status.Price, status.PlayTime)
 
 		if err != nil {
 			utils.LogError("Time and price validation failed for game ID %d: %v", status.GameId, err)
-			return 2, "", err // 2 means, time and price didn't match (convert this to enum later)
+			return 2, err // 2 means, time and price didn't match (convert this to enum later)
 		}
 	} else {
 		err := s.validateLevelsAndPrice(status.GameId, status.Price, status.Levels)
 
 		if err != nil {
 			utils.LogError("Level and price validation failed for game ID %d: %v", status.GameId, err)
-			return 3, "", err // 3 means, level and price didn't match (convert this to enum later)
+			return 3, err // 3 means, level and price didn't match (convert this to enum later)
 		}
 	}
+	return 1, nil
+}
 
-	code, err := s.GenerateCode()
-	status.Code = code
-	res, err := s.playGameRepository.SaveGameStatus(status)
+// Quote prices the tier of the game at the venue with the pricing rule in effect when the order was created
+// (now without an order), with the coupon taken off when there is one.
+func (s *playGameService) Quote(status models.GameStatus) (models.PriceQuote, error) {
+	pricedAt, venue, err := s.pricingMoment(status)
+	if err != nil {
+		return models.PriceQuote{}, err
+	}
 
+	// an unknown venue would price at the catalogue and skip the tier check below.
+	if venue != "" {
+		active, err := s.playGameRepository.VenueActive(venue)
+		if err != nil {
+			return models.PriceQuote{}, err
+		}
+		if !active {
+			utils.LogError("Refusing to quote game ID %d at unknown venue %s", status.GameId, venue)
+			return models.PriceQuote{}, fmt.Errorf("%w: %s", ErrVenueNotFound, venue)
+		}
+	}
+
+	// games turned off at the venue have no tiers there.
+	prices, err := s.playGameRepository.FetchPrices(venue)
 	if err != nil {
-		utils.LogError("Failed to save game status for game ID %d: %v", status.GameId, err)
-		return 0, "", err
+		utils.LogError("Failed to fetch prices for game ID %d: %v", status.GameId, err)
+		return models.PriceQuote{}, err
 	}
 
-	if res == 1 {
-		utils.LogInfo("Successfully saved game status for game ID %d", status.GameId)
-		return 1, code, err
+	rules, err := s.pricingRepository.FetchRules()
+	if err != nil {
+		return models.PriceQuote{}, err
 	}
-	return 0, "", err
+
+	quote := models.PriceQuote{Venue: venue}
+	var tierLabel uint16
+	found := false
+	if status.IsTimed && status.PlayTime != nil {
+		tierLabel = *status.PlayTime
+		for _, tier := range prices.TimeMap[status.GameId] {
+			if tier.Time == tierLabel {
+				quote.BasePrice, found = tier.Price, true
+			}
+		}
+	} else if status.Levels != nil {
+		tierLabel = uint16(*status.Levels)
+		for _, tier := range prices.LevelMap[status.GameId] {
+			if tier.Level == tierLabel {
+				quote.BasePrice, found = tier.Price, true
+			}
+		}
+	}
+	if !found {
+		return quote, ErrTierNotFound
+	}
+	quote.ListPrice = priceAt(rules, venue, status.GameId, tierLabel, quote.BasePrice, pricedAt)
+	quote.Price = quote.ListPrice
+
+	if status.Coupon == "" {
+		return quote, nil
+	}
+
+	coupon, err := s.couponRepository.FetchCoupon(status.Coupon, status.PlayerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return quote, fmt.Errorf("%w: no such coupon", ErrCouponRefused)
+	} else if err != nil {
+		return quote, err
+	}
+
+	quote.Discount, err = applyCoupon(coupon, models.CouponPurchase{
+		ProductType: models.PaymentPurposeGame,
+		GameId:      status.GameId,
+		TierLabel:   tierLabel,
+		Amount:      quote.ListPrice,
+		PlayerId:    status.PlayerId,
+	}, time.Now())
+	if err != nil {
+		return quote, err
+	}
+
+	quote.Coupon = coupon.Code
+	quote.Price = quote.ListPrice - quote.Discount
+	return quote, nil
 }
 
-func (s *playGameService) GetGames() ([]models.GameResponse, error) {
+// pricingMoment is when and where the purchase is priced, the order creation holds the price for a while.
+func (s *playGameService) pricingMoment(status models.GameStatus) (time.Time, string, error) {
+	now := time.Now()
+	if status.OrderId == "" {
+		return now, status.Venue, nil
+	}
+
+	order, err := s.pricingRepository.FetchOrder(status.OrderId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return now, status.Venue, nil
+	} else if err != nil {
+		return now, status.Venue, err
+	}
+
+	hold := time.Duration(config.GetInt("orderPriceHoldMinutes")) * time.Minute
+	if hold <= 0 {
+		hold = defaultOrderPriceHold
+	}
+
+	venue := status.Venue
+	if order.Venue != nil {
+		venue = *order.Venue
+	}
+	if now.Sub(order.CreatedAt) > hold {
+		return now, venue, nil
+	}
+	return order.CreatedAt, venue, nil
+}
+
+// GetGames gives the games available at the venue, priced with its prices and the rules in effect right now.
+// An empty venue gives the whole catalogue at the catalogue prices.
+func (s *playGameService) GetGames(venue string) ([]models.GameResponse, error) {
 	utils.LogInfo("Fetching all games from service")
-	games, err := s.playGameRepository.GetGames()
+	games, err := s.playGameRepository.GetGames(venue)
 
 	if err != nil {
 		utils.LogError("Failed to fetch games: %v", err)
 		return nil, err
 	}
 
-	prices, err := s.playGameRepository.FetchPrices()
+	if err := s.priceGames(games, venue); err != nil {
+		return nil, err
+	}
+
+	utils.LogInfo("Successfully fetched %d games", len(games))
+	return games, nil
+}
+
+// SearchGames gives one page of the catalogue of the venue, priced like GetGames. The price filter,
+// the price sorts and the cursor use the same prices the page shows, after the rules in effect.
+func (s *playGameService) SearchGames(filter models.GameFilter) (models.GamePage, error) {
+	var page models.GamePage
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.GameSortPopular
+	case models.GameSortPopular, models.GameSortNewest, models.GameSortPrice, models.GameSortPriceDesc:
+	default:
+		return page, fmt.Errorf("%w: sort should be popular, newest, price or price_desc", ErrInvalidFilter)
+	}
+	if filter.Mode != "" && filter.Mode != "timed" && filter.Mode != "level" {
+		return page, fmt.Errorf("%w: mode should be timed or level", ErrInvalidFilter)
+	}
+	filter.Genre, filter.Tag = strings.ToLower(filter.Genre), strings.ToLower(filter.Tag)
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return page, fmt.Errorf("%w: minPrice is above maxPrice", ErrInvalidFilter)
+	}
+	if filter.Limit <= 0 || filter.Limit > maxCataloguePage {
+		filter.Limit = defaultCataloguePage
+	}
 
+	var cursorValue *int64
+	var cursorId *int
+	if filter.Cursor != "" {
+		value, id, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return page, err
+		}
+		cursorValue, cursorId = &value, &id
+	}
+
+	prices, err := s.playGameRepository.FetchPrices(filter.Venue)
+	if err != nil {
+		utils.LogError("Failed to fetch prices: %v", err)
+		return page, err
+	}
+	rules, err := s.pricingRepository.FetchRules()
+	if err != nil {
+		return page, err
+	}
+	now := time.Now()
+
+	// one more than the page tells whether there is a next one.
+	limit := filter.Limit
+	filter.Limit++
+	listings, err := s.playGameRepository.SearchGames(filter, fromPrices(prices, rules, filter.Venue, now), cursorValue, cursorId)
+	if err != nil {
+		return page, err
+	}
+
+	if len(listings) > limit {
+		last := listings[limit-1]
+		value := last.Plays
+		if filter.Sort == models.GameSortPrice || filter.Sort == models.GameSortPriceDesc {
+			value = int64(last.FromPrice)
+		}
+		page.NextCursor = encodeCursor(value, int(last.Game.GameId))
+		listings = listings[:limit]
+	}
+
+	page.Games = make([]models.GameResponse, 0, len(listings))
+	for _, listing := range listings {
+		page.Games = append(page.Games, listing.Game)
+	}
+
+	fillPrices(page.Games, prices, rules, filter.Venue, now)
+	return page, nil
+}
+
+func (s *playGameService) SetGameSystem(gameId uint16, system string) error {
+	if system == "" {
+		return fmt.Errorf("%w: system is required", ErrInvalidMetadata)
+	}
+	return s.playGameRepository.SetGameSystem(gameId, system)
+}
+
+// SetGameMetadata replaces what the catalogue shows about the game, genres and tags are kept lowercase.
+func (s *playGameService) SetGameMetadata(gameId uint16, metadata models.GameMetadata) error {
+	metadata.Genres = normaliseLabels(metadata.Genres)
+	metadata.Tags = normaliseLabels(metadata.Tags)
+
+	if metadata.MinPlayers != nil && *metadata.MinPlayers == 0 {
+		return fmt.Errorf("%w: a game needs at least one player", ErrInvalidMetadata)
+	}
+	if metadata.MaxPlayers != nil && (metadata.MinPlayers == nil || *metadata.MaxPlayers < *metadata.MinPlayers) {
+		return fmt.Errorf("%w: maxPlayers needs minPlayers and can't be below it", ErrInvalidMetadata)
+	}
+	if metadata.ReleaseYear != nil && (*metadata.ReleaseYear < 1970 || *metadata.ReleaseYear > time.Now().Year()) {
+		return fmt.Errorf("%w: release year should be between 1970 and this year", ErrInvalidMetadata)
+	}
+	if metadata.AgeRating != nil && !slices.Contains(models.AgeRatings, *metadata.AgeRating) {
+		return fmt.Errorf("%w: age rating should be one of %s", ErrInvalidMetadata, strings.Join(models.AgeRatings, ", "))
+	}
+
+	return s.playGameRepository.SetGameMetadata(gameId, metadata)
+}
+
+func (s *playGameService) GetGenres(venue string) ([]models.Genre, error) {
+	return s.playGameRepository.FetchGenres(venue)
+}
+
+// normaliseLabels lowercases, trims and dedupes genres and tags, never nil.
+func normaliseLabels(labels []string) []string {
+	normalised := []string{}
+	for _, label := range labels {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label != "" && !slices.Contains(normalised, label) {
+			normalised = append(normalised, label)
+		}
+	}
+	return normalised
+}
+
+// priceGames fills the tiers of the games with the prices of the venue and the rules in effect right now.
+func (s *playGameService) priceGames(games []models.GameResponse, venue string) error {
+	prices, err := s.playGameRepository.FetchPrices(venue)
+	if err != nil {
+		utils.LogError("Failed to fetch prices: %v", err)
+		return err
+	}
+
+	rules, err := s.pricingRepository.FetchRules()
+	if err != nil {
+		return err
+	}
+
+	fillPrices(games, prices, rules, venue, time.Now())
+	return nil
+}
+
+// fillPrices fills the tiers of the games with the prices after the rules active at the time.
+func fillPrices(games []models.GameResponse, prices models.PriceMap, rules []models.PricingRule, venue string, now time.Time) {
 	for game := 0; game < len(games); game++ {
 		currId := games[game].GameId
+		tiers := map[uint16]uint16{}
 		if len(prices.TimeMap[currId]) > 0 {
-			games[game].Price.ByTime = append(games[game].Price.ByTime, prices.TimeMap[currId]...)
+			for _, tier := range prices.TimeMap[currId] {
+				tiers[tier.Time] = tier.Price
+				tier.Price = priceAt(rules, venue, currId, tier.Time, tier.Price, now)
+				games[game].Price.ByTime = append(games[game].Price.ByTime, tier)
+			}
 		} else if len(prices.LevelMap[currId]) > 0 {
-			games[game].Price.ByLevel = append(games[game].Price.ByLevel, prices.LevelMap[currId]...)
+			for _, tier := range prices.LevelMap[currId] {
+				tiers[tier.Level] = tier.Price
+				tier.Price = priceAt(rules, venue, currId, tier.Level, tier.Price, now)
+				games[game].Price.ByLevel = append(games[game].Price.ByLevel, tier)
+			}
 		}
+		games[game].NextPriceChange = nextPriceChange(rules, venue, currId, tiers, now)
 	}
+}
 
-	utils.LogInfo("Successfully fetched %d games", len(games))
-	return games, nil
+// fromPrices is the cheapest tier of every game after the rules active at the time.
+func fromPrices(prices models.PriceMap, rules []models.PricingRule, venue string, now time.Time) map[uint16]uint16 {
+	cheapest := make(map[uint16]uint16, len(prices.TimeMap)+len(prices.LevelMap))
+	keep := func(gameId, label, base uint16) {
+		price := priceAt(rules, venue, gameId, label, base, now)
+		if current, ok := cheapest[gameId]; !ok || price < current {
+			cheapest[gameId] = price
+		}
+	}
+	// like fillPrices, the level tiers only count for games without timed ones.
+	for gameId, tiers := range prices.TimeMap {
+		for _, tier := range tiers {
+			keep(gameId, tier.Time, tier.Price)
+		}
+	}
+	for gameId, tiers := range prices.LevelMap {
+		if len(prices.TimeMap[gameId]) > 0 {
+			continue
+		}
+		for _, tier := range tiers {
+			keep(gameId, tier.Level, tier.Price)
+		}
+	}
+	return cheapest
 }
 
-func (s *playGameService) CheckGameCode(code string) (models.GameDetails, error) {
+// the cursor is opaque to the clients, it's the sort value and the id of the last game of the page.
+func encodeCursor(value int64, gameId int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", value, gameId)))
+}
+
+func decodeCursor(cursor string) (int64, int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+
+	var value int64
+	var gameId int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &value, &gameId); err != nil {
+		return 0, 0, ErrInvalidCursor
+	}
+	return value, gameId, nil
+}
+
+func (s *playGameService) CheckGameCode(code string, machineId string) (models.GameDetails, error) {
 	if code == "" {
 		utils.LogError("empty code in service layer? something's fishy 🐠")
 		return models.GameDetails{}, fmt.Errorf("Code is empty")
 	}
 
+	// codes issued before the check character was added are one character shorter, those go straight to
+	// the DB until the legacy cut-off.
+	if !s.codeFormat.IsValid(code) && !s.codeFormat.IsLegacy(code, time.Now()) {
+		utils.LogError("Rejected mistyped code: %s", code)
+		return models.GameDetails{}, ErrInvalidCode
+	}
+
 	status, err := s.playGameRepository.CheckGameCode(code)
 
 	if err != nil {
@@ -103,9 +585,71 @@ func (s *playGameService) CheckGameCode(code string) (models.GameDetails, error)
 		return status, err
 	}
 
+	expiresAt, isExpired, err := s.playGameRepository.FetchCodeExpiry(code)
+	if err != nil {
+		return status, err
+	}
+	status.ExpiresAt = expiresAt
+
+	// the expiry job may not have run yet, so the time is checked as well.
+	if !status.IsPlayed && (isExpired || (expiresAt != nil && time.Now().After(*expiresAt))) {
+		utils.LogError("Code %s expired at %v", code, expiresAt)
+		return status, ErrCodeExpired
+	}
+
+	// machineId is only given by the signed cabinet requests, a machine outside every venue can't play venue codes.
+	if machineId != "" && !status.IsPlayed {
+		codeVenue, machineVenue, err := s.playGameRepository.FetchCodeVenue(code, machineId)
+		if err != nil {
+			return status, err
+		}
+		if codeVenue != nil && (machineVenue == nil || *codeVenue != *machineVenue) {
+			utils.LogError("Code %s of venue %s was entered on machine %s outside it", code, *codeVenue, machineId)
+			return status, ErrCodeOtherVenue
+		}
+	}
+
+	// the cabinet checks it has this exact ROM installed before starting.
+	if err := s.playGameRepository.FetchCodeRom(code, &status); err != nil {
+		return status, err
+	}
+
+	// remembered for the play history, a failure here shouldn't stop the player from playing.
+	if machineId != "" && !status.IsPlayed {
+		s.playGameRepository.RecordCodeMachine(code, machineId)
+	}
+
 	return status, err
 }
 
+// CodeQR renders the code itself, so the cabinet scanner reads exactly what would be typed.
+func (s *playGameService) CodeQR(code string, format string) ([]byte, string, error) {
+	if !s.codeFormat.IsValid(code) {
+		utils.LogError("Refusing to render QR for mistyped code: %s", code)
+		return nil, "", ErrInvalidCode
+	}
+
+	return utils.RenderQR(code, format, qrSize)
+}
+
+// CabinetQR is the sticker for a cabinet, it deep links to the purchase page with the machine and game preselected.
+func (s *playGameService) CabinetQR(machineId string, gameId uint16, format string) ([]byte, string, error) {
+	purchaseURL, err := url.Parse(config.GetString("purchasePageURL"))
+	if err != nil || purchaseURL.Host == "" {
+		utils.LogError("purchasePageURL is missing or invalid in the config: %v", err)
+		return nil, "", fmt.Errorf("purchasePageURL is not configured")
+	}
+
+	query := purchaseURL.Query()
+	query.Set("machine", machineId)
+	if gameId > 0 {
+		query.Set("game", strconv.Itoa(int(gameId)))
+	}
+	purchaseURL.RawQuery = query.Encode()
+
+	return utils.RenderQR(purchaseURL.String(), format, qrSize)
+}
+
 func (s *playGameService) validateTimeAndPrice(gameId uint16, price uint16, playTime *uint16) error {
 	utils.LogInfo("Validating time and price for game ID %d: price=%d, time=%d", gameId, price, *playTime)
 	//call db to cheeck if time and price match with the feeded value.
@@ -124,46 +668,65 @@ func (s *playGameService) validateLevelsAndPrice(gameId uint16, price uint16, le
 
 func (s *playGameService) GenerateCode() (string, error) {
 	ctx := context.Background()
-	latestCode, err := s.redisClient.Get(ctx, "latest_arcade_code").Result()
 
-	if err == redis.Nil {
-		latestCode = staticStartingCode                             // starting code
-		s.redisClient.Set(ctx, "latest_arcade_code", latestCode, 0) // 0 for no expiration
-		return latestCode, err
-	}
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		counter, err := nextCodeScript.Run(ctx, s.redisClient, []string{codeCounterKey}).Int64()
+		if err != nil {
+			utils.LogError("Failed to increment code counter: %v", err)
+			return "", fmt.Errorf("error incrementing code counter: %w", err)
+		}
 
-	newCode := getNextConsecutiveCode(latestCode)
-	s.redisClient.Set(ctx, "latest_arcade_code", newCode, 0) // 0 for no expiration
-	return newCode, nil
-}
+		if counter == -1 {
+			utils.LogError("Code counter is missing from redis, restoring it from the DB")
+			if err := s.RestoreCodeCounter(); err != nil {
+				return "", err
+			}
+			continue
+		}
 
-func getNextConsecutiveCode(code string) string {
-	charset := []rune{'A', 'B', 'O', 'S', 'X', 'Y'}
-	base := len(charset)
-	runes := []rune(code)
-	n := len(runes)
+		code, err := s.codeFormat.Encode(uint64(counter - 1))
+		if err != nil {
+			utils.LogError("Failed to encode sequence number %d: %v", counter-1, err)
+			return "", err
+		}
 
-	carry := 1
-	for i := n - 1; i >= 0; i-- {
-		if carry == 0 {
-			break
+		exists, err := s.playGameRepository.CodeExists(code)
+		if err != nil {
+			return "", err
 		}
-		idx := indexOf(charset, runes[i])
-		if idx == -1 {
-			idx = 0
+
+		if !exists {
+			return code, nil
 		}
-		newIdx := (idx + carry) % base
-		carry = (idx + carry) / base
-		runes[i] = charset[newIdx]
+		utils.LogInfo("Generated code %s is already taken, moving to the next one", code)
 	}
-	return string(runes)
+
+	return "", fmt.Errorf("couldn't find a free code after %d attempts", maxCodeAttempts)
 }
 
-func indexOf(slice []rune, r rune) int {
-	for i, v := range slice {
-		if v == r {
-			return i
+// RestoreCodeCounter sets the redis counter right after the highest sequence number
+// found in the issued codes, called on startup and whenever the counter disappears.
+func (s *playGameService) RestoreCodeCounter() error {
+	codes, err := s.playGameRepository.FetchIssuedCodes()
+	if err != nil {
+		utils.LogError("Failed to fetch issued codes: %v", err)
+		return err
+	}
+
+	next := uint64(0)
+	for _, code := range codes {
+		// codes from the older generators don't decode, they are covered by the CodeExists check.
+		if seq, ok := s.codeFormat.Decode(code); ok && seq >= next {
+			next = seq + 1
 		}
 	}
-	return -1
+
+	counter, err := restoreCodeCounterScript.Run(context.Background(), s.redisClient, []string{codeCounterKey}, next).Int64()
+	if err != nil {
+		utils.LogError("Failed to restore code counter: %v", err)
+		return fmt.Errorf("error restoring code counter: %w", err)
+	}
+
+	utils.LogInfo("Code counter restored at %d from %d issued codes", counter, len(codes))
+	return nil
 }
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// issuedCodes stands in for GameStatus, only the code lookups are used by GenerateCode.
+type issuedCodes struct {
+	repositories.PlayGameRepository
+	mu    sync.Mutex
+	codes map[string]bool
+}
+
+func (r *issuedCodes) CodeExists(code string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.codes[code], nil
+}
+
+func (r *issuedCodes) FetchIssuedCodes() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	codes := make([]string, 0, len(r.codes))
+	for code := range r.codes {
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+func (r *issuedCodes) issue(code string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.codes[code] {
+		return false
+	}
+	r.codes[code] = true
+	return true
+}
+
+func testRedis(t testing.TB) *redis.Client {
+	client := redis.NewClient(&redis.Options{Addr: miniredis.RunT(t).Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestGenerateCodeConcurrent(t *testing.T) {
+	client := testRedis(t)
+	ctx := context.Background()
+
+	repo := &issuedCodes{codes: make(map[string]bool)}
+	format := utils.CodeFormat{Alphabet: []rune("ABOSXY"), Length: 6, Key: []byte("test key")}
+	service := NewPlayGameService(repo, nil, nil, client, format)
+
+	if err := service.RestoreCodeCounter(); err != nil {
+		t.Fatalf("restoring the counter: %v", err)
+	}
+
+	const workers, perWorker = 16, 25
+	issue := func(restoring bool) {
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					// restores racing the increments may only ever move the counter forward.
+					if restoring && w%4 == 0 && i%5 == 0 {
+						if err := service.RestoreCodeCounter(); err != nil {
+							t.Errorf("restoring the counter: %v", err)
+							return
+						}
+					}
+
+					code, err := service.GenerateCode()
+					if err != nil {
+						t.Errorf("generating a code: %v", err)
+						return
+					}
+					if !format.IsValid(code) {
+						t.Errorf("generated code %s isn't valid", code)
+					}
+					if !repo.issue(code) {
+						t.Errorf("code %s was issued twice", code)
+					}
+				}
+			}(w)
+		}
+		wg.Wait()
+	}
+
+	issue(false)
+
+	// the counter disappearing, e.g. a redis restart, is restored from the issued codes.
+	client.Del(ctx, codeCounterKey)
+	issue(true)
+
+	if want := 2 * workers * perWorker; len(repo.codes) != want {
+		t.Fatalf("issued %d unique codes, want %d", len(repo.codes), want)
+	}
+}
+
+// searchedGames answers the catalogue search from the from prices it's given, cheapest first.
+type searchedGames struct {
+	repositories.PlayGameRepository
+	prices     models.PriceMap
+	fromPrices map[uint16]uint16
+}
+
+func (r *searchedGames) FetchPrices(venue string) (models.PriceMap, error) {
+	return r.prices, nil
+}
+
+func (r *searchedGames) SearchGames(filter models.GameFilter, fromPrices map[uint16]uint16, cursorValue *int64, cursorId *int) ([]models.GameListing, error) {
+	r.fromPrices = fromPrices
+	var listings []models.GameListing
+	for gameId, price := range fromPrices {
+		listings = append(listings, models.GameListing{Game: models.GameResponse{GameId: gameId}, FromPrice: price})
+	}
+	sort.Slice(listings, func(i, j int) bool { return listings[i].FromPrice < listings[j].FromPrice })
+	return listings[:min(len(listings), filter.Limit)], nil
+}
+
+type fixedRules struct {
+	repositories.PricingRepository
+	rules []models.PricingRule
+}
+
+func (r *fixedRules) FetchRules() ([]models.PricingRule, error) {
+	return r.rules, nil
+}
+
+func TestSearchGamesRulePrices(t *testing.T) {
+	override, half := uint16(90), 0.5
+	gameTwo, gameThree := uint16(2), uint16(3)
+	repo := &searchedGames{prices: models.PriceMap{
+		TimeMap: map[uint16][]models.TimePrice{
+			1: {{Time: 10, Price: 50}, {Time: 30, Price: 120}},
+			3: {{Time: 10, Price: 60}},
+		},
+		LevelMap: map[uint16][]models.LevelPrice{2: {{Level: 3, Price: 40}}},
+	}}
+	rules := &fixedRules{rules: []models.PricingRule{
+		{Id: 1, StartTime: "00:00", EndTime: "00:00", GameId: &gameTwo, OverridePrice: &override},
+		{Id: 2, StartTime: "00:00", EndTime: "00:00", GameId: &gameThree, Multiplier: &half},
+	}}
+	service := NewPlayGameService(repo, nil, rules, nil, utils.CodeFormat{})
+
+	page, err := service.SearchGames(models.GameFilter{Sort: models.GameSortPrice, Limit: 2})
+	if err != nil {
+		t.Fatalf("searching games: %v", err)
+	}
+
+	want := map[uint16]uint16{1: 50, 2: 90, 3: 30}
+	for gameId, price := range want {
+		if repo.fromPrices[gameId] != price {
+			t.Errorf("game ID %d searched at %d, want %d", gameId, repo.fromPrices[gameId], price)
+		}
+	}
+
+	if len(page.Games) != 2 || page.Games[0].GameId != 3 || page.Games[1].GameId != 1 {
+		t.Fatalf("page has games %+v, want 3 then 1", page.Games)
+	}
+	if got := page.Games[0].Price.ByTime[0].Price; got != 30 {
+		t.Errorf("game ID 3 shown at %d, want 30", got)
+	}
+
+	// the next page starts after the price the last game was shown at.
+	value, gameId, err := decodeCursor(page.NextCursor)
+	if err != nil {
+		t.Fatalf("decoding the cursor: %v", err)
+	}
+	if value != 50 || gameId != 1 {
+		t.Errorf("cursor is %d:%d, want 50:1", value, gameId)
+	}
+}
+
+// lookedUpCodes fails every lookup, it only tells whether a code got as far as the DB.
+type lookedUpCodes struct {
+	repositories.PlayGameRepository
+}
+
+var errLookedUp = errors.New("looked up")
+
+func (lookedUpCodes) CheckGameCode(code string) (models.GameDetails, error) {
+	return models.GameDetails{}, errLookedUp
+}
+
+func TestCheckGameCodeFormat(t *testing.T) {
+	format := utils.CodeFormat{Alphabet: []rune("ABOSXY"), Length: 6, Key: []byte("test key")}
+	valid, err := format.Encode(42)
+	if err != nil {
+		t.Fatalf("encoding a code: %v", err)
+	}
+	// a wrong check character, like a typo the check is there to catch.
+	mistyped := []rune(valid)
+	if mistyped[6] == 'A' {
+		mistyped[6] = 'B'
+	} else {
+		mistyped[6] = 'A'
+	}
+
+	tests := []struct {
+		name        string
+		code        string
+		legacyUntil time.Time
+		want        error
+	}{
+		{"valid code", valid, time.Time{}, errLookedUp},
+		{"mistyped code", string(mistyped), time.Time{}, ErrInvalidCode},
+		{"legacy code before the cut-off", valid[:6], time.Now().Add(time.Hour), errLookedUp},
+		{"legacy code after the cut-off", valid[:6], time.Now().Add(-time.Hour), ErrInvalidCode},
+		{"legacy code without a cut-off", valid[:6], time.Time{}, ErrInvalidCode},
+		{"legacy length with unknown characters", "ZZZZZZ", time.Now().Add(time.Hour), ErrInvalidCode},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			format := format
+			format.LegacyUntil = test.legacyUntil
+			service := NewPlayGameService(lookedUpCodes{}, nil, nil, nil, format)
+
+			if _, err := service.CheckGameCode(test.code, ""); !errors.Is(err, test.want) {
+				t.Fatalf("code %s gave %v, want %v", test.code, err, test.want)
+			}
+		})
+	}
+}
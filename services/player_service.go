@@ -0,0 +1,292 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	otpDigits      = 6
+	otpTTL         = 5 * time.Minute
+	otpResendDelay = time.Minute
+	maxOTPAttempts = 5
+	otpLockout     = time.Hour // wrong otps are counted this long, new otps don't reset the count
+)
+
+var ErrInvalidPhone = errors.New("invalid phone number")
+var ErrOTPTooSoon = errors.New("an otp was sent recently, please wait before asking again")
+var ErrInvalidOTP = errors.New("otp is wrong or has expired")
+var ErrOTPLocked = errors.New("too many wrong otps, please try again later")
+var ErrCodeNotResendable = errors.New("code doesn't belong to the player or can't be played anymore")
+var ErrResendTooSoon = errors.New("a code was resent recently, please wait before asking again")
+
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{9,14}$`)
+
+type PlayerService interface {
+	SendOTP(phone string) error
+	VerifyOTP(phone string, otp string, deviceId string) (models.Player, string, error) // player and the player token
+	GetPlayer(playerId int) (models.Player, error)
+	UpdateName(playerId int, name string) error
+	GetCodes(playerId int, status string) ([]models.PlayerCode, error) // empty status gives all the codes
+	ResendCode(playerId int, code string) error
+	GetWallet(playerId int, limit int) (models.Wallet, error) // the balance with the latest entries
+}
+
+type playerService struct {
+	playerRepository repositories.PlayerRepository
+	redisClient      *redis.Client
+	smsSender        utils.SMSSender
+	otpSecret        []byte // keys the otp hashes kept in redis
+}
+
+func NewPlayerService(playerRepository repositories.PlayerRepository,
+	redisClient *redis.Client, smsSender utils.SMSSender, otpSecret []byte) *playerService {
+	return &playerService{playerRepository: playerRepository, redisClient: redisClient, smsSender: smsSender,
+		otpSecret: otpSecret}
+}
+
+func (s *playerService) SendOTP(phone string) error {
+	phone, err := normalisePhone(phone)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+
+	if locked, err := s.otpLocked(ctx, phone); err != nil || locked {
+		if err == nil {
+			err = ErrOTPLocked
+		}
+		return err
+	}
+
+	// the resend key doubles as a rate limit, nobody can spam a number with texts.
+	allowed, err := s.redisClient.SetNX(ctx, otpResendKey(phone), 1, otpResendDelay).Result()
+	if err != nil {
+		utils.LogError("Failed to check otp rate limit for %s: %v", phone, err)
+		return fmt.Errorf("error reaching redis: %w", err)
+	}
+	if !allowed {
+		return ErrOTPTooSoon
+	}
+
+	otp, err := randomOTP()
+	if err != nil {
+		return err
+	}
+
+	// only the keyed hash is kept, a redis dump shouldn't be enough to login.
+	if err := s.redisClient.Set(ctx, otpKey(phone), s.hashOTP(phone, otp), otpTTL).Err(); err != nil {
+		utils.LogError("Failed to store otp for %s: %v", phone, err)
+		return fmt.Errorf("error reaching redis: %w", err)
+	}
+
+	message := fmt.Sprintf("%s is your GameWala login code, it is valid for %d minutes.", otp, int(otpTTL.Minutes()))
+	if err := s.smsSender.Send(phone, message); err != nil {
+		utils.LogError("Failed to send otp sms to %s: %v", phone, err)
+		return fmt.Errorf("error sending sms: %w", err)
+	}
+
+	utils.LogInfo("OTP sent to %s", phone)
+	return nil
+}
+
+func (s *playerService) VerifyOTP(phone string, otp string, deviceId string) (models.Player, string, error) {
+	phone, err := normalisePhone(phone)
+	if err != nil {
+		return models.Player{}, "", err
+	}
+
+	ctx := context.Background()
+
+	if locked, err := s.otpLocked(ctx, phone); err != nil || locked {
+		if err == nil {
+			utils.LogError("Too many wrong otps for %s", phone)
+			err = ErrOTPLocked
+		}
+		return models.Player{}, "", err
+	}
+
+	stored, err := s.redisClient.Get(ctx, otpKey(phone)).Result()
+	if err != nil && err != redis.Nil {
+		return models.Player{}, "", fmt.Errorf("error reaching redis: %w", err)
+	}
+
+	if err == redis.Nil || subtle.ConstantTimeCompare([]byte(stored), []byte(s.hashOTP(phone, otp))) != 1 {
+		utils.LogError("Wrong otp entered for %s", phone)
+		// the window starts at the first failure, asking for a new otp doesn't move it.
+		pipe := s.redisClient.TxPipeline()
+		pipe.Incr(ctx, otpFailuresKey(phone))
+		pipe.ExpireNX(ctx, otpFailuresKey(phone), otpLockout)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return models.Player{}, "", fmt.Errorf("error reaching redis: %w", err)
+		}
+		return models.Player{}, "", ErrInvalidOTP
+	}
+	s.redisClient.Del(ctx, otpKey(phone), otpFailuresKey(phone))
+
+	playerId, err := s.playerRepository.UpsertPlayer(phone)
+	if err != nil {
+		return models.Player{}, "", err
+	}
+
+	// devices are only remembered to catch referral abuse, a failure doesn't block the login.
+	if deviceId != "" {
+		s.playerRepository.RecordDevice(playerId, deviceId)
+	}
+
+	player, err := s.playerRepository.FetchPlayer(playerId)
+	if err != nil {
+		return player, "", err
+	}
+
+	token, err := utils.CreatePlayerToken(phone, playerId)
+	if err != nil {
+		utils.LogError("Failed to create player token for ID %d: %v", playerId, err)
+		return player, "", err
+	}
+
+	return player, token, nil
+}
+
+func (s *playerService) GetPlayer(playerId int) (models.Player, error) {
+	return s.playerRepository.FetchPlayer(playerId)
+}
+
+func (s *playerService) UpdateName(playerId int, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 50 {
+		return fmt.Errorf("name should be between 1 and 50 characters")
+	}
+	return s.playerRepository.UpdateName(playerId, name)
+}
+
+func (s *playerService) GetCodes(playerId int, status string) ([]models.PlayerCode, error) {
+	codes, err := s.playerRepository.FetchPlayerCodes(playerId)
+	if err != nil || status == "" {
+		return codes, err
+	}
+
+	filtered := []models.PlayerCode{}
+	for _, code := range codes {
+		if code.Status == status {
+			filtered = append(filtered, code)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *playerService) GetWallet(playerId int, limit int) (models.Wallet, error) {
+	return s.playerRepository.FetchWallet(playerId, limit)
+}
+
+// ResendCode texts an unplayed code again to the phone of the player who bought it.
+func (s *playerService) ResendCode(playerId int, code string) error {
+	codes, err := s.GetCodes(playerId, models.CodeStatusUnplayed)
+	if err != nil {
+		return err
+	}
+
+	for _, owned := range codes {
+		if owned.Code != code {
+			continue
+		}
+
+		player, err := s.playerRepository.FetchPlayer(playerId)
+		if err != nil {
+			return err
+		}
+
+		// rate limited like the otp, any of the codes counts so cycling through them doesn't help.
+		allowed, err := s.redisClient.SetNX(context.Background(), codeResendKey(player.Phone), 1, otpResendDelay).Result()
+		if err != nil {
+			utils.LogError("Failed to check code resend rate limit for player ID %d: %v", playerId, err)
+			return fmt.Errorf("error reaching redis: %w", err)
+		}
+		if !allowed {
+			return ErrResendTooSoon
+		}
+
+		message := fmt.Sprintf("Your GameWala code for %s is %s.", owned.Name, owned.Code)
+		if owned.ExpiresAt != nil {
+			message += fmt.Sprintf(" It is valid till %s.", owned.ExpiresAt.Format("02 Jan 2006"))
+		}
+
+		if err := s.smsSender.Send(player.Phone, message); err != nil {
+			utils.LogError("Failed to resend code %s to player ID %d: %v", code, playerId, err)
+			return fmt.Errorf("error sending sms: %w", err)
+		}
+
+		utils.LogInfo("Resent code %s to player ID %d", code, playerId)
+		return nil
+	}
+
+	utils.LogError("Player ID %d asked to resend code %s which isn't an unplayed code of theirs", playerId, code)
+	return ErrCodeNotResendable
+}
+
+// normalisePhone strips the formatting and treats plain 10 digit numbers as indian ones.
+func normalisePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	if len(phone) == 10 && !strings.HasPrefix(phone, "+") {
+		phone = "+91" + phone
+	}
+
+	if !phonePattern.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
+
+func randomOTP() (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < otpDigits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("error reading random bytes: %w", err)
+	}
+	return fmt.Sprintf("%0*d", otpDigits, n), nil
+}
+
+// otpLocked tells whether the phone had too many wrong otps lately, the otp is dropped once it has.
+func (s *playerService) otpLocked(ctx context.Context, phone string) (bool, error) {
+	failures, err := s.redisClient.Get(ctx, otpFailuresKey(phone)).Int()
+	if err != nil && err != redis.Nil {
+		return false, fmt.Errorf("error reaching redis: %w", err)
+	}
+	if failures < maxOTPAttempts {
+		return false, nil
+	}
+	s.redisClient.Del(ctx, otpKey(phone))
+	return true, nil
+}
+
+// hashOTP is keyed with the server secret, the million otps can't be tried against a dumped hash.
+func (s *playerService) hashOTP(phone string, otp string) string {
+	mac := hmac.New(sha256.New, s.otpSecret)
+	mac.Write([]byte(phone + ":" + otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func otpKey(phone string) string         { return "otp:" + phone }
+func otpFailuresKey(phone string) string { return "otp_failures:" + phone }
+func otpResendKey(phone string) string   { return "otp_resend:" + phone }
+func codeResendKey(phone string) string  { return "code_resend:" + phone }
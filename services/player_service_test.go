@@ -0,0 +1,67 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// sentTexts keeps the last message of every phone instead of sending it.
+type sentTexts map[string]string
+
+func (s sentTexts) Send(phone string, message string) error {
+	s[phone] = message
+	return nil
+}
+
+func TestOTPLockoutOutlivesNewOTPs(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	texts := sentTexts{}
+	service := NewPlayerService(nil, client, texts, []byte("test secret"))
+	const phone = "+919800000031"
+
+	for i := 0; i < maxOTPAttempts; i++ {
+		// a new otp every time, like a player waiting out the resend delay.
+		server.FastForward(otpResendDelay)
+		if err := service.SendOTP(phone); err != nil {
+			t.Fatalf("sending otp %d: %v", i, err)
+		}
+		wrong := "000000"
+		if strings.HasPrefix(texts[phone], wrong) {
+			wrong = "111111"
+		}
+		if _, _, err := service.VerifyOTP(phone, wrong, ""); !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("wrong otp %d gave %v, want ErrInvalidOTP", i, err)
+		}
+	}
+
+	server.FastForward(otpResendDelay)
+	if err := service.SendOTP(phone); !errors.Is(err, ErrOTPLocked) {
+		t.Fatalf("sending after %d wrong otps gave %v, want ErrOTPLocked", maxOTPAttempts, err)
+	}
+
+	// the right otp doesn't help either once the phone is locked.
+	otp := strings.Fields(texts[phone])[0]
+	if _, _, err := service.VerifyOTP(phone, otp, ""); !errors.Is(err, ErrOTPLocked) {
+		t.Fatalf("verifying a locked phone gave %v, want ErrOTPLocked", err)
+	}
+
+	server.FastForward(otpLockout)
+	if err := service.SendOTP(phone); err != nil {
+		t.Fatalf("sending after the lockout: %v", err)
+	}
+}
+
+func TestOTPHashIsKeyed(t *testing.T) {
+	one := &playerService{otpSecret: []byte("one")}
+	other := &playerService{otpSecret: []byte("other")}
+	if one.hashOTP("+919800000031", "123456") == other.hashOTP("+919800000031", "123456") {
+		t.Fatal("the otp hash doesn't depend on the secret")
+	}
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// how far ahead the catalogue looks for the next price change, the rules repeat every week.
+const priceChangeHorizonDays = 8
+
+type PricingService interface {
+	// scope is the venues of the admin, nil for all. Venue admins see the rules for every venue but only
+	// add and delete the rules of their venues.
+	GetRules(scope []string) ([]models.PricingRule, error)
+	AddRule(rule models.PricingRule, scope []string) (int, error)
+	DeleteRule(ruleId int, scope []string) error
+}
+
+type pricingService struct {
+	pricingRepository repositories.PricingRepository
+}
+
+func NewPricingService(pricingRepository repositories.PricingRepository) *pricingService {
+	return &pricingService{pricingRepository: pricingRepository}
+}
+
+func (s *pricingService) GetRules(scope []string) ([]models.PricingRule, error) {
+	rules, err := s.pricingRepository.FetchRules()
+	if err != nil || scope == nil {
+		return rules, err
+	}
+
+	var visible []models.PricingRule
+	for _, rule := range rules {
+		if rule.Venue == nil || utils.InScope(scope, *rule.Venue) {
+			visible = append(visible, rule)
+		}
+	}
+	return visible, nil
+}
+
+func (s *pricingService) AddRule(rule models.PricingRule, scope []string) (int, error) {
+	if scope != nil && (rule.Venue == nil || !utils.InScope(scope, *rule.Venue)) {
+		return 0, ErrVenueNotManaged
+	}
+	if rule.Weekday != nil && (*rule.Weekday < 0 || *rule.Weekday > 6) {
+		return 0, fmt.Errorf("weekday has to be between 0 (sunday) and 6")
+	}
+	if _, err := parseClock(rule.StartTime); err != nil {
+		return 0, err
+	}
+	if _, err := parseClock(rule.EndTime); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case (rule.Multiplier == nil) == (rule.OverridePrice == nil):
+		return 0, fmt.Errorf("either a multiplier or an override price is needed")
+	case rule.Multiplier != nil && *rule.Multiplier <= 0:
+		return 0, fmt.Errorf("multiplier has to be positive")
+	case rule.OverridePrice != nil && (rule.GameId == nil || rule.TierLabel == nil):
+		return 0, fmt.Errorf("an override is for one tier, game and tier are needed")
+	}
+
+	return s.pricingRepository.CreateRule(rule)
+}
+
+func (s *pricingService) DeleteRule(ruleId int, scope []string) error {
+	if scope != nil {
+		rules, err := s.pricingRepository.FetchRules()
+		if err != nil {
+			return err
+		}
+		for _, rule := range rules {
+			if rule.Id == ruleId && (rule.Venue == nil || !utils.InScope(scope, *rule.Venue)) {
+				return ErrVenueNotManaged
+			}
+		}
+	}
+	return s.pricingRepository.DeleteRule(ruleId)
+}
+
+// parseClock turns HH:MM into minutes since midnight.
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("time '%s' isn't HH:MM", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ruleMatches leaves out the time, venue and game rules for other venues and games never match.
+func ruleMatches(rule models.PricingRule, venue string, gameId uint16) bool {
+	return (rule.Venue == nil || *rule.Venue == venue) && (rule.GameId == nil || *rule.GameId == gameId)
+}
+
+func ruleActive(rule models.PricingRule, at time.Time) bool {
+	start, err := parseClock(rule.StartTime)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(rule.EndTime)
+	if err != nil {
+		return false
+	}
+
+	at = at.Local()
+	minute := at.Hour()*60 + at.Minute()
+	onDay := func(day time.Time) bool { return rule.Weekday == nil || *rule.Weekday == int(day.Weekday()) }
+
+	switch {
+	case start == end:
+		return onDay(at)
+	case start < end:
+		return onDay(at) && minute >= start && minute < end
+	default: // runs past midnight, the early hours belong to the day before
+		return (onDay(at) && minute >= start) || (onDay(at.AddDate(0, 0, -1)) && minute < end)
+	}
+}
+
+// priceAt applies the most specific rule active at the time, a venue counts more than a game and a game
+// more than a tier. Overrides win over multipliers, then the newest rule.
+func priceAt(rules []models.PricingRule, venue string, gameId uint16, tierLabel uint16, basePrice uint16, at time.Time) uint16 {
+	var best *models.PricingRule
+	bestScore := -1
+	for i := range rules {
+		rule := &rules[i]
+		if !ruleMatches(*rule, venue, gameId) || (rule.TierLabel != nil && *rule.TierLabel != tierLabel) || !ruleActive(*rule, at) {
+			continue
+		}
+
+		score := 0
+		if rule.Venue != nil {
+			score += 8
+		}
+		if rule.GameId != nil {
+			score += 4
+		}
+		if rule.TierLabel != nil {
+			score += 2
+		}
+		if rule.OverridePrice != nil {
+			score += 1
+		}
+		if score > bestScore || (score == bestScore && rule.Id > best.Id) {
+			best, bestScore = rule, score
+		}
+	}
+
+	switch {
+	case best == nil:
+		return basePrice
+	case best.OverridePrice != nil:
+		return *best.OverridePrice
+	default:
+		return uint16(max(1, math.Round(float64(basePrice)**best.Multiplier)))
+	}
+}
+
+// nextPriceChange is the first rule boundary where any tier of the game changes price, nil when
+// nothing changes within the horizon.
+func nextPriceChange(rules []models.PricingRule, venue string, gameId uint16, tiers map[uint16]uint16, now time.Time) *time.Time {
+	prices := func(at time.Time) map[uint16]uint16 {
+		current := make(map[uint16]uint16, len(tiers))
+		for label, base := range tiers {
+			current[label] = priceAt(rules, venue, gameId, label, base, at)
+		}
+		return current
+	}
+
+	var boundaries []time.Time
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for _, rule := range rules {
+		if !ruleMatches(rule, venue, gameId) {
+			continue
+		}
+		start, errStart := parseClock(rule.StartTime)
+		end, errEnd := parseClock(rule.EndTime)
+		if errStart != nil || errEnd != nil {
+			continue
+		}
+
+		for offset := -1; offset <= priceChangeHorizonDays; offset++ {
+			day := today.AddDate(0, 0, offset)
+			if rule.Weekday != nil && *rule.Weekday != int(day.Weekday()) {
+				continue
+			}
+			endDay := day
+			if end <= start {
+				endDay = day.AddDate(0, 0, 1)
+			}
+			boundaries = append(boundaries,
+				day.Add(time.Duration(start)*time.Minute), endDay.Add(time.Duration(end)*time.Minute))
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	current := prices(now)
+	for _, boundary := range boundaries {
+		if !boundary.After(now) {
+			continue
+		}
+		next := prices(boundary)
+		for label, price := range current {
+			if next[label] != price {
+				return &boundary
+			}
+		}
+	}
+	return nil
+}
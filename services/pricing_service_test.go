@@ -0,0 +1,99 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"testing"
+	"time"
+)
+
+func TestRuleActive(t *testing.T) {
+	monday, friday := 1, 5
+	at := func(day int, clock string) time.Time {
+		parsed, _ := time.ParseInLocation("15:04", clock, time.Local)
+		// 2026-10-19 is a monday.
+		return time.Date(2026, 10, 19+day-1, parsed.Hour(), parsed.Minute(), 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name   string
+		rule   models.PricingRule
+		at     time.Time
+		active bool
+	}{
+		{"inside the window", models.PricingRule{StartTime: "10:00", EndTime: "12:00"}, at(1, "11:00"), true},
+		{"at the start", models.PricingRule{StartTime: "10:00", EndTime: "12:00"}, at(1, "10:00"), true},
+		{"at the end", models.PricingRule{StartTime: "10:00", EndTime: "12:00"}, at(1, "12:00"), false},
+		{"before the window", models.PricingRule{StartTime: "10:00", EndTime: "12:00"}, at(1, "09:59"), false},
+		{"all day", models.PricingRule{StartTime: "00:00", EndTime: "00:00"}, at(3, "23:59"), true},
+		{"on its weekday", models.PricingRule{Weekday: &monday, StartTime: "10:00", EndTime: "12:00"}, at(1, "11:00"), true},
+		{"on another weekday", models.PricingRule{Weekday: &monday, StartTime: "10:00", EndTime: "12:00"}, at(2, "11:00"), false},
+		{"past midnight, the evening", models.PricingRule{StartTime: "22:00", EndTime: "02:00"}, at(1, "23:30"), true},
+		{"past midnight, the early hours", models.PricingRule{StartTime: "22:00", EndTime: "02:00"}, at(2, "01:30"), true},
+		{"past midnight, after it ends", models.PricingRule{StartTime: "22:00", EndTime: "02:00"}, at(2, "02:00"), false},
+		{"past midnight, in the afternoon", models.PricingRule{StartTime: "22:00", EndTime: "02:00"}, at(2, "15:00"), false},
+		// friday night runs into saturday, the early hours belong to friday.
+		{"friday night on saturday morning", models.PricingRule{Weekday: &friday, StartTime: "22:00", EndTime: "02:00"}, at(6, "01:00"), true},
+		{"friday night on friday morning", models.PricingRule{Weekday: &friday, StartTime: "22:00", EndTime: "02:00"}, at(5, "01:00"), false},
+		{"unreadable time", models.PricingRule{StartTime: "late", EndTime: "02:00"}, at(1, "23:00"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if active := ruleActive(test.rule, test.at); active != test.active {
+				t.Fatalf("active is %v, want %v", active, test.active)
+			}
+		})
+	}
+}
+
+func TestPriceAt(t *testing.T) {
+	now := time.Date(2026, 10, 19, 11, 0, 0, 0, time.Local)
+	venue, otherVenue := "north", "south"
+	game, otherGame := uint16(7), uint16(8)
+	tier := uint16(30)
+	double, half, tiny := 2.0, 0.5, 0.01
+	hundred, ninety := uint16(100), uint16(90)
+
+	allDay := func(rule models.PricingRule) models.PricingRule {
+		rule.StartTime, rule.EndTime = "00:00", "00:00"
+		return rule
+	}
+
+	tests := []struct {
+		name  string
+		rules []models.PricingRule
+		price uint16
+	}{
+		{"no rules", nil, 60},
+		{"multiplier everywhere", []models.PricingRule{allDay(models.PricingRule{Id: 1, Multiplier: &half})}, 30},
+		{"rule not active now", []models.PricingRule{{Id: 1, StartTime: "18:00", EndTime: "20:00", Multiplier: &half}}, 60},
+		{"rule of another venue", []models.PricingRule{allDay(models.PricingRule{Id: 1, Venue: &otherVenue, Multiplier: &half})}, 60},
+		{"rule of another game", []models.PricingRule{allDay(models.PricingRule{Id: 1, GameId: &otherGame, Multiplier: &half})}, 60},
+		{"rule of another tier", []models.PricingRule{allDay(models.PricingRule{Id: 1, TierLabel: &ninety, Multiplier: &half})}, 60},
+		{"game rule over a general one", []models.PricingRule{
+			allDay(models.PricingRule{Id: 1, Multiplier: &double}),
+			allDay(models.PricingRule{Id: 2, GameId: &game, Multiplier: &half}),
+		}, 30},
+		{"venue rule over a game and tier one", []models.PricingRule{
+			allDay(models.PricingRule{Id: 1, GameId: &game, TierLabel: &tier, OverridePrice: &hundred}),
+			allDay(models.PricingRule{Id: 2, Venue: &venue, Multiplier: &double}),
+		}, 120},
+		{"override over a multiplier as specific", []models.PricingRule{
+			allDay(models.PricingRule{Id: 1, GameId: &game, OverridePrice: &ninety}),
+			allDay(models.PricingRule{Id: 2, GameId: &game, Multiplier: &half}),
+		}, 90},
+		{"newest of equal rules", []models.PricingRule{
+			allDay(models.PricingRule{Id: 2, Multiplier: &double}),
+			allDay(models.PricingRule{Id: 1, Multiplier: &half}),
+		}, 120},
+		{"multiplier never below one", []models.PricingRule{allDay(models.PricingRule{Id: 1, Multiplier: &tiny})}, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if price := priceAt(test.rules, venue, game, tier, 60, now); price != test.price {
+				t.Fatalf("price is %d, want %d", price, test.price)
+			}
+		})
+	}
+}
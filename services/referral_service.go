@@ -0,0 +1,212 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// no 0/O or 1/I, the codes get read out loud.
+const referralAlphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+const referralCodeLength = 8
+
+const (
+	defaultMaxReferrals         = 50
+	defaultReferralCreditAmount = 50
+	defaultReferralWindowDays   = 7   // how old an account can be and still apply a referral code
+	defaultReferralMinPurchase  = 100 // rupees the first purchase has to cost to convert the referral
+)
+
+// a game code is saved by the player's app and can land after the capture, the webhook is failed for
+// this long so the razorpay retry finds it.
+const referralPurchaseWait = 15 * time.Minute
+
+const (
+	referralRewardCredit = "credit"
+	referralRewardGame   = "game"
+)
+
+var ErrReferralRefused = errors.New("referral refused")
+
+type ReferralService interface {
+	PaymentListener
+
+	GetStats(playerId int) (models.ReferralStats, error)
+	ApplyReferral(playerId int, code string, deviceId string) error
+	GetReport(from time.Time, to time.Time) (models.ReferralReport, error)
+}
+
+type referralService struct {
+	referralRepository repositories.ReferralRepository
+	playGameService    PlayGameService
+}
+
+func NewReferralService(referralRepository repositories.ReferralRepository,
+	playGameService PlayGameService) *referralService {
+	return &referralService{referralRepository: referralRepository, playGameService: playGameService}
+}
+
+// GetStats creates the referral code of the player the first time it's asked for.
+func (s *referralService) GetStats(playerId int) (models.ReferralStats, error) {
+	stats, err := s.referralRepository.FetchStats(playerId)
+	if err != nil || stats.ReferralCode != "" {
+		return stats, err
+	}
+
+	code, err := randomReferralCode()
+	if err != nil {
+		return stats, err
+	}
+
+	stats.ReferralCode, err = s.referralRepository.SetReferralCode(playerId, code)
+	return stats, err
+}
+
+func (s *referralService) ApplyReferral(playerId int, code string, deviceId string) error {
+	if deviceId == "" {
+		return fmt.Errorf("%w: device id is missing", ErrReferralRefused)
+	}
+
+	maxReferrals := config.GetInt("maxReferrals")
+	if maxReferrals <= 0 {
+		maxReferrals = defaultMaxReferrals
+	}
+
+	windowDays := config.GetInt("referralWindowDays")
+	if windowDays <= 0 {
+		windowDays = defaultReferralWindowDays
+	}
+
+	reason, err := s.referralRepository.ApplyReferral(playerId, strings.ToUpper(strings.TrimSpace(code)), deviceId,
+		maxReferrals, windowDays)
+	if err != nil {
+		return err
+	}
+
+	if reason != "" {
+		utils.LogError("Referral code %s refused for player ID %d: %s", code, playerId, reason)
+		return fmt.Errorf("%w: %s", ErrReferralRefused, reason)
+	}
+
+	utils.LogInfo("Player ID %d was referred with code %s", playerId, code)
+	return nil
+}
+
+// PaymentCaptured rewards both players when a referred player makes the first purchase of at least
+// "referralMinPurchase" rupees. The payment has to have bought something, a bare order doesn't count.
+func (s *referralService) PaymentCaptured(payment models.CapturedPayment) error {
+	if payment.PlayerId == nil {
+		return nil
+	}
+
+	minPurchase := config.GetInt("referralMinPurchase")
+	if minPurchase <= 0 {
+		minPurchase = defaultReferralMinPurchase
+	}
+	if payment.Amount < int64(minPurchase)*100 {
+		return nil
+	}
+
+	referrerId, converted, err := s.referralRepository.ConvertReferral(*payment.PlayerId, payment.PaymentId)
+	if err != nil || referrerId == 0 {
+		return err
+	}
+	if !converted {
+		if payment.Purpose == models.PaymentPurposeGame && time.Since(payment.CapturedAt) < referralPurchaseWait {
+			return fmt.Errorf("no code saved for payment ID %s yet", payment.PaymentId)
+		}
+		utils.LogInfo("Payment ID %s of player ID %d bought nothing, the referral stays pending",
+			payment.PaymentId, *payment.PlayerId)
+		return nil
+	}
+
+	utils.LogInfo("Referral of player ID %d by player ID %d converted", *payment.PlayerId, referrerId)
+
+	var failed error
+	for _, playerId := range []int{referrerId, *payment.PlayerId} {
+		if err := s.reward(playerId, payment.PaymentId); err != nil {
+			utils.LogError("Failed to give the referral reward to player ID %d: %v", playerId, err)
+			failed = err
+		}
+	}
+	return failed
+}
+
+// refunds don't take the reward back, the referral was still a real customer.
+func (s *referralService) PaymentRefunded(refund models.Refund) error {
+	return nil
+}
+
+// reward gives wallet credit, or a free game tier when "referralReward" is game.
+func (s *referralService) reward(playerId int, paymentId string) error {
+	reference := fmt.Sprintf("referral-%s-%d", paymentId, playerId)
+
+	if config.GetString("referralReward") != referralRewardGame {
+		amount := config.GetInt("referralCreditAmount")
+		if amount <= 0 {
+			amount = defaultReferralCreditAmount
+		}
+		return s.referralRepository.AddWalletCredit(playerId, reference, amount, "referral")
+	}
+
+	playTime := uint16(config.GetInt("referralGamePlayTime"))
+	status := models.GameStatus{
+		Name:             config.GetString("referralGameName"),
+		GameId:           uint16(config.GetInt("referralGameId")),
+		IsTimed:          true,
+		Price:            uint16(config.GetInt("referralGamePrice")),
+		PlayTime:         &playTime,
+		PaymentReference: reference,
+		PlayerId:         &playerId,
+	}
+
+	_, code, err := s.playGameService.IssueCode(status)
+	if err != nil {
+		return err
+	}
+
+	utils.LogInfo("Referral game code %s issued to player ID %d", code, playerId)
+	return nil
+}
+
+func (s *referralService) GetReport(from time.Time, to time.Time) (models.ReferralReport, error) {
+	var report models.ReferralReport
+	var err error
+
+	report.Referrers, err = s.referralRepository.FetchReport(from, to)
+	if err != nil {
+		return report, err
+	}
+
+	for _, referrer := range report.Referrers {
+		report.Referred += referrer.Referred
+		report.Converted += referrer.Converted
+		report.Revenue += referrer.Revenue
+	}
+
+	if report.Referred > 0 {
+		report.ConversionRate = float64(report.Converted) / float64(report.Referred)
+	}
+	return report, nil
+}
+
+func randomReferralCode() (string, error) {
+	code := make([]byte, referralCodeLength)
+	max := big.NewInt(int64(len(referralAlphabet)))
+
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("error reading random bytes: %w", err)
+		}
+		code[i] = referralAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const defaultReportDir = "reports"
+
+var ErrInvalidReport = errors.New("invalid report")
+
+type ReportService interface {
+	// GetRevenue splits the range by period, from and to are days and to is exclusive. scope nil for every venue.
+	GetRevenue(from time.Time, to time.Time, period string, scope []string) (models.RevenueReport, error)
+	ExportCSV(report models.RevenueReport, w io.Writer) error
+	ExportXLSX(report models.RevenueReport, w io.Writer) error
+
+	// SendDailySummary is the end of day job, it writes the report of yesterday to the report directory
+	// and texts the totals to the owner once a day.
+	SendDailySummary() error
+}
+
+type reportService struct {
+	handlePaymentRepository repositories.HandlePaymentRepository
+	smsSender               utils.SMSSender
+}
+
+func NewReportService(handlePaymentRepository repositories.HandlePaymentRepository, smsSender utils.SMSSender) *reportService {
+	return &reportService{handlePaymentRepository: handlePaymentRepository, smsSender: smsSender}
+}
+
+func (s *reportService) GetRevenue(from time.Time, to time.Time, period string, scope []string) (models.RevenueReport, error) {
+	switch period {
+	case "":
+		period = models.RevenueDaily
+	case models.RevenueDaily, models.RevenueWeekly, models.RevenueMonthly:
+	default:
+		return models.RevenueReport{}, fmt.Errorf("%w: period should be day, week or month", ErrInvalidReport)
+	}
+	if !from.Before(to) {
+		return models.RevenueReport{}, fmt.Errorf("%w: to can't be before from", ErrInvalidReport)
+	}
+
+	rows, err := s.handlePaymentRepository.FetchRevenue(from, to, period, scope)
+	if err != nil {
+		return models.RevenueReport{}, err
+	}
+
+	report := models.RevenueReport{
+		From:      from.Format(reportDay),
+		To:        to.AddDate(0, 0, -1).Format(reportDay),
+		Period:    period,
+		Rows:      rows,
+		ByPurpose: revenueTotals(rows, func(row models.RevenueRow) string { return row.Purpose }),
+		ByMethod:  revenueTotals(rows, func(row models.RevenueRow) string { return row.Method }),
+		ByVenue:   revenueTotals(rows, func(row models.RevenueRow) string { return row.Venue }),
+	}
+	for _, row := range rows {
+		addRevenue(&report.Total, row)
+	}
+	return report, nil
+}
+
+func (s *reportService) ExportCSV(report models.RevenueReport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"period", "purpose", "method", "venue", "payments", "gross_inr", "refunded_inr", "net_inr"})
+
+	for _, row := range report.Rows {
+		writer.Write([]string{
+			row.Period,
+			row.Purpose,
+			row.Method,
+			row.Venue,
+			strconv.FormatInt(row.Payments, 10),
+			rupees(row.Gross),
+			rupees(row.Refunded),
+			rupees(row.Net),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ExportXLSX has the rows on the first sheet and the totals by purpose, method and venue on their own sheets.
+func (s *reportService) ExportXLSX(report models.RevenueReport, w io.Writer) error {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	amount, err := f.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	if err != nil {
+		return fmt.Errorf("error creating sheet style: %w", err)
+	}
+
+	rows := [][]interface{}{{"Period", "Purpose", "Method", "Venue", "Payments", "Gross (INR)", "Refunded (INR)", "Net (INR)"}}
+	for _, row := range report.Rows {
+		rows = append(rows, []interface{}{row.Period, row.Purpose, row.Method, row.Venue, row.Payments,
+			float64(row.Gross) / 100, float64(row.Refunded) / 100, float64(row.Net) / 100})
+	}
+	if err := writeSheet(f, "Revenue", rows, "F", "H", amount); err != nil {
+		return err
+	}
+
+	grandTotal := report.Total
+	grandTotal.Key = "Total"
+	for _, totals := range []struct {
+		sheet  string
+		key    string
+		totals []models.RevenueTotal
+	}{
+		{"By purpose", "Purpose", report.ByPurpose},
+		{"By method", "Method", report.ByMethod},
+		{"By venue", "Venue", report.ByVenue},
+	} {
+		rows := [][]interface{}{{totals.key, "Payments", "Gross (INR)", "Refunded (INR)", "Net (INR)"}}
+		for _, total := range append(slices.Clip(totals.totals), grandTotal) {
+			rows = append(rows, []interface{}{total.Key, total.Payments,
+				float64(total.Gross) / 100, float64(total.Refunded) / 100, float64(total.Net) / 100})
+		}
+		if err := writeSheet(f, totals.sheet, rows, "C", "E", amount); err != nil {
+			return err
+		}
+	}
+
+	f.DeleteSheet("Sheet1")
+	return f.Write(w)
+}
+
+func (s *reportService) SendDailySummary() error {
+	dir := config.GetString("reportDir")
+	if dir == "" {
+		dir = defaultReportDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("error creating report directory: %w", err)
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	yesterday := today.AddDate(0, 0, -1)
+
+	// the file is the mark that the day was already summarised.
+	path := filepath.Join(dir, fmt.Sprintf("revenue-%s.csv", yesterday.Format(reportDay)))
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	report, err := s.GetRevenue(yesterday, today, models.RevenueDaily, nil)
+	if err != nil {
+		return err
+	}
+
+	// written aside first, a half written file would stop the day from being summarised again.
+	partial := path + ".partial"
+	file, err := os.Create(partial)
+	if err != nil {
+		return fmt.Errorf("error creating report file: %w", err)
+	}
+	if err := s.ExportCSV(report, file); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing report file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing report file: %w", err)
+	}
+	if err := os.Rename(partial, path); err != nil {
+		return fmt.Errorf("error writing report file: %w", err)
+	}
+	utils.LogInfo("Revenue summary of %s written to %s", report.From, path)
+
+	if phone := config.GetString("reportPhone"); phone != "" {
+		message := fmt.Sprintf("GameWala revenue %s: net Rs %s from %d payments, Rs %s refunded.",
+			report.From, rupees(report.Total.Net), report.Total.Payments, rupees(report.Total.Refunded))
+		if err := s.smsSender.Send(phone, message); err != nil {
+			return fmt.Errorf("error sending revenue summary: %w", err)
+		}
+	}
+	return nil
+}
+
+const reportDay = "2006-01-02"
+
+// revenueTotals adds the rows up by the key, sorted by the key.
+func revenueTotals(rows []models.RevenueRow, key func(models.RevenueRow) string) []models.RevenueTotal {
+	byKey := map[string]*models.RevenueTotal{}
+	for _, row := range rows {
+		total, ok := byKey[key(row)]
+		if !ok {
+			total = &models.RevenueTotal{Key: key(row)}
+			byKey[key(row)] = total
+		}
+		addRevenue(total, row)
+	}
+
+	totals := []models.RevenueTotal{}
+	for _, total := range byKey {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Key < totals[j].Key })
+	return totals
+}
+
+func addRevenue(total *models.RevenueTotal, row models.RevenueRow) {
+	total.Payments += row.Payments
+	total.Gross += row.Gross
+	total.Refunded += row.Refunded
+	total.Net += row.Net
+}
+
+func rupees(paise int64) string {
+	return fmt.Sprintf("%.2f", float64(paise)/100)
+}
+
+// writeSheet fills the sheet from A1 with the first row as the header, the columns from..to are amounts.
+func writeSheet(f *excelize.File, sheet string, rows [][]interface{}, from string, to string, amount int) error {
+	if _, err := f.NewSheet(sheet); err != nil {
+		return fmt.Errorf("error creating sheet %s: %w", sheet, err)
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return fmt.Errorf("error writing sheet %s: %w", sheet, err)
+		}
+	}
+	if len(rows) > 1 {
+		last := strconv.Itoa(len(rows))
+		if err := f.SetCellStyle(sheet, from+"2", to+last, amount); err != nil {
+			return fmt.Errorf("error styling sheet %s: %w", sheet, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,257 @@
+package services
+
+import (
+	"GameWala-Arcade/config"
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+var ErrSystemNotFound = errors.New("system isn't registered or isn't active")
+var ErrRomNotFound = errors.New("rom doesn't exist")
+var ErrChecksumMismatch = errors.New("file doesn't match the sha256 checksum")
+var ErrInvalidRom = errors.New("invalid rom")
+
+const defaultRomDownloadMinutes = 15
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type RomService interface {
+	GetSystems() ([]models.EmulatorSystem, error)
+	SaveSystem(system models.EmulatorSystem) error
+
+	Upload(upload models.RomUpload, file io.ReadSeeker, adminId int) (models.Rom, error)
+	GetRoms(gameId *uint16) ([]models.Rom, error)
+	GetRom(romId int) (models.Rom, error)
+	SetCurrent(romId int) error
+
+	// Download gives a short lived link to the current ROM of the game.
+	Download(gameId uint16) (models.RomDownload, error)
+	RecordInstall(machineId string, romId int, sha256 string) error
+}
+
+type romService struct {
+	romRepository repositories.RomRepository
+}
+
+func NewRomService(romRepository repositories.RomRepository) *romService {
+	return &romService{romRepository: romRepository}
+}
+
+func (s *romService) GetSystems() ([]models.EmulatorSystem, error) {
+	return s.romRepository.FetchSystems()
+}
+
+func (s *romService) SaveSystem(system models.EmulatorSystem) error {
+	system.Id = strings.ToLower(strings.TrimSpace(system.Id))
+	if system.Id == "" || strings.ContainsAny(system.Id, " /?#") {
+		return fmt.Errorf("%w: system id can't be empty or have spaces or url characters", ErrInvalidRom)
+	}
+	if strings.TrimSpace(system.Name) == "" {
+		return fmt.Errorf("%w: system name can't be empty", ErrInvalidRom)
+	}
+	if system.Bios == nil {
+		system.Bios = []models.BiosFile{}
+	}
+	for i, bios := range system.Bios {
+		if bios.File == "" || filepath.Base(bios.File) != bios.File {
+			return fmt.Errorf("%w: bios file %q must be a plain file name", ErrInvalidRom, bios.File)
+		}
+		system.Bios[i].Sha256 = strings.ToLower(bios.Sha256)
+		if !sha256Pattern.MatchString(system.Bios[i].Sha256) {
+			return fmt.Errorf("%w: bios file %s needs a sha256 checksum", ErrInvalidRom, bios.File)
+		}
+	}
+	return s.romRepository.SaveSystem(system)
+}
+
+func (s *romService) Upload(upload models.RomUpload, file io.ReadSeeker, adminId int) (models.Rom, error) {
+	upload.FileName = filepath.Base(upload.FileName)
+	upload.Version = strings.TrimSpace(upload.Version)
+	upload.Sha256 = strings.ToLower(upload.Sha256)
+	if upload.FileName == "." || upload.FileName == "/" || upload.Version == "" || strings.ContainsAny(upload.Version, " /?#") {
+		return models.Rom{}, fmt.Errorf("%w: needs a file and a version without spaces or url characters", ErrInvalidRom)
+	}
+	if upload.Sha256 != "" && !sha256Pattern.MatchString(upload.Sha256) {
+		return models.Rom{}, fmt.Errorf("%w: sha256 must be 64 hex characters", ErrInvalidRom)
+	}
+
+	if _, err := s.activeSystem(upload.SystemId); err != nil {
+		return models.Rom{}, err
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return models.Rom{}, fmt.Errorf("error reading rom: %w", err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if upload.Sha256 != "" && upload.Sha256 != checksum {
+		utils.LogError("ROM %s of game ID %d hashed to %s, expected %s", upload.FileName, upload.GameId, checksum, upload.Sha256)
+		return models.Rom{}, ErrChecksumMismatch
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return models.Rom{}, fmt.Errorf("error reading rom: %w", err)
+	}
+
+	// versions get their own key so a cabinet downloading the old one isn't cut off by an upload.
+	objectKey := fmt.Sprintf("roms/%s/%d/%s/%s", upload.SystemId, upload.GameId, upload.Version, upload.FileName)
+	client, err := romStorage()
+	if err != nil {
+		return models.Rom{}, err
+	}
+	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:        aws.String(config.GetString("romBucketName")),
+		Key:           aws.String(objectKey),
+		Body:          file,
+		ContentLength: aws.Int64(size),
+	})
+	if err != nil {
+		utils.LogError("Failed to upload ROM %s: %v", objectKey, err)
+		return models.Rom{}, fmt.Errorf("error uploading rom: %w", err)
+	}
+
+	romId, err := s.romRepository.CreateRom(upload, objectKey, checksum, size, adminId)
+	if err != nil {
+		return models.Rom{}, err
+	}
+	return s.GetRom(romId)
+}
+
+func (s *romService) GetRoms(gameId *uint16) ([]models.Rom, error) {
+	return s.romRepository.FetchRoms(gameId, nil)
+}
+
+func (s *romService) GetRom(romId int) (models.Rom, error) {
+	roms, err := s.romRepository.FetchRoms(nil, &romId)
+	if err != nil {
+		return models.Rom{}, err
+	}
+	if len(roms) == 0 {
+		return models.Rom{}, ErrRomNotFound
+	}
+	return roms[0], nil
+}
+
+func (s *romService) SetCurrent(romId int) error {
+	found, err := s.romRepository.SetCurrentRom(romId)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrRomNotFound
+	}
+	return nil
+}
+
+func (s *romService) Download(gameId uint16) (models.RomDownload, error) {
+	roms, err := s.romRepository.FetchRoms(&gameId, nil)
+	if err != nil {
+		return models.RomDownload{}, err
+	}
+
+	var current *models.Rom
+	for i := range roms {
+		if roms[i].IsCurrent {
+			current = &roms[i]
+			break
+		}
+	}
+	if current == nil {
+		return models.RomDownload{}, ErrRomNotFound
+	}
+
+	system, err := s.activeSystem(current.SystemId)
+	if err != nil {
+		return models.RomDownload{}, err
+	}
+
+	minutes := config.GetInt("romDownloadMinutes")
+	if minutes <= 0 {
+		minutes = defaultRomDownloadMinutes
+	}
+	expiry := time.Duration(minutes) * time.Minute
+
+	client, err := romStorage()
+	if err != nil {
+		return models.RomDownload{}, err
+	}
+	request, err := s3.NewPresignClient(client).PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(config.GetString("romBucketName")),
+		Key:    aws.String(current.ObjectKey),
+	}, s3.WithPresignExpires(expiry))
+	if err != nil {
+		utils.LogError("Failed to sign download of ROM ID %d: %v", current.Id, err)
+		return models.RomDownload{}, fmt.Errorf("error signing rom download: %w", err)
+	}
+
+	return models.RomDownload{
+		Rom:       *current,
+		URL:       request.URL,
+		ExpiresAt: time.Now().Add(expiry),
+		Core:      system.Core,
+		Bios:      system.Bios,
+	}, nil
+}
+
+// RecordInstall is reported by the cabinet after it verified the file, the checksum it got has to match ours.
+func (s *romService) RecordInstall(machineId string, romId int, checksum string) error {
+	rom, err := s.GetRom(romId)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(rom.Sha256, checksum) {
+		utils.LogError("Machine %s installed ROM ID %d with checksum %s, expected %s", machineId, romId, checksum, rom.Sha256)
+		return ErrChecksumMismatch
+	}
+	return s.romRepository.RecordInstall(machineId, romId)
+}
+
+func (s *romService) activeSystem(systemId string) (models.EmulatorSystem, error) {
+	systems, err := s.romRepository.FetchSystems()
+	if err != nil {
+		return models.EmulatorSystem{}, err
+	}
+	for _, system := range systems {
+		if system.Id == systemId && system.IsActive {
+			return system, nil
+		}
+	}
+	return models.EmulatorSystem{}, ErrSystemNotFound
+}
+
+// romStorage is the same supabase storage the product images live in, the ROMs go to their own private bucket.
+func romStorage() (*s3.Client, error) {
+	supabaseURL := fmt.Sprintf(s3BaseUrl, config.GetString("supabaseProjectID"))
+	creds := credentials.NewStaticCredentialsProvider(config.GetString("key"), config.GetString("secret"), "")
+
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
+		awsconfig.WithRegion(config.GetString("region")),
+		awsconfig.WithCredentialsProvider(creds),
+	)
+	if err != nil {
+		utils.LogError("Unable to load SDK config: %v", err)
+		return nil, fmt.Errorf("error loading storage config: %w", err)
+	}
+
+	return s3.NewFromConfig(cfg, func(o *s3.Options) {
+		o.BaseEndpoint = &supabaseURL
+		o.UsePathStyle = true
+		o.Region = config.GetString("region")
+	}), nil
+}
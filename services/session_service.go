@@ -0,0 +1,70 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+var ErrSessionNotFound = errors.New("no open session for this code")
+
+// SessionListener is told about every session the cabinets close.
+type SessionListener interface {
+	SessionEnded(session models.PlaySession) error
+}
+
+type SessionService interface {
+	HandleEvent(event models.SessionEvent, machineId string) error
+	AddListener(listener SessionListener)
+}
+
+type sessionService struct {
+	sessionRepository repositories.SessionRepository
+	listeners         []SessionListener
+}
+
+func NewSessionService(sessionRepository repositories.SessionRepository) *sessionService {
+	return &sessionService{sessionRepository: sessionRepository}
+}
+
+func (s *sessionService) AddListener(listener SessionListener) {
+	s.listeners = append(s.listeners, listener)
+}
+
+func (s *sessionService) HandleEvent(event models.SessionEvent, machineId string) error {
+	if event.Level < 0 {
+		return fmt.Errorf("level can't be negative")
+	}
+
+	switch event.Type {
+	case models.SessionEventStart:
+		_, err := s.sessionRepository.StartSession(event.Code, event.GameId, machineId)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+
+	case models.SessionEventLevel:
+		return s.sessionRepository.RecordLevel(event.Code, event.Level)
+
+	case models.SessionEventEnd:
+		session, err := s.sessionRepository.EndSession(event.Code, event.Level, event.Completed)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		} else if err != nil {
+			return err
+		}
+
+		for _, listener := range s.listeners {
+			if err := listener.SessionEnded(session); err != nil {
+				utils.LogError("Session listener failed for session ID %d: %v", session.Id, err)
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown session event '%s'", event.Type)
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrVenueNotFound = errors.New("venue doesn't exist")
+var ErrVenueNotManaged = errors.New("you don't manage this venue")
+
+type VenueService interface {
+	GetVenues(scope []string) ([]models.Venue, error) // scope is the venues of the admin, nil for all
+	AddVenue(venue models.Venue) error
+	UpdateVenue(venue models.Venue) error
+	GetDetails(venueId string) (models.VenueDetails, error)
+
+	AdminVenues(adminId int) ([]string, error)
+	SetAdminVenues(adminId int, venues []string) error
+
+	SetGame(venueId string, game models.VenueGame) error
+	SetPrice(venueId string, price models.VenuePrice) error
+	SetStock(venueId string, stock models.VenueStock) error
+}
+
+type venueService struct {
+	venueRepository repositories.VenueRepository
+}
+
+func NewVenueService(venueRepository repositories.VenueRepository) *venueService {
+	return &venueService{venueRepository: venueRepository}
+}
+
+func (s *venueService) GetVenues(scope []string) ([]models.Venue, error) {
+	return s.venueRepository.FetchVenues(scope)
+}
+
+func (s *venueService) AddVenue(venue models.Venue) error {
+	if strings.TrimSpace(venue.Id) != venue.Id || strings.ContainsAny(venue.Id, " /?#") {
+		return fmt.Errorf("venue id can't have spaces or url characters")
+	}
+	return s.venueRepository.CreateVenue(venue)
+}
+
+func (s *venueService) UpdateVenue(venue models.Venue) error {
+	updated, err := s.venueRepository.UpdateVenue(venue)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrVenueNotFound
+	}
+	return nil
+}
+
+func (s *venueService) GetDetails(venueId string) (models.VenueDetails, error) {
+	venue, err := s.venue(venueId)
+	if err != nil {
+		return models.VenueDetails{}, err
+	}
+	return s.venueRepository.FetchDetails(venue)
+}
+
+func (s *venueService) AdminVenues(adminId int) ([]string, error) {
+	return s.venueRepository.FetchAdminVenues(adminId)
+}
+
+func (s *venueService) SetAdminVenues(adminId int, venues []string) error {
+	for _, venueId := range venues {
+		if _, err := s.venue(venueId); err != nil {
+			return fmt.Errorf("%w: %s", err, venueId)
+		}
+	}
+	return s.venueRepository.SetAdminVenues(adminId, venues)
+}
+
+func (s *venueService) SetGame(venueId string, game models.VenueGame) error {
+	if _, err := s.venue(venueId); err != nil {
+		return err
+	}
+	return s.venueRepository.SetGame(venueId, game)
+}
+
+func (s *venueService) SetPrice(venueId string, price models.VenuePrice) error {
+	if price.ItemType != "time" && price.ItemType != "level" {
+		return fmt.Errorf("item type should be either time or level")
+	}
+	if price.Price != nil && *price.Price == 0 {
+		return fmt.Errorf("price should be more than 0, turn the game off instead")
+	}
+	if _, err := s.venue(venueId); err != nil {
+		return err
+	}
+	return s.venueRepository.SetPrice(venueId, price)
+}
+
+func (s *venueService) SetStock(venueId string, stock models.VenueStock) error {
+	if stock.Units < 0 {
+		return fmt.Errorf("units can't be negative")
+	}
+	if _, err := s.venue(venueId); err != nil {
+		return err
+	}
+	return s.venueRepository.SetStock(venueId, stock)
+}
+
+func (s *venueService) venue(venueId string) (models.Venue, error) {
+	venues, err := s.venueRepository.FetchVenues([]string{venueId})
+	if err != nil {
+		return models.Venue{}, err
+	}
+	if len(venues) == 0 {
+		return models.Venue{}, ErrVenueNotFound
+	}
+	return venues[0], nil
+}
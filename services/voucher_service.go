@@ -0,0 +1,185 @@
+package services
+
+import (
+	"GameWala-Arcade/models"
+	"GameWala-Arcade/repositories"
+	"GameWala-Arcade/utils"
+	"encoding/base64"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"time"
+)
+
+// keeps a single request from locking up the code counter for too long.
+const maxVoucherBatchSize = 500
+
+var ErrInvalidVoucherBatch = errors.New("invalid voucher batch")
+
+type VoucherService interface {
+	IssueBatch(req models.VoucherBatchRequest, adminId int) (models.VoucherBatch, error)
+	GetBatches() ([]models.VoucherBatch, error)
+	GetBatch(batchId int) (models.VoucherBatch, error)
+	ExportCSV(batch models.VoucherBatch, w io.Writer) error
+	ExportQRSheet(batch models.VoucherBatch, w io.Writer) error
+}
+
+type voucherService struct {
+	voucherRepository repositories.VoucherRepository
+	playGameService   PlayGameService
+}
+
+func NewVoucherService(voucherRepository repositories.VoucherRepository,
+	playGameService PlayGameService) *voucherService {
+	return &voucherService{voucherRepository: voucherRepository, playGameService: playGameService}
+}
+
+// IssueBatch creates the batch and one prepaid game status per code, the payment
+// reference of every code points back to the batch so no payment is needed.
+// The codes are drawn first and saved with the batch in one transaction.
+func (s *voucherService) IssueBatch(req models.VoucherBatchRequest, adminId int) (models.VoucherBatch, error) {
+	utils.LogInfo("Processing voucher batch '%s' for game ID %d", req.Label, req.GameId)
+
+	if req.Count <= 0 || req.Count > maxVoucherBatchSize {
+		return models.VoucherBatch{}, fmt.Errorf("%w: count should be between 1 and %d", ErrInvalidVoucherBatch, maxVoucherBatchSize)
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return models.VoucherBatch{}, fmt.Errorf("%w: expiry date is already in the past", ErrInvalidVoucherBatch)
+	}
+
+	status := models.GameStatus{
+		Name:      req.Name,
+		GameId:    req.GameId,
+		IsTimed:   req.IsTimed,
+		Price:     req.Price,
+		PlayTime:  req.PlayTime,
+		Levels:    req.Levels,
+		ExpiresAt: req.ExpiresAt,
+	}
+
+	if _, err := s.playGameService.ValidatePrice(status); err != nil {
+		return models.VoucherBatch{}, fmt.Errorf("%w: price doesn't match the game tier", ErrInvalidVoucherBatch)
+	}
+
+	codes := make([]string, req.Count)
+	for i := range codes {
+		code, err := s.playGameService.GenerateCode()
+		if err != nil {
+			utils.LogError("Voucher batch '%s' couldn't draw code %d of %d: %v", req.Label, i+1, req.Count, err)
+			return models.VoucherBatch{}, err
+		}
+		codes[i] = code
+	}
+
+	batchId, err := s.voucherRepository.CreateBatch(req, adminId, codes)
+	if err != nil {
+		return models.VoucherBatch{}, err
+	}
+
+	utils.LogInfo("Successfully issued %d voucher codes in batch ID %d", req.Count, batchId)
+	return s.GetBatch(batchId)
+}
+
+func (s *voucherService) GetBatches() ([]models.VoucherBatch, error) {
+	return s.voucherRepository.FetchBatches()
+}
+
+func (s *voucherService) GetBatch(batchId int) (models.VoucherBatch, error) {
+	batch, err := s.voucherRepository.FetchBatch(batchId)
+	if err != nil {
+		return batch, err
+	}
+
+	batch.Codes, err = s.voucherRepository.FetchVoucherCodes(batchId)
+	return batch, err
+}
+
+func (s *voucherService) ExportCSV(batch models.VoucherBatch, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"code", "game", "play_time", "levels", "expires_at", "played"})
+
+	for _, code := range batch.Codes {
+		writer.Write([]string{
+			code.Code,
+			batch.Name,
+			optionalNumber(batch.PlayTime),
+			optionalNumber(batch.Levels),
+			optionalDate(batch.ExpiresAt),
+			strconv.FormatBool(code.IsPlayed),
+		})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+var qrSheetTemplate = template.Must(template.New("sheet").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Batch.Label}}</title>
+<style>
+body { font-family: sans-serif; }
+.sheet { display: flex; flex-wrap: wrap; }
+.voucher { width: 45mm; margin: 4mm; padding: 3mm; border: 1px dashed #999; text-align: center; page-break-inside: avoid; }
+.voucher img { width: 38mm; height: 38mm; }
+.code { font-size: 16pt; letter-spacing: 2px; font-weight: bold; }
+</style>
+</head>
+<body>
+<h2>{{.Batch.Label}} - {{.Batch.Name}}</h2>
+<div class="sheet">
+{{range .Vouchers}}<div class="voucher">
+<img src="data:image/png;base64,{{.Image}}" alt="{{.Code}}">
+<div class="code">{{.Code}}</div>
+{{if $.Batch.ExpiresAt}}<div>valid till {{$.Batch.ExpiresAt.Format "02 Jan 2006"}}</div>{{end}}
+</div>
+{{end}}</div>
+</body>
+</html>
+`))
+
+// ExportQRSheet renders a printable html page with a QR for every unplayed code of the batch.
+func (s *voucherService) ExportQRSheet(batch models.VoucherBatch, w io.Writer) error {
+	type voucher struct {
+		Code  string
+		Image template.URL
+	}
+
+	var vouchers []voucher
+	for _, code := range batch.Codes {
+		if code.IsPlayed {
+			continue
+		}
+
+		png, _, err := utils.RenderQR(code.Code, utils.QRFormatPNG, 256)
+		if err != nil {
+			utils.LogError("Failed to render QR for code %s: %v", code.Code, err)
+			return fmt.Errorf("error rendering qr code: %w", err)
+		}
+		vouchers = append(vouchers, voucher{Code: code.Code, Image: template.URL(base64.StdEncoding.EncodeToString(png))})
+	}
+
+	return qrSheetTemplate.Execute(w, struct {
+		Batch    models.VoucherBatch
+		Vouchers []voucher
+	}{batch, vouchers})
+}
+
+func optionalNumber[T uint8 | uint16](n *T) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.Itoa(int(*n))
+}
+
+func optionalDate(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
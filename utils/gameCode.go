@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"GameWala-Arcade/config"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"time"
+)
+
+// controller friendly letters, every one of them can be typed with the arcade buttons.
+const defaultCodeAlphabet = "ABOSXY"
+const defaultCodeLength = 6
+
+// rounds of the feistel network used to shuffle the sequence numbers.
+const feistelRounds = 4
+
+// capacity has to stay below this so the feistel domain fits in an uint64.
+const maxCodeCapacity = uint64(1) << 62
+
+var ErrCodesExhausted = errors.New("every code of the configured length has been issued, increase codeLength")
+
+var ErrCodeKeyMissing = errors.New("codeKey is missing from the config, codes would be easy to guess")
+
+// CodeFormat describes how game codes look, Length is the encoded part only,
+// one extra check character is always appended at the end.
+// Key shuffles the sequence numbers so consecutive purchases don't get neighbouring codes,
+// changing it makes the already issued codes undecodable.
+// Codes issued before the check character are Length long, they're only taken until LegacyUntil.
+type CodeFormat struct {
+	Alphabet    []rune
+	Length      int
+	Key         []byte
+	LegacyUntil time.Time
+}
+
+// LoadCodeFormat reads "codeAlphabet", "codeLength" and "codeKey" from the config, the alphabet and
+// length fall back to the defaults if they are missing or unusable. The key has no default.
+func LoadCodeFormat() (CodeFormat, error) {
+	format := CodeFormat{Alphabet: []rune(defaultCodeAlphabet), Length: defaultCodeLength}
+
+	if alphabet := config.GetString("codeAlphabet"); alphabet != "" {
+		if hasDuplicateRunes(alphabet) || len([]rune(alphabet)) < 2 {
+			LogError("Ignoring code alphabet '%s', it needs at least 2 unique characters", alphabet)
+		} else {
+			format.Alphabet = []rune(alphabet)
+		}
+	}
+
+	if length := config.GetInt("codeLength"); length > 0 {
+		format.Length = length
+	}
+
+	for format.Capacity() == 0 {
+		LogError("Code length %d is too long for the alphabet, reducing it", format.Length)
+		format.Length--
+	}
+
+	format.Key = []byte(config.GetString("codeKey"))
+	if len(format.Key) == 0 {
+		return CodeFormat{}, ErrCodeKeyMissing
+	}
+
+	// without a date the codes without a check character aren't taken anymore.
+	if until := config.GetString("legacyCodesUntil"); until != "" {
+		legacyUntil, err := time.ParseInLocation("2006-01-02", until, time.Local)
+		if err != nil {
+			LogError("Ignoring legacyCodesUntil '%s', it should be a date like 2026-12-31", until)
+		} else {
+			format.LegacyUntil = legacyUntil
+		}
+	}
+
+	return format, nil
+}
+
+// IsLegacy tells if the code looks like one issued before the check character and those are still taken.
+func (f CodeFormat) IsLegacy(code string, now time.Time) bool {
+	runes := []rune(code)
+	if len(runes) != f.Length || !now.Before(f.LegacyUntil) {
+		return false
+	}
+	for _, r := range runes {
+		if f.indexOf(r) == -1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Capacity is the number of different codes this format can produce, 0 if it doesn't fit.
+func (f CodeFormat) Capacity() uint64 {
+	capacity := uint64(1)
+	for i := 0; i < f.Length; i++ {
+		capacity *= uint64(len(f.Alphabet))
+		if capacity >= maxCodeCapacity {
+			return 0
+		}
+	}
+	return capacity
+}
+
+// Encode turns a sequence number into a code, every sequence number below Capacity gives a different code.
+func (f CodeFormat) Encode(seq uint64) (string, error) {
+	capacity := f.Capacity()
+	if seq >= capacity {
+		return "", ErrCodesExhausted
+	}
+
+	n := f.permute(seq, capacity)
+	base := uint64(len(f.Alphabet))
+	body := make([]rune, f.Length)
+
+	for i := f.Length - 1; i >= 0; i-- {
+		body[i] = f.Alphabet[n%base]
+		n /= base
+	}
+
+	return string(append(body, f.checkChar(body))), nil
+}
+
+// Decode gives back the sequence number of a code produced by Encode.
+func (f CodeFormat) Decode(code string) (uint64, bool) {
+	if !f.IsValid(code) {
+		return 0, false
+	}
+
+	base := uint64(len(f.Alphabet))
+	n := uint64(0)
+	for _, r := range []rune(code)[:f.Length] {
+		n = n*base + uint64(f.indexOf(r))
+	}
+
+	return f.unpermute(n, f.Capacity()), true
+}
+
+// IsValid tells if the code has the right length, only known characters and a matching check character.
+func (f CodeFormat) IsValid(code string) bool {
+	runes := []rune(code)
+	if len(runes) != f.Length+1 {
+		return false
+	}
+
+	body := runes[:f.Length]
+	for _, r := range body {
+		if f.indexOf(r) == -1 {
+			return false
+		}
+	}
+
+	return runes[f.Length] == f.checkChar(body)
+}
+
+// checkChar is Luhn mod N over the alphabet, catches every single wrong
+// character and most of the swapped neighbours.
+func (f CodeFormat) checkChar(body []rune) rune {
+	base := len(f.Alphabet)
+	factor := 2
+	sum := 0
+
+	for i := len(body) - 1; i >= 0; i-- {
+		addend := factor * f.indexOf(body[i])
+		addend = addend/base + addend%base
+		sum += addend
+
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+
+	return f.Alphabet[(base-sum%base)%base]
+}
+
+// permute is a keyed bijection on [0, capacity), a feistel network on the
+// smallest even bit width that covers capacity, cycle walking until it lands inside.
+func (f CodeFormat) permute(n uint64, capacity uint64) uint64 {
+	half := feistelHalfBits(capacity)
+	n = f.feistel(n, half)
+	for n >= capacity {
+		n = f.feistel(n, half)
+	}
+	return n
+}
+
+func (f CodeFormat) unpermute(n uint64, capacity uint64) uint64 {
+	half := feistelHalfBits(capacity)
+	n = f.unfeistel(n, half)
+	for n >= capacity {
+		n = f.unfeistel(n, half)
+	}
+	return n
+}
+
+func (f CodeFormat) feistel(n uint64, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := n>>half, n&mask
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^(f.roundValue(round, right)&mask)
+	}
+	return left<<half | right
+}
+
+func (f CodeFormat) unfeistel(n uint64, half uint) uint64 {
+	mask := uint64(1)<<half - 1
+	left, right := n>>half, n&mask
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^(f.roundValue(round, left)&mask), left
+	}
+	return left<<half | right
+}
+
+func (f CodeFormat) roundValue(round int, half uint64) uint64 {
+	var input [9]byte
+	input[0] = byte(round)
+	binary.BigEndian.PutUint64(input[1:], half)
+
+	mac := hmac.New(sha256.New, f.Key)
+	mac.Write(input[:])
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+func feistelHalfBits(capacity uint64) uint {
+	width := uint(bits.Len64(capacity - 1))
+	if width < 2 {
+		width = 2
+	}
+	return (width + 1) / 2
+}
+
+func (f CodeFormat) indexOf(r rune) int {
+	for i, v := range f.Alphabet {
+		if v == r {
+			return i
+		}
+	}
+	return -1
+}
+
+func hasDuplicateRunes(s string) bool {
+	seen := make(map[rune]bool)
+	for _, r := range s {
+		if seen[r] {
+			return true
+		}
+		seen[r] = true
+	}
+	return false
+}
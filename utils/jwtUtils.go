@@ -3,6 +3,7 @@ package utils
 import (
 	"GameWala-Arcade/config"
 	"fmt"
+	"strings"
 	"time"
 
 	"net/http"
@@ -11,19 +12,33 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var secretKey = []byte(config.GetString("secretyKey"))
+// the audience keeps admin and player tokens apart, one can never be used as the other.
+const (
+	adminAudience  = "admin"
+	playerAudience = "player"
+)
+
+const PlayerTokenCookie = "player_token"
+
+// read lazily, the config isn't loaded yet when package level vars are initialised.
+func secretKey() []byte {
+	return []byte(config.GetString("secretyKey"))
+}
 
-func CreateToken(username string, id int) (string, error) {
+// CreateToken signs the admin token, venues limits the admin to those venues and is empty for a global admin.
+func CreateToken(username string, id int, venues []string) (string, error) {
 	// Creating a new JWT token with claims
 	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"sub":     username,
 		"user_id": id,                                     // Subject (user identifier)
 		"iss":     "GameWala",                             // Issuer
+		"aud":     adminAudience,                          // Audience
 		"exp":     time.Now().Add(time.Hour * 168).Unix(), // Expiration time
 		"iat":     time.Now().Unix(),                      // Issued at
+		"venues":  venues,
 	})
 
-	tokenString, err := claims.SignedString(secretKey)
+	tokenString, err := claims.SignedString(secretKey())
 	if err != nil {
 		return "", err
 	}
@@ -32,6 +47,19 @@ func CreateToken(username string, id int) (string, error) {
 	return tokenString, nil
 }
 
+func CreatePlayerToken(phone string, playerId int) (string, error) {
+	claims := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":       phone,
+		"player_id": playerId,
+		"iss":       "GameWala",
+		"aud":       playerAudience,
+		"exp":       time.Now().Add(time.Hour * 24 * 30).Unix(),
+		"iat":       time.Now().Unix(),
+	})
+
+	return claims.SignedString(secretKey())
+}
+
 func AuthenticateMiddleware(c *gin.Context) {
 	tokenString, err := c.Cookie("token")
 	if err != nil {
@@ -42,7 +70,7 @@ func AuthenticateMiddleware(c *gin.Context) {
 	}
 
 	// Verify the token
-	token, err := verifyToken(tokenString)
+	token, err := verifyToken(tokenString, adminAudience)
 	if err != nil {
 		fmt.Printf("Token verification failed: %v", err)
 		c.JSON(http.StatusUnauthorized, "Token verification failed")
@@ -68,13 +96,112 @@ func AuthenticateMiddleware(c *gin.Context) {
 
 	c.Set("user_id", int(userID))
 
+	// the venues are read at login, a change takes effect with the next login.
+	if venues, ok := claims["venues"].([]interface{}); ok && len(venues) > 0 {
+		scoped := make([]string, 0, len(venues))
+		for _, venue := range venues {
+			if id, ok := venue.(string); ok {
+				scoped = append(scoped, id)
+			}
+		}
+		c.Set("admin_venues", scoped)
+	}
+
+	c.Next()
+}
+
+// GlobalAdminMiddleware goes after AuthenticateMiddleware, it keeps venue admins out of the settings
+// shared by every venue.
+func GlobalAdminMiddleware(c *gin.Context) {
+	if AdminVenues(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins of every venue can do this"})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// AdminVenues gives the venues the logged in admin manages, nil when they manage every venue.
+func AdminVenues(c *gin.Context) []string {
+	venues, exists := c.Get("admin_venues")
+	if !exists {
+		return nil
+	}
+	return venues.([]string)
+}
+
+// ManagesVenue tells whether the logged in admin may see and change the venue.
+func ManagesVenue(c *gin.Context, venue string) bool {
+	return InScope(AdminVenues(c), venue)
+}
+
+// InScope tells whether the venue is one of the scope, a nil scope is an admin of every venue.
+func InScope(scope []string, venue string) bool {
+	if scope == nil {
+		return true
+	}
+	for _, id := range scope {
+		if id == venue {
+			return true
+		}
+	}
+	return false
+}
+
+// PlayerAuthMiddleware rejects the request unless a valid player token is sent,
+// either in the player cookie or as a bearer token for the mobile clients.
+func PlayerAuthMiddleware(c *gin.Context) {
+	playerId, err := playerFromRequest(c)
+	if err != nil {
+		LogError("Player authentication failed: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "please login to continue"})
+		c.Abort()
+		return
+	}
+
+	c.Set("player_id", playerId)
 	c.Next()
 }
 
-func verifyToken(tokenString string) (*jwt.Token, error) {
+// OptionalPlayerMiddleware never rejects, it only remembers the player when a valid token is sent.
+func OptionalPlayerMiddleware(c *gin.Context) {
+	if playerId, err := playerFromRequest(c); err == nil {
+		c.Set("player_id", playerId)
+	}
+	c.Next()
+}
+
+func playerFromRequest(c *gin.Context) (int, error) {
+	tokenString, err := c.Cookie(PlayerTokenCookie)
+	if err != nil {
+		tokenString = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if tokenString == "" {
+			return 0, fmt.Errorf("player token is missing")
+		}
+	}
+
+	token, err := verifyToken(tokenString, playerAudience)
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, fmt.Errorf("invalid token claims")
+	}
+
+	playerId, exists := claims["player_id"].(float64)
+	if !exists {
+		return 0, fmt.Errorf("player id not found in token")
+	}
+
+	return int(playerId), nil
+}
+
+func verifyToken(tokenString string, audience string) (*jwt.Token, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
-	})
+		return secretKey(), nil
+	}, jwt.WithAudience(audience), jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
 		return nil, err
@@ -96,3 +223,12 @@ func CheckCookies(c *gin.Context) int {
 	}
 	return UserId.(int)
 }
+
+// CheckPlayer gives the logged in player, 0 when nobody is logged in.
+func CheckPlayer(c *gin.Context) int {
+	playerId, exists := c.Get("player_id")
+	if !exists {
+		return 0
+	}
+	return playerId.(int)
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// RenderQR draws the content as a png or svg QR in process, returns the image and its content type.
+func RenderQR(content string, format string, size int) ([]byte, string, error) {
+	qr, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return nil, "", fmt.Errorf("error encoding qr code: %w", err)
+	}
+
+	switch format {
+	case QRFormatPNG:
+		png, err := qr.PNG(size)
+		if err != nil {
+			return nil, "", fmt.Errorf("error rendering qr png: %w", err)
+		}
+		return png, "image/png", nil
+	case QRFormatSVG:
+		return qrSVG(qr.Bitmap(), size), "image/svg+xml", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported qr format: %s", format)
+	}
+}
+
+// qrSVG draws one rect per dark module, the bitmap already includes the quiet zone.
+func qrSVG(bitmap [][]bool, size int) []byte {
+	var svg bytes.Buffer
+	fmt.Fprintf(&svg, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, len(bitmap), len(bitmap))
+	fmt.Fprintf(&svg, `<rect width="100%%" height="100%%" fill="#fff"/>`)
+
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&svg, `<rect x="%d" y="%d" width="1" height="1"/>`, x, y)
+			}
+		}
+	}
+
+	svg.WriteString(`</svg>`)
+	return svg.Bytes()
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"GameWala-Arcade/config"
+	"errors"
+	"fmt"
+)
+
+var ErrSMSProviderMissing = errors.New("smsProvider is missing from the config, set it to console and environment to development for local development")
+
+// SMSSender delivers text messages to a phone number, new providers only need to implement this.
+type SMSSender interface {
+	Send(phone string, message string) error
+}
+
+// ConsoleSMSSender prints the message instead of sending it, meant for local development.
+type ConsoleSMSSender struct{}
+
+func (ConsoleSMSSender) Send(phone string, message string) error {
+	fmt.Printf("SMS to %s: %s\n", phone, message)
+	LogInfo("SMS to %s: %s", phone, message)
+	return nil
+}
+
+// NewSMSSender picks the sender from the "smsProvider" config. There is no default, a missing or unknown
+// provider would leave the OTPs printed to the console of a server nobody reads.
+func NewSMSSender() (SMSSender, error) {
+	switch provider := config.GetString("smsProvider"); provider {
+	case "":
+		return nil, ErrSMSProviderMissing
+	case "console":
+		// the otps would end up in the logs of a real server.
+		if config.GetString("environment") != "development" {
+			return nil, errors.New("smsProvider console prints the otps, it's only allowed with environment development")
+		}
+		LogInfo("smsProvider is console, text messages are only printed")
+		return ConsoleSMSSender{}, nil
+	default:
+		return nil, fmt.Errorf("unknown smsProvider '%s'", provider)
+	}
+}